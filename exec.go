@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/config"
+	"github.com/rivethorn/envoy/internal/cred"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+	"github.com/rivethorn/envoy/internal/ui"
+)
+
+const execUsage = "usage: envoy [-layer file]... exec [-backend provider] [-pristine] <service>... -- <cmd> [args...]"
+
+// execCommand runs "envoy exec" as chamber exec and envconsul -prefix do:
+// the variables under each service, pulled from the backend in order and
+// named as chamber names them, are set over the process environment and
+// the -layer files, and the command is executed with them. A service is a
+// parameter hierarchy, /<service>/, with the aws backend and a key prefix
+// with the others.
+func execCommand(sources []ui.Source, args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	backend := fs.String("backend", "aws", "pull services from `provider`")
+	pristine := fs.Bool("pristine", false, "start from an empty environment rather than the process's")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	i := slices.Index(rest, "--")
+	if i < 1 || i == len(rest)-1 {
+		return errors.New(execUsage)
+	}
+	services, argv := rest[:i], rest[i+1:]
+
+	vars := make(map[string]string)
+	if !*pristine {
+		for _, kv := range os.Environ() {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				vars[k] = v
+			}
+		}
+	}
+	for _, s := range sources {
+		if s.Remote {
+			return fmt.Errorf("exec takes services, not remote layers (%s)", s.Spec)
+		}
+		items, err := env.ParseFile(s.Spec)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			vars[it.Key] = it.Value
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	p, err := remote.Open(*backend, remote.Config{Creds: cred.Open(), Sources: cfg.Sources, KV: cfg.KV})
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for _, service := range services {
+		entries, err := p.Pull(ctx, servicePath(*backend, service))
+		if err != nil {
+			return fmt.Errorf("%s: %w", service, err)
+		}
+		for _, e := range entries {
+			key := env.ChamberName(e.Key)
+			if _, ok := vars[key]; ok {
+				fmt.Fprintf(os.Stderr, "warning: service %s overwriting environment variable %s\n", service, key)
+			}
+			vars[key] = e.Value
+		}
+	}
+
+	environ := make([]string, 0, len(vars))
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		environ = append(environ, k+"="+vars[k])
+	}
+	if path, ok := vars["PATH"]; ok {
+		os.Setenv("PATH", path)
+	}
+	return execve(argv, environ)
+}
+
+// servicePath turns a chamber service into the path backend pulls it
+// from.
+func servicePath(backend, service string) string {
+	service = strings.Trim(service, "/")
+	if backend == "aws" {
+		return "/" + service
+	}
+	return service
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+
+	"github.com/rivethorn/envoy/internal/secmem"
+)
+
+// execve replaces Envoy with argv run in environ.
+func execve(argv, environ []string) error {
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	secmem.Purge()
+	return syscall.Exec(path, argv, environ)
+}
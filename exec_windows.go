@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/rivethorn/envoy/internal/secmem"
+)
+
+// execve runs argv in environ and exits with its status; Windows cannot
+// replace a running process. Ctrl+C reaches the child through the shared
+// console, so Envoy ignores it and waits.
+func execve(argv, environ []string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = environ
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	signal.Ignore(os.Interrupt)
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		return err
+	}
+	secmem.Purge()
+	os.Exit(cmd.ProcessState.ExitCode())
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package bundle reads and writes Envoy state bundles: a gzipped tar
+// holding a session's variables with their metadata, so a curated setup can
+// be handed to someone else or carried to another machine.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/explain"
+)
+
+// Version is the bundle layout written by Write. Read rejects newer ones.
+const Version = 1
+
+// Archive members.
+const (
+	manifestName = "manifest.json"
+	varsName     = "vars.json"
+	explainName  = "explain.json"
+	metaName     = "meta.json"
+)
+
+// maxMember bounds how much of one member Read will decode.
+const maxMember = 64 << 20
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Envoy   string    `json:"envoy"` // version that wrote it
+}
+
+// Bundle is the content of a bundle file.
+type Bundle struct {
+	Manifest
+	Vars    []env.KeyState
+	Explain map[string]explain.Entry // user descriptions
+	Meta    map[string]string        // meta-variables the values refer to
+}
+
+// Write encodes b as a gzipped tar.
+func Write(w io.Writer, b *Bundle) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	type member struct {
+		name string
+		v    any
+	}
+	members := []member{{manifestName, b.Manifest}, {varsName, b.Vars}}
+	if len(b.Explain) > 0 {
+		members = append(members, member{explainName, b.Explain})
+	}
+	if len(b.Meta) > 0 {
+		members = append(members, member{metaName, b.Meta})
+	}
+	for _, m := range members {
+		data, err := json.MarshalIndent(m.v, "", "  ")
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    m.name,
+			Mode:    0o600,
+			Size:    int64(len(data)),
+			ModTime: b.Created,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read decodes a bundle written by Write. Unknown members are ignored.
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	defer gz.Close()
+
+	b := &Bundle{}
+	seen := false
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		switch hdr.Name {
+		case manifestName:
+			v, seen = &b.Manifest, true
+		case varsName:
+			v = &b.Vars
+		case explainName:
+			v = &b.Explain
+		case metaName:
+			v = &b.Meta
+		default:
+			continue
+		}
+		if err := json.NewDecoder(io.LimitReader(tr, maxMember)).Decode(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+	if !seen {
+		return nil, errors.New("not a bundle: no manifest")
+	}
+	if b.Version > Version {
+		return nil, fmt.Errorf("bundle version %d is newer than this Envoy supports (%d)", b.Version, Version)
+	}
+	return b, nil
+}
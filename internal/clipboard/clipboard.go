@@ -0,0 +1,52 @@
+// Package clipboard reads the system clipboard through the platform's
+// command line tools.
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no clipboard tool is installed.
+var ErrUnavailable = errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+
+// readers lists the commands that print the clipboard, in order of
+// preference for this platform.
+func readers() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	}
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-paste", "--no-newline"})
+	}
+	return append(cmds,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+	)
+}
+
+// Read returns the clipboard's text.
+func Read() (string, error) {
+	for _, argv := range readers() {
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(argv[0], argv[1:]...).Output()
+		if err != nil {
+			var ee *exec.ExitError
+			if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+				return "", errors.New(strings.TrimSpace(string(ee.Stderr)))
+			}
+			return "", err
+		}
+		return strings.ReplaceAll(string(out), "\r\n", "\n"), nil
+	}
+	return "", ErrUnavailable
+}
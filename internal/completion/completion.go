@@ -0,0 +1,259 @@
+// Package completion generates shell completion scripts for the envoy CLI.
+package completion
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kind says how a flag's value is completed.
+type Kind int
+
+const (
+	None    Kind = iota // boolean flag, takes no value
+	Any                 // free text
+	File                // any file
+	EnvFile             // .env, .env.* and *.env files in the CWD
+	Remote              // provider:path
+	Level               // log level
+)
+
+type Command struct {
+	Name, Usage string
+}
+
+type Flag struct {
+	Name, Usage string
+	Kind        Kind
+}
+
+// Spec describes the command line to complete.
+type Spec struct {
+	Commands  []Command
+	Flags     []Flag
+	Providers []string
+	Levels    []string
+}
+
+// FromFlagSet lists fs's flags. kinds gives the completion for value
+// flags; value flags missing from it complete as free text.
+func FromFlagSet(fs *flag.FlagSet, kinds map[string]Kind) []Flag {
+	var out []Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		_, usage := flag.UnquoteUsage(f)
+		k, ok := kinds[f.Name]
+		if !ok {
+			k = Any
+			if b, isBool := f.Value.(interface{ IsBoolFlag() bool }); isBool && b.IsBoolFlag() {
+				k = None
+			}
+		}
+		out = append(out, Flag{Name: f.Name, Usage: usage, Kind: k})
+	})
+	return out
+}
+
+// Shells lists the supported shells.
+func Shells() []string {
+	return []string{"bash", "fish", "zsh"}
+}
+
+// Write prints the completion script for shell.
+func Write(w io.Writer, shell string, s Spec) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bash(s)
+	case "zsh":
+		script = zsh(s)
+	case "fish":
+		script = fish(s)
+	default:
+		return fmt.Errorf("unsupported shell %q (want %s)", shell, strings.Join(Shells(), ", "))
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+func (s Spec) commandNames() []string {
+	var names []string
+	for _, c := range s.Commands {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func (s Spec) flagsOf(k Kind) []string {
+	var names []string
+	for _, f := range s.Flags {
+		if f.Kind == k {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func (s Spec) remotePrefixes() string {
+	var out []string
+	for _, p := range s.Providers {
+		out = append(out, p+":")
+	}
+	return strings.Join(out, " ")
+}
+
+// bashFlagCase builds a case pattern matching -name and --name.
+func bashFlagCase(names []string) string {
+	var pats []string
+	for _, n := range names {
+		pats = append(pats, "-"+n, "--"+n)
+	}
+	return strings.Join(pats, "|")
+}
+
+func bash(s Spec) string {
+	var flags []string
+	for _, f := range s.Flags {
+		flags = append(flags, "-"+f.Name)
+	}
+	var b strings.Builder
+	b.WriteString("# bash completion for envoy\n")
+	b.WriteString("_envoy_envfiles() {\n")
+	b.WriteString("\tlocal f\n")
+	b.WriteString("\tfor f in $(compgen -f -- \"$1\"); do\n")
+	b.WriteString("\t\tcase \"${f##*/}\" in .env|.env.*|*.env) echo \"$f\" ;; esac\n")
+	b.WriteString("\tdone\n")
+	b.WriteString("\tcompgen -d -- \"$1\"\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_envoy() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	kinds := []struct {
+		k   Kind
+		cmd string
+	}{
+		{EnvFile, "compopt -o filenames; COMPREPLY=($(_envoy_envfiles \"$cur\"))"},
+		{File, "compopt -o filenames; COMPREPLY=($(compgen -f -- \"$cur\"))"},
+		{Remote, fmt.Sprintf("compopt -o nospace; COMPREPLY=($(compgen -W %q -- \"$cur\"))", s.remotePrefixes())},
+		{Level, fmt.Sprintf("COMPREPLY=($(compgen -W %q -- \"$cur\"))", strings.Join(s.Levels, " "))},
+		{Any, "COMPREPLY=()"},
+	}
+	for _, k := range kinds {
+		if names := s.flagsOf(k.k); len(names) > 0 {
+			fmt.Fprintf(&b, "\t%s)\n\t\t%s\n\t\treturn ;;\n", bashFlagCase(names), k.cmd)
+		}
+	}
+	b.WriteString("\tcompletion)\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(Shells(), " "))
+	b.WriteString("\t\treturn ;;\n")
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(flags, " "))
+	b.WriteString("\telse\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(s.commandNames(), " "))
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -F _envoy envoy\n")
+	return b.String()
+}
+
+// zshDesc escapes text for an _arguments description.
+func zshDesc(s string) string {
+	r := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+	return r.Replace(s)
+}
+
+func zsh(s Spec) string {
+	var b strings.Builder
+	b.WriteString("#compdef envoy\n\n")
+	b.WriteString("_envoy_commands() {\n")
+	b.WriteString("\tlocal -a cmds\n")
+	b.WriteString("\tcmds=(\n")
+	for _, c := range s.Commands {
+		fmt.Fprintf(&b, "\t\t'%s:%s'\n", c.Name, zshDesc(c.Usage))
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\t_describe command cmds\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_envoy_args() {\n")
+	fmt.Fprintf(&b, "\t[[ $line[1] == completion ]] && _values shell %s\n", strings.Join(Shells(), " "))
+	b.WriteString("}\n\n")
+	b.WriteString("_envoy() {\n")
+	b.WriteString("\t_arguments \\\n")
+	for _, f := range s.Flags {
+		spec := fmt.Sprintf("-%s[%s]", f.Name, zshDesc(f.Usage))
+		switch f.Kind {
+		case None:
+		case EnvFile:
+			spec += ":file:_files -g '(.env|.env.*|*.env)(-.)'"
+		case File:
+			spec += ":file:_files"
+		case Remote:
+			spec += fmt.Sprintf(":remote:(%s)", s.remotePrefixes())
+		case Level:
+			spec += fmt.Sprintf(":level:(%s)", strings.Join(s.Levels, " "))
+		default:
+			spec += ":value: "
+		}
+		if f.Kind == EnvFile || f.Kind == Remote {
+			spec = "*" + spec // repeatable
+		}
+		fmt.Fprintf(&b, "\t\t%s \\\n", zshQuote(spec))
+	}
+	b.WriteString("\t\t'1: :_envoy_commands' \\\n")
+	b.WriteString("\t\t'2: :_envoy_args'\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_envoy \"$@\"\n")
+	return b.String()
+}
+
+// zshQuote single-quotes an _arguments spec; descriptions are already
+// escaped by zshDesc, so only the outer quoting is added here.
+func zshQuote(spec string) string {
+	if strings.Contains(spec, "_files -g '") {
+		// The glob needs its own quotes; switch to double quotes.
+		return `"` + strings.ReplaceAll(spec, `"`, `\"`) + `"`
+	}
+	return "'" + spec + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func fish(s Spec) string {
+	var b strings.Builder
+	b.WriteString("# fish completion for envoy\n")
+	b.WriteString("function __envoy_envfiles\n")
+	b.WriteString("\tfor f in .env .env.* *.env\n")
+	b.WriteString("\t\ttest -f $f; and echo $f\n")
+	b.WriteString("\tend\n")
+	b.WriteString("end\n\n")
+	b.WriteString("complete -c envoy -f\n")
+	for _, c := range s.Commands {
+		fmt.Fprintf(&b, "complete -c envoy -n __fish_use_subcommand -a %s -d %s\n", c.Name, fishQuote(c.Usage))
+	}
+	fmt.Fprintf(&b, "complete -c envoy -n '__fish_seen_subcommand_from completion' -a %s\n", fishQuote(strings.Join(Shells(), " ")))
+	for _, f := range s.Flags {
+		opt := "-o " + f.Name
+		if len(f.Name) == 1 {
+			opt = "-s " + f.Name
+		}
+		line := fmt.Sprintf("complete -c envoy %s -d %s", opt, fishQuote(f.Usage))
+		switch f.Kind {
+		case EnvFile:
+			line += " -r -a '(__envoy_envfiles)'"
+		case File:
+			line += " -r -F"
+		case Remote:
+			line += " -x -a " + fishQuote(s.remotePrefixes())
+		case Level:
+			line += " -x -a " + fishQuote(strings.Join(s.Levels, " "))
+		case Any:
+			line += " -x"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+// Package config loads the user's Envoy settings.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/explain"
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+type Config struct {
+	Export Export `json:"export"`
+	Update Update `json:"update"`
+
+	// Input selects the key bindings: "vim" (the default) or "basic".
+	Input string `json:"input"`
+
+	// Sort orders the table's keys: "alpha" (the default) or "natural",
+	// which puts SERVER2 before SERVER10.
+	Sort string `json:"sort"`
+
+	// Keys is the rule for new keys: POSIX names unless Pattern says
+	// otherwise, warned about unless Reject is set.
+	Keys env.KeyPolicy `json:"keys"`
+
+	// Unmask shows secret-looking values in the clear rather than as ••••.
+	Unmask bool `json:"unmask"`
+
+	// Types shows a column with each value's inferred type.
+	Types bool `json:"types"`
+
+	// Palette colors the table: "default", "high-contrast", "colorblind"
+	// or "none". NO_COLOR and --no-color select "none".
+	Palette string `json:"palette"`
+
+	// Accessible turns on accessibility mode: the status line describes
+	// the selection, and selection, focus and status changes are announced
+	// as text, also to the file or named pipe Announce names.
+	Accessible bool   `json:"accessible"`
+	Announce   string `json:"announce"`
+
+	Lock Lock `json:"lock"`
+
+	// Highlight colors the table's rows by their keys and values.
+	Highlight []Highlight `json:"highlight"`
+
+	// StatusLine lays out the status line as a template of % segments,
+	// such as "%m %f %r/%R %q %d"; see the ui package for the list. Empty
+	// keeps the built-in layout.
+	StatusLine string `json:"statusline"`
+
+	// Diff controls how changed values are compared.
+	Diff Diff `json:"diff"`
+
+	// Sources defines generic HTTP and GraphQL services usable wherever a
+	// provider name is, as in :pull <source> <path>.
+	Sources map[string]remote.HTTPSource `json:"sources"`
+
+	// KV maps the keys of the consul and etcd providers to names.
+	KV remote.KVKeys `json:"kv"`
+
+	// Explain adds to or replaces the bundled variable descriptions.
+	Explain map[string]explain.Entry `json:"explain"`
+}
+
+// Diff selects the algorithm, "myers" (the default) or "histogram", and
+// the unit, "char" (the default) or "word", of value diffs.
+type Diff struct {
+	Algorithm string `json:"algorithm"`
+	Unit      string `json:"unit"`
+}
+
+// Lock blanks the screen after IdleMinutes without a key press (0, the
+// default, never does) and on :lock. Any key unlocks it unless
+// PassphraseSHA256 holds the hex SHA-256 digest of a passphrase, as printed
+// by "printf %s phrase | sha256sum".
+type Lock struct {
+	IdleMinutes      int    `json:"idle_minutes"`
+	PassphraseSHA256 string `json:"passphrase_sha256"`
+}
+
+// Highlight is a rule for coloring rows: it applies to the rows whose key
+// matches the Key regexp and whose value matches the Value regexp, either
+// of which may be left out. Color and Background are color names or
+// #rrggbb; Style is a comma-separated list of bold, dim, italic, underline,
+// blink and reverse. Cell limits it to the "key" or "value" cell. Rules
+// apply in order, so a later one wins where two set the same thing; the
+// colors Envoy gives modified, broken or secret values win over both.
+type Highlight struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Color      string `json:"color"`
+	Background string `json:"background"`
+	Style      string `json:"style"`
+	Cell       string `json:"cell"`
+}
+
+// Update controls the background release check. It is off by default.
+type Update struct {
+	Check bool `json:"check"`
+}
+
+// Export holds defaults for :w. Files maps a glob, matched against the
+// output path or its base name, to settings for matching files.
+type Export struct {
+	Order      string                `json:"order"`
+	Files      map[string]FileExport `json:"files"`
+	Provenance bool                  `json:"provenance"` // "# from:" comments per key
+
+	// Redact defines redaction profiles for :w --redact, adding to or
+	// replacing the built-in ones.
+	Redact map[string]env.Redaction `json:"redact"`
+
+	// Sign writes a detached GnuPG signature (file.asc) next to every
+	// export, made with SignKey or gpg's default key.
+	Sign    bool   `json:"sign"`
+	SignKey string `json:"sign_key"`
+}
+
+type FileExport struct {
+	Order string   `json:"order"`
+	Keys  []string `json:"keys"` // key order when Order is "schema"
+}
+
+// Dir is the directory holding Envoy's configuration files.
+func Dir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "envoy")
+}
+
+// Path is the config file location; ENVOY_CONFIG overrides it.
+func Path() string {
+	if p := os.Getenv("ENVOY_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "config.json")
+}
+
+// Load reads the config file. A missing file yields the defaults.
+func Load() (*Config, error) {
+	c := &Config{}
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return &Config{}, err
+	}
+	return c, nil
+}
+
+// Redaction looks up a redaction profile, the config's first.
+func (c *Config) Redaction(name string) (env.Redaction, bool) {
+	if r, ok := c.Export.Redact[name]; ok {
+		return r, true
+	}
+	r, ok := env.Redactions[name]
+	return r, ok
+}
+
+// RedactionNames lists every profile name, sorted.
+func (c *Config) RedactionNames() []string {
+	names := env.RedactionNames()
+	for n := range c.Export.Redact {
+		if !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ExportFor returns the export settings for path: the first matching file
+// entry in pattern order, falling back to the global order.
+func (c *Config) ExportFor(path string) FileExport {
+	base := filepath.Base(path)
+	for _, pattern := range slices.Sorted(maps.Keys(c.Export.Files)) {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return c.Export.Files[pattern]
+		}
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return c.Export.Files[pattern]
+		}
+	}
+	return FileExport{Order: c.Export.Order}
+}
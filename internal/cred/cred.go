@@ -0,0 +1,180 @@
+// Package cred stores tokens for remote providers outside the process
+// environment.
+package cred
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var ErrNotFound = errors.New("credential not found")
+
+// ErrLocked is returned by a store that needs a passphrase it was not
+// given.
+var ErrLocked = errors.New("passphrase required")
+
+// Store keeps one token per provider name.
+type Store interface {
+	Get(provider string) (string, error)
+	Set(provider, token string) error
+	Delete(provider string) error
+}
+
+// envFallback maps providers to the variables they are conventionally
+// configured with, used when nothing is stored.
+var envFallback = map[string]string{
+	"vault":  "VAULT_TOKEN",
+	"github": "GITHUB_TOKEN",
+	"aws":    "AWS_SESSION_TOKEN",
+	"consul": "CONSUL_HTTP_TOKEN",
+}
+
+// Open returns the OS keychain when one is reachable, otherwise an
+// encrypted file under the user config directory. The file store needs a
+// passphrase before use.
+func Open() Store {
+	if k := Keychain("envoy"); k != nil {
+		return k
+	}
+	return &File{Path: DefaultFilePath()}
+}
+
+// DefaultFilePath is where the encrypted file store lives.
+func DefaultFilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "envoy", "credentials.enc")
+}
+
+// Lookup returns the stored token for provider, falling back to the
+// provider's conventional environment variable when none is stored or the
+// store is locked.
+func Lookup(s Store, provider string) (string, error) {
+	if s != nil {
+		tok, err := s.Get(provider)
+		if err == nil {
+			return tok, nil
+		}
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrLocked) {
+			return "", err
+		}
+	}
+	if name, ok := envFallback[provider]; ok {
+		if v := os.Getenv(name); v != "" {
+			return v, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Keyring talks to the OS keychain through its command line tool.
+type Keyring struct {
+	Service string
+	tool    string
+}
+
+// NewKeyring returns a keyring store, or nil if no supported tool is
+// installed.
+func NewKeyring() *Keyring {
+	tool := "secret-tool"
+	if runtime.GOOS == "darwin" {
+		tool = "security"
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil
+	}
+	return &Keyring{Service: "envoy", tool: tool}
+}
+
+func (k *Keyring) Get(provider string) (string, error) {
+	if k.tool == "security" {
+		return k.securityGet(provider)
+	}
+	out, err := exec.Command(k.tool, "lookup", "service", k.Service, "provider", provider).Output()
+	tok := strings.TrimRight(string(out), "\n")
+	if err != nil || tok == "" {
+		return "", ErrNotFound
+	}
+	return tok, nil
+}
+
+func (k *Keyring) Set(provider, token string) error {
+	var cmd *exec.Cmd
+	if k.tool == "security" {
+		// Interactively, so the token is read from stdin rather than
+		// passed where ps shows it; as hex, so no value needs quoting.
+		cmd = exec.Command(k.tool, "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+			securityQuote(k.Service), securityQuote(provider), hex.EncodeToString([]byte(token))))
+	} else {
+		cmd = exec.Command(k.tool, "store", "--label", k.Service+" "+provider, "service", k.Service, "provider", provider)
+		cmd.Stdin = strings.NewReader(token)
+	}
+	return runQuiet(cmd)
+}
+
+// securityGet reads an entry with security -g, which prints a password
+// that is not plain text, such as a multi-line value, in hex; -w would
+// print that hex as the password.
+func (k *Keyring) securityGet(provider string) (string, error) {
+	out, err := exec.Command(k.tool, "find-generic-password", "-s", k.Service, "-a", provider, "-g").CombinedOutput()
+	if err != nil {
+		return "", ErrNotFound
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		rest, ok := strings.CutPrefix(line, "password: ")
+		if !ok {
+			continue
+		}
+		var tok string
+		if h, ok := strings.CutPrefix(rest, "0x"); ok {
+			h, _, _ = strings.Cut(h, " ")
+			b, err := hex.DecodeString(h)
+			if err != nil {
+				return "", err
+			}
+			tok = string(b)
+		} else if len(rest) >= 2 && rest[0] == '"' && rest[len(rest)-1] == '"' {
+			tok = rest[1 : len(rest)-1]
+		}
+		if tok == "" {
+			return "", ErrNotFound
+		}
+		return tok, nil
+	}
+	return "", ErrNotFound
+}
+
+// securityQuote quotes s as an argument for security -i.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (k *Keyring) Delete(provider string) error {
+	var cmd *exec.Cmd
+	if k.tool == "security" {
+		cmd = exec.Command(k.tool, "delete-generic-password", "-s", k.Service, "-a", provider)
+	} else {
+		cmd = exec.Command(k.tool, "clear", "service", k.Service, "provider", provider)
+	}
+	return runQuiet(cmd)
+}
+
+func runQuiet(cmd *exec.Cmd) error {
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cred
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	saltLen    = 16
+	kdfRounds  = 600_000
+	fileHeader = "envoy-cred-v1\n"
+)
+
+// File keeps tokens in a JSON map encrypted with AES-GCM, keyed by a
+// passphrase through PBKDF2.
+type File struct {
+	Path       string
+	Passphrase string
+}
+
+func (f *File) Get(provider string) (string, error) {
+	if _, err := os.Stat(f.Path); errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	m, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	tok, ok := m[provider]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return tok, nil
+}
+
+func (f *File) Set(provider, token string) error {
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	m[provider] = token
+	return f.save(m)
+}
+
+func (f *File) Delete(provider string) error {
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := m[provider]; !ok {
+		return ErrNotFound
+	}
+	delete(m, provider)
+	return f.save(m)
+}
+
+func (f *File) load() (map[string]string, error) {
+	if f.Passphrase == "" {
+		return nil, ErrLocked
+	}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(fileHeader)+saltLen || string(data[:len(fileHeader)]) != fileHeader {
+		return nil, errors.New("credential file is corrupt")
+	}
+	data = data[len(fileHeader):]
+	gcm, err := f.cipher(data[:saltLen])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltLen:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("credential file is corrupt")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase")
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(plain, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *File) save(m map[string]string) error {
+	plain, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := f.cipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := append([]byte(fileHeader), salt...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, plain, nil)
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, out, 0o600)
+}
+
+func (f *File) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, f.Passphrase, salt, kdfRounds, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package cred
+
+// Keychain returns the OS keychain entries of service, through the macOS
+// security tool or the secret-service secret-tool, or nil when neither is
+// installed.
+func Keychain(service string) Store {
+	k := NewKeyring()
+	if k == nil {
+		return nil
+	}
+	k.Service = service
+	return k
+}
@@ -0,0 +1,112 @@
+package cred
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// Keychain returns the Windows Credential Manager entries of service.
+func Keychain(service string) Store {
+	return &WinCred{Service: service}
+}
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead   = advapi32.NewProc("CredReadW")
+	procCredWrite  = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential mirrors the Win32 CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// WinCred keeps generic credentials in the Windows Credential Manager,
+// one per account, targeted service/account.
+type WinCred struct {
+	Service string
+}
+
+func (w *WinCred) target(account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(w.Service + "/" + account)
+}
+
+func (w *WinCred) Get(account string) (string, error) {
+	target, err := w.target(account)
+	if err != nil {
+		return "", err
+	}
+	var c *credential
+	r, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&c)))
+	if r == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(c)))
+	if c.CredentialBlobSize == 0 {
+		return "", ErrNotFound
+	}
+	return string(unsafe.Slice(c.CredentialBlob, c.CredentialBlobSize)), nil
+}
+
+func (w *WinCred) Set(account, token string) error {
+	target, err := w.target(account)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(token)
+	c := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		c.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&c)), 0); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (w *WinCred) Delete(account string) error {
+	target, err := w.target(account)
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		if errors.Is(err, errorNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
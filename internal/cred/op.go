@@ -0,0 +1,143 @@
+package cred
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// OpScheme prefixes 1Password secret references: op://vault/item/field,
+// or op://vault/item/section/field. The op CLI reads them.
+const OpScheme = "op://"
+
+// opTimeout bounds one op call, which may wait for the desktop app to
+// unlock.
+const opTimeout = 2 * time.Minute
+
+// ErrNoOp is returned when the 1Password CLI is not installed.
+var ErrNoOp = errors.New("1Password CLI (op) not found on PATH")
+
+// OpRef returns the reference to field of item in vault.
+func OpRef(vault, item, field string) string {
+	return OpScheme + vault + "/" + item + "/" + field
+}
+
+// ParseOpRef splits an op:// reference into its vault, item and field; a
+// section, when present, stays in front of the field.
+func ParseOpRef(value string) (vault, item, field string, ok bool) {
+	rest, ok := strings.CutPrefix(value, OpScheme)
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// IsOpRef reports whether value is an op:// reference.
+func IsOpRef(value string) bool {
+	_, _, _, ok := ParseOpRef(value)
+	return ok
+}
+
+// op runs the 1Password CLI, returning its output or its error message.
+func op(ctx context.Context, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("op"); err != nil {
+		return nil, ErrNoOp
+	}
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "op", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimPrefix(strings.TrimSpace(stderr.String()), "[ERROR] ")
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("op %s: %s", args[0], msg)
+	}
+	return out, nil
+}
+
+// ResolveOp reads the secret an op:// reference names.
+func ResolveOp(ctx context.Context, ref string) (string, error) {
+	out, err := op(ctx, "read", "--no-newline", ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ref, err)
+	}
+	return string(out), nil
+}
+
+// StoreOp puts value in field of item in vault, creating the item as a
+// password item when it does not exist, and returns the reference to it.
+// The value reaches op through a template file readable only by the user,
+// never its command line.
+func StoreOp(ctx context.Context, vault, item, field, value string) (string, error) {
+	var it map[string]any
+	out, err := op(ctx, "item", "get", item, "--vault", vault, "--format", "json")
+	if err == nil {
+		if err := json.Unmarshal(out, &it); err != nil {
+			return "", fmt.Errorf("op item get: %v", err)
+		}
+	} else if !strings.Contains(err.Error(), "isn't an item") {
+		return "", err
+	}
+
+	create := it == nil
+	if create {
+		it = map[string]any{"title": item, "category": "PASSWORD"}
+	}
+	// Fields stay generic maps so an edit keeps what Envoy does not know
+	// about them, such as their section.
+	fields, _ := it["fields"].([]any)
+	found := false
+	for _, f := range fields {
+		if m, ok := f.(map[string]any); ok && (m["label"] == field || m["id"] == field) {
+			m["value"], found = value, true
+		}
+	}
+	if !found {
+		f := map[string]any{"id": field, "type": "CONCEALED", "label": field, "value": value}
+		if field == "password" {
+			f["purpose"] = "PASSWORD"
+		}
+		fields = append(fields, f)
+	}
+	it["fields"] = fields
+
+	tmpl, err := json.Marshal(it)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "envoy-op-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(tmpl)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if create {
+		_, err = op(ctx, "item", "create", "--vault", vault, "--template", f.Name())
+	} else {
+		_, err = op(ctx, "item", "edit", item, "--vault", vault, "--template", f.Name())
+	}
+	if err != nil {
+		return "", err
+	}
+	return OpRef(vault, item, field), nil
+}
@@ -0,0 +1,73 @@
+package cred
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RefScheme prefixes values that refer to an OS keychain entry instead of
+// holding the secret: keyring://service/account.
+const RefScheme = "keyring://"
+
+// ErrNoKeychain is returned when no OS keychain is reachable.
+var ErrNoKeychain = errors.New("no OS keychain available")
+
+// Ref returns the reference to account under service.
+func Ref(service, account string) string {
+	return RefScheme + service + "/" + account
+}
+
+// ParseRef splits a keyring:// reference into its service and account.
+func ParseRef(value string) (service, account string, ok bool) {
+	rest, ok := strings.CutPrefix(value, RefScheme)
+	if !ok {
+		return "", "", false
+	}
+	service, account, ok = strings.Cut(rest, "/")
+	if !ok || service == "" || account == "" {
+		return "", "", false
+	}
+	return service, account, true
+}
+
+// IsRef reports whether value is a secret reference: keyring:// or op://.
+func IsRef(value string) bool {
+	_, _, ok := ParseRef(value)
+	return ok || IsOpRef(value)
+}
+
+// ResolveRef returns the secret a keyring:// or op:// reference names.
+// Values that are not references come back unchanged.
+func ResolveRef(value string) (string, error) {
+	if IsOpRef(value) {
+		return ResolveOp(context.Background(), value)
+	}
+	service, account, ok := ParseRef(value)
+	if !ok {
+		return value, nil
+	}
+	k := Keychain(service)
+	if k == nil {
+		return "", ErrNoKeychain
+	}
+	v, err := k.Get(account)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", value, err)
+	}
+	return v, nil
+}
+
+// StoreRef puts value in the OS keychain under service and account and
+// returns the reference to it.
+func StoreRef(service, account, value string) (string, error) {
+	k := Keychain(service)
+	if k == nil {
+		return "", ErrNoKeychain
+	}
+	if err := k.Set(account, value); err != nil {
+		return "", err
+	}
+	return Ref(service, account), nil
+}
@@ -0,0 +1,180 @@
+// Package dbconn recognizes database URLs and DSNs and checks that the
+// server they name can be reached from this machine.
+//
+// Without drivers the check speaks just enough of each protocol to know
+// the right kind of server answered: a Redis PING (after AUTH when the
+// URL has a password), a PostgreSQL SSLRequest and the MySQL greeting.
+// MongoDB is checked at the TCP level. Drivers compiled in with build tags
+// (see driver_*.go) log in for real.
+package dbconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Timeout bounds one check when the caller's context has no deadline.
+const Timeout = 5 * time.Second
+
+// Kinds of database.
+const (
+	Postgres = "postgres"
+	MySQL    = "mysql"
+	Redis    = "redis"
+	MongoDB  = "mongodb"
+)
+
+var defaultPorts = map[string]string{Postgres: "5432", MySQL: "3306", Redis: "6379", MongoDB: "27017"}
+
+// Target is a parsed connection string.
+type Target struct {
+	Kind string
+	Host string // host name; for mongodb+srv, the SRV domain
+	Port string
+	User string
+	Pass string
+	TLS  bool // rediss://
+	SRV  bool // mongodb+srv://
+	DSN  string
+}
+
+// Addr is host:port.
+func (t Target) Addr() string { return net.JoinHostPort(t.Host, t.Port) }
+
+var (
+	mysqlDSN = regexp.MustCompile(`^(?:([^:@]*)(?::([^@]*))?@)?tcp\(([^)]+)\)/`)
+	pgKV     = regexp.MustCompile(`(?:^|\s)(host|port|user|password)=(\S+)`)
+)
+
+// Parse recognizes s as a database URL, a MySQL driver DSN
+// (user:pass@tcp(host:port)/db) or a PostgreSQL keyword DSN
+// (host=... port=...).
+func Parse(s string) (Target, bool) {
+	s = strings.TrimSpace(s)
+	if m := mysqlDSN.FindStringSubmatch(s); m != nil {
+		t := Target{Kind: MySQL, User: m[1], Pass: m[2], DSN: s}
+		t.Host, t.Port = splitHostPort(m[3], MySQL)
+		return t, true
+	}
+	if isKeywordDSN(s) {
+		t := Target{Kind: Postgres, Host: "localhost", Port: defaultPorts[Postgres], DSN: s}
+		for _, m := range pgKV.FindAllStringSubmatch(s, -1) {
+			switch m[1] {
+			case "host":
+				t.Host = m[2]
+			case "port":
+				t.Port = m[2]
+			case "user":
+				t.User = m[2]
+			case "password":
+				t.Pass = m[2]
+			}
+		}
+		return t, true
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return Target{}, false
+	}
+	t := Target{DSN: s}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		t.Kind = Postgres
+	case "mysql":
+		t.Kind = MySQL
+	case "redis":
+		t.Kind = Redis
+	case "rediss":
+		t.Kind, t.TLS = Redis, true
+	case "mongodb":
+		t.Kind = MongoDB
+	case "mongodb+srv":
+		t.Kind, t.SRV = MongoDB, true
+	default:
+		return Target{}, false
+	}
+	// A replica set lists several hosts; the first is enough to check.
+	host, _, _ := strings.Cut(u.Host, ",")
+	t.Host, t.Port = splitHostPort(host, t.Kind)
+	t.User = u.User.Username()
+	t.Pass, _ = u.User.Password()
+	return t, true
+}
+
+// isKeywordDSN reports whether s is a run of key=value words naming a host,
+// as in "host=db port=5432 dbname=app".
+func isKeywordDSN(s string) bool {
+	words := strings.Fields(s)
+	for _, w := range words {
+		if k, _, ok := strings.Cut(w, "="); !ok || k == "" || strings.Contains(k, "://") {
+			return false
+		}
+	}
+	return slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, "host=") })
+}
+
+func splitHostPort(hostport, kind string) (string, string) {
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		return h, p
+	}
+	return strings.Trim(hostport, "[]"), defaultPorts[kind]
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Target  Target
+	OK      bool
+	Detail  string // what answered, or how far the check got
+	Err     error
+	Latency time.Duration
+}
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("failed: %v", r.Err)
+	}
+	return fmt.Sprintf("ok: %s in %s", r.Detail, r.Latency.Round(100*time.Microsecond))
+}
+
+// Checker logs in to a database with a real driver.
+type Checker func(ctx context.Context, t Target) (detail string, err error)
+
+var drivers = map[string]Checker{}
+
+// Register installs a driver's checker for kind, replacing the built-in
+// handshake. Driver files call it from init.
+func Register(kind string, c Checker) { drivers[kind] = c }
+
+// HasDriver reports whether a driver is compiled in for kind.
+func HasDriver(kind string) bool { return drivers[kind] != nil }
+
+// Test checks that t's server answers, with its driver when one is
+// compiled in and otherwise with the built-in handshake.
+func Test(ctx context.Context, t Target) Result {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+	r := Result{Target: t}
+	start := time.Now()
+	check := drivers[t.Kind]
+	if check == nil {
+		check = handshake
+	}
+	r.Detail, r.Err = check(ctx, t)
+	r.Latency = time.Since(start)
+	if errors.Is(r.Err, context.DeadlineExceeded) {
+		r.Err = errors.New("timed out")
+	}
+	r.OK = r.Err == nil
+	return r
+}
@@ -0,0 +1,27 @@
+//go:build mongodb
+
+// Build with -tags mongodb, after go get go.mongodb.org/mongo-driver, to log
+// in to MongoDB rather than only open its port.
+
+package dbconn
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	Register(MongoDB, func(ctx context.Context, t Target) (string, error) {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(t.DSN))
+		if err != nil {
+			return "", err
+		}
+		defer client.Disconnect(context.Background())
+		if err := client.Ping(ctx, nil); err != nil {
+			return "", err
+		}
+		return "logged in with the MongoDB driver", nil
+	})
+}
@@ -0,0 +1,43 @@
+//go:build mysql
+
+// Build with -tags mysql, after go get github.com/go-sql-driver/mysql, to
+// log in to MySQL rather than only read the server's greeting.
+
+package dbconn
+
+import (
+	"net/url"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	registerSQL(MySQL, "mysql", mysqlDriverDSN)
+}
+
+// mysqlDriverDSN converts a mysql:// URL to the driver's
+// user:pass@tcp(host:port)/db form; driver DSNs pass through.
+func mysqlDriverDSN(t Target) string {
+	u, err := url.Parse(t.DSN)
+	if err != nil || u.Scheme != "mysql" {
+		return t.DSN
+	}
+	var b strings.Builder
+	if t.User != "" {
+		b.WriteString(t.User)
+		if t.Pass != "" {
+			b.WriteString(":" + t.Pass)
+		}
+		b.WriteString("@")
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString("tcp(" + t.Addr() + ")" + path)
+	if u.RawQuery != "" {
+		b.WriteString("?" + u.RawQuery)
+	}
+	return b.String()
+}
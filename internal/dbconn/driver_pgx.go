@@ -0,0 +1,13 @@
+//go:build pgx
+
+// Build with -tags pgx, after go get github.com/jackc/pgx/v5, to log in to
+// PostgreSQL rather than only greet the server.
+
+package dbconn
+
+import _ "github.com/jackc/pgx/v5/stdlib"
+
+func init() {
+	// pgx takes both URLs and keyword DSNs as they are.
+	registerSQL(Postgres, "pgx", func(t Target) string { return t.DSN })
+}
@@ -0,0 +1,148 @@
+package dbconn
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// handshake is the driverless check: connect, then confirm the server
+// speaks the expected protocol.
+func handshake(ctx context.Context, t Target) (string, error) {
+	addr := t.Addr()
+	if t.SRV {
+		_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "mongodb", "tcp", t.Host)
+		if err != nil {
+			return "", err
+		}
+		if len(srvs) == 0 {
+			return "", fmt.Errorf("no SRV records for %s", t.Host)
+		}
+		addr = net.JoinHostPort(strings.TrimSuffix(srvs[0].Target, "."), fmt.Sprint(srvs[0].Port))
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	if t.TLS {
+		tc := tls.Client(conn, &tls.Config{ServerName: t.Host})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return "", err
+		}
+		conn = tc
+	}
+
+	switch t.Kind {
+	case Redis:
+		return redisPing(conn, t)
+	case Postgres:
+		return postgresHello(conn)
+	case MySQL:
+		return mysqlGreeting(conn)
+	}
+	return "port " + addr + " open; no driver compiled in to log in", nil
+}
+
+// redisPing authenticates when the URL has a password and sends PING.
+func redisPing(conn net.Conn, t Target) (string, error) {
+	rd := bufio.NewReader(conn)
+	call := func(args ...string) (string, error) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(args))
+		for _, a := range args {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+		}
+		if _, err := io.WriteString(conn, b.String()); err != nil {
+			return "", err
+		}
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "-") {
+			return "", errors.New(strings.TrimPrefix(line, "-"))
+		}
+		return line, nil
+	}
+	if t.Pass != "" {
+		args := []string{"AUTH", t.Pass}
+		if t.User != "" {
+			args = []string{"AUTH", t.User, t.Pass}
+		}
+		if _, err := call(args...); err != nil {
+			return "", fmt.Errorf("AUTH: %w", err)
+		}
+	}
+	reply, err := call("PING")
+	if err != nil {
+		return "", err
+	}
+	if reply != "+PONG" {
+		return "", fmt.Errorf("not a Redis server (PING answered %q)", reply)
+	}
+	if t.Pass != "" {
+		return "Redis logged in, PING answered", nil
+	}
+	return "Redis PING answered", nil
+}
+
+// postgresHello sends an SSLRequest, which every PostgreSQL server
+// answers with one byte before any authentication.
+func postgresHello(conn net.Conn) (string, error) {
+	var req [8]byte
+	binary.BigEndian.PutUint32(req[0:], 8)
+	binary.BigEndian.PutUint32(req[4:], 80877103)
+	if _, err := conn.Write(req[:]); err != nil {
+		return "", err
+	}
+	var b [1]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil {
+		return "", fmt.Errorf("not a PostgreSQL server: %w", err)
+	}
+	switch b[0] {
+	case 'S':
+		return "PostgreSQL server answered (TLS available)", nil
+	case 'N':
+		return "PostgreSQL server answered (no TLS)", nil
+	}
+	return "", fmt.Errorf("not a PostgreSQL server (answered %q)", b[0])
+}
+
+// mysqlGreeting reads the handshake a MySQL server sends on connect, or
+// the error it sends instead, such as a host not allowed to connect.
+func mysqlGreeting(conn net.Conn) (string, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return "", fmt.Errorf("not a MySQL server: %w", err)
+	}
+	n := int(hdr[0]) | int(hdr[1])<<8 | int(hdr[2])<<16
+	if n == 0 || n > 1<<16 {
+		return "", errors.New("not a MySQL server")
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return "", err
+	}
+	switch payload[0] {
+	case 10:
+		version, _, _ := strings.Cut(string(payload[1:]), "\x00")
+		return "MySQL server " + version + " answered", nil
+	case 0xff:
+		if len(payload) > 3 {
+			return "", errors.New(string(payload[3:]))
+		}
+	}
+	return "", errors.New("not a MySQL server")
+}
@@ -0,0 +1,22 @@
+package dbconn
+
+import (
+	"context"
+	"database/sql"
+)
+
+// registerSQL installs a checker that opens the target with a database/sql
+// driver and pings it, which logs in.
+func registerSQL(kind, driver string, dsn func(Target) string) {
+	Register(kind, func(ctx context.Context, t Target) (string, error) {
+		db, err := sql.Open(driver, dsn(t))
+		if err != nil {
+			return "", err
+		}
+		defer db.Close()
+		if err := db.PingContext(ctx); err != nil {
+			return "", err
+		}
+		return "logged in with the " + driver + " driver", nil
+	})
+}
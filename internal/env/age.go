@@ -0,0 +1,207 @@
+package env
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// AgeExt ends the names of files encrypted with age. Such files are
+// decrypted when imported and encrypted when written, through the age CLI;
+// the rest of the name picks the format as usual.
+const AgeExt = ".age"
+
+// AgeRecipientsFile, next to an encrypted file, lists the recipients it is
+// encrypted to, one per line as `age -R` reads them. Teams keeping
+// encrypted env files in git can commit one.
+const AgeRecipientsFile = ".age-recipients"
+
+var (
+	// ErrNoAge is returned when the age CLI is not installed.
+	ErrNoAge = errors.New("age not found on PATH")
+	// ErrAgeIdentity is returned when an encrypted file is read with no
+	// identity set and none found where AgeIdentityPath looks.
+	ErrAgeIdentity = errors.New("no age identity to decrypt with")
+	// ErrAgeRecipients is returned when an encrypted file is written with
+	// no recipients set and no recipients file beside it.
+	ErrAgeRecipients = errors.New("no age recipients to encrypt to")
+)
+
+// AgeKeys are what a store decrypts and encrypts .age files with.
+type AgeKeys struct {
+	Identity   string   // identity file, as for age -i
+	Recipients []string // public keys, as for age -r
+}
+
+// IsAgePath reports whether path names an age-encrypted file.
+func IsAgePath(path string) bool {
+	return strings.HasSuffix(path, AgeExt)
+}
+
+// plainPath strips the age extension from path, leaving the name that
+// picks the format.
+func plainPath(path string) string {
+	return strings.TrimSuffix(path, AgeExt)
+}
+
+// SetAgeKeys sets the identity and recipients for .age files.
+func (s *Store) SetAgeKeys(k AgeKeys) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.age = k
+}
+
+// AgeKeys returns the keys set with SetAgeKeys.
+func (s *Store) AgeKeys() AgeKeys {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.age
+}
+
+// AgeFS wraps fsys to decrypt .age files as they are opened and encrypt
+// them as they are written, with keys; other files pass through.
+func AgeFS(fsys FS, keys AgeKeys) FS {
+	return ageFS{FS: fsys, keys: keys}
+}
+
+type ageFS struct {
+	FS
+	keys AgeKeys
+}
+
+func (a ageFS) Open(name string) (fs.File, error) {
+	if !IsAgePath(name) {
+		return a.FS.Open(name)
+	}
+	f, err := a.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	identity := cmp.Or(a.keys.Identity, AgeIdentityPath())
+	if identity == "" {
+		return nil, fmt.Errorf("%s: %w", name, ErrAgeIdentity)
+	}
+	plain, err := runAge(data, "--decrypt", "--identity", identity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &memFile{name: filepath.Base(name), Reader: bytes.NewReader(plain)}, nil
+}
+
+func (a ageFS) Create(path string) (io.WriteCloser, error) {
+	if !IsAgePath(path) {
+		return a.FS.Create(path)
+	}
+	args := []string{"--encrypt", "--armor"}
+	for _, r := range a.keys.Recipients {
+		args = append(args, "--recipient", r)
+	}
+	if rf := filepath.Join(filepath.Dir(path), AgeRecipientsFile); fileExists(rf) {
+		args = append(args, "--recipients-file", rf)
+	}
+	if len(args) == 2 {
+		return nil, fmt.Errorf("%s: %w", path, ErrAgeRecipients)
+	}
+	return &sealWriter{fsys: a.FS, path: path, seal: func(plain []byte) ([]byte, error) {
+		return runAge(plain, args...)
+	}}, nil
+}
+
+// sealWriter collects what is written and, on Close, writes it to path
+// through fsys once seal has encrypted it.
+type sealWriter struct {
+	fsys FS
+	path string
+	seal func([]byte) ([]byte, error)
+	buf  bytes.Buffer
+}
+
+func (w *sealWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *sealWriter) Close() error {
+	sealed, err := w.seal(w.buf.Bytes())
+	clear(w.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+	f, err := w.fsys.Create(w.path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(sealed); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runAge runs the age CLI on input and returns its output.
+func runAge(input []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("age"); err != nil {
+		return nil, ErrNoAge
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// AgeRecipient returns the public key of the identity file, for encrypting
+// files to oneself.
+func AgeRecipient(identity string) (string, error) {
+	if _, err := exec.LookPath("age-keygen"); err != nil {
+		return "", errors.New("age-keygen not found on PATH")
+	}
+	out, err := exec.Command("age-keygen", "-y", identity).Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+			return "", errors.New(strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line, nil
+}
+
+// AgeIdentityPath returns the first identity file found where age and sops
+// users usually keep one, or "".
+func AgeIdentityPath() string {
+	var paths []string
+	if p := os.Getenv("SOPS_AGE_KEY_FILE"); p != "" {
+		paths = append(paths, p)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "age", "keys.txt"), filepath.Join(dir, "sops", "age", "keys.txt"))
+	}
+	for _, p := range paths {
+		if fileExists(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
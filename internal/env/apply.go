@@ -0,0 +1,134 @@
+package env
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// processLocked returns the environment edits are applied to: the running
+// process, or the base list of a detached store.
+func (s *Store) processLocked() map[string]string {
+	env := s.base
+	if !s.detached {
+		env = os.Environ()
+	}
+	out := make(map[string]string, len(env))
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		out[k] = v
+	}
+	return out
+}
+
+// Process returns the environment edits are applied to, as a map.
+func (s *Store) Process() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.processLocked()
+}
+
+// Pending lists what Apply would change in the process, sorted by key: a
+// set for every key whose value differs, an unset for every process key
+// deleted from the store. Prev holds the process value being replaced;
+// Value has meta-variables resolved.
+func (s *Store) Pending() []Op {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pendingLocked()
+}
+
+func (s *Store) pendingLocked() []Op {
+	proc := s.processLocked()
+	var out []Op
+	for k, it := range s.items {
+		v, _ := s.resolveLocked(it.open().Value)
+		pv, ok := proc[k]
+		if ok && pv == v {
+			continue
+		}
+		op := Op{Key: k, Value: v}
+		if ok {
+			op.Prev = &Item{Key: k, Value: pv}
+		}
+		out = append(out, op)
+	}
+	for k, pv := range proc {
+		if _, ok := s.items[k]; !ok {
+			out = append(out, Op{Key: k, Delete: true, Prev: &Item{Key: k, Value: pv}})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Apply makes the process environment match the store for keys, or for
+// every pending key when keys is empty. A detached store updates its base
+// list instead. It returns the changes made; keys already in step are
+// skipped. A change that fails undoes those made before it, leaving the
+// process as it was.
+func (s *Store) Apply(keys []string) ([]Op, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops := s.pendingLocked()
+	if len(keys) > 0 {
+		want := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			want[k] = true
+		}
+		var sel []Op
+		for _, op := range ops {
+			if want[op.Key] {
+				sel = append(sel, op)
+			}
+		}
+		ops = sel
+	}
+
+	var done []Op
+	for _, op := range ops {
+		if err := s.applyLocked(op); err != nil {
+			s.revertLocked(done)
+			return nil, fmt.Errorf("%s: %w", op.Key, err)
+		}
+		done = append(done, op)
+	}
+	return done, nil
+}
+
+// revertLocked undoes applied ops, last first, restoring the process
+// values they replaced.
+func (s *Store) revertLocked(done []Op) {
+	for i := len(done) - 1; i >= 0; i-- {
+		op := Op{Key: done[i].Key, Delete: done[i].Prev == nil}
+		if done[i].Prev != nil {
+			op.Value = done[i].Prev.Value
+		}
+		if err := s.applyLocked(op); err != nil {
+			slog.Warn("reverting apply failed", "key", op.Key, "err", err)
+		}
+	}
+}
+
+func (s *Store) applyLocked(op Op) error {
+	if !s.detached {
+		if op.Delete {
+			return os.Unsetenv(op.Key)
+		}
+		return os.Setenv(op.Key, op.Value)
+	}
+	prefix := op.Key + "="
+	base := s.base[:0]
+	for _, e := range s.base {
+		if !strings.HasPrefix(e, prefix) {
+			base = append(base, e)
+		}
+	}
+	if !op.Delete {
+		base = append(base, prefix+op.Value)
+	}
+	s.base = base
+	return nil
+}
@@ -0,0 +1,112 @@
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Budget is a platform's limit on environment size. Zero limits are
+// unlimited.
+type Budget struct {
+	Name     string
+	Desc     string
+	Total    int // bytes across all variables
+	PerVar   int // bytes per variable
+	MaxVars  int
+	Overhead int // bytes counted per variable besides key and value
+}
+
+var budgets = []Budget{
+	{Name: "lambda", Desc: "AWS Lambda: 4 KB across all keys and values", Total: 4 << 10},
+	{Name: "azure", Desc: "Azure Functions on Windows: 32,767-character environment block", Total: 32767, Overhead: 2},
+	{Name: "cloudrun", Desc: "Google Cloud Run: 32 KB per variable, 1000 variables", PerVar: 32 << 10, MaxVars: 1000},
+	{Name: "heroku", Desc: "Heroku: 32 KB of config vars", Total: 32 << 10},
+}
+
+// Budgets lists the known platform budgets.
+func Budgets() []Budget {
+	return append([]Budget{}, budgets...)
+}
+
+// BudgetFor looks a budget up by name.
+func BudgetFor(name string) (Budget, bool) {
+	for _, b := range budgets {
+		if strings.EqualFold(b.Name, name) {
+			return b, true
+		}
+	}
+	return Budget{}, false
+}
+
+// BudgetNames lists the budget names, for usage messages.
+func BudgetNames() []string {
+	var out []string
+	for _, b := range budgets {
+		out = append(out, b.Name)
+	}
+	return out
+}
+
+// BudgetUse is one variable's share of a budget.
+type BudgetUse struct {
+	Key    string
+	Size   int
+	Secret bool // a candidate for a secret manager
+}
+
+// BudgetReport measures a set of variables against a Budget.
+type BudgetReport struct {
+	Budget
+	Used     int
+	Vars     []BudgetUse // largest first
+	Pushers  []string    // the fewest largest keys whose removal fits Total
+	Oversize []string    // keys over PerVar
+}
+
+// Over reports whether any limit is exceeded.
+func (r BudgetReport) Over() bool {
+	return (r.Total > 0 && r.Used > r.Total) || len(r.Oversize) > 0 ||
+		(r.MaxVars > 0 && len(r.Vars) > r.MaxVars)
+}
+
+// Summary is a one-line usage figure such as "lambda 3.1/4.0 KB".
+func (r BudgetReport) Summary() string {
+	if r.Total > 0 {
+		return fmt.Sprintf("%s %.1f/%.1f KB", r.Name, kb(r.Used), kb(r.Total))
+	}
+	return fmt.Sprintf("%s %d vars, %.1f KB", r.Name, len(r.Vars), kb(r.Used))
+}
+
+func kb(n int) float64 { return float64(n) / 1024 }
+
+// MeasureBudget measures keys against b.
+func (s *Store) MeasureBudget(b Budget, keys []string) BudgetReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r := BudgetReport{Budget: b}
+	for _, k := range keys {
+		it, ok := s.itemLocked(k)
+		if !ok {
+			continue
+		}
+		size := len(k) + len(it.Value) + b.Overhead
+		r.Used += size
+		r.Vars = append(r.Vars, BudgetUse{Key: k, Size: size, Secret: LooksSecret(k, it.Value)})
+		if b.PerVar > 0 && len(it.Value) > b.PerVar {
+			r.Oversize = append(r.Oversize, k)
+		}
+	}
+	sort.SliceStable(r.Vars, func(i, j int) bool { return r.Vars[i].Size > r.Vars[j].Size })
+	if b.Total > 0 {
+		left := r.Used
+		for _, v := range r.Vars {
+			if left <= b.Total {
+				break
+			}
+			r.Pushers = append(r.Pushers, v.Key)
+			left -= v.Size
+		}
+	}
+	return r
+}
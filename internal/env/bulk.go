@@ -0,0 +1,147 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParseBulk parses a pasted blob as either a flat JSON object or as dotenv
+// lines (KEY=VALUE, optionally prefixed with "export"). Pairs come back in
+// input order; when a key repeats, the last value wins.
+func ParseBulk(text string) ([]Item, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("nothing to parse")
+	}
+
+	var items []Item
+	if strings.HasPrefix(text, "{") {
+		var err error
+		if items, err = parseJSONObject(text); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, val, ok := parseKV(line)
+			if !ok || key == "" {
+				continue
+			}
+			items = append(items, Item{Key: key, Value: val})
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("no KEY=VALUE pairs found")
+	}
+	return dedupeItems(items), nil
+}
+
+// parseJSONObject reads a flat JSON object while keeping key order. Nested
+// objects and arrays are kept as compact JSON strings.
+func parseJSONObject(text string) ([]Item, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("expected a JSON object")
+	}
+	var items []Item
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		items = append(items, Item{Key: key, Value: jsonScalar(raw)})
+	}
+	return items, nil
+}
+
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return string(raw)
+	}
+	return b.String()
+}
+
+func dedupeItems(items []Item) []Item {
+	pos := make(map[string]int, len(items))
+	out := make([]Item, 0, len(items))
+	for _, it := range items {
+		if i, ok := pos[it.Key]; ok {
+			out[i].Value = it.Value
+			continue
+		}
+		pos[it.Key] = len(out)
+		out = append(out, it)
+	}
+	return out
+}
+
+// LineIssue is a problem found on one line of a batch. Errors keep the
+// batch from being added; warnings do not.
+type LineIssue struct {
+	Line  int // 1-based
+	Msg   string
+	Error bool
+}
+
+// CheckLines parses text as one KEY=VALUE pair per line, skipping blank and
+// comment lines, and reports the lines that are malformed, name a key that
+// is not a shell identifier, or repeat an earlier key. Pairs come back in
+// input order, each with the line it came from; a repeated key keeps its
+// last value.
+func CheckLines(text string) ([]Item, []int, []LineIssue) {
+	var items []Item
+	var lines []int
+	var issues []LineIssue
+	seen := make(map[string]int)
+	for i, line := range strings.Split(text, "\n") {
+		n := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := parseKV(line)
+		switch {
+		case !ok && strings.HasPrefix(line, "="):
+			issues = append(issues, LineIssue{n, "missing key", true})
+			continue
+		case !ok:
+			issues = append(issues, LineIssue{n, "expected KEY=VALUE", true})
+			continue
+		case strings.ContainsAny(key, " \t"):
+			issues = append(issues, LineIssue{n, "key contains a space", true})
+			continue
+		case !identRe.MatchString(key):
+			issues = append(issues, LineIssue{n, "not a shell identifier; shells drop it", false})
+		}
+		if first, ok := seen[key]; ok {
+			issues = append(issues, LineIssue{n, fmt.Sprintf("repeats line %d; this value wins", first), false})
+			items[slices.Index(lines, first)].Value = val
+			continue
+		}
+		seen[key] = n
+		items = append(items, Item{Key: key, Value: val})
+		lines = append(lines, n)
+	}
+	return items, lines, issues
+}
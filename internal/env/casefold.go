@@ -0,0 +1,50 @@
+package env
+
+import (
+	"sort"
+	"strings"
+)
+
+// CaseCollisions returns groups of keys that differ only by case (Path vs
+// PATH). Windows treats such keys as one variable, Unix as several.
+func (s *Store) CaseCollisions() [][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	groups := make(map[string][]string)
+	for _, k := range s.order {
+		u := strings.ToUpper(k)
+		groups[u] = append(groups[u], k)
+	}
+	var out [][]string
+	for _, keys := range groups {
+		if len(keys) > 1 {
+			out = append(out, keys)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToUpper(out[i][0]) < strings.ToUpper(out[j][0])
+	})
+	return out
+}
+
+// MergeCase keeps key and deletes every other key that differs from it only
+// by case. It returns the deleted keys.
+func (s *Store) MergeCase(key string) []string {
+	var drop []string
+	for _, group := range s.CaseCollisions() {
+		if !strings.EqualFold(group[0], key) {
+			continue
+		}
+		for _, k := range group {
+			if k != key {
+				drop = append(drop, k)
+			}
+		}
+	}
+	s.Begin()
+	defer s.Commit()
+	for _, k := range drop {
+		s.Delete(k)
+	}
+	return drop
+}
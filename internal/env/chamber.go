@@ -0,0 +1,34 @@
+package env
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ChamberName returns the variable chamber exec sets for a chamber key:
+// db-password is DB_PASSWORD.
+func ChamberName(key string) string {
+	return strings.ReplaceAll(strings.ToUpper(key), "-", "_")
+}
+
+// ChamberItems renames items read from a chamber export to the variables
+// chamber exec would set; a later key wins over an earlier one it folds
+// into.
+func ChamberItems(items []Item) []Item {
+	for i := range items {
+		items[i].Key = ChamberName(items[i].Key)
+	}
+	return dedupeItems(items)
+}
+
+// writeChamber writes items as chamber export prints them and chamber
+// import reads them back: one JSON object on one line, keys lowercased and
+// sorted.
+func writeChamber(w io.Writer, items []Item) error {
+	m := make(map[string]string, len(items))
+	for _, it := range items {
+		m[strings.ToLower(it.Key)] = it.Value
+	}
+	return json.NewEncoder(w).Encode(m)
+}
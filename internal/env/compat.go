@@ -0,0 +1,406 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tools whose reading of a dotenv file Compat emulates.
+const (
+	ToolDocker  = "docker"  // docker run --env-file
+	ToolCompose = "compose" // docker compose .env and env_file
+	ToolBash    = "bash"    // bash's source or .
+	ToolRuby    = "ruby"    // the Ruby dotenv gem
+)
+
+// CompatTools lists the tools in the order Compat reports them.
+var CompatTools = []string{ToolDocker, ToolCompose, ToolBash, ToolRuby}
+
+// CompatResult is how one tool reads a value back.
+type CompatResult struct {
+	Tool  string
+	Value string
+	Found bool     // the tool set the key at all
+	Same  bool     // it read back the value written
+	Notes []string // what it did along the way
+}
+
+// Compat writes key=value as the dotenv export would and reads the line
+// back with an emulation of each tool's parser. lookup resolves the
+// variables tools expand.
+func Compat(key, value string, lookup func(string) (string, bool)) (string, []CompatResult) {
+	var b strings.Builder
+	_ = writeDotenv(&b, []Item{{Key: key, Value: value}})
+	text := b.String()
+	var out []CompatResult
+	for _, tool := range CompatTools {
+		var vars map[string]string
+		var notes []string
+		switch tool {
+		case ToolDocker:
+			vars, notes = readDocker(text)
+		case ToolCompose:
+			vars, notes = readCompose(text, lookup)
+		case ToolBash:
+			vars, notes = readBash(text, lookup)
+		case ToolRuby:
+			vars, notes = readRuby(text, lookup)
+		}
+		v, found := vars[key]
+		out = append(out, CompatResult{Tool: tool, Value: v, Found: found, Same: found && v == value, Notes: notes})
+	}
+	return strings.TrimSuffix(text, "\n"), out
+}
+
+// readDocker reads like docker's --env-file: one KEY=VALUE per line, the
+// value taken verbatim, quotes and all.
+func readDocker(text string) (map[string]string, []string) {
+	vars := make(map[string]string)
+	var notes []string
+	for n, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		switch {
+		case !ok:
+			notes = append(notes, fmt.Sprintf("line %d has no =; docker passes a variable of that name through", n+1))
+		case strings.ContainsAny(k, " \t"):
+			notes = append(notes, fmt.Sprintf("line %d: docker refuses a name with whitespace", n+1))
+		default:
+			if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "'") {
+				notes = append(notes, "keeps the quotes as part of the value")
+			}
+			vars[k] = v
+		}
+	}
+	return vars, notes
+}
+
+// readCompose reads like docker compose: quotes removed, escapes in
+// double quotes honored, and ${VAR} expanded outside single quotes.
+func readCompose(text string, lookup func(string) (string, bool)) (map[string]string, []string) {
+	vars := make(map[string]string)
+	var notes []string
+	for text != "" {
+		var line string
+		line, text, _ = strings.Cut(text, "\n")
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "export "))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimLeft(v, " \t")
+		expand := true
+		switch {
+		case v != "" && (v[0] == '"' || v[0] == '\''):
+			// A quoted value runs to the matching quote, over lines if need be.
+			q := v[0]
+			rest := v[1:] + "\n" + text
+			end := closingQuote(rest, q)
+			if end < 0 {
+				notes = append(notes, "compose finds no closing quote")
+				return vars, notes
+			}
+			v = rest[:end]
+			if after := rest[end+1:]; strings.Contains(after, "\n") {
+				_, text, _ = strings.Cut(after, "\n")
+			} else {
+				text = ""
+			}
+			if q == '\'' {
+				expand = false
+			} else {
+				v = composeEscapes(v)
+			}
+		default:
+			if i := strings.Index(v, " #"); i >= 0 {
+				v = v[:i]
+				notes = append(notes, "drops an inline comment")
+			}
+			v = strings.TrimSpace(v)
+		}
+		if expand && strings.Contains(v, "$") {
+			var unset []string
+			in := interpolator{lookup: lookup, unset: func(name string) { unset = append(unset, name) }}
+			x, err := in.expand(v)
+			if err != nil {
+				notes = append(notes, "fails: "+err.Error())
+				continue
+			}
+			if x != v {
+				notes = append(notes, "expands $ references")
+			}
+			for _, name := range unset {
+				notes = append(notes, name+" is unset and becomes empty")
+			}
+			v = x
+		}
+		vars[k] = v
+	}
+	return vars, dedupeNotes(notes)
+}
+
+// closingQuote finds the first q in s not escaped by a backslash.
+func closingQuote(s string, q byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			return i
+		}
+	}
+	return -1
+}
+
+// composeEscapes undoes the escapes compose honors in double quotes.
+func composeEscapes(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// readBash reads the assignment as bash's source would, without running
+// anything: command substitutions become empty and are noted.
+func readBash(text string, lookup func(string) (string, bool)) (map[string]string, []string) {
+	vars := make(map[string]string)
+	k, s, ok := strings.Cut(text, "=")
+	if !ok || !identRe.MatchString(k) {
+		return vars, []string{"bash takes the line as a command, not an assignment"}
+	}
+	var notes []string
+	note := func(msg string) { notes = append(notes, msg) }
+	expand := func(s string, i int) (string, int) {
+		// s[i] is '$'; returns the expansion and the index after it.
+		switch {
+		case i+1 < len(s) && s[i+1] == '$':
+			note("$$ becomes the shell's process ID")
+			return "<pid>", i + 2
+		case i+1 < len(s) && s[i+1] == '(':
+			note("runs a command ($(...))")
+			depth := 0
+			for j := i + 1; j < len(s); j++ {
+				switch s[j] {
+				case '(':
+					depth++
+				case ')':
+					if depth--; depth == 0 {
+						return "", j + 1
+					}
+				}
+			}
+			return "", len(s)
+		case i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				note("bad substitution")
+				return "", len(s)
+			}
+			v, err := Interpolate(s[i:end+1], lookup)
+			if err != nil {
+				note("fails: " + err.Error())
+			}
+			note("expands $ references")
+			return v, end + 1
+		case i+1 < len(s) && (s[i+1] == '_' || isLetter(s[i+1])):
+			j := i + 1
+			for j < len(s) && wordRune(rune(s[j])) {
+				j++
+			}
+			v, set := lookup(s[i+1 : j])
+			note("expands $ references")
+			if !set {
+				note(s[i+1:j] + " is unset and becomes empty")
+			}
+			return v, j
+		}
+		return "$", i + 1
+	}
+
+	var b strings.Builder
+	i := 0
+word:
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) && s[i+1] != '\n' {
+				b.WriteByte(s[i+1])
+			}
+			i += 2
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				note("bash finds no closing quote")
+				return vars, dedupeNotes(notes)
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 2
+		case c == '"':
+			i++
+			for {
+				if i >= len(s) {
+					note("bash finds no closing quote")
+					return vars, dedupeNotes(notes)
+				}
+				c := s[i]
+				if c == '"' {
+					i++
+					break
+				}
+				switch {
+				case c == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0:
+					if s[i+1] != '\n' {
+						b.WriteByte(s[i+1])
+					}
+					i += 2
+				case c == '$':
+					v, next := expand(s, i)
+					b.WriteString(v)
+					i = next
+				case c == '`':
+					note("runs a command (`...`)")
+					end := strings.IndexByte(s[i+1:], '`')
+					if end < 0 {
+						i = len(s)
+					} else {
+						i += end + 2
+					}
+				default:
+					b.WriteByte(c)
+					i++
+				}
+			}
+		case c == '$':
+			v, next := expand(s, i)
+			b.WriteString(v)
+			i = next
+		case c == '`':
+			note("runs a command (`...`)")
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += end + 2
+			}
+		case c == '~' && i == 0:
+			home, _ := lookup("HOME")
+			b.WriteString(home)
+			note("expands a leading ~ to $HOME")
+			i++
+		case strings.IndexByte(" \t\n;&|<>()", c) >= 0:
+			break word
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	if rest := strings.TrimSpace(s[i:]); rest != "" && rest[0] != '#' {
+		note("the rest of the line runs as a command")
+		if strings.IndexByte(" \t", s[i]) >= 0 {
+			// "K=v cmd" sets K for cmd only.
+			return vars, dedupeNotes(notes)
+		}
+	}
+	vars[k] = b.String()
+	return vars, dedupeNotes(notes)
+}
+
+var (
+	rubyLineRe = regexp.MustCompile(`(?m)^\s*(?:export\s+)?([\w.]+)(?:(?:\s*=\s*?|:\s+?)(\s*'(?:\\'|[^'])*'|\s*"(?:\\"|[^"])*"|[^#\n]+)?)?\s*(?:#.*)?$`)
+	rubyVarRe  = regexp.MustCompile(`(\\)?\$(\{)?([A-Za-z0-9_]+)?(\})?`)
+	rubyEscRe  = regexp.MustCompile(`\\([^$])`)
+)
+
+// readRuby reads like the Ruby dotenv gem: quotes removed, \n and other
+// escapes undone in double quotes, and $VAR and $(cmd) substituted
+// outside single quotes.
+func readRuby(text string, lookup func(string) (string, bool)) (map[string]string, []string) {
+	vars := make(map[string]string)
+	var notes []string
+	for _, m := range rubyLineRe.FindAllStringSubmatch(text, -1) {
+		k, v := m[1], strings.TrimSpace(m[2])
+		quote := byte(0)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			quote, v = v[0], v[1:len(v)-1]
+		}
+		if quote == '"' {
+			v = strings.NewReplacer(`\n`, "\n", `\r`, "\r").Replace(v)
+			v = rubyEscRe.ReplaceAllString(v, "$1")
+		}
+		if quote != '\'' {
+			var ran bool
+			if v, ran = dropCommands(v); ran {
+				notes = append(notes, "runs a command ($(...))")
+			}
+			v = rubyVarRe.ReplaceAllStringFunc(v, func(ref string) string {
+				p := rubyVarRe.FindStringSubmatch(ref)
+				switch {
+				case p[1] != "":
+					return ref[1:]
+				case p[3] == "":
+					return ref
+				}
+				val, set := lookup(p[3])
+				if !set {
+					notes = append(notes, p[3]+" is unset and becomes empty")
+				}
+				notes = append(notes, "expands $ references")
+				return val
+			})
+		}
+		vars[k] = v
+	}
+	return vars, dedupeNotes(notes)
+}
+
+// dropCommands removes the unescaped $(...) substitutions from v, which
+// Ruby dotenv would replace with the commands' output.
+func dropCommands(v string) (string, bool) {
+	var b strings.Builder
+	ran := false
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			b.WriteString(v[i : i+2])
+			i++
+			continue
+		}
+		if v[i] != '$' || i+1 == len(v) || v[i+1] != '(' {
+			b.WriteByte(v[i])
+			continue
+		}
+		depth, j := 0, i+1
+		for ; j < len(v); j++ {
+			if v[j] == '(' {
+				depth++
+			} else if v[j] == ')' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		if j == len(v) {
+			b.WriteString(v[i:])
+			break
+		}
+		ran = true
+		i = j
+	}
+	return b.String(), ran
+}
+
+func dedupeNotes(notes []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, n := range notes {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
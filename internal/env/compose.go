@@ -0,0 +1,295 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFile is the part of a docker-compose file the importer reads.
+type composeFile struct {
+	Services map[string]struct {
+		Environment yaml.Node `yaml:"environment"`
+		EnvFile     yaml.Node `yaml:"env_file"`
+	} `yaml:"services"`
+}
+
+// ParseCompose returns the environment a docker-compose service starts
+// with: its env_file files in order, then its environment section, each
+// overriding what came before. Values are interpolated as compose does,
+// looking variables up with lookup; unset lists those that were unset with
+// no default. An empty service names the only one in the file.
+func ParseCompose(fsys FS, path, service string, lookup func(string) (string, bool)) (items []Item, unset []string, err error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	var cf composeFile
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := slices.Sorted(maps.Keys(cf.Services))
+	if service == "" {
+		if len(names) != 1 {
+			return nil, nil, fmt.Errorf("%s has %d services; name one of %s", path, len(names), strings.Join(names, ", "))
+		}
+		service = names[0]
+	}
+	svc, ok := cf.Services[service]
+	if !ok {
+		return nil, nil, fmt.Errorf("no service %q in %s (have %s)", service, path, strings.Join(names, ", "))
+	}
+
+	missing := make(map[string]bool)
+	interp := interpolator{lookup: lookup, unset: func(name string) { missing[name] = true }}.expand
+
+	files, err := composeEnvFiles(&svc.EnvFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: service %s: %w", path, service, err)
+	}
+	for _, ef := range files {
+		p, err := interp(ef.path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: env_file: %w", path, err)
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(path), p)
+		}
+		fileItems, err := ParseFS(fsys, p)
+		if errors.Is(err, os.ErrNotExist) && !ef.required {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, fileItems...)
+	}
+
+	env := &svc.Environment
+	switch env.Kind {
+	case 0:
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			k, v := env.Content[i].Value, env.Content[i+1]
+			if v.Tag == "!!null" {
+				// A bare key passes the variable through when it is set.
+				if val, ok := lookup(k); ok {
+					items = append(items, Item{Key: k, Value: val})
+				}
+				continue
+			}
+			val, err := interp(v.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s: %w", path, k, err)
+			}
+			items = append(items, Item{Key: k, Value: val})
+		}
+	case yaml.SequenceNode:
+		for _, n := range env.Content {
+			k, v, hasValue := strings.Cut(n.Value, "=")
+			if !hasValue {
+				if val, ok := lookup(k); ok {
+					items = append(items, Item{Key: k, Value: val})
+				}
+				continue
+			}
+			val, err := interp(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s: %w", path, k, err)
+			}
+			items = append(items, Item{Key: k, Value: val})
+		}
+	default:
+		return nil, nil, fmt.Errorf("%s: service %s: environment is neither a map nor a list", path, service)
+	}
+	return dedupeItems(items), slices.Sorted(maps.Keys(missing)), nil
+}
+
+// ParseCompose reads a compose service's environment through the store's
+// filesystem, interpolating the store's values.
+func (s *Store) ParseCompose(path, service string) ([]Item, []string, error) {
+	return ParseCompose(s.fs(), path, service, func(key string) (string, bool) {
+		it, ok := s.Get(key)
+		return it.Value, ok
+	})
+}
+
+type composeEnvFile struct {
+	path     string
+	required bool
+}
+
+// composeEnvFiles reads env_file, which is a path, a list of paths, or a
+// list of {path, required} entries.
+func composeEnvFiles(n *yaml.Node) ([]composeEnvFile, error) {
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.ScalarNode:
+		return []composeEnvFile{{path: n.Value, required: true}}, nil
+	case yaml.SequenceNode:
+	default:
+		return nil, errors.New("env_file is neither a path nor a list")
+	}
+	var out []composeEnvFile
+	for _, c := range n.Content {
+		if c.Kind == yaml.ScalarNode {
+			out = append(out, composeEnvFile{path: c.Value, required: true})
+			continue
+		}
+		ef := struct {
+			Path     string `yaml:"path"`
+			Required *bool  `yaml:"required"`
+		}{}
+		if err := c.Decode(&ef); err != nil {
+			return nil, fmt.Errorf("line %d: %w", c.Line, err)
+		}
+		out = append(out, composeEnvFile{path: ef.Path, required: ef.Required == nil || *ef.Required})
+	}
+	return out, nil
+}
+
+// Interpolate expands compose-style references in s: $VAR, ${VAR},
+// ${VAR:-default} and ${VAR-default} (default when unset or empty, or
+// only when unset), ${VAR:+alt} and ${VAR+alt} (the reverse), and
+// ${VAR:?msg} and ${VAR?msg}, which fail. Defaults may hold references
+// themselves; $$ is a literal $.
+func Interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	return interpolator{lookup: lookup}.expand(s)
+}
+
+// interpolator calls unset, when set, for each variable expanded to ""
+// for want of a value and a default.
+type interpolator struct {
+	lookup func(string) (string, bool)
+	unset  func(string)
+}
+
+func (in interpolator) get(name string) string {
+	v, ok := in.lookup(name)
+	if !ok && in.unset != nil {
+		in.unset(name)
+	}
+	return v
+}
+
+func (in interpolator) expand(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unclosed ${ in %q", s)
+			}
+			v, err := in.braced(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i = end
+		case next == '_' || isLetter(next):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || isLetter(s[j]) || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			b.WriteString(in.get(s[i+1 : j]))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// closingBrace finds the } matching a ${ whose body starts at from.
+func closingBrace(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// braced expands the body of a ${...} reference.
+func (in interpolator) braced(body string) (string, error) {
+	end := 0
+	for end < len(body) && (body[end] == '_' || isLetter(body[end]) || end > 0 && body[end] >= '0' && body[end] <= '9') {
+		end++
+	}
+	name, rest := body[:end], body[end:]
+	if name == "" {
+		return "", fmt.Errorf("bad reference ${%s}", body)
+	}
+	if rest == "" {
+		return in.get(name), nil
+	}
+	v, set := in.lookup(name)
+	colon := strings.HasPrefix(rest, ":")
+	if colon {
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return "", fmt.Errorf("bad reference ${%s}", body)
+	}
+	op, arg := rest[0], rest[1:]
+	// With a colon an empty value counts as unset.
+	present := set && (!colon || v != "")
+	switch op {
+	case '-':
+		if present {
+			return v, nil
+		}
+		return in.expand(arg)
+	case '+':
+		if present {
+			return in.expand(arg)
+		}
+		return "", nil
+	case '?':
+		if present {
+			return v, nil
+		}
+		msg, err := in.expand(arg)
+		if err != nil {
+			return "", err
+		}
+		if msg == "" {
+			msg = "required"
+		}
+		return "", fmt.Errorf("%s: %s", name, msg)
+	}
+	return "", fmt.Errorf("bad reference ${%s}", body)
+}
@@ -0,0 +1,211 @@
+package env
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source formats ParseFS reads, as DetectFormat names them.
+const (
+	SourceDotenv     = "dotenv"
+	SourceJSON       = "json"
+	SourceYAML       = "yaml"
+	SourceManifest   = "k8s" // Kubernetes ConfigMaps and Secrets
+	SourceTOML       = "toml"
+	SourceXML        = "xml" // Java XML properties
+	SourceProperties = "properties"
+	SourceVault      = "dotenv-vault"
+)
+
+var (
+	// manifestKind finds the kind line of a ConfigMap, Secret or List.
+	manifestKind = regexp.MustCompile(`(?m)^kind:\s*(ConfigMap|Secret|List)\s*$`)
+	// tomlTable matches a TOML table header, [name] or [[name]].
+	tomlTable = regexp.MustCompile(`(?m)^\s*\[\[?\s*[A-Za-z0-9_"'-]+(\s*\.\s*[A-Za-z0-9_"'-]+)*\s*\]\]?\s*(#.*)?$`)
+	// yamlPair matches a "key: value" line, or a key opening a nested map.
+	yamlPair = regexp.MustCompile(`^[A-Za-z0-9_.-]+:(\s|$)`)
+	// propertiesPair matches a Java properties line with a dotted key.
+	propertiesPair = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+\s*[=:]`)
+)
+
+// DetectFormat returns the format of the file text read from path. The
+// content decides where it is unmistakable: a JSON object, XML, a
+// Kubernetes manifest or TOML tables. Otherwise the extension does, and
+// without a known one the shape of most lines: "key: value" reads as YAML,
+// dotted keys and ! comments as Java properties, KEY=VALUE as dotenv.
+func DetectFormat(path, text string) string {
+	if IsEnvrcPath(path) {
+		return SourceDotenv
+	}
+	t := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(t, "{"):
+		return SourceJSON
+	case strings.HasPrefix(t, "<"):
+		return SourceXML
+	case manifestKind.MatchString(t):
+		return SourceManifest
+	case tomlTable.MatchString(t):
+		return SourceTOML
+	}
+	switch strings.ToLower(filepath.Ext(plainPath(path))) {
+	case ".json":
+		return SourceJSON
+	case ".yaml", ".yml":
+		return SourceYAML
+	case ".toml":
+		return SourceTOML
+	case ".xml":
+		return SourceXML
+	case ".properties":
+		return SourceProperties
+	}
+	var dotenv, yml, props int
+	for _, line := range strings.Split(t, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case line == "---" || strings.HasPrefix(line, "- "):
+			yml++
+		case strings.HasPrefix(line, "!") || propertiesPair.MatchString(line):
+			props++
+		case yamlPair.MatchString(line):
+			yml++
+		default:
+			if key, _, ok := parseKV(line); ok && identRe.MatchString(key) {
+				dotenv++
+			}
+		}
+	}
+	switch {
+	case yml > dotenv && yml >= props:
+		return SourceYAML
+	case props > dotenv:
+		return SourceProperties
+	}
+	return SourceDotenv
+}
+
+// parseStructured reads text in one of the formats other than dotenv.
+func parseStructured(format, text string) ([]Item, error) {
+	var items []Item
+	var err error
+	switch format {
+	case SourceJSON:
+		items, err = parseJSONObject(text)
+	case SourceYAML:
+		items, err = parseYAMLMap(text)
+	case SourceManifest:
+		return ParseManifests(text)
+	case SourceTOML:
+		items, err = parseTOML(text)
+	case SourceXML:
+		items, err = parseXMLProperties(text)
+	case SourceProperties:
+		items, err = parseProperties(text)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dedupeItems(items), nil
+}
+
+// parseYAMLMap reads a YAML mapping. Nested maps are flattened, their keys
+// joined with "_"; lists are kept as compact JSON.
+func parseYAMLMap(text string) ([]Item, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("expected a map of keys")
+	}
+	var items []Item
+	var walk func(prefix string, n *yaml.Node) error
+	walk = func(prefix string, n *yaml.Node) error {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			key := prefix + k.Value
+			switch v.Kind {
+			case yaml.MappingNode:
+				if err := walk(key+"_", v); err != nil {
+					return err
+				}
+			case yaml.ScalarNode:
+				value := v.Value
+				if v.Tag == "!!null" {
+					value = ""
+				}
+				items = append(items, Item{Key: key, Value: value})
+			default:
+				var x any
+				if err := v.Decode(&x); err != nil {
+					return fmt.Errorf("line %d: %v", v.Line, err)
+				}
+				b, err := json.Marshal(x)
+				if err != nil {
+					return fmt.Errorf("line %d: %v", v.Line, err)
+				}
+				items = append(items, Item{Key: key, Value: string(b)})
+			}
+		}
+		return nil
+	}
+	return items, walk("", root)
+}
+
+// parseXMLProperties reads a Java properties file in its XML form:
+// <properties><entry key="k">value</entry></properties>.
+func parseXMLProperties(text string) ([]Item, error) {
+	var doc struct {
+		XMLName xml.Name `xml:"properties"`
+		Entries []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, err
+	}
+	var items []Item
+	for _, e := range doc.Entries {
+		items = append(items, Item{Key: e.Key, Value: e.Value})
+	}
+	return items, nil
+}
+
+// SourceFormat returns the format path was detected in when it was last
+// imported, unless that was dotenv.
+func (s *Store) SourceFormat(path string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.formats[filepath.Clean(path)]
+	return f, ok
+}
+
+func (s *Store) recordFormat(path, format string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path = filepath.Clean(path)
+	if format == SourceDotenv {
+		delete(s.formats, path)
+		return
+	}
+	if s.formats == nil {
+		s.formats = make(map[string]string)
+	}
+	s.formats[path] = format
+}
@@ -0,0 +1,182 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// Charsets an env file can be read from and written back in.
+const (
+	UTF8    = "utf-8"
+	UTF8BOM = "utf-8-bom"
+	UTF16LE = "utf-16le" // written with a byte order mark
+	UTF16BE = "utf-16be" // written with a byte order mark
+)
+
+// Charsets lists the charset names, UTF8 first.
+var Charsets = []string{UTF8, UTF8BOM, UTF16LE, UTF16BE}
+
+// Encoding is how a text file is stored. The zero value is UTF-8 with LF
+// line endings; Windows tools often write a byte order mark, UTF-16 and
+// CRLF instead.
+type Encoding struct {
+	Charset string // "" means UTF8
+	CRLF    bool
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Plain reports whether e is UTF-8 without a BOM and with LF line endings.
+func (e Encoding) Plain() bool {
+	return (e.Charset == "" || e.Charset == UTF8) && !e.CRLF
+}
+
+func (e Encoding) String() string {
+	s := strings.ToUpper(e.Charset)
+	switch e.Charset {
+	case "", UTF8:
+		s = "UTF-8"
+	case UTF8BOM:
+		s = "UTF-8 with BOM"
+	}
+	if e.CRLF {
+		s += ", CRLF"
+	}
+	return s
+}
+
+// Decode returns data as text with "\n" line endings, along with the
+// encoding it was found in. A byte order mark selects the charset; UTF-16
+// without one is recognised by its NUL bytes, which env files never hold.
+func Decode(data []byte) (string, Encoding, error) {
+	var e Encoding
+	var text string
+	body := data
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		e.Charset = UTF8BOM
+		body = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		e.Charset, body = UTF16LE, data[2:]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		e.Charset, body = UTF16BE, data[2:]
+	case len(data) >= 2 && data[1] == 0:
+		e.Charset = UTF16LE
+	case len(data) >= 2 && data[0] == 0:
+		e.Charset = UTF16BE
+	}
+	if e.Charset == UTF16LE || e.Charset == UTF16BE {
+		if len(body)%2 != 0 {
+			return "", e, errors.New("truncated UTF-16 text")
+		}
+		units := make([]uint16, len(body)/2)
+		for i := range units {
+			hi, lo := body[2*i+1], body[2*i]
+			if e.Charset == UTF16BE {
+				hi, lo = lo, hi
+			}
+			units[i] = uint16(hi)<<8 | uint16(lo)
+		}
+		text = string(utf16.Decode(units))
+	} else {
+		text = string(body)
+	}
+	if strings.Contains(text, "\r\n") {
+		e.CRLF = true
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return text, e, nil
+}
+
+// Encode converts text with "\n" line endings to e.
+func (e Encoding) Encode(text string) []byte {
+	if e.CRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	switch e.Charset {
+	case UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), text...)
+	case UTF16LE, UTF16BE:
+		units := utf16.Encode([]rune(text))
+		out := make([]byte, 0, 2+2*len(units))
+		if e.Charset == UTF16LE {
+			out = append(out, bomUTF16LE...)
+			for _, u := range units {
+				out = append(out, byte(u), byte(u>>8))
+			}
+		} else {
+			out = append(out, bomUTF16BE...)
+			for _, u := range units {
+				out = append(out, byte(u>>8), byte(u))
+			}
+		}
+		return out
+	}
+	return []byte(text)
+}
+
+// writer wraps fn so its output is converted to e.
+func (e Encoding) writer(fn func(w io.Writer) error) func(w io.Writer) error {
+	if e.Plain() {
+		return fn
+	}
+	return func(w io.Writer) error {
+		var buf strings.Builder
+		if err := fn(&buf); err != nil {
+			return err
+		}
+		_, err := w.Write(e.Encode(buf.String()))
+		return err
+	}
+}
+
+// ParseCharset checks a charset name, case-insensitively.
+func ParseCharset(name string) (string, error) {
+	n := strings.ToLower(name)
+	switch n {
+	case "utf8":
+		n = UTF8
+	case "utf16le":
+		n = UTF16LE
+	case "utf16be":
+		n = UTF16BE
+	}
+	for _, c := range Charsets {
+		if n == c {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown encoding %q (%s)", name, strings.Join(Charsets, ", "))
+}
+
+// Encoding returns the encoding path was last imported in, when that was
+// not plain UTF-8.
+func (s *Store) Encoding(path string) (Encoding, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.encodings[filepath.Clean(path)]
+	return e, ok
+}
+
+func (s *Store) recordEncoding(path string, e Encoding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path = filepath.Clean(path)
+	if e.Plain() {
+		delete(s.encodings, path)
+		return
+	}
+	if s.encodings == nil {
+		s.encodings = make(map[string]Encoding)
+	}
+	s.encodings[path] = e
+}
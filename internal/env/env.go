@@ -4,11 +4,16 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
-	"sort"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/secmem"
 )
 
 type Item struct {
@@ -16,15 +21,50 @@ type Item struct {
 	Value    string
 	Modified bool
 	Deleted  bool
+	From     *Provenance // set by parsing and on export when known
+	Expires  time.Time   // rotation deadline; zero when none
+	Section  string      // name of the section comment above it, set by parsing
+
+	sealed *secmem.Sealed // Value, when the store holds it sealed
 }
 
 type Store struct {
 	mu       sync.RWMutex
 	order    []string        // stable key order
+	natural  bool            // order compares digit runs by value
 	items    map[string]Item // current items
 	filtered []string        // keys matching filter
 	query    string
 	dirty    bool
+	layers   map[string][]Layer // per-key values by source, in load order
+	seq      map[string]int     // first-seen position of each key
+	nextSeq  int
+	prov     map[string]Provenance
+	expiry   map[string]time.Time
+	section  map[string]string // section header each key was loaded under
+	sections []string          // section names, first seen first
+	// bySection groups keys by section in the order; see SetSectionSort.
+	bySection bool
+
+	encodings map[string]Encoding // imported files not in plain UTF-8, by path
+	formats   map[string]string   // imported files not in dotenv, by path
+	meta      map[string]string   // meta-variables, referenced as ${@name}
+
+	tx        *txState
+	listeners []func([]Op)
+	undo      []change // latest last
+	redo      []change
+
+	// A detached store is loaded from a fixed environ list; Apply updates
+	// that list rather than the running process.
+	detached bool
+	base     []string
+
+	fsys FS // nil means OSFS
+	age  AgeKeys
+
+	keyPolicy KeyPolicy
+	keyRe     *regexp.Regexp // compiled keyPolicy.Pattern, nil for POSIX names
 }
 
 func NewStore() *Store {
@@ -35,12 +75,61 @@ func NewStore() *Store {
 	return s
 }
 
+// NewStoreFrom returns a detached store loaded from environ, a list of
+// KEY=VALUE strings in os.Environ form.
+func NewStoreFrom(environ []string) *Store {
+	s := &Store{
+		items:    make(map[string]Item),
+		detached: true,
+		base:     append([]string{}, environ...),
+	}
+	s.LoadFromProcess()
+	return s
+}
+
+// NewStoreFromItems returns a detached store holding items as they were
+// parsed, with their provenance, deadlines and sections, unmodified.
+func NewStoreFromItems(items []Item) *Store {
+	environ := make([]string, len(items))
+	for i, it := range items {
+		environ[i] = it.Key + "=" + it.Value
+	}
+	s := NewStoreFrom(environ)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, it := range items {
+		if it.From != nil {
+			s.setProvenanceLocked(it.Key, *it.From)
+		}
+		if !it.Expires.IsZero() {
+			s.setExpiryLocked(it.Key, it.Expires)
+		}
+		if it.Section != "" {
+			s.setSectionLocked(it.Key, it.Section)
+		}
+	}
+	return s
+}
+
+// LoadFromProcess resets the store to the process environment, or to the
+// original list for a detached store.
 func (s *Store) LoadFromProcess() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.order = s.order[:0]
+	s.destroyAllLocked()
 	s.items = make(map[string]Item)
-	env := os.Environ()
+	s.layers = make(map[string][]Layer)
+	s.seq = make(map[string]int)
+	s.nextSeq = 0
+	s.prov = make(map[string]Provenance)
+	s.expiry = make(map[string]time.Time)
+	s.section = make(map[string]string)
+	s.sections = nil
+	env := s.base
+	if !s.detached {
+		env = os.Environ()
+	}
 	for _, e := range env {
 		parts := strings.SplitN(e, "=", 2)
 		key := parts[0]
@@ -48,13 +137,16 @@ func (s *Store) LoadFromProcess() {
 		if len(parts) > 1 {
 			val = parts[1]
 		}
-		s.items[key] = Item{Key: key, Value: val}
+		s.setItemLocked(Item{Key: key, Value: val})
 		s.order = append(s.order, key)
+		s.markSeenLocked(key)
+		s.recordLayerLocked(key, SourceProcess, val)
 	}
-	sort.Strings(s.order)
+	slices.SortFunc(s.order, s.compareLocked)
 	s.filtered = append([]string{}, s.order...)
 	s.query = ""
 	s.dirty = false
+	s.undo, s.redo = nil, nil
 }
 
 func (s *Store) ListKeys() []string {
@@ -63,6 +155,24 @@ func (s *Store) ListKeys() []string {
 	return append([]string{}, s.filtered...)
 }
 
+// AllKeys returns every key in order, ignoring the filter.
+func (s *Store) AllKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string{}, s.order...)
+}
+
+// Vars returns every key's value, ignoring the filter.
+func (s *Store) Vars() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]string, len(s.items))
+	for k, it := range s.items {
+		out[k] = it.open().Value
+	}
+	return out
+}
+
 func (s *Store) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -75,37 +185,72 @@ func (s *Store) GetByIndex(idx int) (Item, bool) {
 	if idx < 0 || idx >= len(s.filtered) {
 		return Item{}, false
 	}
-	key := s.filtered[idx]
-	it, ok := s.items[key]
-	return it, ok
+	return s.itemLocked(s.filtered[idx])
+}
+
+func (s *Store) Get(key string) (Item, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.itemLocked(key)
 }
 
-func (s *Store) Upsert(key, val string) {
+// Upsert sets key to val. When the key policy rejects key, which it does
+// only for keys not yet in the store, it returns the *KeyError and leaves
+// the store alone.
+func (s *Store) Upsert(key, val string) error {
+	if err := s.CheckKey(key); err != nil && err.(*KeyError).Rejected {
+		if _, ok := s.Get(key); !ok {
+			return err
+		}
+	}
+	s.set(key, val)
+	return nil
+}
+
+// set is Upsert without the key policy, for keys already in the store.
+func (s *Store) set(key, val string) {
+	// A transaction of its own, when not in one, makes it undoable.
+	s.Begin()
+	defer s.Commit()
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	_, exists := s.items[key]
-	s.items[key] = Item{Key: key, Value: val, Modified: true}
+	prev, exists := s.itemLocked(key)
+	s.setItemLocked(Item{Key: key, Value: val, Modified: true})
+	delete(s.prov, key)
 	if !exists {
-		s.order = insertSortedUnique(s.order, key)
+		s.order = insertSortedUnique(s.order, key, s.compareLocked)
+		s.markSeenLocked(key)
 	}
 	s.applyFilterLocked(s.query)
 	s.dirty = true
-	_ = os.Setenv(key, val)
+	op := Op{Key: key, Value: val}
+	if exists {
+		op.Prev = &prev
+	}
+	listeners := s.recordLocked(op)
+	s.mu.Unlock()
+	notify(listeners, op)
 }
 
 func (s *Store) Delete(key string) {
+	s.Begin()
+	defer s.Commit()
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if it, ok := s.items[key]; ok {
-		it.Deleted = true
-		it.Modified = true
-		s.items[key] = it
+	it, ok := s.itemLocked(key)
+	if !ok {
+		s.mu.Unlock()
+		return
 	}
-	delete(s.items, key)
+	s.deleteItemLocked(key)
+	delete(s.prov, key)
+	delete(s.expiry, key)
+	delete(s.section, key)
 	removeKey(&s.order, key)
 	removeKey(&s.filtered, key)
 	s.dirty = true
-	_ = os.Unsetenv(key)
+	op := Op{Key: key, Delete: true, Prev: &it}
+	listeners := s.recordLocked(op)
+	s.mu.Unlock()
+	notify(listeners, op)
 }
 
 func (s *Store) Filter(query string) {
@@ -123,7 +268,7 @@ func (s *Store) applyFilterLocked(query string) {
 	q := strings.ToLower(query)
 	out := make([]string, 0, len(s.order))
 	for _, k := range s.order {
-		v := s.items[k].Value
+		v := s.valueLocked(k)
 		if strings.Contains(strings.ToLower(k), q) || strings.Contains(strings.ToLower(v), q) {
 			out = append(out, k)
 		}
@@ -138,71 +283,226 @@ func (s *Store) Dirty() bool {
 }
 
 func (s *Store) Export(path string) error {
+	return s.ExportFormat(path, "dotenv")
+}
+
+// SetFS routes the store's file I/O through fsys.
+func (s *Store) SetFS(fsys FS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fsys = fsys
+}
+
+func (s *Store) fs() FS {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if path == "" {
-		path = ".env"
+	fsys := s.fsys
+	if fsys == nil {
+		fsys = OSFS{}
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(path)
+	return AgeFS(fsys, s.age)
+}
+
+// Import merges a dotenv file as one transaction; on a read error nothing
+// is applied. The file's encoding is remembered for Encoding.
+func (s *Store) Import(path string) (int, error) {
+	f, err := s.ReadImport(path)
 	if err != nil {
-		return err
-	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	for _, k := range s.order {
-		it, ok := s.items[k]
-		if !ok {
-			continue
-		}
-		line := fmt.Sprintf("%s=%s\n", safeKey(k), quoteIfNeeded(it.Value))
-		if _, err := w.WriteString(line); err != nil {
-			return err
-		}
+		return 0, err
 	}
-	if err := w.Flush(); err != nil {
-		return err
+	return s.MergeFile(f), nil
+}
+
+// ImportFile is a file read for import: its items and how it was written.
+type ImportFile struct {
+	Path  string
+	Items []Item
+
+	enc    Encoding
+	format string
+}
+
+// ReadImport reads path through the store's filesystem, without changing
+// the store, so that it can be checked before MergeFile imports it.
+func (s *Store) ReadImport(path string) (ImportFile, error) {
+	items, enc, format, err := parseFS(s.fs(), path)
+	if err != nil {
+		return ImportFile{}, err
 	}
-	return nil
+	return ImportFile{Path: path, Items: items, enc: enc, format: format}, nil
 }
 
-func (s *Store) Import(path string) (int, error) {
+// MergeFile is Import of a file ReadImport has read.
+func (s *Store) MergeFile(f ImportFile) int {
+	s.recordEncoding(f.Path, f.enc)
+	s.recordFormat(f.Path, f.format)
+	return s.Merge(f.Path, f.Items)
+}
+
+// ParseFile reads a dotenv file through the store's filesystem without
+// changing the store.
+func (s *Store) ParseFile(path string) ([]Item, error) {
+	return ParseFS(s.fs(), path)
+}
+
+// ParseFile reads a dotenv file from the OS filesystem, decrypting a .age
+// file with the identity AgeIdentityPath finds.
+func ParseFile(path string) ([]Item, error) {
+	return ParseFS(AgeFS(OSFS{}, AgeKeys{}), path)
+}
+
+// ParseFS reads a dotenv file from fsys, converting it from UTF-16 or
+// stripping a byte order mark when needed. Provenance and expiry comments
+// directly above a key are attached to its item, and each item is named the
+// section of the latest section comment ("## Database ##") above it. A file
+// DetectFormat finds to be in another format, JSON, YAML, Kubernetes
+// manifests, TOML or Java properties, is read as that instead, and of a
+// direnv .envrc only the assignments are read. A file encrypted with sops,
+// in any of its formats, is decrypted through the sops CLI and read as
+// dotenv, as is the environment of a dotenv-vault file a DOTENV_KEY opens.
+// On a read error the pairs parsed so far are returned along with it.
+func ParseFS(fsys FS, path string) ([]Item, error) {
+	items, _, _, err := parseFS(fsys, path)
+	return items, err
+}
+
+// parseFS is ParseFS, returning also the encoding and the format the file
+// was read in.
+func parseFS(fsys FS, path string) ([]Item, Encoding, string, error) {
 	if path == "" {
-		return 0, errors.New("import path required")
+		return nil, Encoding{}, "", errors.New("import path required")
 	}
-	file, err := os.Open(path)
+	file, err := fsys.Open(vaultFile(path))
 	if err != nil {
-		return 0, err
+		return nil, Encoding{}, "", err
 	}
 	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, Encoding{}, "", err
+	}
+	text, enc, err := Decode(data)
+	if err != nil {
+		return nil, enc, "", fmt.Errorf("%s: %w", path, err)
+	}
+	format := SourceDotenv
+	if IsVaultPath(path) {
+		if text, err = vaultDecrypt(fsys, path, text); err != nil {
+			return nil, enc, "", err
+		}
+		format = SourceVault
+	} else if typ := sopsType(path, text); typ != "" {
+		if text, err = sopsDecrypt(path, typ, text); err != nil {
+			return nil, enc, "", err
+		}
+	} else if format = DetectFormat(path, text); format != SourceDotenv {
+		items, err := parseStructured(format, text)
+		if err != nil {
+			return nil, enc, format, fmt.Errorf("%s: read as %s: %w", path, format, err)
+		}
+		return items, enc, format, nil
+	}
 
-	added := 0
-	sc := bufio.NewScanner(file)
+	envrc := IsEnvrcPath(path)
+	var items []Item
+	var meta Item      // comments collected for the next key
+	var section string // the latest section header
+	sc := bufio.NewScanner(strings.NewReader(text))
+	n := 0
 	for sc.Scan() {
+		n++
 		line := strings.TrimSpace(sc.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if line == "" {
+			meta = Item{}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if name, ok := sectionHeader(line); ok {
+				section = name
+			} else if p, ok := parseProvenance(line); ok {
+				meta.From = &p
+			} else if t, ok := parseExpires(line); ok {
+				meta.Expires = t
+			}
+			continue
+		}
+		var key, val string
+		ok := true
+		switch {
+		case envrc && !envrcAssign.MatchString(line):
+			slog.Debug("skipping envrc command", "path", path, "line", n)
+			meta = Item{}
 			continue
+		case envrc:
+			key, val = parseEnvrcLine(line)
+		default:
+			key, val, ok = parseKV(line)
 		}
-		key, val, ok := parseKV(line)
 		if !ok || key == "" {
+			slog.Warn("skipping unparsable line", "path", path, "line", n)
+			meta = Item{}
 			continue
 		}
-		s.Upsert(key, val)
-		added++
+		meta.Key, meta.Value, meta.Section = key, val, section
+		items = append(items, meta)
+		meta = Item{}
 	}
-	if err := sc.Err(); err != nil {
-		return added, err
+	return items, enc, format, sc.Err()
+}
+
+// Merge upserts items in one transaction and records them as the layer
+// named source. Items keep the provenance they were parsed with; the rest
+// are stamped as fetched from source now. It returns the number of items
+// applied, which leaves out keys the key policy rejects.
+func (s *Store) Merge(source string, items []Item) int {
+	now := time.Now()
+	s.Begin()
+	defer s.Commit()
+	n := 0
+	for _, it := range items {
+		if s.Upsert(it.Key, it.Value) != nil {
+			continue
+		}
+		n++
+		p := Provenance{Source: source, Fetched: now}
+		if it.From != nil {
+			p = *it.From
+		}
+		s.mu.Lock()
+		s.recordLayerLocked(it.Key, source, it.Value)
+		s.setProvenanceLocked(it.Key, p)
+		if !it.Expires.IsZero() {
+			s.setExpiryLocked(it.Key, it.Expires)
+		}
+		if it.Section != "" {
+			s.setSectionLocked(it.Key, it.Section)
+		}
+		s.mu.Unlock()
 	}
-	return added, nil
+	if s.SectionSort() {
+		s.mu.Lock()
+		s.resortLocked()
+		s.mu.Unlock()
+	}
+	return n
 }
 
 // Helpers
 
-func insertSortedUnique(arr []string, key string) []string {
-	i := sort.SearchStrings(arr, key)
-	if i < len(arr) && arr[i] == key {
+func (s *Store) markSeenLocked(key string) {
+	if _, ok := s.seq[key]; ok {
+		return
+	}
+	if s.seq == nil {
+		s.seq = make(map[string]int)
+	}
+	s.seq[key] = s.nextSeq
+	s.nextSeq++
+}
+
+func insertSortedUnique(arr []string, key string, cmp func(a, b string) int) []string {
+	i, found := slices.BinarySearchFunc(arr, key, cmp)
+	if found {
 		return arr
 	}
 	arr = append(arr, "")
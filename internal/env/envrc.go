@@ -0,0 +1,59 @@
+package env
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// envrcAssign matches the lines of a direnv .envrc that set a variable:
+// KEY=value, with or without export.
+var envrcAssign = regexp.MustCompile(`^(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*=`)
+
+// IsEnvrcPath reports whether path names a direnv .envrc file. Such a file
+// is a shell script: only its assignments are read, and direnv commands
+// such as use, layout and PATH_add are passed over.
+func IsEnvrcPath(path string) bool {
+	base := filepath.Base(plainPath(path))
+	return base == ".envrc" || strings.HasSuffix(base, ".envrc")
+}
+
+// parseEnvrcLine splits an .envrc assignment, unquoting the value as the
+// shell would: 'single' and "double" quoted parts, backslash escapes, and
+// nothing after the first unquoted blank, such as a trailing comment.
+// Variables in the value are left for the store to expand.
+func parseEnvrcLine(line string) (key, value string) {
+	if rest, ok := strings.CutPrefix(line, "export"); ok && strings.IndexAny(rest, " \t") == 0 {
+		line = strings.TrimSpace(rest)
+	}
+	key, raw, _ := strings.Cut(line, "=")
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case ' ', '\t', ';':
+			return key, b.String()
+		case '\'':
+			end := strings.IndexByte(raw[i+1:], '\'')
+			if end < 0 {
+				end = len(raw) - i - 1
+			}
+			b.WriteString(raw[i+1 : i+1+end])
+			i += end + 1
+		case '"':
+			for i++; i < len(raw) && raw[i] != '"'; i++ {
+				if raw[i] == '\\' && i+1 < len(raw) && strings.IndexByte(`$"\`+"`", raw[i+1]) >= 0 {
+					i++
+				}
+				b.WriteByte(raw[i])
+			}
+		case '\\':
+			if i+1 < len(raw) {
+				i++
+				b.WriteByte(raw[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return key, b.String()
+}
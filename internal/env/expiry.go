@@ -0,0 +1,93 @@
+package env
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const expiresPrefix = "# expires: "
+
+// Expiry is a key's rotation deadline.
+type Expiry struct {
+	Key string
+	At  time.Time
+}
+
+func expiresComment(t time.Time) string {
+	return expiresPrefix + t.Format(time.DateOnly)
+}
+
+// ParseExpiry reads a date as written in "# expires:" comments: a day, or
+// a full RFC 3339 time.
+func ParseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func parseExpires(line string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(line, expiresPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := ParseExpiry(rest)
+	return t, err == nil
+}
+
+// SetExpiry sets key's rotation deadline; the zero time clears it. Unlike
+// provenance, the deadline stays with the key when its value is edited.
+func (s *Store) SetExpiry(key string, t time.Time) {
+	// A transaction of its own, when not in one, makes it undoable.
+	s.Begin()
+	defer s.Commit()
+	s.mu.Lock()
+	s.setExpiryLocked(key, t)
+	s.dirty = true
+	op := Op{Key: key, Expires: &t}
+	listeners := s.recordLocked(op)
+	s.mu.Unlock()
+	notify(listeners, op)
+}
+
+func (s *Store) setExpiryLocked(key string, t time.Time) {
+	if t.IsZero() {
+		delete(s.expiry, key)
+		return
+	}
+	if s.expiry == nil {
+		s.expiry = make(map[string]time.Time)
+	}
+	s.expiry[key] = t
+}
+
+// Expiry returns key's rotation deadline.
+func (s *Store) Expiry(key string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.expiry[key]
+	return t, ok
+}
+
+// Expiring lists keys whose deadline falls within d from now, including
+// those already past, soonest first.
+func (s *Store) Expiring(d time.Duration) []Expiry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	limit := time.Now().Add(d)
+	var out []Expiry
+	for k, t := range s.expiry {
+		if _, ok := s.items[k]; ok && t.Before(limit) {
+			out = append(out, Expiry{Key: k, At: t})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].At.Equal(out[j].At) {
+			return out[i].At.Before(out[j].At)
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
@@ -0,0 +1,480 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Formatter writes items, in order, in one output format.
+type Formatter func(w io.Writer, items []Item) error
+
+type format struct {
+	write    Formatter
+	file     string // default file name when used as a batch target
+	blanks   bool   // line-based: groups may be separated by blank lines
+	comments bool   // "#" comment lines are allowed between entries
+	ext      string // file extension that selects the format
+	doc      bool   // a table for reading, not an environment to load
+	kind     string // Kubernetes object kind; write is made per export
+}
+
+var formats = map[string]format{
+	"dotenv":   {write: writeDotenv, file: ".env", blanks: true, comments: true},
+	"fly":      {write: writeFly, file: "fly.secrets", blanks: true},
+	"heroku":   {write: writeHeroku, file: "heroku-config.sh"},
+	"vercel":   {write: writeVercel, file: ".env.vercel", blanks: true, comments: true},
+	"markdown": {write: writeMarkdown, file: "ENV.md", ext: ".md", doc: true},
+	"csv":      {write: writeCSV, file: "env.csv", ext: ".csv", doc: true},
+	"json":     {write: writeJSON, file: "env.json", ext: ".json"},
+	"bash":     {write: writeBash, file: "env.sh", ext: ".sh", blanks: true, comments: true},
+	"fish":     {write: writeFish, file: "env.fish", ext: ".fish", blanks: true, comments: true},
+	"pwsh":     {write: writePwsh, file: "env.ps1", ext: ".ps1", blanks: true, comments: true},
+	"envrc":    {write: writeBash, file: ".envrc", ext: ".envrc", blanks: true, comments: true},
+	"toml":     {write: writeTOML, file: "env.toml", ext: ".toml"},
+	"chamber":  {write: writeChamber, file: "chamber.json"},
+
+	"properties": {write: writeProperties, file: "env.properties", ext: ".properties", blanks: true, comments: true},
+
+	"k8s-configmap": {file: "configmap.yaml", kind: kindConfigMap},
+	"k8s-secret":    {file: "secret.yaml", kind: kindSecret},
+}
+
+// ExportOptions controls how a store is written.
+type ExportOptions struct {
+	Format string // registered format name; "" means dotenv
+	Order  Order
+	Schema []string // key order for OrderSchema
+
+	// Provenance writes a "# from:" comment above each key whose source
+	// is known, in formats that allow comments.
+	Provenance bool
+
+	// Redact rewrites sensitive values when set.
+	Redact *Redaction
+
+	// ResolveRefs says whether the secrets keyring:// and op:// references
+	// name are written in their place. Nil resolves them in every format
+	// but dotenv, which keeps the reference so the secret stays out of the
+	// file, and the documentation formats.
+	ResolveRefs *bool
+
+	// Encoding of the file; the zero value is plain UTF-8.
+	Encoding Encoding
+
+	// Name is the object name of a Kubernetes manifest; by default it is
+	// made from the file name.
+	Name string
+
+	// Only, when not nil, limits the export to these keys.
+	Only []string
+}
+
+// FormatNames lists the registered export formats.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for n := range formats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TargetFile returns the default file name for a batch export target.
+func TargetFile(name string) (string, bool) {
+	f, ok := formats[name]
+	return f.file, ok
+}
+
+// FormatForPath returns the format a path's extension selects, or "". An
+// age extension is looked past.
+func FormatForPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(plainPath(path)))
+	for name, f := range formats {
+		if f.ext != "" && f.ext == ext {
+			return name
+		}
+	}
+	return ""
+}
+
+// IsDocFormat reports whether name writes documentation, such as a
+// Markdown table, rather than a file the variables can be loaded from.
+func IsDocFormat(name string) bool {
+	return formats[name].doc
+}
+
+// ExportFormat writes the store to path in the named format.
+func (s *Store) ExportFormat(path, name string) error {
+	return s.ExportWith(path, ExportOptions{Format: name})
+}
+
+// ExportWith writes the store to path. An empty path uses the format's
+// default file name. A sops-encrypted file at path is written encrypted
+// again, and a dotenv-vault path into its environment. The encoding
+// written is remembered for Encoding.
+func (s *Store) ExportWith(path string, o ExportOptions) error {
+	fsys := s.fs()
+	if sealed, ok := sopsEncrypter(fsys, path); ok {
+		// A sops-managed file is written as dotenv for sops to encrypt
+		// back into its own format.
+		o.Format, fsys = "dotenv", sealed
+	} else if sealed, ok := vaultEncrypter(fsys, path); ok {
+		o.Format, fsys = "dotenv", sealed
+	}
+	if o.Format == "" {
+		o.Format = "dotenv"
+	}
+	f, ok := formats[o.Format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", o.Format)
+	}
+	if path == "" {
+		path = f.file
+	}
+	if f.kind != "" {
+		f.write = manifestWriter(f.kind, cmp.Or(o.Name, ManifestName(path)))
+	}
+	groups := s.ordered(o.Order, o.Schema)
+	if o.Only != nil {
+		groups = onlyKeys(groups, o.Only)
+	}
+	if err := s.resolveItems(groups); err != nil {
+		return err
+	}
+	if resolve := o.ResolveRefs; resolve != nil && *resolve || resolve == nil && o.Format != "dotenv" && !f.doc {
+		if err := resolveRefs(groups); err != nil {
+			return err
+		}
+	}
+	if o.Redact != nil {
+		for _, g := range groups {
+			for i := range g {
+				g[i].Value, _ = o.Redact.Apply(g[i].Key, g[i].Value)
+			}
+		}
+	}
+	err := writeFile(fsys, path, o.Encoding.writer(func(w io.Writer) error {
+		if !f.blanks {
+			var all []Item
+			for _, g := range groups {
+				all = append(all, g...)
+			}
+			return f.write(w, all)
+		}
+		for i, g := range groups {
+			if i > 0 {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			if f.comments {
+				if err := writeWithComments(w, f.write, g, o.Provenance); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.write(w, g); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	if err == nil {
+		s.recordEncoding(path, o.Encoding)
+	}
+	return err
+}
+
+// writeWithComments writes each item after its metadata comments: expiry
+// always, provenance when prov is set.
+func writeWithComments(w io.Writer, write Formatter, items []Item, prov bool) error {
+	for _, it := range items {
+		var comments []string
+		if prov && it.From != nil {
+			comments = append(comments, it.From.Comment())
+		}
+		if !it.Expires.IsZero() {
+			comments = append(comments, expiresComment(it.Expires))
+		}
+		for _, c := range comments {
+			if _, err := io.WriteString(w, c+"\n"); err != nil {
+				return err
+			}
+		}
+		if err := write(w, []Item{it}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportTargets writes one file per target format into dir and returns the
+// paths written.
+func (s *Store) ExportTargets(dir string, targets []string, o ExportOptions) ([]string, error) {
+	for _, t := range targets {
+		if _, ok := formats[t]; !ok {
+			return nil, fmt.Errorf("unknown target %q", t)
+		}
+	}
+	var written []string
+	for _, t := range targets {
+		path := filepath.Join(dir, formats[t].file)
+		o.Format = t
+		if err := s.ExportWith(path, o); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func writeFile(fsys FS, path string, fn func(w io.Writer) error) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := fsys.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := fn(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeDotenv(w io.Writer, items []Item) error {
+	for _, it := range items {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", safeKey(it.Key), quoteIfNeeded(it.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendDotenv adds items as KEY=value lines to the end of the dotenv file
+// at path, leaving what is there untouched. A missing file is created
+// readable only by its owner.
+func AppendDotenv(path string, items []Item) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, fi.Size()-1); err == nil && last[0] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	writeDotenv(&buf, items)
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFly emits input for `flyctl secrets import`: NAME=VALUE lines, with
+// multi-line values wrapped in triple quotes.
+func writeFly(w io.Writer, items []Item) error {
+	for _, it := range items {
+		v := it.Value
+		if strings.Contains(v, "\n") {
+			v = `"""` + v + `"""`
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", safeKey(it.Key), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeroku emits a single `heroku config:set` command line.
+func writeHeroku(w io.Writer, items []Item) error {
+	var b strings.Builder
+	b.WriteString("heroku config:set")
+	for _, it := range items {
+		b.WriteString(" \\\n  ")
+		b.WriteString(shellQuote(safeKey(it.Key) + "=" + it.Value))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeVercel mirrors the output of `vercel env pull`: every value double
+// quoted, with newlines escaped.
+func writeVercel(w io.Writer, items []Item) error {
+	for _, it := range items {
+		v := strings.ReplaceAll(it.Value, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		v = strings.ReplaceAll(v, "\n", `\n`)
+		if _, err := fmt.Fprintf(w, "%s=\"%s\"\n", safeKey(it.Key), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBash emits "export K=V" lines to source into bash or another POSIX
+// shell. Values are single quoted unless they are plainly safe.
+func writeBash(w io.Writer, items []Item) error {
+	for _, it := range items {
+		v := it.Value
+		if v == "" || strings.ContainsFunc(v, shellSpecial) {
+			v = shellQuote(v)
+		}
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", safeKey(it.Key), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellSpecial reports whether r needs quoting in a shell word.
+func shellSpecial(r rune) bool {
+	return !wordRune(r) && !strings.ContainsRune("-.,:/@%+=", r)
+}
+
+// wordRune reports whether r is an ASCII letter, digit or underscore.
+func wordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_'
+}
+
+// writeFish emits "set -gx K V" lines for fish's source. -g keeps the
+// variables when the file is sourced from inside a function. Inside fish
+// single quotes only \ and \' are escapes.
+func writeFish(w io.Writer, items []Item) error {
+	for _, it := range items {
+		v := strings.ReplaceAll(it.Value, `\`, `\\`)
+		v = strings.ReplaceAll(v, "'", `\'`)
+		if _, err := fmt.Fprintf(w, "set -gx %s '%s'\n", safeKey(it.Key), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePwsh emits `$env:K = "V"` lines for PowerShell's dot-sourcing; a
+// key that is not a plain name is braced. Backtick, dollar and the double
+// quotes PowerShell recognizes, typographic ones included, are escaped
+// with a backtick, and so are line breaks.
+func writePwsh(w io.Writer, items []Item) error {
+	for _, it := range items {
+		var v strings.Builder
+		for _, r := range it.Value {
+			switch r {
+			case '`', '$', '"', '\u201C', '\u201D', '\u201E':
+				v.WriteRune('`')
+				v.WriteRune(r)
+			case '\n':
+				v.WriteString("`n")
+			case '\r':
+				v.WriteString("`r")
+			case 0:
+				v.WriteString("`0")
+			default:
+				v.WriteRune(r)
+			}
+		}
+		key := safeKey(it.Key)
+		name := "$env:" + key
+		if strings.ContainsFunc(key, func(r rune) bool { return !wordRune(r) }) {
+			name = "${env:" + strings.NewReplacer("`", "``", "}", "`}").Replace(key) + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%s = \"%s\"\n", name, v.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeMarkdown emits a two-column Markdown table. Values are code spans;
+// a multi-line value is written as text with <br> between its lines so the
+// variable stays on one row. Pipes are escaped either way.
+func writeMarkdown(w io.Writer, items []Item) error {
+	var b strings.Builder
+	b.WriteString("| Variable | Value |\n| --- | --- |\n")
+	for _, it := range items {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", safeKey(it.Key), markdownCell(it.Value))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(v string) string {
+	v = strings.ReplaceAll(v, "|", `\|`)
+	switch {
+	case v == "":
+		return ""
+	case strings.Contains(v, "\n"):
+		v = strings.ReplaceAll(v, "\r\n", "\n")
+		return strings.ReplaceAll(v, "\n", "<br>")
+	case strings.Contains(v, "`"):
+		return "`` " + v + " ``"
+	}
+	return "`" + v + "`"
+}
+
+// writeCSV emits a header row and one key,value record per variable.
+func writeCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"key", "value"}); err != nil {
+		return err
+	}
+	for _, it := range items {
+		if err := cw.Write([]string{safeKey(it.Key), it.Value}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON emits one flat object mapping each key to its value as a
+// string, in store order. HTML characters are left unescaped so URLs read
+// as written.
+func writeJSON(w io.Writer, items []Item) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	str := func(s string) string {
+		b.Reset()
+		enc.Encode(s) // a string always encodes
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	var out strings.Builder
+	out.WriteString("{")
+	for i, it := range items {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		fmt.Fprintf(&out, "\n  %s: %s", str(safeKey(it.Key)), str(it.Value))
+	}
+	if len(items) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
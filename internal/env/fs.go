@@ -0,0 +1,120 @@
+package env
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the filesystem a Store imports from and exports to. Unlike a plain
+// fs.FS, names are ordinary OS-style paths and may be absolute.
+type FS interface {
+	fs.FS
+	MkdirAll(path string, perm fs.FileMode) error
+	Create(path string) (io.WriteCloser, error)
+}
+
+// OSFS is the real filesystem.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error)            { return os.Open(name) }
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Create(path string) (io.WriteCloser, error)   { return os.Create(path) }
+
+// MemFS is an in-memory FS. Directories are implicit.
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string][]byte)}
+}
+
+func memKey(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))
+}
+
+// WriteFile stores data at name.
+func (m *MemFS) WriteFile(name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[memKey(name)] = append([]byte{}, data...)
+}
+
+// ReadFile returns a copy of the data stored at name.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[memKey(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte{}, data...), nil
+}
+
+// Names lists stored files.
+func (m *MemFS) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]string, 0, len(m.files))
+	for n := range m.files {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		err.(*fs.PathError).Op = "open"
+		return nil, err
+	}
+	return &memFile{name: path.Base(memKey(name)), Reader: bytes.NewReader(data)}, nil
+}
+
+func (m *MemFS) MkdirAll(string, fs.FileMode) error { return nil }
+
+func (m *MemFS) Create(name string) (io.WriteCloser, error) {
+	return &memWriter{fs: m, name: name}, nil
+}
+
+type memFile struct {
+	name string
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return memInfo{f.name, f.Size()}, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memInfo struct {
+	name string
+	size int64
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return 0o644 }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return false }
+func (i memInfo) Sys() any           { return nil }
+
+type memWriter struct {
+	fs   *MemFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *memWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *memWriter) Close() error {
+	w.fs.WriteFile(w.name, w.buf.Bytes())
+	return nil
+}
@@ -0,0 +1,126 @@
+package env
+
+import (
+	"slices"
+	"time"
+)
+
+// historyLimit is how many changes Undo can step back through.
+const historyLimit = 200
+
+// change is one undoable batch of ops with the state before it.
+type change struct {
+	before snapshot
+	ops    []Op
+}
+
+func (s *Store) pushUndoLocked(c change) {
+	// Stepping needs only the keys ops touched, and which changed a
+	// deadline; keeping their values would leave secrets in the history in
+	// the clear.
+	keys := make([]Op, len(c.ops))
+	for i, op := range c.ops {
+		keys[i] = Op{Key: op.Key, Expires: op.Expires}
+	}
+	c.ops = keys
+	s.undo = append(s.undo, c)
+	if n := len(s.undo) - historyLimit; n > 0 {
+		for _, old := range s.undo[:n] {
+			old.before.destroy()
+		}
+		s.undo = slices.Delete(s.undo, 0, n)
+	}
+	for _, r := range s.redo {
+		r.before.destroy()
+	}
+	s.redo = nil
+}
+
+// Undo reverts the latest change: an edit, a delete, or a whole import or
+// other transaction. It returns the ops that undid it, which listeners
+// receive too, and false when there is nothing to undo.
+func (s *Store) Undo() ([]Op, bool) {
+	return s.step(&s.undo, &s.redo)
+}
+
+// Redo reapplies the change Undo last reverted.
+func (s *Store) Redo() ([]Op, bool) {
+	return s.step(&s.redo, &s.undo)
+}
+
+// CanUndo and CanRedo report how many changes each can step through.
+func (s *Store) CanUndo() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.undo)
+}
+
+func (s *Store) CanRedo() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.redo)
+}
+
+// step moves the store back to the state saved by the top of from, saving
+// the current state on to.
+func (s *Store) step(from, to *[]change) ([]Op, bool) {
+	s.mu.Lock()
+	if s.tx != nil || len(*from) == 0 {
+		s.mu.Unlock()
+		return nil, false
+	}
+	c := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	now := s.snapshotLocked()
+	s.restoreLocked(c.before)
+	*to = append(*to, change{before: now, ops: c.ops})
+	ops := diffOps(now.items, s.items, s.expiry, c.ops)
+	listeners := slices.Clone(s.listeners)
+	s.mu.Unlock()
+
+	if len(ops) > 0 {
+		for _, fn := range listeners {
+			fn(ops)
+		}
+	}
+	return ops, true
+}
+
+// diffOps describes the move from items before to items after for the keys
+// touched by ops, once per key in the order first touched, and the
+// deadlines of the keys whose expiry ops touched as they are in expiry.
+func diffOps(before, after map[string]Item, expiry map[string]time.Time, ops []Op) []Op {
+	var out []Op
+	seen := make(map[string]bool)
+	seenExpiry := make(map[string]bool)
+	for _, op := range ops {
+		if op.Expires != nil {
+			if !seenExpiry[op.Key] {
+				seenExpiry[op.Key] = true
+				t := expiry[op.Key]
+				out = append(out, Op{Key: op.Key, Expires: &t})
+			}
+			continue
+		}
+		if seen[op.Key] {
+			continue
+		}
+		seen[op.Key] = true
+		var d Op
+		d.Key = op.Key
+		if prev, ok := before[op.Key]; ok {
+			prev = prev.open()
+			d.Prev = &prev
+		}
+		if it, ok := after[op.Key]; ok {
+			d.Value = it.open().Value
+		} else {
+			d.Delete = true
+		}
+		if d.Prev == nil && d.Delete {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package env
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Kind is the shape of a value, inferred from the value and its key.
+type Kind int
+
+const (
+	KindNone Kind = iota // empty value
+	KindString
+	KindInt
+	KindBool
+	KindURL
+	KindPath
+	KindSecret
+	KindJSON
+)
+
+var kindNames = [...]string{"", "string", "int", "bool", "URL", "path", "secret", "JSON"}
+
+func (k Kind) String() string { return kindNames[k] }
+
+// Infer guesses what kind of value key holds. A probable secret is
+// reported as such whatever its shape, so it can be handled with care.
+func Infer(key, value string) Kind {
+	v := strings.TrimSpace(value)
+	switch {
+	case v == "":
+		return KindNone
+	case LooksSecret(key, value):
+		return KindSecret
+	case (v[0] == '{' || v[0] == '[') && json.Valid([]byte(v)):
+		return KindJSON
+	case isBool(v):
+		return KindBool
+	case isInt(v):
+		return KindInt
+	case isURL(v):
+		return KindURL
+	case LooksLikePath(key, value):
+		return KindPath
+	}
+	return KindString
+}
+
+func isBool(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	return false
+}
+
+func isInt(v string) bool {
+	_, err := strconv.ParseInt(v, 10, 64)
+	return err == nil
+}
+
+func isURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
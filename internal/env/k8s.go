@@ -0,0 +1,174 @@
+package env
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kubernetes object kinds the k8s formats read and write.
+const (
+	kindConfigMap = "ConfigMap"
+	kindSecret    = "Secret"
+)
+
+// IsManifestPath reports whether path names a YAML file, which is written
+// as Kubernetes manifests rather than dotenv.
+func IsManifestPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(plainPath(path))) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// manifest is the part of a Kubernetes object the parser looks at. A List
+// holds further objects in Items.
+type manifest struct {
+	Kind       string     `yaml:"kind"`
+	Data       yaml.Node  `yaml:"data"`
+	StringData yaml.Node  `yaml:"stringData"`
+	Items      []manifest `yaml:"items"`
+}
+
+// ParseManifests reads the ConfigMaps and Secrets in a YAML stream, in
+// order. Secret data is base64-decoded; stringData wins over data for the
+// same key, as it does in the cluster. Other kinds are skipped.
+func ParseManifests(text string) ([]Item, error) {
+	dec := yaml.NewDecoder(strings.NewReader(text))
+	var items []Item
+	found := false
+	var add func(m manifest) error
+	add = func(m manifest) error {
+		switch m.Kind {
+		case kindConfigMap:
+			found = true
+			return manifestData(&m.Data, false, &items)
+		case kindSecret:
+			found = true
+			if err := manifestData(&m.Data, true, &items); err != nil {
+				return err
+			}
+			return manifestData(&m.StringData, false, &items)
+		}
+		for _, it := range m.Items {
+			if err := add(it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for {
+		var m manifest
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := add(m); err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, errors.New("no ConfigMap or Secret found")
+	}
+	return dedupeItems(items), nil
+}
+
+// manifestData appends the pairs of a data map, in file order.
+func manifestData(n *yaml.Node, encoded bool, items *[]Item) error {
+	if n.Kind == 0 || n.Tag == "!!null" {
+		return nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: data is not a map", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if v.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: %s is not a string", v.Line, k.Value)
+		}
+		value := v.Value
+		if encoded {
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %s is not base64: %v", v.Line, k.Value, err)
+			}
+			value = string(b)
+		}
+		*items = append(*items, Item{Key: k.Value, Value: value})
+	}
+	return nil
+}
+
+// manifestKey is what Kubernetes accepts as a data key.
+var manifestKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// ManifestName turns a file name into an object name for manifests written
+// without --name: lower case, with runs of other characters as dashes.
+func ManifestName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(plainPath(path)), filepath.Ext(plainPath(path)))
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(base) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "env"
+	}
+	return b.String()
+}
+
+// manifestWriter returns a Formatter writing items as one object of kind,
+// named name, keeping the store's key order. Secret values are
+// base64-encoded into data.
+func manifestWriter(kind, name string) Formatter {
+	return func(w io.Writer, items []Item) error {
+		data := &yaml.Node{Kind: yaml.MappingNode}
+		for _, it := range items {
+			key := safeKey(it.Key)
+			if !manifestKey.MatchString(key) {
+				return fmt.Errorf("%q is not a valid %s key", key, kind)
+			}
+			v := it.Value
+			if kind == kindSecret {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			data.Content = append(data.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: v, Tag: "!!str"})
+		}
+		str := func(s string) *yaml.Node { return &yaml.Node{Kind: yaml.ScalarNode, Value: s} }
+		doc := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			str("apiVersion"), str("v1"),
+			str("kind"), str(kind),
+			str("metadata"), {Kind: yaml.MappingNode, Content: []*yaml.Node{str("name"), str(name)}},
+		}}
+		if kind == kindSecret {
+			doc.Content = append(doc.Content, str("type"), str("Opaque"))
+		}
+		doc.Content = append(doc.Content, str("data"), data)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+}
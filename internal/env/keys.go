@@ -0,0 +1,123 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyPolicy is the rule keys set through Upsert are held to. By default a
+// key should be a POSIX name: ASCII letters, digits and underscores, not
+// starting with a digit. Other keys are accepted with a warning unless
+// Reject is set.
+type KeyPolicy struct {
+	Pattern string `json:"pattern"` // a regexp keys must match instead
+	Reject  bool   `json:"reject"`  // refuse keys that break the policy
+}
+
+// KeyError describes a key that breaks the store's key policy.
+type KeyError struct {
+	Key      string
+	Problem  string
+	Fix      string // a key that passes, "" when none was found
+	Rejected bool   // the store refuses the key rather than warn
+}
+
+func (e *KeyError) Error() string {
+	msg := fmt.Sprintf("key %q %s", e.Key, e.Problem)
+	if e.Fix != "" {
+		msg += "; try " + e.Fix
+	}
+	return msg
+}
+
+// SetKeyPolicy sets the policy Upsert and CheckKey apply.
+func (s *Store) SetKeyPolicy(p KeyPolicy) error {
+	var re *regexp.Regexp
+	if p.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("bad key pattern: %w", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyPolicy, s.keyRe = p, re
+	return nil
+}
+
+// KeyPolicy returns the policy set by SetKeyPolicy.
+func (s *Store) KeyPolicy() KeyPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keyPolicy
+}
+
+// CheckKey returns a *KeyError when key breaks the key policy, whether the
+// store would refuse it or only warn.
+func (s *Store) CheckKey(key string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkKeyLocked(key)
+}
+
+func (s *Store) checkKeyLocked(key string) error {
+	problem := keyProblem(key)
+	if s.keyRe != nil {
+		problem = ""
+		if !s.keyRe.MatchString(key) {
+			problem = "does not match " + s.keyRe.String()
+		}
+	}
+	if problem == "" {
+		return nil
+	}
+	fix := FixKey(key)
+	if fix == key || s.keyRe != nil && !s.keyRe.MatchString(fix) {
+		fix = ""
+	}
+	return &KeyError{Key: key, Problem: problem, Fix: fix, Rejected: s.keyPolicy.Reject}
+}
+
+// keyProblem says why key is not a POSIX name, "" when it is one.
+func keyProblem(key string) string {
+	switch {
+	case identRe.MatchString(key):
+		return ""
+	case key == "":
+		return "is empty"
+	case strings.ContainsAny(key, " \t"):
+		return "contains a space"
+	case strings.Contains(key, "="):
+		return "contains ="
+	case strings.ContainsFunc(key, func(r rune) bool { return r > 0x7f }):
+		return "contains non-ASCII characters"
+	case key[0] >= '0' && key[0] <= '9':
+		return "starts with a digit"
+	}
+	return "contains characters other than letters, digits and _"
+}
+
+// FixKey suggests a POSIX name for key: every run of other characters
+// between valid ones becomes one underscore, runs at either end are
+// dropped, and a leading digit gets an underscore in front.
+func FixKey(key string) string {
+	var b strings.Builder
+	gap := false
+	for _, r := range key {
+		if !wordRune(r) {
+			gap = true
+			continue
+		}
+		if gap && b.Len() > 0 && r != '_' && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+		gap = false
+		b.WriteRune(r)
+	}
+	fixed := b.String()
+	if fixed != "" && fixed[0] >= '0' && fixed[0] <= '9' {
+		fixed = "_" + fixed
+	}
+	return fixed
+}
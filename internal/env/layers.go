@@ -0,0 +1,80 @@
+package env
+
+import (
+	"sort"
+
+	"github.com/rivethorn/envoy/internal/secmem"
+)
+
+// SourceProcess names the layer loaded from the process environment.
+const SourceProcess = "process"
+
+// Layer is one source's value for a key.
+type Layer struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+
+	sealed *secmem.Sealed // Value, when the store holds it sealed
+}
+
+// Override describes a key set by several layers with differing values.
+// Chain is ordered from lowest to highest precedence.
+type Override struct {
+	Key   string
+	Chain []Layer
+}
+
+func (s *Store) recordLayerLocked(key, source, val string) {
+	if s.layers == nil {
+		s.layers = make(map[string][]Layer)
+	}
+	chain := s.layers[key]
+	for i, l := range chain {
+		if l.Source == source {
+			// A source reloaded later keeps its place but moves to the top.
+			l.sealed.Destroy()
+			chain = append(chain[:i], chain[i+1:]...)
+			break
+		}
+	}
+	s.layers[key] = append(chain, sealLayer(key, Layer{Source: source, Value: val}))
+}
+
+// Layers returns the override chain recorded for key.
+func (s *Store) Layers(key string) []Layer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return openLayers(s.layers[key])
+}
+
+// Shadowed reports whether key is set by more than one layer with
+// differing values.
+func (s *Store) Shadowed(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return conflicting(s.layers[key])
+}
+
+// Overrides lists every shadowed key, sorted by key.
+func (s *Store) Overrides() []Override {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Override
+	for k, chain := range s.layers {
+		if _, ok := s.items[k]; ok && conflicting(chain) {
+			out = append(out, Override{Key: k, Chain: openLayers(chain)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func conflicting(chain []Layer) bool {
+	chain = openLayers(chain)
+	for i := 1; i < len(chain); i++ {
+		if chain[i].Value != chain[0].Value {
+			return true
+		}
+	}
+	return false
+}
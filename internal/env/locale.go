@@ -0,0 +1,181 @@
+package env
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Picker is how a key's value is chosen from what the machine provides.
+type Picker int
+
+const (
+	PickNone Picker = iota
+	PickLocale
+	PickZone
+)
+
+// PickerFor reports whether key takes a locale or a time zone.
+func PickerFor(key string) Picker {
+	switch {
+	case key == "LANG" || strings.HasPrefix(key, "LC_"):
+		return PickLocale
+	case key == "TZ":
+		return PickZone
+	}
+	return PickNone
+}
+
+// Choices lists the values available for p on this machine.
+func Choices(p Picker) ([]string, error) {
+	switch p {
+	case PickLocale:
+		return Locales()
+	case PickZone:
+		return Zones()
+	}
+	return nil, nil
+}
+
+// ValidChoice reports whether v exists on this machine for p. Other keys
+// accept any value.
+func ValidChoice(p Picker, v string) bool {
+	switch p {
+	case PickLocale:
+		return ValidLocale(v)
+	case PickZone:
+		return ValidZone(v)
+	}
+	return true
+}
+
+// Locales lists installed locales as reported by `locale -a`.
+func Locales() ([]string, error) {
+	out, err := exec.Command("locale", "-a").Output()
+	if err != nil {
+		return nil, err
+	}
+	var locs []string
+	for _, l := range strings.Split(string(bytes.TrimSpace(out)), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			locs = append(locs, l)
+		}
+	}
+	sort.Strings(locs)
+	return locs, nil
+}
+
+// ValidLocale reports whether v names an installed locale. Codesets are
+// compared the way glibc normalizes them, so en_US.UTF-8 matches
+// en_US.utf8.
+func ValidLocale(v string) bool {
+	if v == "" || v == "C" || v == "POSIX" {
+		return true
+	}
+	locs, err := Locales()
+	if err != nil {
+		return true // cannot tell; do not block edits
+	}
+	want := normalizeLocale(v)
+	for _, l := range locs {
+		if normalizeLocale(l) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLocale(v string) string {
+	name, mod, _ := strings.Cut(v, "@")
+	lang, codeset, ok := strings.Cut(name, ".")
+	if !ok {
+		return v
+	}
+	var b strings.Builder
+	for _, r := range codeset {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	out := lang + "." + b.String()
+	if mod != "" {
+		out += "@" + mod
+	}
+	return out
+}
+
+// zoneDirs are searched in order for the zoneinfo database.
+var zoneDirs = []string{"/usr/share/zoneinfo", "/usr/lib/zoneinfo", "/usr/share/lib/zoneinfo"}
+
+func zoneDir() string {
+	if d := os.Getenv("ZONEINFO"); d != "" {
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() {
+			return d
+		}
+	}
+	for _, d := range zoneDirs {
+		if _, err := os.Stat(d); err == nil {
+			return d
+		}
+	}
+	return ""
+}
+
+// Zones lists the time zones in the system zoneinfo database.
+func Zones() ([]string, error) {
+	dir := zoneDir()
+	if dir == "" {
+		return nil, os.ErrNotExist
+	}
+	var zones []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			// posix/ and right/ duplicate the main tree.
+			if name == "posix" || name == "right" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isTZif(path) && name != "posixrules" && name != "localtime" {
+			zones = append(zones, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	sort.Strings(zones)
+	return zones, err
+}
+
+func isTZif(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	_, err = f.Read(magic)
+	return err == nil && string(magic) == "TZif"
+}
+
+// ValidZone reports whether v is a usable TZ value: a zone name, or a
+// ":"-prefixed name or absolute zoneinfo path.
+func ValidZone(v string) bool {
+	if v == "" {
+		return true
+	}
+	v = strings.TrimPrefix(v, ":")
+	if filepath.IsAbs(v) {
+		return isTZif(v)
+	}
+	_, err := time.LoadLocation(v)
+	return err == nil
+}
@@ -0,0 +1,72 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// loginPassed are the variables a new terminal hands its login shell; the
+// rest of a login shell's environment comes from its startup files.
+var loginPassed = []string{
+	"HOME", "USER", "LOGNAME", "SHELL", "TERM", "LANG",
+	"DISPLAY", "WAYLAND_DISPLAY", "XDG_RUNTIME_DIR", "DBUS_SESSION_BUS_ADDRESS",
+}
+
+// loginMarker separates anything startup files print from the env output.
+const loginMarker = "ENVOY-LOGIN-ENV\x00"
+
+// loginPath is the PATH login(1) starts shells with.
+const loginPath = "/usr/local/bin:/usr/bin:/bin"
+
+// ShellVolatile are variables every shell sets for itself, which differ
+// between any two shells and say nothing about configuration.
+var ShellVolatile = []string{"_", "SHLVL", "PWD", "OLDPWD"}
+
+// LoginShell returns the user's shell: $SHELL, or /bin/sh.
+func LoginShell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
+
+// LoginEnviron starts shell as a fresh login shell from the minimal
+// environment a new terminal gives it and returns the environment its
+// startup files produce, without ShellVolatile.
+func LoginEnviron(ctx context.Context, shell string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, shell, "-l", "-c", `printf 'ENVOY-LOGIN-ENV\000'; env -0`)
+	cmd.Env = []string{"PATH=" + loginPath}
+	for _, k := range loginPassed {
+		if v, ok := os.LookupEnv(k); ok {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", shell, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", shell, err)
+	}
+
+	_, dump, ok := strings.Cut(string(out), loginMarker)
+	if !ok {
+		return nil, fmt.Errorf("%s: no environment in its output", shell)
+	}
+	vars := make(map[string]string)
+	for _, kv := range strings.Split(dump, "\x00") {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			vars[k] = v
+		}
+	}
+	for _, k := range ShellVolatile {
+		delete(vars, k)
+	}
+	return vars, nil
+}
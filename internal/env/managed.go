@@ -0,0 +1,22 @@
+package env
+
+// managedVars are variables the shell or terminal maintains, with who sets
+// them. An edit from Envoy is overwritten or never reaches a new shell.
+var managedVars = map[string]string{
+	"SHLVL":                "each shell sets it to its nesting depth as it starts",
+	"PWD":                  "the shell updates it on every cd",
+	"OLDPWD":               "the shell updates it on every cd",
+	"_":                    "the shell sets it for every command it runs",
+	"TERM":                 "the terminal sets it for the programs it starts",
+	"TERM_PROGRAM":         "the terminal sets it for the programs it starts",
+	"TERM_PROGRAM_VERSION": "the terminal sets it for the programs it starts",
+	"COLUMNS":              "the shell updates it when the window is resized",
+	"LINES":                "the shell updates it when the window is resized",
+}
+
+// Managed reports whether key is maintained by the shell or terminal, and
+// by what.
+func Managed(key string) (who string, ok bool) {
+	who, ok = managedVars[key]
+	return who, ok
+}
@@ -0,0 +1,137 @@
+package env
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// metaRef matches a reference to a meta-variable in a value: ${@name}.
+var metaRef = regexp.MustCompile(`\$\{@([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ValidMetaName reports whether name can be referenced as ${@name}.
+func ValidMetaName(name string) bool {
+	return identRe.MatchString(name)
+}
+
+// SetMeta defines or changes a meta-variable. Meta-variables belong to the
+// buffer, not the environment: values refer to them as ${@name} and are
+// resolved on export and apply.
+func (s *Store) SetMeta(name, value string) error {
+	if !ValidMetaName(name) {
+		return fmt.Errorf("invalid meta-variable name %q", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.meta == nil {
+		s.meta = make(map[string]string)
+	}
+	s.meta[name] = value
+	s.dirty = true
+	return nil
+}
+
+// DeleteMeta removes a meta-variable; references to it stay unresolved.
+func (s *Store) DeleteMeta(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.meta, name)
+	s.dirty = true
+}
+
+// Meta returns a copy of the meta-variables.
+func (s *Store) Meta() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.meta)
+}
+
+// MetaUsers lists the keys whose value refers to ${@name}, in key order.
+func (s *Store) MetaUsers(name string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []string
+	for _, k := range s.order {
+		if slices.Contains(metaNames(s.valueLocked(k)), name) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// Resolve replaces meta-variable references in value. Names without a
+// definition are left as written and returned in missing.
+func (s *Store) Resolve(value string) (resolved string, missing []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resolveLocked(value)
+}
+
+func (s *Store) resolveLocked(value string) (string, []string) {
+	if !strings.Contains(value, "${@") {
+		return value, nil
+	}
+	var missing []string
+	out := metaRef.ReplaceAllStringFunc(value, func(ref string) string {
+		name := metaRef.FindStringSubmatch(ref)[1]
+		v, ok := s.meta[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return ref
+		}
+		return v
+	})
+	return out, missing
+}
+
+// resolveItems resolves every value in groups in place. It fails when a
+// value refers to an undefined meta-variable.
+func (s *Store) resolveItems(groups [][]Item) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, g := range groups {
+		for i := range g {
+			v, missing := s.resolveLocked(g[i].Value)
+			if len(missing) > 0 {
+				return fmt.Errorf("%s refers to undefined @%s (:meta to define it)", g[i].Key, missing[0])
+			}
+			g[i].Value = v
+		}
+	}
+	return nil
+}
+
+// resolveRefs replaces keyring:// and op:// references in groups with the
+// secrets they name in the OS keychain or 1Password.
+func resolveRefs(groups [][]Item) error {
+	for _, g := range groups {
+		for i := range g {
+			if !cred.IsRef(g[i].Value) {
+				continue
+			}
+			v, err := cred.ResolveRef(g[i].Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", g[i].Key, err)
+			}
+			g[i].Value = v
+		}
+	}
+	return nil
+}
+
+// metaNames lists the meta-variables value refers to.
+func metaNames(value string) []string {
+	var out []string
+	for _, m := range metaRef.FindAllStringSubmatch(value, -1) {
+		if !slices.Contains(out, m[1]) {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
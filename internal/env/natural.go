@@ -0,0 +1,74 @@
+package env
+
+import (
+	"cmp"
+	"strings"
+)
+
+// CompareNatural orders strings with runs of digits compared by value, so
+// SERVER2 sorts before SERVER10. Equal values with different padding fall
+// back to plain comparison to keep the order total.
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			ei, ej := digitsEnd(a, i), digitsEnd(b, j)
+			na := strings.TrimLeft(a[i:ei], "0")
+			nb := strings.TrimLeft(b[j:ej], "0")
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			i, j = ei, ej
+			continue
+		}
+		if c := cmp.Compare(a[i], b[j]); c != 0 {
+			return c
+		}
+		i++
+		j++
+	}
+	if c := cmp.Compare(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func digitsEnd(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// SetNaturalSort switches the key order between plain lexicographic and
+// natural (CompareNatural) ordering.
+func (s *Store) SetNaturalSort(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.natural = on
+	s.resortLocked()
+}
+
+// NaturalSort reports whether keys are in natural order.
+func (s *Store) NaturalSort() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.natural
+}
+
+func (s *Store) compareLocked(a, b string) int {
+	if s.bySection {
+		if c := s.compareSectionLocked(a, b); c != 0 {
+			return c
+		}
+	}
+	if s.natural {
+		return CompareNatural(a, b)
+	}
+	return strings.Compare(a, b)
+}
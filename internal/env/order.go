@@ -0,0 +1,111 @@
+package env
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Order selects how exported keys are arranged.
+type Order string
+
+const (
+	OrderAlpha   Order = "alpha"   // lexicographic by key
+	OrderNatural Order = "natural" // numbers by value: SERVER2 before SERVER10
+	OrderFile    Order = "file"    // the order keys were first loaded or added
+	OrderPrefix  Order = "prefix"  // alphabetical, blank line between prefix groups
+	OrderSchema  Order = "schema"  // listed keys first, the rest alphabetical
+)
+
+// ParseOrder validates an order name; "" means alphabetical.
+func ParseOrder(s string) (Order, error) {
+	switch o := Order(strings.ToLower(s)); o {
+	case "":
+		return OrderAlpha, nil
+	case OrderAlpha, OrderNatural, OrderFile, OrderPrefix, OrderSchema:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown order %q (alpha, natural, file, prefix, schema)", s)
+	}
+}
+
+// ordered returns the items grouped for export. Only OrderPrefix yields
+// more than one group.
+func (s *Store) ordered(o Order, schema []string) [][]Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := append([]string{}, s.order...)
+	if s.natural {
+		// The table's natural order is not the export default.
+		sort.Strings(keys)
+	}
+	switch o {
+	case OrderNatural:
+		slices.SortFunc(keys, CompareNatural)
+	case OrderFile:
+		sort.SliceStable(keys, func(i, j int) bool { return s.seq[keys[i]] < s.seq[keys[j]] })
+	case OrderSchema:
+		rank := make(map[string]int, len(schema))
+		for i, k := range schema {
+			rank[k] = i + 1
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			ri, rj := rank[keys[i]], rank[keys[j]]
+			switch {
+			case ri > 0 && rj > 0:
+				return ri < rj
+			default:
+				return ri > 0 && rj == 0
+			}
+		})
+	}
+
+	var groups [][]Item
+	var cur []Item
+	prev := ""
+	for i, k := range keys {
+		it, ok := s.itemLocked(k)
+		if !ok {
+			continue
+		}
+		if o == OrderPrefix && i > 0 && keyPrefix(k) != prev && len(cur) > 0 {
+			groups = append(groups, cur)
+			cur = nil
+		}
+		prev = keyPrefix(k)
+		if p, ok := s.prov[k]; ok {
+			it.From = &p
+		}
+		it.Expires = s.expiry[k]
+		it.Section = s.section[k]
+		cur = append(cur, it)
+	}
+	if len(cur) > 0 {
+		groups = append(groups, cur)
+	}
+	return groups
+}
+
+// onlyKeys keeps the items of groups whose keys are in keys, leaving out
+// the groups that empties.
+func onlyKeys(groups [][]Item, keys []string) [][]Item {
+	var out [][]Item
+	for _, g := range groups {
+		g = slices.DeleteFunc(slices.Clone(g), func(it Item) bool { return !slices.Contains(keys, it.Key) })
+		if len(g) > 0 {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// keyPrefix returns the part of key up to and including the first
+// underscore, e.g. "AWS_" for AWS_REGION, or "" when there is none.
+func keyPrefix(key string) string {
+	if i := strings.IndexByte(key, '_'); i > 0 {
+		return key[:i+1]
+	}
+	return ""
+}
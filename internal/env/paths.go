@@ -0,0 +1,46 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var pathKeySuffixes = []string{"_PATH", "_FILE", "_DIR", "_CREDENTIALS", "_CERT", "_KEYFILE"}
+
+// LooksLikePath reports whether value is probably a single filesystem path,
+// judged by its shape or, for relative names, by the key.
+func LooksLikePath(key, value string) bool {
+	if value == "" || strings.ContainsAny(value, "\n:") {
+		// Colon-separated lists like PATH and URLs are not single paths.
+		return false
+	}
+	for _, p := range []string{"/", "~/", "./", "../"} {
+		if strings.HasPrefix(value, p) {
+			return true
+		}
+	}
+	k := strings.ToUpper(key)
+	for _, s := range pathKeySuffixes {
+		if strings.HasSuffix(k, s) {
+			return !strings.Contains(value, " ")
+		}
+	}
+	return false
+}
+
+// ResolvePath expands a leading "~/" in a path-like value.
+func ResolvePath(value string) string {
+	if strings.HasPrefix(value, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, value[2:])
+		}
+	}
+	return value
+}
+
+// PathExists reports whether the path a value refers to exists.
+func PathExists(value string) bool {
+	_, err := os.Stat(ResolvePath(value))
+	return err == nil
+}
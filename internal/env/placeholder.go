@@ -0,0 +1,12 @@
+package env
+
+import "regexp"
+
+// placeholderRe matches stub values: a whole value in angle brackets such
+// as <CHANGE_ME> or <your-api-key>, or a marker word anywhere in it.
+var placeholderRe = regexp.MustCompile(`^\s*<[^<>]+>\s*$|\b(?:TODO|FIXME|TBD|CHANGE_?ME|REPLACE_?ME)\b`)
+
+// IsPlaceholder reports whether value is a stub still to be filled in.
+func IsPlaceholder(value string) bool {
+	return placeholderRe.MatchString(value)
+}
@@ -0,0 +1,161 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// parseProperties reads a Java .properties file: "key=value", "key: value"
+// or "key value" lines, # and ! comments, a trailing backslash continuing
+// a line, and backslash escapes.
+func parseProperties(text string) ([]Item, error) {
+	var items []Item
+	lines := strings.Split(text, "\n")
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimLeft(lines[n], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continued(line) && n+1 < len(lines) {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[n], " \t\f")
+		}
+		key, value := splitProperty(line)
+		k, err := unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		v, err := unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		items = append(items, Item{Key: k, Value: v})
+	}
+	return items, nil
+}
+
+// continued reports whether line ends in an odd number of backslashes.
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// splitProperty splits a logical properties line at the first unescaped
+// '=', ':' or whitespace, with the whitespace around it.
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '\\':
+			i++
+		case '=', ':', ' ', '\t', '\f':
+			key, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+			if c == ' ' || c == '\t' || c == '\f' {
+				if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+					rest = rest[1:]
+				}
+			} else {
+				rest = rest[1:]
+			}
+			return key, strings.TrimLeft(rest, " \t\f")
+		}
+	}
+	return line, ""
+}
+
+// unescapeProperty resolves the backslash escapes of a properties key or
+// value: \t, \n, \r, \f, \uXXXX, and any other character as itself.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", errors.New(`bad \u escape`)
+			}
+			u, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errors.New(`bad \u escape`)
+			}
+			i += 4
+			r := rune(u)
+			// Outside the BMP a character is a pair of escapes.
+			if utf16.IsSurrogate(r) && i+7 <= len(s) && s[i+1:i+3] == `\u` {
+				if low, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					if pair := utf16.DecodeRune(r, rune(low)); pair != utf8.RuneError {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// writeProperties emits key=value lines Java's Properties.load reads back:
+// separators and comment marks in keys, leading spaces and line breaks in
+// values, and everything outside ASCII, as that reads ISO-8859-1, escaped.
+func writeProperties(w io.Writer, items []Item) error {
+	for _, it := range items {
+		line := escapeProperty(safeKey(it.Key), true) + "=" + escapeProperty(it.Value, false)
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r), !key && i == 0 && (r == '#' || r == '!'):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, u)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provenance records where a value was loaded from and when.
+type Provenance struct {
+	Source  string    `json:"source"`
+	Fetched time.Time `json:"fetched"`
+}
+
+const provenancePrefix = "# from: "
+
+// Comment renders p as the line written above a key on export.
+func (p Provenance) Comment() string {
+	return fmt.Sprintf("%s%s, fetched %s", provenancePrefix, p.Source, p.Fetched.UTC().Format(time.RFC3339))
+}
+
+// parseProvenance reads a comment written by Comment. A bare date is
+// accepted for hand-written comments.
+func parseProvenance(line string) (Provenance, bool) {
+	rest, ok := strings.CutPrefix(line, provenancePrefix)
+	if !ok {
+		return Provenance{}, false
+	}
+	i := strings.LastIndex(rest, ", fetched ")
+	if i < 0 {
+		return Provenance{Source: strings.TrimSpace(rest)}, true
+	}
+	p := Provenance{Source: strings.TrimSpace(rest[:i])}
+	ts := strings.TrimSpace(rest[i+len(", fetched "):])
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, ts); err == nil {
+			p.Fetched = t
+			break
+		}
+	}
+	return p, p.Source != ""
+}
+
+// Provenance reports where key's current value came from. Values edited
+// in Envoy or inherited from the process have none.
+func (s *Store) Provenance(key string) (Provenance, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.prov[key]
+	return p, ok
+}
+
+func (s *Store) setProvenanceLocked(key string, p Provenance) {
+	if s.prov == nil {
+		s.prov = make(map[string]Provenance)
+	}
+	s.prov[key] = p
+}
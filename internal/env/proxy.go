@@ -0,0 +1,178 @@
+package env
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// proxyFamilies are the proxy variables, lowercase. Each may also be set
+// in uppercase; tools disagree on which one they read.
+var proxyFamilies = []string{"http_proxy", "https_proxy", "ftp_proxy", "all_proxy", "no_proxy"}
+
+// ProxyIssue is a problem with one proxy variable family. Keys lists the
+// members that are set; Key is the one the problem is about.
+type ProxyIssue struct {
+	Family  string
+	Keys    []string
+	Key     string
+	Problem string
+}
+
+// ProxyIssues checks the proxy variables for missing case variants,
+// conflicting values and malformed URLs or NO_PROXY lists.
+func (s *Store) ProxyIssues() []ProxyIssue {
+	var out []ProxyIssue
+	for _, fam := range proxyFamilies {
+		var keys []string
+		for _, k := range []string{fam, strings.ToUpper(fam)} {
+			if _, ok := s.Get(k); ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		add := func(key, format string, a ...any) {
+			out = append(out, ProxyIssue{Family: fam, Keys: keys, Key: key, Problem: fmt.Sprintf(format, a...)})
+		}
+		vals := make([]string, len(keys))
+		for i, k := range keys {
+			it, _ := s.Get(k)
+			vals[i] = it.Value
+		}
+		switch {
+		case len(keys) == 1:
+			add(keys[0], "only %s is set; some tools read only the other case", keys[0])
+		case vals[0] != vals[1]:
+			add(keys[0], "%s and %s differ", keys[0], keys[1])
+		}
+		for i, k := range keys {
+			var problems []string
+			if fam == "no_proxy" {
+				problems = noProxyProblems(vals[i])
+			} else if p := proxyURLProblem(vals[i]); p != "" {
+				problems = []string{p}
+			}
+			for _, p := range problems {
+				add(k, "%s: %s", k, p)
+			}
+		}
+	}
+	return out
+}
+
+func proxyURLProblem(v string) string {
+	if v == "" {
+		return "empty"
+	}
+	if !strings.Contains(v, "://") {
+		return "no scheme; write http://" + v
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return err.Error()
+	}
+	if u.Host == "" {
+		return "no host"
+	}
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Sprintf("path %q is ignored by most tools", u.Path)
+	}
+	return ""
+}
+
+func noProxyProblems(v string) []string {
+	var out []string
+	if strings.ContainsAny(v, " \t") {
+		out = append(out, "contains whitespace")
+	}
+	for _, e := range strings.Split(v, ",") {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "":
+			out = append(out, "empty entry")
+		case strings.Contains(e, "://"):
+			out = append(out, fmt.Sprintf("%q has a scheme; use the bare host", e))
+		case strings.Contains(e, "/"):
+			if _, _, err := net.ParseCIDR(e); err != nil {
+				out = append(out, fmt.Sprintf("%q is not a host or valid CIDR", e))
+			}
+		case strings.HasPrefix(e, "*") && e != "*":
+			out = append(out, fmt.Sprintf("%q: wildcards are not widely supported; use %s", e, strings.TrimPrefix(e, "*")))
+		}
+	}
+	return dedupeStrings(out)
+}
+
+func dedupeStrings(in []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// NormalizeNoProxy trims whitespace, drops empty entries and strips URL
+// schemes and leading wildcards from a NO_PROXY list.
+func NormalizeNoProxy(v string) string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, e := range strings.Split(v, ",") {
+		e = strings.TrimSpace(e)
+		if i := strings.Index(e, "://"); i >= 0 {
+			e = strings.TrimSuffix(e[i+3:], "/")
+		}
+		if strings.HasPrefix(e, "*.") {
+			e = e[1:]
+		}
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return strings.Join(out, ",")
+}
+
+// SyncProxy sets both cases of key's proxy family to key's value,
+// normalizing NO_PROXY lists. It returns the keys written.
+func (s *Store) SyncProxy(key string) []string {
+	fam := strings.ToLower(key)
+	if !isProxyFamily(fam) {
+		return nil
+	}
+	it, ok := s.Get(key)
+	if !ok {
+		return nil
+	}
+	v := it.Value
+	if fam == "no_proxy" {
+		v = NormalizeNoProxy(v)
+	}
+	var written []string
+	s.Begin()
+	defer s.Commit()
+	for _, k := range []string{fam, strings.ToUpper(fam)} {
+		if cur, ok := s.Get(k); ok && cur.Value == v {
+			continue
+		}
+		s.Upsert(k, v)
+		written = append(written, k)
+	}
+	return written
+}
+
+func isProxyFamily(name string) bool {
+	for _, f := range proxyFamilies {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
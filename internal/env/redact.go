@@ -0,0 +1,97 @@
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Redaction modes.
+const (
+	RedactMask = "mask" // replace with <redacted>
+	RedactHash = "hash" // replace with a short SHA-256, so equal values stay comparable
+)
+
+const redactedText = "<redacted>"
+
+// Redaction is a named profile that rewrites sensitive values on export.
+// Keys and Keep are globs matched against the key, case-insensitively.
+type Redaction struct {
+	Secrets bool     `json:"secrets"` // values LooksSecret flags
+	URLs    bool     `json:"urls"`    // passwords inside URL values
+	Keys    []string `json:"keys"`    // always redacted
+	Keep    []string `json:"keep"`    // never redacted; wins over the rest
+	Mode    string   `json:"mode"`    // RedactMask (default) or RedactHash
+}
+
+// Redactions are the built-in profiles; config entries of the same name
+// replace them.
+var Redactions = map[string]Redaction{
+	"share-with-support": {Secrets: true, URLs: true},
+	"compare":            {Secrets: true, URLs: true, Mode: RedactHash},
+}
+
+// RedactionNames lists the built-in profile names, sorted.
+func RedactionNames() []string {
+	names := make([]string, 0, len(Redactions))
+	for n := range Redactions {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Validate reports a profile that cannot be applied.
+func (r Redaction) Validate() error {
+	switch r.Mode {
+	case "", RedactMask, RedactHash:
+	default:
+		return fmt.Errorf("unknown redaction mode %q (mask or hash)", r.Mode)
+	}
+	for _, g := range append(slices.Clone(r.Keys), r.Keep...) {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("bad key pattern %q: %w", g, err)
+		}
+	}
+	return nil
+}
+
+// Apply returns value as written under the profile and whether it changed.
+func (r Redaction) Apply(key, value string) (string, bool) {
+	if value == "" || matchAny(r.Keep, key) {
+		return value, false
+	}
+	if matchAny(r.Keys, key) || (r.Secrets && LooksSecret(key, value)) {
+		return r.replace(value), true
+	}
+	if r.URLs {
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				return u.Redacted(), true
+			}
+		}
+	}
+	return value, false
+}
+
+func (r Redaction) replace(value string) string {
+	if r.Mode == RedactHash {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return redactedText
+}
+
+func matchAny(globs []string, key string) bool {
+	k := strings.ToUpper(key)
+	for _, g := range globs {
+		if ok, _ := path.Match(strings.ToUpper(g), k); ok {
+			return true
+		}
+	}
+	return false
+}
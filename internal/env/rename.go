@@ -0,0 +1,106 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rename is one key renamed by RenamePrefix.
+type Rename struct {
+	From, To string
+}
+
+// RenamePlan is what RenamePrefix would do.
+type RenamePlan struct {
+	Renames    []Rename
+	Refs       []string // keys whose values refer to a renamed key
+	Collisions []string // new names already held by keys not being renamed
+}
+
+// PlanPrefixRename works out renaming every key that starts with from to
+// start with to instead, in key order.
+func (s *Store) PlanPrefixRename(from, to string) RenamePlan {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var p RenamePlan
+	renamed := make(map[string]string)
+	for _, k := range s.order {
+		if strings.HasPrefix(k, from) {
+			r := Rename{From: k, To: to + strings.TrimPrefix(k, from)}
+			p.Renames = append(p.Renames, r)
+			renamed[r.From] = r.To
+		}
+	}
+	for _, r := range p.Renames {
+		if _, taken := s.items[r.To]; taken {
+			if _, moving := renamed[r.To]; !moving {
+				p.Collisions = append(p.Collisions, r.To)
+			}
+		}
+	}
+	for _, k := range s.order {
+		if v := s.valueLocked(k); rewriteRefs(v, renamed) != v {
+			p.Refs = append(p.Refs, k)
+		}
+	}
+	return p
+}
+
+// RenamePrefix renames every key that starts with from to start with to
+// instead, in one transaction. Expiry dates and sections move with their
+// keys, and $OLD and ${OLD} references in values are rewritten to the new
+// names.
+func (s *Store) RenamePrefix(from, to string) (RenamePlan, error) {
+	if from == "" || from == to {
+		return RenamePlan{}, fmt.Errorf("nothing to rename from %q to %q", from, to)
+	}
+	if !identRe.MatchString(to + "X") {
+		return RenamePlan{}, fmt.Errorf("invalid prefix %q", to)
+	}
+	p := s.PlanPrefixRename(from, to)
+	if len(p.Collisions) > 0 {
+		return p, fmt.Errorf("%s already exist", strings.Join(p.Collisions, ", "))
+	}
+	renamed := make(map[string]string, len(p.Renames))
+	for _, r := range p.Renames {
+		renamed[r.From] = r.To
+	}
+
+	s.Begin()
+	values := s.Vars()
+	for _, k := range p.Refs {
+		values[k] = rewriteRefs(values[k], renamed)
+		if _, moving := renamed[k]; !moving {
+			s.set(k, values[k])
+		}
+	}
+	for _, r := range p.Renames {
+		t, hasExpiry := s.Expiry(r.From)
+		section := s.Section(r.From)
+		s.Delete(r.From)
+		s.set(r.To, values[r.From])
+		if hasExpiry {
+			s.SetExpiry(r.To, t)
+		}
+		s.SetSection(r.To, section)
+	}
+	return p, s.Commit()
+}
+
+// rewriteRefs replaces references to renamed keys in value.
+func rewriteRefs(value string, renamed map[string]string) string {
+	if !strings.Contains(value, "$") {
+		return value
+	}
+	return refRe.ReplaceAllStringFunc(value, func(m string) string {
+		name := strings.Trim(refRe.FindStringSubmatch(m)[1], "{}")
+		to, ok := renamed[name]
+		switch {
+		case !ok:
+			return m
+		case strings.HasPrefix(m, "${"):
+			return "${" + to + "}"
+		}
+		return "$" + to
+	})
+}
@@ -0,0 +1,75 @@
+package env
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// Finding is a value ScanSecret takes for a live credential.
+type Finding struct {
+	Key    string
+	Rule   string // the rule that matched, e.g. "aws-access-key"
+	Reason string
+}
+
+// tokenRules recognise credentials by their published formats. Test-mode
+// keys such as Stripe's sk_test_ are left out on purpose.
+var tokenRules = []struct {
+	name, reason string
+	re           *regexp.Regexp
+}{
+	{"private-key", "PEM private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"aws-access-key", "AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", "GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{"gitlab-token", "GitLab personal access token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"slack-token", "Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe-live-key", "Stripe live key", regexp.MustCompile(`\b(?:sk|rk)_live_[A-Za-z0-9]{20,}\b`)},
+	{"google-api-key", "Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"openai-key", "OpenAI API key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}\b`)},
+	{"jwt", "JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,}`)},
+	{"url-password", "password in a URL", regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// dummyRe matches values that stand in for a credential rather than
+// being one: sample words and runs of one character.
+var dummyRe = regexp.MustCompile(`(?i)^(?:x+|\*+|0+|changeme|secret|password|passwd|test|testing|example|dummy|sample|none|null|redacted|\$\{.*\})$`)
+
+// ScanSecret reports whether value looks like a live credential, judged
+// first by known token formats, then by a secret-sounding key holding a
+// value varied enough to be real, then by entropy alone. References to a
+// keychain or 1Password are not credentials themselves.
+func ScanSecret(key, value string) (Finding, bool) {
+	f := Finding{Key: key}
+	if value == "" || IsPlaceholder(value) || dummyRe.MatchString(value) || cred.IsRef(value) {
+		return f, false
+	}
+	for _, r := range tokenRules {
+		if !r.re.MatchString(value) || r.name == "url-password" && strings.Contains(value, ":${") {
+			continue
+		}
+		f.Rule, f.Reason = r.name, r.reason
+		return f, true
+	}
+	if SecretKey(key) && len(value) >= 8 && entropy(value) >= 3.0 {
+		f.Rule, f.Reason = "key-name", "secret-sounding key with a varied value"
+		return f, true
+	}
+	if len(value) >= 24 && !strings.ContainsAny(value, " /:.") && entropy(value) >= 4.5 {
+		f.Rule, f.Reason = "entropy", "long random-looking value"
+		return f, true
+	}
+	return f, false
+}
+
+// SecretKey reports whether key's name suggests a credential.
+func SecretKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, h := range secretKeyHints {
+		if strings.Contains(k, h) {
+			return true
+		}
+	}
+	return strings.HasSuffix(k, "_KEY")
+}
@@ -0,0 +1,122 @@
+package env
+
+import "github.com/rivethorn/envoy/internal/secmem"
+
+// The store keeps values LooksSecret flags sealed (see package secmem),
+// in items and layers alike, and opens them only as they are read. Each
+// sealed value belongs to one place: snapshots hold copies, so that a
+// value is destroyed as soon as the store replaces or deletes it.
+
+// sealItem seals its value when it looks like a secret.
+func sealItem(it Item) Item {
+	if it.sealed == nil && LooksSecret(it.Key, it.Value) {
+		it.sealed, it.Value = secmem.Seal(it.Value), ""
+	}
+	return it
+}
+
+// open returns it with its value in the clear.
+func (it Item) open() Item {
+	if it.sealed != nil {
+		it.Value, it.sealed = it.sealed.Open(), nil
+	}
+	return it
+}
+
+// itemLocked returns the item for key, opened.
+func (s *Store) itemLocked(key string) (Item, bool) {
+	it, ok := s.items[key]
+	return it.open(), ok
+}
+
+// valueLocked returns key's value in the clear, "" when unset.
+func (s *Store) valueLocked(key string) string {
+	it, _ := s.itemLocked(key)
+	return it.Value
+}
+
+func (s *Store) setItemLocked(it Item) {
+	if old := s.items[it.Key]; old.sealed != it.sealed {
+		old.sealed.Destroy()
+	}
+	s.items[it.Key] = sealItem(it)
+}
+
+// deleteItemLocked removes key's item and layers, destroying their sealed
+// values.
+func (s *Store) deleteItemLocked(key string) {
+	s.items[key].sealed.Destroy()
+	destroyLayers(s.layers[key])
+	delete(s.items, key)
+	delete(s.layers, key)
+}
+
+// destroyAllLocked destroys every sealed value the store holds, before
+// its items and layers are replaced wholesale.
+func (s *Store) destroyAllLocked() {
+	for _, it := range s.items {
+		it.sealed.Destroy()
+	}
+	for _, chain := range s.layers {
+		destroyLayers(chain)
+	}
+}
+
+func destroyLayers(chain []Layer) {
+	for _, l := range chain {
+		l.sealed.Destroy()
+	}
+}
+
+// destroy destroys the sealed values of a snapshot no longer needed.
+func (snap snapshot) destroy() {
+	for _, it := range snap.items {
+		it.sealed.Destroy()
+	}
+	for _, chain := range snap.layers {
+		destroyLayers(chain)
+	}
+}
+
+// cloneItems copies items for a snapshot, sealed values included.
+func cloneItems(m map[string]Item) map[string]Item {
+	out := make(map[string]Item, len(m))
+	for k, it := range m {
+		it.sealed = it.sealed.Clone()
+		out[k] = it
+	}
+	return out
+}
+
+func sealLayer(key string, l Layer) Layer {
+	if LooksSecret(key, l.Value) {
+		l.sealed, l.Value = secmem.Seal(l.Value), ""
+	}
+	return l
+}
+
+// cloneLayers copies layer chains for a snapshot, sealed values included.
+func cloneLayers(m map[string][]Layer) map[string][]Layer {
+	out := make(map[string][]Layer, len(m))
+	for k, chain := range m {
+		c := make([]Layer, len(chain))
+		for i, l := range chain {
+			l.sealed = l.sealed.Clone()
+			c[i] = l
+		}
+		out[k] = c
+	}
+	return out
+}
+
+// openLayers returns a copy of chain with its values in the clear.
+func openLayers(chain []Layer) []Layer {
+	out := make([]Layer, len(chain))
+	for i, l := range chain {
+		if l.sealed != nil {
+			l.Value, l.sealed = l.sealed.Open(), nil
+		}
+		out[i] = l
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package env
+
+import (
+	"math"
+	"strings"
+)
+
+var secretKeyHints = []string{
+	"SECRET", "TOKEN", "PASSWORD", "PASSWD", "API_KEY", "APIKEY",
+	"PRIVATE", "CREDENTIAL",
+}
+
+// LooksSecret reports whether a key/value pair probably holds a credential,
+// judged by the key name, then by ScanSecret, then by the value's entropy.
+func LooksSecret(key, value string) bool {
+	if value == "" {
+		return false
+	}
+	if SecretKey(key) {
+		return true
+	}
+	if _, ok := ScanSecret(key, value); ok {
+		return true
+	}
+	return len(value) >= 20 && !strings.ContainsAny(value, " /:") && entropy(value) >= 4.0
+}
+
+// entropy returns the Shannon entropy of s in bits per byte.
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var freq [256]int
+	for i := 0; i < len(s); i++ {
+		freq[s[i]]++
+	}
+	n := float64(len(s))
+	h := 0.0
+	for _, c := range freq {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
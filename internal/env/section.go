@@ -0,0 +1,111 @@
+package env
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sectionRe matches a comment that heads a section of a dotenv file: two
+// or more #s before the name ("## Database ##", "### Database"), or the
+// name between rules of -, = or * ("# --- Database ---").
+var sectionRe = regexp.MustCompile(`^(?:#{2,}\s*([^#].*?)\s*#*|#\s*[-=*]{3,}\s*(.+?)\s*[-=*]*)$`)
+
+// sectionHeader returns the name a section comment gives, leaving out bare
+// rules and commented-out assignments.
+func sectionHeader(line string) (string, bool) {
+	m := sectionRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	name := strings.TrimSpace(m[1] + m[2])
+	named := strings.ContainsFunc(name, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	if !named || strings.Contains(name, "=") {
+		return "", false
+	}
+	return name, true
+}
+
+// SetSection puts key in the named section; "" takes it out of any.
+func (s *Store) SetSection(key, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setSectionLocked(key, name)
+	if s.bySection {
+		s.resortLocked()
+	}
+}
+
+func (s *Store) setSectionLocked(key, name string) {
+	if name == "" {
+		delete(s.section, key)
+		return
+	}
+	if s.section == nil {
+		s.section = make(map[string]string)
+	}
+	s.section[key] = name
+	if !slices.Contains(s.sections, name) {
+		s.sections = append(s.sections, name)
+	}
+}
+
+// Section returns the section key was loaded under, or "".
+func (s *Store) Section(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.section[key]
+}
+
+// Sections lists the names of the sections holding keys, in the order
+// they were first seen.
+func (s *Store) Sections() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []string
+	for _, name := range s.sections {
+		for k, n := range s.section {
+			if _, ok := s.items[k]; ok && n == name {
+				out = append(out, name)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// SetSectionSort groups keys by section when on: keys in no section come
+// first, then each section in the order it was first seen. Within a group,
+// and with grouping off, keys keep the plain or natural order.
+func (s *Store) SetSectionSort(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bySection = on
+	s.resortLocked()
+}
+
+// SectionSort reports whether keys are grouped by section.
+func (s *Store) SectionSort() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bySection
+}
+
+// compareSectionLocked orders a and b by the rank of their sections.
+func (s *Store) compareSectionLocked(a, b string) int {
+	rank := func(key string) int {
+		name, ok := s.section[key]
+		if !ok {
+			return 0
+		}
+		return slices.Index(s.sections, name) + 1
+	}
+	return cmp.Compare(rank(a), rank(b))
+}
+
+func (s *Store) resortLocked() {
+	slices.SortFunc(s.order, s.compareLocked)
+	s.applyFilterLocked(s.query)
+}
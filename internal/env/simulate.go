@@ -0,0 +1,212 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SimChange says how a child's value differs from the table.
+type SimChange int
+
+const (
+	SimSame    SimChange = iota
+	SimSet               // assigned by the command line
+	SimUnset             // removed by env -u or env -i
+	SimDropped           // not a shell identifier; the shell drops it
+)
+
+// SimVar is one variable as a simulated child process sees it.
+type SimVar struct {
+	Key    string
+	Value  string // what the child receives
+	Table  string // the table's value, when the key is in the table
+	Change SimChange
+	// Literal is set when the value holds $NAME or ${NAME} that nothing
+	// will expand: the child gets the text as written.
+	Literal bool
+}
+
+// Simulation is the environment a child process would start with.
+type Simulation struct {
+	Vars    []SimVar // sorted by key; unset and dropped keys included
+	Command string   // program left after assignments and env options
+}
+
+// Gone reports whether v does not reach the child.
+func (v SimVar) Gone() bool {
+	return v.Change == SimUnset || v.Change == SimDropped
+}
+
+var (
+	identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	refRe   = regexp.MustCompile(`\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// Simulate computes the environment a child receives from the store's
+// current values, ignoring the filter. With no command the child is
+// exec'd directly and gets every key verbatim. A command is taken to run
+// through sh -c: leading NAME=value words are expanded and added, an env
+// prefix may unset (-u) or clear (-i), and names that are not shell
+// identifiers are dropped.
+func (s *Store) Simulate(cmd string) (Simulation, error) {
+	s.mu.RLock()
+	table := make(map[string]string, len(s.items))
+	for k, it := range s.items {
+		table[k] = it.open().Value
+	}
+	s.mu.RUnlock()
+
+	words, err := shellWords(cmd)
+	if err != nil {
+		return Simulation{}, err
+	}
+	shell := len(words) > 0
+
+	vars := make(map[string]*SimVar, len(table))
+	for k, v := range table {
+		sv := &SimVar{Key: k, Value: v, Table: v}
+		if shell && !identRe.MatchString(k) {
+			sv.Change = SimDropped
+		}
+		vars[k] = sv
+	}
+	lookup := func(name string) string {
+		if name == "$" {
+			return "$" // a quoted or escaped dollar
+		}
+		if v, ok := vars[name]; ok && !v.Gone() {
+			return v.Value
+		}
+		return ""
+	}
+	assign := func(w word) bool {
+		name, _, ok := strings.Cut(w.text, "=")
+		if !ok || !identRe.MatchString(name) {
+			return false
+		}
+		_, tmpl, _ := strings.Cut(w.tmpl, "=")
+		val := os.Expand(tmpl, lookup)
+		v, ok := vars[name]
+		if !ok {
+			v = &SimVar{Key: name}
+			vars[name] = v
+		}
+		v.Value, v.Change = val, SimSet
+		return true
+	}
+
+	for len(words) > 0 && assign(words[0]) {
+		words = words[1:]
+	}
+	if len(words) > 0 && words[0].text == "env" {
+		words = words[1:]
+	options:
+		for len(words) > 0 {
+			w := words[0].text
+			switch {
+			case w == "-i" || w == "--ignore-environment" || w == "-":
+				for _, v := range vars {
+					v.Change = SimUnset
+				}
+			case w == "-u" || w == "--unset":
+				if len(words) < 2 {
+					return Simulation{}, errors.New("env: -u needs a name")
+				}
+				words = words[1:]
+				unset(vars, words[0].text)
+			case strings.HasPrefix(w, "--unset="):
+				unset(vars, strings.TrimPrefix(w, "--unset="))
+			case strings.HasPrefix(w, "-u") && len(w) > 2:
+				unset(vars, w[2:])
+			case !assign(words[0]):
+				break options
+			}
+			words = words[1:]
+		}
+	}
+	sim := Simulation{}
+	if len(words) > 0 {
+		sim.Command = words[0].text
+	}
+	for _, v := range vars {
+		if !v.Gone() && v.Change != SimSet {
+			v.Literal = refRe.MatchString(v.Value)
+		}
+		sim.Vars = append(sim.Vars, *v)
+	}
+	sort.Slice(sim.Vars, func(i, j int) bool { return sim.Vars[i].Key < sim.Vars[j].Key })
+	return sim, nil
+}
+
+func unset(vars map[string]*SimVar, name string) {
+	if v, ok := vars[name]; ok {
+		v.Change = SimUnset
+	}
+}
+
+// word is one shell word. tmpl is the same text for os.Expand, with
+// dollars that were quoted or escaped doubled so they stay literal.
+type word struct {
+	text, tmpl string
+}
+
+// shellWords splits a command line the way sh does for simple commands:
+// whitespace separates words, quotes group, backslash escapes.
+func shellWords(line string) ([]word, error) {
+	var out []word
+	var text, tmpl strings.Builder
+	in, quote := false, rune(0)
+	flush := func() {
+		if in {
+			out = append(out, word{text: text.String(), tmpl: tmpl.String()})
+		}
+		text.Reset()
+		tmpl.Reset()
+		in = false
+	}
+	// put adds r to the word; literal dollars are doubled in tmpl.
+	put := func(r rune, literal bool) {
+		text.WriteRune(r)
+		if r == '$' && literal {
+			tmpl.WriteRune('$')
+		}
+		tmpl.WriteRune(r)
+	}
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			put(r, true)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				put(r, true)
+			}
+		case r == '\\':
+			in, escaped = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				put(r, false)
+			}
+		case r == '\'' || r == '"':
+			in, quote = true, r
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			in = true
+			put(r, false)
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote in command")
+	}
+	flush()
+	return out, nil
+}
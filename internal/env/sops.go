@@ -0,0 +1,243 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoSops is returned when a sops-encrypted file is met and the sops CLI
+// is not installed.
+var ErrNoSops = errors.New("sops not found on PATH")
+
+// sopsConfig is the file sops reads creation rules from, looked for in
+// the encrypted file's directory and those above it.
+const sopsConfig = ".sops.yaml"
+
+// sopsMeta is the part of a sops file's metadata that says how to encrypt
+// it again.
+type sopsMeta struct {
+	Age               []sopsKey `yaml:"age"`
+	PGP               []sopsKey `yaml:"pgp"`
+	KMS               []sopsKey `yaml:"kms"`
+	GCPKMS            []sopsKey `yaml:"gcp_kms"`
+	EncryptedRegex    string    `yaml:"encrypted_regex"`
+	UnencryptedRegex  string    `yaml:"unencrypted_regex"`
+	EncryptedSuffix   string    `yaml:"encrypted_suffix"`
+	UnencryptedSuffix string    `yaml:"unencrypted_suffix"`
+}
+
+// sopsKey is one master key of a sops file; which field is set depends on
+// its kind.
+type sopsKey struct {
+	Recipient  string `yaml:"recipient"`
+	FP         string `yaml:"fp"`
+	ARN        string `yaml:"arn"`
+	ResourceID string `yaml:"resource_id"`
+}
+
+// sopsType returns the sops store, "yaml", "json" or "dotenv", that path
+// is written in when text carries sops metadata, or "".
+func sopsType(path, text string) string {
+	typ := "dotenv"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		typ = "yaml"
+	case ".json":
+		typ = "json"
+	}
+	if _, ok := parseSopsMeta(typ, text); ok {
+		return typ
+	}
+	return ""
+}
+
+// sopsDotenvMeta matches the metadata lines of a dotenv file, which sops
+// flattens: sops_age__list_0__map_recipient=age1….
+var sopsDotenvMeta = regexp.MustCompile(`^sops_(age|pgp|kms|gcp_kms)__list_\d+__map_(recipient|fp|arn|resource_id)=(.*)$`)
+
+// parseSopsMeta reads the metadata of a file in store typ.
+func parseSopsMeta(typ, text string) (sopsMeta, bool) {
+	var m sopsMeta
+	if typ != "dotenv" {
+		// JSON is YAML too.
+		var doc struct {
+			Sops *sopsMeta `yaml:"sops"`
+		}
+		if yaml.Unmarshal([]byte(text), &doc) != nil || doc.Sops == nil {
+			return m, false
+		}
+		return *doc.Sops, true
+	}
+	found := false
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "sops_") {
+			continue
+		}
+		found = found || strings.HasPrefix(line, "sops_mac=") || strings.HasPrefix(line, "sops_version=")
+		if g := sopsDotenvMeta.FindStringSubmatch(line); g != nil {
+			switch v := g[3]; g[1] {
+			case "age":
+				m.Age = append(m.Age, sopsKey{Recipient: v})
+			case "pgp":
+				m.PGP = append(m.PGP, sopsKey{FP: v})
+			case "kms":
+				m.KMS = append(m.KMS, sopsKey{ARN: v})
+			case "gcp_kms":
+				m.GCPKMS = append(m.GCPKMS, sopsKey{ResourceID: v})
+			}
+			continue
+		}
+		k, v, _ := strings.Cut(line, "=")
+		switch k {
+		case "sops_encrypted_regex":
+			m.EncryptedRegex = v
+		case "sops_unencrypted_regex":
+			m.UnencryptedRegex = v
+		case "sops_encrypted_suffix":
+			m.EncryptedSuffix = v
+		case "sops_unencrypted_suffix":
+			m.UnencryptedSuffix = v
+		}
+	}
+	return m, found
+}
+
+// args returns the sops encrypt flags that reproduce m's keys and rules.
+func (m sopsMeta) args() []string {
+	var args []string
+	list := func(flag string, keys []sopsKey, id func(sopsKey) string) {
+		var ids []string
+		for _, k := range keys {
+			ids = append(ids, id(k))
+		}
+		if len(ids) > 0 {
+			args = append(args, flag, strings.Join(ids, ","))
+		}
+	}
+	list("--age", m.Age, func(k sopsKey) string { return k.Recipient })
+	list("--pgp", m.PGP, func(k sopsKey) string { return k.FP })
+	list("--kms", m.KMS, func(k sopsKey) string { return k.ARN })
+	list("--gcp-kms", m.GCPKMS, func(k sopsKey) string { return k.ResourceID })
+	for _, r := range [][2]string{
+		{"--encrypted-regex", m.EncryptedRegex},
+		{"--unencrypted-regex", m.UnencryptedRegex},
+		{"--encrypted-suffix", m.EncryptedSuffix},
+		{"--unencrypted-suffix", m.UnencryptedSuffix},
+	} {
+		if r[1] != "" {
+			args = append(args, r[0], r[1])
+		}
+	}
+	return args
+}
+
+// sopsDecrypt decrypts the sops file text, read from path in store typ,
+// into dotenv.
+func sopsDecrypt(path, typ, text string) (string, error) {
+	out, err := runSops([]byte(text), "decrypt", "--input-type", typ, "--output-type", "dotenv", "--filename-override", path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return string(out), nil
+}
+
+// sopsEncrypter returns fsys set to encrypt what is written to path again
+// when the file there is sops-encrypted. The creation rules of a
+// .sops.yaml above it apply; without one, the keys and rules in the file's
+// own metadata do.
+func sopsEncrypter(fsys FS, path string) (FS, bool) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return fsys, false
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return fsys, false
+	}
+	text, _, err := Decode(data)
+	if err != nil {
+		return fsys, false
+	}
+	typ := sopsType(path, text)
+	if typ == "" {
+		return fsys, false
+	}
+	args := []string{"encrypt", "--input-type", "dotenv", "--output-type", typ, "--filename-override", path}
+	if !hasSopsConfig(path) {
+		m, _ := parseSopsMeta(typ, text)
+		args = append(args, m.args()...)
+	}
+	return sopsFS{FS: fsys, args: args}, true
+}
+
+// hasSopsConfig reports whether a .sops.yaml is in path's directory or
+// one above it.
+func hasSopsConfig(path string) bool {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	for {
+		if fileExists(filepath.Join(dir, sopsConfig)) {
+			return true
+		}
+		up := filepath.Dir(dir)
+		if up == dir {
+			return false
+		}
+		dir = up
+	}
+}
+
+// IsSopsFile reports whether the file at path is sops-encrypted.
+func IsSopsFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	text, _, err := Decode(data)
+	return err == nil && sopsType(path, text) != ""
+}
+
+// sopsFS encrypts what is written through it with sops.
+type sopsFS struct {
+	FS
+	args []string
+}
+
+func (s sopsFS) Create(path string) (io.WriteCloser, error) {
+	return &sealWriter{fsys: s.FS, path: path, seal: func(plain []byte) ([]byte, error) {
+		return runSops(plain, s.args...)
+	}}, nil
+}
+
+// runSops runs the sops CLI on input and returns its output.
+func runSops(input []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return nil, ErrNoSops
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sops", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
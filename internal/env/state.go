@@ -0,0 +1,60 @@
+package env
+
+import (
+	"slices"
+	"time"
+)
+
+// KeyState is everything the store holds for one key.
+type KeyState struct {
+	Key     string      `json:"key"`
+	Value   string      `json:"value"`
+	Layers  []Layer     `json:"layers,omitempty"`
+	From    *Provenance `json:"from,omitempty"`
+	Expires time.Time   `json:"expires,omitzero"`
+}
+
+// State returns the keys this session added to the process environment:
+// those loaded from another source, edited, or given an expiry. Keys
+// inherited unchanged from the process belong to the machine and are left
+// out.
+func (s *Store) State() []KeyState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []KeyState
+	for _, k := range s.order {
+		it, _ := s.itemLocked(k)
+		exp, hasExp := s.expiry[k]
+		foreign := slices.ContainsFunc(s.layers[k], func(l Layer) bool { return l.Source != SourceProcess })
+		if !it.Modified && !hasExp && !foreign {
+			continue
+		}
+		st := KeyState{Key: k, Value: it.Value, Layers: openLayers(s.layers[k]), Expires: exp}
+		if p, ok := s.prov[k]; ok {
+			st.From = &p
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// Restore applies saved key states in one transaction: values, their
+// layer chains, provenance and expiry. Keys not in states are kept. It
+// returns the number of keys applied.
+func (s *Store) Restore(states []KeyState) int {
+	s.Begin()
+	defer s.Commit()
+	for _, st := range states {
+		s.set(st.Key, st.Value)
+		s.mu.Lock()
+		for _, l := range st.Layers {
+			s.recordLayerLocked(st.Key, l.Source, l.Value)
+		}
+		if st.From != nil {
+			s.setProvenanceLocked(st.Key, *st.From)
+		}
+		s.setExpiryLocked(st.Key, st.Expires)
+		s.mu.Unlock()
+	}
+	return len(states)
+}
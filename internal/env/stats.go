@@ -0,0 +1,121 @@
+package env
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// systemVars are variables normally provided by the OS, login or shell.
+var systemVars = map[string]bool{
+	"PATH": true, "HOME": true, "USER": true, "LOGNAME": true, "SHELL": true,
+	"LANG": true, "TERM": true, "PWD": true, "OLDPWD": true, "SHLVL": true,
+	"TMPDIR": true, "TZ": true, "HOSTNAME": true, "MAIL": true, "EDITOR": true,
+	"DISPLAY": true, "XDG_RUNTIME_DIR": true, "XDG_CONFIG_HOME": true,
+	"XDG_DATA_HOME": true, "XDG_CACHE_HOME": true, "LD_LIBRARY_PATH": true,
+}
+
+// startupEnv is the environment the process was started with, before any
+// edits made through a Store.
+var startupEnv = environMap()
+
+func environMap() map[string]string {
+	m := make(map[string]string)
+	for _, e := range os.Environ() {
+		k, v, _ := strings.Cut(e, "=")
+		m[k] = v
+	}
+	return m
+}
+
+type Stats struct {
+	Count     int
+	Bytes     int        // sum of len(KEY=VALUE) over all entries
+	Longest   []Item     // longest values first
+	Shared    [][]string // groups of keys sharing one non-empty value
+	Empty     []string
+	Secrets   []string
+	Shadowing []string // system variables whose value differs from startup
+}
+
+// Stats summarizes every item in the store, ignoring the active filter.
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var st Stats
+	for _, k := range s.order {
+		it, _ := s.itemLocked(k)
+		st.Count++
+		st.Bytes += len(k) + 1 + len(it.Value)
+		st.Longest = append(st.Longest, it)
+		if it.Value == "" {
+			st.Empty = append(st.Empty, k)
+		}
+		if LooksSecret(k, it.Value) {
+			st.Secrets = append(st.Secrets, k)
+		}
+		if systemVars[k] {
+			if orig, ok := startupEnv[k]; !ok || orig != it.Value {
+				st.Shadowing = append(st.Shadowing, k)
+			}
+		}
+	}
+
+	sort.SliceStable(st.Longest, func(i, j int) bool {
+		return len(st.Longest[i].Value) > len(st.Longest[j].Value)
+	})
+	if len(st.Longest) > 5 {
+		st.Longest = st.Longest[:5]
+	}
+
+	st.Shared = s.sharedLocked()
+	sort.Slice(st.Shared, func(i, j int) bool { return st.Shared[i][0] < st.Shared[j][0] })
+	return st
+}
+
+// DuplicateValues returns groups of keys sharing one non-empty value.
+// Groups whose value looks secret come first, then longer values, since a
+// reused token is more likely a copy-paste slip than a shared "1".
+func (s *Store) DuplicateValues() [][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := s.sharedLocked()
+	secret := func(g []string) bool {
+		return slices.ContainsFunc(g, func(k string) bool { return LooksSecret(k, s.valueLocked(k)) })
+	}
+	slices.SortFunc(groups, func(x, y []string) int {
+		if sx, sy := secret(x), secret(y); sx != sy {
+			if sx {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(len(s.valueLocked(y[0])), len(s.valueLocked(x[0]))); c != 0 {
+			return c
+		}
+		return strings.Compare(x[0], y[0])
+	})
+	return groups
+}
+
+// sharedLocked groups keys by value, in key order, keeping groups of two
+// or more with a non-empty value.
+func (s *Store) sharedLocked() [][]string {
+	byValue := make(map[string][]string)
+	for _, k := range s.order {
+		if v := s.valueLocked(k); v != "" {
+			byValue[v] = append(byValue[v], k)
+		}
+	}
+	var out [][]string
+	for _, keys := range byValue {
+		if len(keys) > 1 {
+			out = append(out, keys)
+		}
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the pairs of a TOML document. The keys of a table are
+// prefixed with its name, dots as "_", and placed in a section of that
+// name. Strings are unquoted; arrays, inline tables and other values are
+// kept as written.
+func parseTOML(text string) ([]Item, error) {
+	var items []Item
+	prefix, section := "", ""
+	lines := strings.Split(text, "\n")
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimSpace(lines[n])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name := strings.Trim(tomlComment(line), "[] ")
+			section = tomlKey(name, ".")
+			prefix = tomlKey(name, "_") + "_"
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		v = strings.TrimSpace(v)
+		start := n + 1
+		switch {
+		case strings.HasPrefix(v, `"""`) || strings.HasPrefix(v, "'''"):
+			quote := v[:3]
+			for strings.Count(v, quote) < 2 && n+1 < len(lines) {
+				n++
+				v += "\n" + lines[n]
+			}
+		case strings.HasPrefix(v, "[") || strings.HasPrefix(v, "{"):
+			v = tomlComment(v)
+			for tomlOpen(v) > 0 && n+1 < len(lines) {
+				n++
+				v += " " + tomlComment(lines[n])
+			}
+		}
+		value, err := tomlValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", start, err)
+		}
+		items = append(items, Item{Key: prefix + tomlKey(strings.TrimSpace(k), "_"), Value: value, Section: section})
+	}
+	return items, nil
+}
+
+// tomlKey joins the parts of a dotted TOML key with sep, unquoting them.
+func tomlKey(key, sep string) string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i <= len(key); i++ {
+		switch {
+		case i == len(key) || quote == 0 && key[i] == '.':
+			parts = append(parts, tomlKeyPart(strings.TrimSpace(key[start:i])))
+			start = i + 1
+		case quote != 0:
+			if key[i] == '\\' && quote == '"' {
+				i++
+			} else if key[i] == quote {
+				quote = 0
+			}
+		case key[i] == '"' || key[i] == '\'':
+			quote = key[i]
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// tomlKeyPart unquotes one part of a dotted key.
+func tomlKeyPart(p string) string {
+	switch {
+	case strings.HasPrefix(p, `"`):
+		if s, err := strconv.Unquote(p); err == nil {
+			return s
+		}
+	case strings.HasPrefix(p, "'"):
+		return strings.Trim(p, "'")
+	}
+	return p
+}
+
+// tomlOpen counts the brackets and braces left open in v, outside strings.
+func tomlOpen(v string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// tomlComment strips a trailing comment from a line outside strings.
+func tomlComment(v string) string {
+	var quote byte
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return strings.TrimSpace(v[:i])
+		}
+	}
+	return strings.TrimSpace(v)
+}
+
+// tomlValue unquotes a TOML string value; other values come back as
+// written, without a trailing comment.
+func tomlValue(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"""`):
+		end := strings.LastIndex(v, `"""`)
+		if end < 3 {
+			return "", errors.New("unterminated string")
+		}
+		s := strings.TrimPrefix(v[3:end], "\n")
+		return strconv.Unquote(`"` + strings.NewReplacer("\n", `\n`, `"`, `\"`, `\"`, `\"`).Replace(s) + `"`)
+	case strings.HasPrefix(v, "'''"):
+		end := strings.LastIndex(v, "'''")
+		if end < 3 {
+			return "", errors.New("unterminated string")
+		}
+		return strings.TrimPrefix(v[3:end], "\n"), nil
+	}
+	v = tomlComment(v)
+	switch {
+	case strings.HasPrefix(v, `"`):
+		s, err := strconv.Unquote(v)
+		if err != nil {
+			return "", fmt.Errorf("bad string %s", v)
+		}
+		return s, nil
+	case strings.HasPrefix(v, "'"):
+		if len(v) < 2 || !strings.HasSuffix(v, "'") {
+			return "", errors.New("unterminated string")
+		}
+		return v[1 : len(v)-1], nil
+	}
+	return v, nil
+}
+
+// tomlBareKey matches a key TOML takes without quotes.
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// writeTOML emits string pairs. A key in a section whose name, dots as
+// "_", prefixes it, as parseTOML makes them, goes back into a table of that
+// name; the rest come first, at the top level, as TOML requires.
+func writeTOML(w io.Writer, items []Item) error {
+	var b strings.Builder
+	var tables []string
+	inTable := make(map[string][]Item)
+	for _, it := range items {
+		key := safeKey(it.Key)
+		if it.Section != "" {
+			if rest, ok := strings.CutPrefix(key, tomlKey(it.Section, "_")+"_"); ok && rest != "" {
+				if _, seen := inTable[it.Section]; !seen {
+					tables = append(tables, it.Section)
+				}
+				inTable[it.Section] = append(inTable[it.Section], Item{Key: rest, Value: it.Value})
+				continue
+			}
+		}
+		fmt.Fprintf(&b, "%s = %s\n", tomlName(key), tomlString(it.Value))
+	}
+	for i, t := range tables {
+		if i > 0 || b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		parts := strings.Split(t, ".")
+		for j, p := range parts {
+			parts[j] = tomlName(p)
+		}
+		fmt.Fprintf(&b, "[%s]\n", strings.Join(parts, "."))
+		for _, it := range inTable[t] {
+			fmt.Fprintf(&b, "%s = %s\n", tomlName(it.Key), tomlString(it.Value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tomlName quotes key unless it is bare.
+func tomlName(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package env
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Op is one mutation applied to a store. Prev is the item it replaced, or
+// nil when the key did not exist. An op with Expires set changes the key's
+// rotation deadline instead of its value; the zero time clears it.
+type Op struct {
+	Key     string
+	Value   string
+	Delete  bool
+	Prev    *Item
+	Expires *time.Time
+}
+
+// ErrRolledBack is returned by the outermost Commit of a transaction an
+// inner Rollback failed; its changes have been discarded.
+var ErrRolledBack = errors.New("transaction rolled back")
+
+// txState is the state saved by Begin, restored by Rollback.
+type txState struct {
+	depth  int
+	failed bool // an inner Rollback ran
+	before snapshot
+	ops    []Op
+}
+
+// snapshot is the store's content at one point in time.
+type snapshot struct {
+	items   map[string]Item
+	order   []string
+	layers  map[string][]Layer
+	prov    map[string]Provenance
+	expiry  map[string]time.Time
+	section map[string]string
+	dirty   bool
+}
+
+func (s *Store) snapshotLocked() snapshot {
+	return snapshot{
+		items:   cloneItems(s.items),
+		order:   slices.Clone(s.order),
+		layers:  cloneLayers(s.layers),
+		prov:    maps.Clone(s.prov),
+		expiry:  maps.Clone(s.expiry),
+		section: maps.Clone(s.section),
+		dirty:   s.dirty,
+	}
+}
+
+// restoreLocked makes snap the store's state; snap is not used again.
+func (s *Store) restoreLocked(snap snapshot) {
+	s.destroyAllLocked()
+	s.items = snap.items
+	s.order = snap.order
+	s.layers = snap.layers
+	s.prov = snap.prov
+	s.expiry = snap.expiry
+	s.section = snap.section
+	s.dirty = snap.dirty
+	s.applyFilterLocked(s.query)
+}
+
+// OnChange registers fn to receive every batch of mutations: one op per
+// call outside a transaction, the whole batch once on Commit.
+func (s *Store) OnChange(fn func(ops []Op)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Begin starts a transaction. Until Commit no change events fire.
+// Transactions nest; only the outermost Commit emits.
+func (s *Store) Begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		s.tx.depth++
+		return
+	}
+	s.tx = &txState{depth: 1, before: s.snapshotLocked()}
+}
+
+// Commit ends a transaction, emitting one change event for its changes.
+// The outermost Commit of a transaction rolled back inside discards it
+// instead and returns ErrRolledBack.
+func (s *Store) Commit() error {
+	s.mu.Lock()
+	if s.tx == nil {
+		s.mu.Unlock()
+		return errors.New("commit without transaction")
+	}
+	if s.tx.depth--; s.tx.depth > 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.tx.failed {
+		s.restoreLocked(s.tx.before)
+		s.tx = nil
+		s.mu.Unlock()
+		return ErrRolledBack
+	}
+	ops := s.tx.ops
+	if len(ops) > 0 {
+		s.pushUndoLocked(change{before: s.tx.before, ops: ops})
+	} else {
+		s.tx.before.destroy()
+	}
+	s.tx = nil
+	listeners := slices.Clone(s.listeners)
+	s.mu.Unlock()
+
+	if len(ops) > 0 {
+		for _, fn := range listeners {
+			fn(ops)
+		}
+	}
+	return nil
+}
+
+// Rollback ends a transaction in place of Commit, discarding every change
+// made since the outermost Begin. Inside a nested transaction it only
+// marks the whole as failed; the outermost Rollback or Commit restores the
+// store.
+func (s *Store) Rollback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx == nil {
+		return
+	}
+	if s.tx.depth--; s.tx.depth > 0 {
+		s.tx.failed = true
+		return
+	}
+	s.restoreLocked(s.tx.before)
+	s.tx = nil
+}
+
+// recordLocked queues op for the current transaction, or returns the
+// listeners to notify right away.
+func (s *Store) recordLocked(op Op) []func([]Op) {
+	if s.tx != nil {
+		s.tx.ops = append(s.tx.ops, op)
+		return nil
+	}
+	return slices.Clone(s.listeners)
+}
+
+func notify(listeners []func([]Op), op Op) {
+	for _, fn := range listeners {
+		fn([]Op{op})
+	}
+}
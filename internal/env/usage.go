@@ -0,0 +1,107 @@
+package env
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Limits on the source tree UsedKeys reads, so a large checkout, or a
+// home directory, stays quick.
+const (
+	usageMaxEntries = 50000 // files and directories visited
+	usageMaxSize    = 256 << 10
+)
+
+// usageRe matches a variable read by name in common languages: getenv
+// calls, process.env and import.meta.env, and ENV or environ lookups.
+var usageRe = regexp.MustCompile(
+	`(?:\bos\.(?:Getenv|LookupEnv|getenv)|\bgetenv|\benv::var(?:_os)?|\bSystem\.getenv|\bEnvironment\.GetEnvironmentVariable|\bos\.environ\.get|\bENV\.fetch)\(\s*["']([A-Za-z_][A-Za-z0-9_]*)["']` +
+		`|\b(?:process|import\.meta)\.env\.([A-Za-z_][A-Za-z0-9_]*)` +
+		`|(?:\bprocess\.env|\bos\.environ|\bENV|\$_ENV|\$_SERVER)\[\s*["']([A-Za-z_][A-Za-z0-9_]*)["']\s*\]`)
+
+// usageExts are the source files UsedKeys reads.
+var usageExts = map[string]bool{
+	".go": true, ".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true,
+	".py": true, ".rb": true, ".rs": true, ".java": true, ".kt": true, ".cs": true, ".php": true,
+	".ex": true, ".exs": true, ".svelte": true, ".vue": true,
+}
+
+// usageSkipDirs hold dependencies and build output rather than the
+// project's own code.
+var usageSkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, ".venv": true, "venv": true, "__pycache__": true, ".next": true,
+}
+
+// UsedKeys scans the source files under root for variables the code reads
+// by name and returns them sorted.
+func UsedKeys(root string) []string {
+	seen := make(map[string]bool)
+	visited := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if visited++; visited > usageMaxEntries {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if path != root && (usageSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !usageExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if fi, err := d.Info(); err != nil || fi.Size() > usageMaxSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, m := range usageRe.FindAllSubmatch(data, -1) {
+			for _, g := range m[1:] {
+				if len(g) > 0 {
+					seen[string(g)] = true
+				}
+			}
+		}
+		return nil
+	})
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// SiblingFiles lists the other env files next to path: .env, .env.* and
+// *.env files in the same directory.
+func SiblingFiles(path string) []string {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	self, _ := filepath.Abs(path)
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")) {
+			continue
+		}
+		p := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(p); abs == self {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
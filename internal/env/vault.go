@@ -0,0 +1,310 @@
+package env
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// VaultExt ends the names of dotenv-vault files. Each environment in one is
+// a DOTENV_VAULT_<ENV> line holding a dotenv file encrypted with
+// AES-256-GCM; a path may pick one with a fragment, .env.vault#production,
+// so that several environments of one vault load as separate layers.
+const VaultExt = ".vault"
+
+// VaultKeysFile, next to a vault, holds a DOTENV_KEY_<ENV> line for each
+// environment, as dotenv-vault writes it. Keys set in DOTENV_KEY, comma
+// separated, are tried first.
+const VaultKeysFile = ".env.keys"
+
+// vaultDefault is the environment read when neither the path nor
+// DOTENV_KEY names one.
+const vaultDefault = "development"
+
+// ErrVaultKey is returned when a vault environment is read with no key for
+// it in DOTENV_KEY or the keys file.
+var ErrVaultKey = errors.New("no DOTENV_KEY for vault environment")
+
+// IsVaultPath reports whether path names a dotenv-vault file, with or
+// without an environment fragment.
+func IsVaultPath(path string) bool {
+	file, _, _ := strings.Cut(path, "#")
+	return strings.HasSuffix(file, VaultExt)
+}
+
+// vaultFile strips the environment fragment from a vault path, leaving the
+// file name; other paths are returned as they are.
+func vaultFile(path string) string {
+	if !IsVaultPath(path) {
+		return path
+	}
+	file, _, _ := strings.Cut(path, "#")
+	return file
+}
+
+// vaultKey is one DOTENV_KEY: the environment it opens and its AES key.
+type vaultKey struct {
+	env string
+	key []byte
+}
+
+// parseVaultKey reads a key URI,
+// dotenv://:key_<hex>@dotenv.org/vault/.env.vault?environment=<env>.
+func parseVaultKey(uri string) (vaultKey, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return vaultKey{}, fmt.Errorf("invalid DOTENV_KEY: %w", err)
+	}
+	pass, _ := u.User.Password()
+	env := u.Query().Get("environment")
+	if u.Scheme != "dotenv" || !strings.HasPrefix(pass, "key_") || len(pass) < 68 || env == "" {
+		return vaultKey{}, errors.New("invalid DOTENV_KEY: expected dotenv://:key_…@dotenv.org/vault/.env.vault?environment=…")
+	}
+	key, err := hex.DecodeString(pass[len(pass)-64:])
+	if err != nil {
+		return vaultKey{}, fmt.Errorf("invalid DOTENV_KEY: %w", err)
+	}
+	return vaultKey{env: strings.ToLower(env), key: key}, nil
+}
+
+// vaultKeyURI formats key as dotenv-vault writes it to the keys file.
+func vaultKeyURI(k vaultKey) string {
+	return fmt.Sprintf("dotenv://:key_%s@dotenv.org/vault/.env.vault?environment=%s", hex.EncodeToString(k.key), k.env)
+}
+
+// vaultKeys returns the keys in DOTENV_KEY followed by those in the keys
+// file beside path.
+func vaultKeys(fsys FS, path string) ([]vaultKey, error) {
+	var uris []string
+	if v := os.Getenv("DOTENV_KEY"); v != "" {
+		uris = strings.Split(v, ",")
+	}
+	if items, err := ParseFS(fsys, filepath.Join(filepath.Dir(path), VaultKeysFile)); err == nil {
+		for _, it := range items {
+			if strings.HasPrefix(it.Key, "DOTENV_KEY_") {
+				uris = append(uris, it.Value)
+			}
+		}
+	}
+	var keys []vaultKey
+	for _, uri := range uris {
+		k, err := parseVaultKey(uri)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// vaultEnv returns the environment path picks: its fragment, else that of
+// the first key in DOTENV_KEY, else development.
+func vaultEnv(path string) string {
+	if _, env, ok := strings.Cut(path, "#"); ok && env != "" {
+		return strings.ToLower(env)
+	}
+	first, _, _ := strings.Cut(os.Getenv("DOTENV_KEY"), ",")
+	if k, err := parseVaultKey(first); err == nil {
+		return k.env
+	}
+	return vaultDefault
+}
+
+// vaultName is the key an environment's ciphertext is stored under.
+func vaultName(env string) string {
+	return "DOTENV_VAULT_" + strings.ToUpper(env)
+}
+
+// VaultEnvironments returns the environments in a vault's text, in file
+// order.
+func VaultEnvironments(text string) []string {
+	var envs []string
+	for _, line := range strings.Split(text, "\n") {
+		key, _, ok := parseKV(line)
+		if name, found := strings.CutPrefix(key, "DOTENV_VAULT_"); ok && found && !strings.HasSuffix(name, "_VERSION") {
+			envs = append(envs, strings.ToLower(name))
+		}
+	}
+	return envs
+}
+
+// vaultDecrypt returns the dotenv file stored in the vault text for the
+// environment path picks, trying each key for it in turn.
+func vaultDecrypt(fsys FS, path, text string) (string, error) {
+	keys, err := vaultKeys(fsys, vaultFile(path))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	env := vaultEnv(path)
+	var sealed string
+	found := false
+	for _, line := range strings.Split(text, "\n") {
+		if key, value, ok := parseKV(line); ok && key == vaultName(env) {
+			sealed, found = value, true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("%s: no %s environment (has %s)", path, env, cmp.Or(strings.Join(VaultEnvironments(text), ", "), "none"))
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s: %w", path, env, err)
+	}
+	tried := false
+	for _, k := range keys {
+		if k.env != env {
+			continue
+		}
+		tried = true
+		if plain, err := vaultOpen(k.key, data); err == nil {
+			return string(plain), nil
+		}
+	}
+	if tried {
+		return "", fmt.Errorf("%s: DOTENV_KEY does not decrypt %s", path, env)
+	}
+	return "", fmt.Errorf("%s: %w %s", path, ErrVaultKey, env)
+}
+
+// vaultOpen decrypts nonce||ciphertext||tag with key.
+func vaultOpen(key, data []byte) ([]byte, error) {
+	gcm, err := vaultCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// vaultSeal encrypts plain with key under a fresh nonce.
+func vaultSeal(key, plain []byte) ([]byte, error) {
+	gcm, err := vaultCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func vaultCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// vaultEncrypter returns fsys wrapped to write path into its vault
+// environment, and whether path is a vault at all. An environment with no
+// key yet gets a new one, added to the keys file beside the vault.
+func vaultEncrypter(fsys FS, path string) (FS, bool) {
+	if !IsVaultPath(path) {
+		return fsys, false
+	}
+	return vaultFS{FS: fsys}, true
+}
+
+type vaultFS struct {
+	FS
+}
+
+func (v vaultFS) Create(path string) (io.WriteCloser, error) {
+	file := vaultFile(path)
+	keys, err := vaultKeys(v.FS, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	env := vaultEnv(path)
+	i := slices.IndexFunc(keys, func(k vaultKey) bool { return k.env == env })
+	return &sealWriter{fsys: v.FS, path: file, seal: func(plain []byte) ([]byte, error) {
+		var old string
+		if f, err := v.FS.Open(file); err == nil {
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			old = string(data)
+		}
+		var k vaultKey
+		if i >= 0 {
+			k = keys[i]
+		} else {
+			k = vaultKey{env: env, key: make([]byte, 32)}
+			if _, err := rand.Read(k.key); err != nil {
+				return nil, err
+			}
+			if err := addVaultKey(v.FS, file, k); err != nil {
+				return nil, err
+			}
+		}
+		sealed, err := vaultSeal(k.key, plain)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(setVaultLine(old, env, base64.StdEncoding.EncodeToString(sealed))), nil
+	}}, nil
+}
+
+// setVaultLine replaces the environment's line in the vault text, or adds
+// it at the end under a comment naming it. Other environments are kept as
+// they are.
+func setVaultLine(text, env, sealed string) string {
+	line := fmt.Sprintf("%s=%q", vaultName(env), sealed)
+	var out []string
+	replaced := false
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		l := sc.Text()
+		if key, _, ok := parseKV(l); ok && key == vaultName(env) {
+			l, replaced = line, true
+		}
+		out = append(out, l)
+	}
+	if !replaced {
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+		out = append(out, "# "+env, line)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// addVaultKey appends k to the keys file beside the vault at path.
+func addVaultKey(fsys FS, path string, k vaultKey) error {
+	keysPath := filepath.Join(filepath.Dir(path), VaultKeysFile)
+	var old []byte
+	if f, err := fsys.Open(keysPath); err == nil {
+		old, err = io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if len(old) > 0 && old[len(old)-1] != '\n' {
+		old = append(old, '\n')
+	}
+	old = fmt.Appendf(old, "DOTENV_KEY_%s=%q\n", strings.ToUpper(k.env), vaultKeyURI(k))
+	return writeFile(fsys, keysPath, func(w io.Writer) error {
+		_, err := w.Write(old)
+		return err
+	})
+}
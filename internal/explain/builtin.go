@@ -0,0 +1,117 @@
+package explain
+
+var builtin = map[string]Entry{
+	"PATH": {
+		Summary: "Colon-separated directories searched, in order, for commands.",
+		Notes:   []string{"The first match wins, so earlier entries shadow later ones.", "An empty entry (::) means the current directory, which is a security risk."},
+		Links:   []string{"https://pubs.opengroup.org/onlinepubs/9699919799/basedefs/V1_chap08.html"},
+	},
+	"HOME":    {Summary: "The current user's home directory; ~ expands to it."},
+	"USER":    {Summary: "Login name of the current user."},
+	"LOGNAME": {Summary: "Login name of the current user (POSIX name for USER)."},
+	"SHELL":   {Summary: "The user's login shell, not necessarily the shell running now."},
+	"PWD":     {Summary: "Current working directory, maintained by the shell."},
+	"OLDPWD":  {Summary: "Previous working directory; `cd -` returns to it."},
+	"TERM": {
+		Summary: "Terminal type, used to look up capabilities in terminfo.",
+		Notes:   []string{"A wrong value breaks colours and key handling in TUI programs."},
+	},
+	"COLORTERM": {Summary: "Advertises colour support; \"truecolor\" or \"24bit\" enables 24-bit colour."},
+	"EDITOR":    {Summary: "Preferred line-oriented or fallback text editor."},
+	"VISUAL":    {Summary: "Preferred full-screen editor; most tools try it before EDITOR."},
+	"PAGER":     {Summary: "Program used to page long output, e.g. less."},
+	"TZ": {
+		Summary: "Time zone, e.g. Europe/Berlin or UTC.",
+		Links:   []string{"https://www.gnu.org/software/libc/manual/html_node/TZ-Variable.html"},
+	},
+	"LANG": {
+		Summary: "Default locale for every LC_* category not set explicitly.",
+		Notes:   []string{"Precedence: LC_ALL > LC_* > LANG.", "A locale that is not installed falls back to C/POSIX, often breaking UTF-8."},
+		Links:   []string{"https://www.gnu.org/software/gettext/manual/html_node/Locale-Environment-Variables.html"},
+	},
+	"LC_ALL": {
+		Summary: "Overrides every locale category, including LANG.",
+		Notes:   []string{"Meant for debugging; setting it permanently masks per-category settings."},
+	},
+	"LC_*":            {Summary: "Locale for one category (LC_CTYPE, LC_TIME, LC_NUMERIC, ...); overrides LANG."},
+	"TMPDIR":          {Summary: "Directory for temporary files; defaults to /tmp."},
+	"XDG_CONFIG_HOME": {Summary: "Base directory for user configuration; defaults to ~/.config.", Links: []string{"https://specifications.freedesktop.org/basedir-spec/latest/"}},
+	"XDG_DATA_HOME":   {Summary: "Base directory for user data; defaults to ~/.local/share."},
+	"XDG_STATE_HOME":  {Summary: "Base directory for user state such as logs and history; defaults to ~/.local/state."},
+	"XDG_CACHE_HOME":  {Summary: "Base directory for non-essential cached data; defaults to ~/.cache."},
+	"XDG_RUNTIME_DIR": {Summary: "Per-login runtime directory for sockets and similar, usually /run/user/UID."},
+	"XDG_*":           {Summary: "XDG Base Directory or desktop session setting.", Links: []string{"https://specifications.freedesktop.org/basedir-spec/latest/"}},
+	"DISPLAY":         {Summary: "X11 display to connect to, e.g. :0."},
+	"WAYLAND_DISPLAY": {Summary: "Wayland compositor socket name, e.g. wayland-0."},
+	"SSH_AUTH_SOCK":   {Summary: "Socket of the running ssh-agent; ssh and git use it for keys."},
+	"SSH_*":           {Summary: "Set by sshd or ssh-agent for the current session."},
+	"LD_LIBRARY_PATH": {
+		Summary: "Extra directories searched for shared libraries before the system defaults.",
+		Notes:   []string{"Ignored for setuid binaries.", "Prefer rpath or ldconfig over setting this globally."},
+	},
+	"LD_PRELOAD": {
+		Summary: "Shared libraries loaded before all others, overriding their symbols.",
+		Notes:   []string{"Unexpected values here are a common malware technique."},
+	},
+	"HTTP_PROXY": {
+		Summary: "Proxy URL for plain HTTP requests.",
+		Notes:   []string{"Many tools only read the lowercase http_proxy; curl ignores uppercase HTTP_PROXY for safety.", "Keep both cases in sync."},
+		Links:   []string{"https://about.gitlab.com/blog/2021/01/27/we-need-to-talk-no-proxy/"},
+	},
+	"HTTPS_PROXY": {
+		Summary: "Proxy URL for HTTPS requests.",
+		Notes:   []string{"Usually an http:// URL: the proxy is reached over HTTP and tunnels TLS with CONNECT.", "Keep https_proxy and HTTPS_PROXY in sync."},
+	},
+	"ALL_PROXY": {Summary: "Fallback proxy for any protocol without its own *_PROXY variable; socks5:// URLs are common."},
+	"NO_PROXY": {
+		Summary: "Comma-separated hosts, domains and CIDRs that bypass the proxy.",
+		Notes:   []string{"Matching rules vary by tool: leading dots, wildcards and CIDR ranges are not universally supported.", "Keep no_proxy and NO_PROXY in sync."},
+		Links:   []string{"https://about.gitlab.com/blog/2021/01/27/we-need-to-talk-no-proxy/"},
+	},
+	"AWS_ACCESS_KEY_ID":     {Summary: "AWS access key ID; paired with AWS_SECRET_ACCESS_KEY.", Links: []string{"https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-envvars.html"}},
+	"AWS_SECRET_ACCESS_KEY": {Summary: "AWS secret access key. Treat as a secret; never commit it."},
+	"AWS_SESSION_TOKEN":     {Summary: "Temporary session token for STS credentials; expires."},
+	"AWS_PROFILE":           {Summary: "Named profile from ~/.aws/config and ~/.aws/credentials.", Notes: []string{"Explicit AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY take precedence over the profile."}},
+	"AWS_REGION":            {Summary: "Default AWS region; takes precedence over AWS_DEFAULT_REGION in most SDKs."},
+	"AWS_DEFAULT_REGION":    {Summary: "Default AWS region used by the CLI when AWS_REGION is unset."},
+	"AWS_*": {
+		Summary: "AWS SDK or CLI setting.",
+		Links:   []string{"https://docs.aws.amazon.com/sdkref/latest/guide/settings-reference.html"},
+	},
+	"NODE_OPTIONS": {
+		Summary: "Extra command-line options applied to every node process.",
+		Notes:   []string{"e.g. --max-old-space-size=4096 raises the heap limit.", "Only a subset of flags is allowed here."},
+		Links:   []string{"https://nodejs.org/api/cli.html#node_optionsoptions"},
+	},
+	"NODE_ENV":       {Summary: "Conventional runtime mode for Node apps: development, production or test.", Notes: []string{"npm install skips devDependencies when it is production."}},
+	"NODE_PATH":      {Summary: "Extra directories node searches for modules; discouraged in favour of local installs."},
+	"NPM_CONFIG_*":   {Summary: "Overrides the npm config setting of the same name (lowercased, underscores as dashes)."},
+	"GOPATH":         {Summary: "Go workspace: module cache and `go install` output; defaults to ~/go."},
+	"GOROOT":         {Summary: "Go installation directory; normally inferred and best left unset."},
+	"GOPROXY":        {Summary: "Comma-separated Go module proxy URLs; \"direct\" fetches from VCS."},
+	"GOPRIVATE":      {Summary: "Module path globs fetched directly and skipped in the checksum database."},
+	"GOFLAGS":        {Summary: "Default flags for go commands."},
+	"CGO_ENABLED":    {Summary: "1 enables cgo, 0 builds pure-Go (static) binaries."},
+	"GO*":            {Summary: "Go toolchain setting; see `go help environment`."},
+	"PYTHONPATH":     {Summary: "Extra directories prepended to Python's module search path."},
+	"VIRTUAL_ENV":    {Summary: "Path of the active Python virtualenv, set by its activate script."},
+	"PYTHON*":        {Summary: "CPython interpreter setting.", Links: []string{"https://docs.python.org/3/using/cmdline.html#environment-variables"}},
+	"JAVA_HOME":      {Summary: "JDK installation directory used by build tools such as Maven and Gradle."},
+	"JAVA_OPTS":      {Summary: "Extra JVM options read by many launcher scripts (not by java itself)."},
+	"CARGO_HOME":     {Summary: "Cargo's home directory; defaults to ~/.cargo."},
+	"RUSTUP_HOME":    {Summary: "rustup's toolchain directory; defaults to ~/.rustup."},
+	"RUST_LOG":       {Summary: "Log filter for env_logger/tracing, e.g. info or mycrate=debug."},
+	"RUST_BACKTRACE": {Summary: "1 prints a backtrace on panic; full prints every frame."},
+	"DOCKER_HOST":    {Summary: "Docker daemon address, e.g. unix:///var/run/docker.sock or ssh://host."},
+	"KUBECONFIG":     {Summary: "Colon-separated kubeconfig files merged by kubectl; defaults to ~/.kube/config."},
+	"GIT_*":          {Summary: "Git setting; see `git help git` under ENVIRONMENT VARIABLES."},
+	"GITHUB_TOKEN":   {Summary: "GitHub API token used by gh, Actions and many tools. Treat as a secret."},
+	"GH_TOKEN":       {Summary: "GitHub token for the gh CLI; takes precedence over GITHUB_TOKEN."},
+	"VAULT_ADDR":     {Summary: "HashiCorp Vault server address."},
+	"VAULT_TOKEN":    {Summary: "HashiCorp Vault token. Treat as a secret."},
+	"DATABASE_URL":   {Summary: "Database connection URL; usually contains a password."},
+	"CI":             {Summary: "Set to true by most CI systems; tools use it to disable prompts and colour."},
+	"NO_COLOR":       {Summary: "Any non-empty value asks programs to disable colour output.", Links: []string{"https://no-color.org"}},
+	"DEBUG":          {Summary: "Namespace filter for the debug npm package, or a generic debug switch."},
+	"ENVOY_CONFIG":   {Summary: "Path of Envoy's config file."},
+}
@@ -0,0 +1,62 @@
+// Package explain describes well-known environment variables.
+package explain
+
+import (
+	"sort"
+	"strings"
+)
+
+// Entry documents a variable. Keys ending in "*" match by prefix.
+type Entry struct {
+	Summary string   `json:"summary"`
+	Notes   []string `json:"notes,omitempty"`
+	Links   []string `json:"links,omitempty"`
+}
+
+// DB is the bundled knowledge base plus any user additions.
+type DB struct {
+	exact    map[string]Entry
+	prefixes []string // longest first
+	prefix   map[string]Entry
+}
+
+// New returns the bundled entries overlaid with extra, which takes
+// precedence on conflicts.
+func New(extra map[string]Entry) *DB {
+	db := &DB{exact: make(map[string]Entry), prefix: make(map[string]Entry)}
+	for _, m := range []map[string]Entry{builtin, extra} {
+		for k, e := range m {
+			k = strings.ToUpper(k)
+			if p, ok := strings.CutSuffix(k, "*"); ok {
+				db.prefix[p] = e
+			} else {
+				db.exact[k] = e
+			}
+		}
+	}
+	for p := range db.prefix {
+		db.prefixes = append(db.prefixes, p)
+	}
+	sort.Slice(db.prefixes, func(i, j int) bool {
+		if len(db.prefixes[i]) != len(db.prefixes[j]) {
+			return len(db.prefixes[i]) > len(db.prefixes[j])
+		}
+		return db.prefixes[i] < db.prefixes[j]
+	})
+	return db
+}
+
+// Lookup finds the entry for key, ignoring case. Exact entries win over
+// prefix entries, and longer prefixes over shorter ones.
+func (db *DB) Lookup(key string) (Entry, bool) {
+	k := strings.ToUpper(key)
+	if e, ok := db.exact[k]; ok {
+		return e, true
+	}
+	for _, p := range db.prefixes {
+		if strings.HasPrefix(k, p) {
+			return db.prefix[p], true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,151 @@
+// Package fuzzy ranks strings against a typed pattern. The pattern's
+// characters must appear in order; tight matches that start words rank
+// first.
+package fuzzy
+
+import (
+	"slices"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 8 // match at the start of a word
+	bonusConsecutive = 8 // match right after the previous one
+	bonusCase        = 1 // same case as typed
+	penaltyGap       = 1 // per skipped character between matches
+	maxLeadPenalty   = 3 // cap on the penalty for a late first match
+
+	none = -1 << 30 // no match
+)
+
+// Match scores s against pattern, ignoring case. ok is false when pattern
+// is not a subsequence of s. pos holds the rune indexes of the matched
+// characters.
+func Match(pattern, s string) (score int, pos []int, ok bool) {
+	p := []rune(pattern)
+	r := []rune(s)
+	m, n := len(p), len(r)
+	if m == 0 {
+		return 0, nil, true
+	}
+	if m > n {
+		return 0, nil, false
+	}
+
+	// best[i][j] is the top score with p[i] matched at r[j]; from[i][j] is
+	// where p[i-1] matched on that path. Gap penalties are linear, so the
+	// best earlier row entry can be carried forward as a running maximum
+	// of best[i-1][k]+k.
+	best := make([][]int, m)
+	from := make([][]int, m)
+	for i := range m {
+		best[i] = make([]int, n)
+		from[i] = make([]int, n)
+		for j := range n {
+			best[i][j] = none
+		}
+	}
+	for i := range m {
+		runMax, runAt := none, -1
+		for j := i; j < n; j++ {
+			if i > 0 && j >= 2 && best[i-1][j-2] != none && best[i-1][j-2]+j-2 > runMax {
+				runMax, runAt = best[i-1][j-2]+j-2, j-2
+			}
+			c := charScore(p[i], r, j)
+			if c == none {
+				continue
+			}
+			if i == 0 {
+				best[0][j] = c - min(j, maxLeadPenalty)*penaltyGap
+				continue
+			}
+			if prev := best[i-1][j-1]; prev != none {
+				best[i][j], from[i][j] = prev+c+bonusConsecutive, j-1
+			}
+			if runAt >= 0 {
+				if v := runMax - (j-1)*penaltyGap + c; v > best[i][j] {
+					best[i][j], from[i][j] = v, runAt
+				}
+			}
+		}
+	}
+
+	end := -1
+	for j := m - 1; j < n; j++ {
+		if best[m-1][j] != none && (end < 0 || best[m-1][j] > best[m-1][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	score = best[m-1][end]
+	pos = make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		pos[i] = j
+		j = from[i][j]
+	}
+	return score, pos, true
+}
+
+// charScore scores pattern rune c against r[j], or returns none when they
+// differ.
+func charScore(c rune, r []rune, j int) int {
+	if unicode.ToLower(c) != unicode.ToLower(r[j]) {
+		return none
+	}
+	s := scoreMatch
+	if c == r[j] {
+		s += bonusCase
+	}
+	if boundary(r, j) {
+		s += bonusBoundary
+	}
+	return s
+}
+
+// boundary reports whether r[j] starts a word: the first rune, one after a
+// separator, or an upper-case letter after a lower-case one.
+func boundary(r []rune, j int) bool {
+	if j == 0 {
+		return true
+	}
+	prev := r[j-1]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(r[j])
+}
+
+// Result is one ranked candidate.
+type Result struct {
+	Index int // into the slice given to Rank
+	Score int
+	Pos   []int
+}
+
+// Rank matches pattern against every item and returns the matches best
+// first; ties go to the shorter item, then to the earlier one. An empty
+// pattern matches everything in the original order.
+func Rank(pattern string, items []string) []Result {
+	var out []Result
+	if pattern == "" {
+		for i := range items {
+			out = append(out, Result{Index: i})
+		}
+		return out
+	}
+	for i, s := range items {
+		if score, pos, ok := Match(pattern, s); ok {
+			out = append(out, Result{Index: i, Score: score, Pos: pos})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Result) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return len(items[a.Index]) - len(items[b.Index])
+	})
+	return out
+}
@@ -0,0 +1,332 @@
+// Package journal keeps an append-only record of store mutations so unsaved
+// edits survive a crash, much like a vim swap file.
+//
+// Each session writes journals of its own, locked with package lock while
+// it runs, so that sessions sharing a buffer never touch each other's.
+// Values are sealed with a key kept in the credential store; without one,
+// values that look secret are left out rather than written in the clear.
+package journal
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/cred"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/lock"
+)
+
+// keyName is the credential the journal key is kept under.
+const keyName = "envoy-journal"
+
+// Entry is one journaled mutation.
+type Entry struct {
+	Key      string     `json:"k"`
+	Value    string     `json:"v,omitempty"`
+	Sealed   []byte     `json:"s,omitempty"` // Value, sealed with the journal key
+	Withheld bool       `json:"w,omitempty"` // a value left out or no longer readable
+	Delete   bool       `json:"d,omitempty"`
+	Expires  *time.Time `json:"x,omitempty"` // a change of deadline, see env.Op
+}
+
+type record struct {
+	Time time.Time `json:"t"`
+	Ops  []Entry   `json:"ops"`
+}
+
+// Journal is the journal file of one buffer in one session.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	key  []byte
+	lock *lock.Lock
+}
+
+var (
+	heldMu sync.Mutex
+	held   = make(map[string]bool) // journals this process has open
+)
+
+// Dir is where journals are kept.
+func Dir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "envoy-journal")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "envoy", "journal")
+}
+
+// ID derives a journal name from what identifies a buffer: its file path,
+// or the working directory for the process environment.
+func ID(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Key returns the key journal values are sealed with, kept in s and made
+// on first use, or nil when s cannot keep one (a locked file store).
+func Key(s cred.Store) []byte {
+	if s == nil {
+		return nil
+	}
+	tok, err := s.Get(keyName)
+	if errors.Is(err, cred.ErrNotFound) {
+		k := make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			return nil
+		}
+		if err := s.Set(keyName, hex.EncodeToString(k)); err != nil {
+			slog.Info("journal key not stored; secret values will not be journaled", "err", err)
+			return nil
+		}
+		return k
+	}
+	if err != nil {
+		slog.Info("journal key unavailable; secret values will not be journaled", "err", err)
+		return nil
+	}
+	k, err := hex.DecodeString(tok)
+	if err != nil || len(k) != 32 {
+		slog.Warn("ignoring malformed journal key")
+		return nil
+	}
+	return k
+}
+
+// Open creates a journal for id that belongs to this session alone.
+func Open(id string, key []byte) (*Journal, error) {
+	if err := os.MkdirAll(Dir(), 0o700); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(Dir(), id+"-"+hex.EncodeToString(nonce)+".jsonl")
+	l, _, err := lock.Acquire(path, false)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		l.Release()
+		return nil, err
+	}
+	hold(path, true)
+	return &Journal{path: path, f: f, key: key, lock: l}, nil
+}
+
+// Orphans returns the journals for id left with entries by sessions that
+// are no longer running, oldest first, locked so that no other session
+// recovers them too. Empty ones are deleted.
+func Orphans(id string, key []byte) []*Journal {
+	paths, _ := filepath.Glob(filepath.Join(Dir(), id+"*.jsonl"))
+	var out []*Journal
+	mod := make(map[*Journal]time.Time)
+	for _, path := range paths {
+		if holding(path) {
+			continue
+		}
+		l, _, err := lock.Acquire(path, false)
+		if err != nil {
+			continue // still running, or being recovered elsewhere
+		}
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o600)
+		if err != nil {
+			l.Release()
+			continue
+		}
+		hold(path, true)
+		j := &Journal{path: path, f: f, key: key, lock: l}
+		if !j.Pending() {
+			_ = j.Remove()
+			continue
+		}
+		if info, err := f.Stat(); err == nil {
+			mod[j] = info.ModTime()
+		}
+		out = append(out, j)
+	}
+	slices.SortFunc(out, func(a, b *Journal) int { return mod[a].Compare(mod[b]) })
+	return out
+}
+
+func hold(path string, on bool) {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if on {
+		held[path] = true
+	} else {
+		delete(held, path)
+	}
+}
+
+func holding(path string) bool {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	return held[path]
+}
+
+func (j *Journal) Path() string { return j.path }
+
+// Pending reports whether the journal holds entries.
+func (j *Journal) Pending() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries, err := j.entriesLocked()
+	return err == nil && len(entries) > 0
+}
+
+// Append writes one batch and syncs it to disk.
+func (j *Journal) Append(ops []env.Op) error {
+	rec := record{Time: time.Now(), Ops: make([]Entry, len(ops))}
+	for i, op := range ops {
+		e := Entry{Key: op.Key, Delete: op.Delete, Expires: op.Expires}
+		switch {
+		case op.Value == "":
+		case j.key != nil:
+			sealed, err := seal(j.key, op.Value)
+			if err != nil {
+				return err
+			}
+			e.Sealed = sealed
+		case env.LooksSecret(op.Key, op.Value):
+			e.Withheld = true
+		default:
+			e.Value = op.Value
+		}
+		rec.Ops[i] = e
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Entries reads every journaled mutation in order, sealed values opened.
+// One that cannot be opened, its key gone, is marked Withheld. A torn
+// final line, as left by a crash mid-write, is ignored.
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entriesLocked()
+}
+
+func (j *Journal) entriesLocked() ([]Entry, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		var rec record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		for _, e := range rec.Ops {
+			if e.Sealed != nil {
+				v, err := open(j.key, e.Sealed)
+				e.Value, e.Sealed, e.Withheld = v, nil, err != nil
+			}
+			out = append(out, e)
+		}
+	}
+	return out, sc.Err()
+}
+
+// Reset empties the journal, e.g. after the buffer was written.
+func (j *Journal) Reset() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Truncate(0)
+}
+
+// Remove closes and deletes the journal, on a clean exit or once it has
+// been recovered.
+func (j *Journal) Remove() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.f.Close()
+	if rmErr := os.Remove(j.path); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+		err = rmErr
+	}
+	return errors.Join(err, j.releaseLocked())
+}
+
+// Close closes the journal, keeping its contents for a later session.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return errors.Join(j.f.Close(), j.releaseLocked())
+}
+
+func (j *Journal) releaseLocked() error {
+	hold(j.path, false)
+	return j.lock.Release()
+}
+
+// seal encrypts v with AES-GCM under key, nonce first.
+func seal(key []byte, v string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(v), nil), nil
+}
+
+func open(key, sealed []byte) (string, error) {
+	if key == nil {
+		return "", errors.New("no journal key")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("sealed value too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	return string(plain), err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
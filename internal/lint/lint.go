@@ -0,0 +1,334 @@
+// Package lint checks an environment against rules teams write in YAML:
+// patterns on keys and values, required and mutually exclusive keys, and
+// conditions such as "if TLS=true then CERT_PATH must be set".
+//
+// A rule file holds a list under "rules":
+//
+//	rules:
+//	  - name: tls-needs-cert
+//	    severity: error
+//	    when: {key: TLS, value: "^true$"}
+//	    require: [CERT_PATH]
+//	    exists: [CERT_PATH]
+//	  - name: no-debug-in-prod
+//	    when: {key: APP_ENV, value: "^prod"}
+//	    key: "^DEBUG$"
+//	    not_value: "^(1|true)$"
+//
+// A rule applies only when its "when" condition holds, then runs every
+// check it defines.
+package lint
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity ranks findings.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+)
+
+// Rule is one convention. At least one of Key, Require, Exclusive or
+// Exists must be set.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Message  string   `yaml:"message"`
+	Severity Severity `yaml:"severity"` // default warning
+	When     *Cond    `yaml:"when"`
+
+	// Key selects keys by regexp. With Value or NotValue it checks their
+	// values; alone, every matching key is a finding.
+	Key      string `yaml:"key"`
+	Value    string `yaml:"value"`     // values must match
+	NotValue string `yaml:"not_value"` // values must not match
+
+	Require   []string `yaml:"require"`   // must be set and non-empty
+	Exclusive []string `yaml:"exclusive"` // at most one may be set
+	Exists    []string `yaml:"exists"`    // values, when set, must be existing paths
+
+	key, value, notValue *regexp.Regexp
+}
+
+// Cond holds when key is set and, if Value is given, its value matches
+// that regexp.
+type Cond struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+
+	value *regexp.Regexp
+}
+
+// Finding is one rule violation.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Key      string
+	Message  string
+	Source   string // rule file
+}
+
+// Ruleset is a compiled list of rules.
+type Ruleset struct {
+	rules   []Rule
+	sources []string // rule file per rule
+}
+
+//go:embed builtin.yaml
+var builtin []byte
+
+// BuiltinSource names the rules shipped with Envoy in findings.
+const BuiltinSource = "builtin"
+
+// ProjectFile is the rule file looked up in the working directory.
+const ProjectFile = ".envoy-lint.yaml"
+
+// UserPath is the user's rule file in the config directory.
+func UserPath() string {
+	return filepath.Join(config.Dir(), "lint.yaml")
+}
+
+// Default loads the built-in rules, then the user's and the project's rule
+// files when they exist. A later rule with the same name replaces an
+// earlier one.
+func Default() (*Ruleset, error) {
+	rs := &Ruleset{}
+	if err := rs.Add(BuiltinSource, builtin); err != nil {
+		return nil, err
+	}
+	for _, path := range []string{UserPath(), ProjectFile} {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := rs.Add(path, data); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// Add parses a rule file and appends its rules.
+func (rs *Ruleset) Add(source string, data []byte) error {
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("%s: rule %s: %w", source, ruleName(r.Name, i), err)
+		}
+		if j := rs.index(r.Name); j >= 0 {
+			rs.rules[j], rs.sources[j] = *r, source
+			continue
+		}
+		rs.rules = append(rs.rules, *r)
+		rs.sources = append(rs.sources, source)
+	}
+	return nil
+}
+
+// Len returns the number of rules.
+func (rs *Ruleset) Len() int { return len(rs.rules) }
+
+// Keys lists the keys the rules name outright, in require, exclusive and
+// exists lists, sorted.
+func (rs *Ruleset) Keys() []string {
+	seen := make(map[string]bool)
+	for _, r := range rs.rules {
+		for _, k := range slices.Concat(r.Require, r.Exclusive, r.Exists) {
+			seen[k] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (rs *Ruleset) index(name string) int {
+	for i, r := range rs.rules {
+		if r.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func ruleName(name string, i int) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return errors.New("name required")
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = Warning
+	case Error, Warning, Info:
+	default:
+		return fmt.Errorf("unknown severity %q", r.Severity)
+	}
+	if r.Key == "" && len(r.Require) == 0 && len(r.Exclusive) == 0 && len(r.Exists) == 0 {
+		return errors.New("no check: set key, require, exclusive or exists")
+	}
+	if r.Key == "" && (r.Value != "" || r.NotValue != "") {
+		return errors.New("value and not_value need key")
+	}
+	var err error
+	compile := func(expr string) *regexp.Regexp {
+		if expr == "" || err != nil {
+			return nil
+		}
+		var re *regexp.Regexp
+		re, err = regexp.Compile(expr)
+		return re
+	}
+	r.key = compile(r.Key)
+	r.value = compile(r.Value)
+	r.notValue = compile(r.NotValue)
+	if r.When != nil {
+		if r.When.Key == "" {
+			return errors.New("when needs a key")
+		}
+		r.When.value = compile(r.When.Value)
+	}
+	return err
+}
+
+// Check runs every rule over vars and returns the findings sorted by key,
+// then rule.
+func (rs *Ruleset) Check(vars map[string]string) []Finding {
+	var out []Finding
+	for i, r := range rs.rules {
+		for _, f := range r.check(vars) {
+			f.Source = rs.sources[i]
+			out = append(out, f)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+func (r *Rule) check(vars map[string]string) []Finding {
+	if c := r.When; c != nil {
+		v, ok := vars[c.Key]
+		if !ok || (c.value == nil && v == "") || (c.value != nil && !c.value.MatchString(v)) {
+			return nil
+		}
+	}
+	var out []Finding
+	add := func(key, def string) {
+		msg := r.Message
+		if msg == "" {
+			msg = def
+		}
+		out = append(out, Finding{Rule: r.Name, Severity: r.Severity, Key: key, Message: msg})
+	}
+
+	if r.key != nil {
+		for _, k := range sortedKeys(vars) {
+			if !r.key.MatchString(k) {
+				continue
+			}
+			v := vars[k]
+			switch {
+			case r.value == nil && r.notValue == nil:
+				add(k, "key matches "+r.Key)
+			case r.value != nil && !r.value.MatchString(v):
+				add(k, "value does not match "+r.Value)
+			case r.notValue != nil && r.notValue.MatchString(v):
+				add(k, "value matches "+r.NotValue)
+			}
+		}
+	}
+	for _, k := range r.Require {
+		if vars[k] == "" {
+			add(k, "required"+r.because())
+		}
+	}
+	var set []string
+	for _, k := range r.Exclusive {
+		if _, ok := vars[k]; ok {
+			set = append(set, k)
+		}
+	}
+	if len(set) > 1 {
+		for _, k := range set {
+			add(k, "only one of "+strings.Join(r.Exclusive, ", ")+" may be set")
+		}
+	}
+	for _, k := range r.Exists {
+		v := vars[k]
+		if v == "" {
+			continue
+		}
+		if _, err := os.Stat(v); err != nil {
+			add(k, "path does not exist: "+v+r.because())
+		}
+	}
+	return out
+}
+
+// because explains the rule's condition in a default message.
+func (r *Rule) because() string {
+	if r.When == nil {
+		return ""
+	}
+	if r.When.Value == "" {
+		return " when " + r.When.Key + " is set"
+	}
+	return fmt.Sprintf(" when %s matches %s", r.When.Key, r.When.Value)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Errors counts error-severity findings.
+func Errors(fs []Finding) int {
+	n := 0
+	for _, f := range fs {
+		if f.Severity == Error {
+			n++
+		}
+	}
+	return n
+}
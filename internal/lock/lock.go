@@ -0,0 +1,143 @@
+// Package lock keeps advisory lock files next to env files being edited,
+// like vim's swap files, so two sessions notice each other.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// Info identifies the session holding a lock.
+type Info struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	User    string    `json:"user"`
+	Started time.Time `json:"started"`
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s@%s (pid %d) since %s", i.User, i.Host, i.PID, i.Started.Local().Format(time.DateTime))
+}
+
+// ErrLocked is returned by Acquire when another live session holds the
+// lock; the returned Info describes it.
+var ErrLocked = errors.New("locked by another session")
+
+// Lock is a lock file this session holds.
+type Lock struct {
+	path string
+	info Info
+}
+
+// Path is the lock file for file: ".name.envoy-lock" beside it.
+func Path(file string) string {
+	dir, name := filepath.Split(file)
+	return filepath.Join(dir, "."+name+".envoy-lock")
+}
+
+func self() Info {
+	host, _ := os.Hostname()
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return Info{PID: os.Getpid(), Host: host, User: name, Started: time.Now()}
+}
+
+// Acquire locks file. If another live session holds it, Acquire returns
+// its Info and ErrLocked unless force is set, in which case the lock is
+// taken over. Locks left by dead processes on this host are replaced
+// silently.
+func Acquire(file string, force bool) (*Lock, *Info, error) {
+	path := Path(file)
+	me := self()
+	data, err := json.Marshal(me)
+	if err != nil {
+		return nil, nil, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := f.Write(data)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, nil, werr
+			}
+			return &Lock{path: path, info: me}, nil, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, nil, err
+		}
+		holder, err := read(path)
+		if err == nil && holder.live(me) && !force {
+			return nil, holder, ErrLocked
+		}
+		// Stale, unreadable or taken over: replace it.
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, fmt.Errorf("could not create %s", path)
+}
+
+func read(path string) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var i Info
+	if err := json.Unmarshal(data, &i); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// live reports whether the holder may still be running. Only processes on
+// this host can be checked; remote holders are assumed alive.
+func (i *Info) live(me Info) bool {
+	if i.Host != me.Host {
+		return true
+	}
+	if i.PID == me.PID {
+		return false // our own leftover
+	}
+	return processAlive(i.PID)
+}
+
+func processAlive(pid int) bool {
+	if runtime.GOOS == "windows" {
+		return true // no cheap check; err on the side of warning
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Release removes the lock file if this session still holds it.
+func (l *Lock) Release() error {
+	holder, err := read(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if holder.PID != l.info.PID || holder.Host != l.info.Host || !holder.Started.Equal(l.info.Started) {
+		return nil // taken over by another session
+	}
+	return os.Remove(l.path)
+}
@@ -0,0 +1,93 @@
+// Package logging sets up Envoy's log file.
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var current string // path of the active log file
+
+// DefaultPath is $XDG_STATE_HOME/envoy/envoy.log, falling back to
+// ~/.local/state.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "envoy.log")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "envoy", "envoy.log")
+}
+
+// Levels are the names ParseLevel accepts.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// ParseLevel accepts debug, info, warn and error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return l, fmt.Errorf("unknown log level %q", s)
+	}
+	return l, nil
+}
+
+// Setup appends log records at or above level to path and makes that the
+// default slog logger. The returned closer flushes and closes the file.
+func Setup(path string, level slog.Level) (io.Closer, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	h := slog.NewTextHandler(f, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(h))
+	current = path
+	return f, nil
+}
+
+// Discard silences logging, used when the log file cannot be opened.
+func Discard() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// Path returns the active log file, or "" when logging to nowhere.
+func Path() string {
+	return current
+}
+
+// Tail returns up to n of the last lines of the active log file.
+func Tail(n int) ([]string, error) {
+	if current == "" {
+		return nil, fmt.Errorf("logging is disabled")
+	}
+	f, err := os.Open(current)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+		if len(lines) > 2*n {
+			lines = append([]string{}, lines[len(lines)-n:]...)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,94 @@
+// Package probe checks whether HTTP endpoints named in the environment
+// answer from this machine.
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Timeout bounds one probe when the caller's context has no deadline.
+const Timeout = 5 * time.Second
+
+// Result is the outcome of probing one URL.
+type Result struct {
+	URL     string
+	Status  int           // HTTP status; 0 when there was no response
+	Latency time.Duration // until the response headers arrived
+	Err     error         // why there was no response
+	When    time.Time
+}
+
+// Reachable reports whether the endpoint answered at all.
+func (r Result) Reachable() bool { return r.Err == nil }
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return "unreachable: " + r.Err.Error()
+	}
+	return fmt.Sprintf("%d %s in %s", r.Status, http.StatusText(r.Status), r.Latency.Round(100*time.Microsecond))
+}
+
+// Probable reports whether value is an http or https URL.
+func Probable(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// client reports redirects rather than following them: a 3xx answers the
+// question of whether the host is there.
+var client = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// Probe sends a HEAD request to rawURL, falling back to GET for servers
+// that refuse HEAD.
+func Probe(ctx context.Context, rawURL string) Result {
+	r := Result{URL: rawURL, When: time.Now()}
+	if !Probable(rawURL) {
+		r.Err = errors.New("not an http or https URL")
+		return r
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		req.Header.Set("User-Agent", "envoy-probe")
+		start := time.Now()
+		resp, err := client.Do(req)
+		r.Latency = time.Since(start)
+		if err != nil {
+			r.Err = cause(err)
+			return r
+		}
+		resp.Body.Close()
+		r.Status = resp.StatusCode
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	return r
+}
+
+// cause drops the "Head \"url\":" wrapping of client errors.
+func cause(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		if errors.Is(ue.Err, context.DeadlineExceeded) {
+			return errors.New("timed out")
+		}
+		return ue.Err
+	}
+	return err
+}
@@ -0,0 +1,384 @@
+package remote
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("aws", newAWS)
+}
+
+// awsSMPrefix selects Secrets Manager in an aws path.
+const awsSMPrefix = "sm:"
+
+// ssmPage is the most parameters GetParametersByPath returns at once.
+const ssmPage = 10
+
+// awsProvider reads and writes AWS Systems Manager parameters and Secrets
+// Manager secrets.
+//
+// A path such as "/myapp/prod/" is a Parameter Store hierarchy: every
+// parameter under it becomes a variable named after the rest of its name,
+// so /myapp/prod/db/password is DB_PASSWORD. SecureString values are
+// decrypted. Pushing a variable that has no parameter yet creates one the
+// other way round, DB_PASSWORD as /myapp/prod/db/password.
+// "sm:myapp/prod" is a Secrets Manager secret holding a JSON object of
+// variables; a secret holding a plain string becomes one variable named
+// after the secret. "?region=eu-west-1" overrides the configured region.
+type awsProvider struct {
+	auth   *awsAuth
+	client *Client
+	// endpoint overrides https://<service>.<region>.amazonaws.com.
+	endpoint string
+}
+
+func newAWS(cfg Config) (Provider, error) {
+	return &awsProvider{auth: &awsAuth{}, client: cfg.Client}, nil
+}
+
+// awsPath is a parsed aws provider path.
+type awsPath struct {
+	Secret string // Secrets Manager secret ID, or ""
+	Prefix string // Parameter Store hierarchy, without a trailing slash
+	Region string
+}
+
+func parseAWSPath(p string) (awsPath, error) {
+	var ap awsPath
+	p, query, _ := strings.Cut(p, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return ap, fmt.Errorf("bad options %q: %v", query, err)
+	}
+	if ap.Region = q.Get("region"); ap.Region == "" {
+		if ap.Region, err = awsRegion(); err != nil {
+			return ap, err
+		}
+	}
+	if id, ok := strings.CutPrefix(p, awsSMPrefix); ok {
+		if ap.Secret = id; id == "" {
+			return ap, errors.New("sm: needs a secret name")
+		}
+		return ap, nil
+	}
+	if p == "" {
+		return ap, errors.New("path must name a parameter hierarchy such as /myapp/prod/ or a secret as sm:<name>")
+	}
+	ap.Prefix = strings.TrimSuffix(p, "/")
+	return ap, nil
+}
+
+// call invokes a JSON API action of service, such as AmazonSSM.PutParameter.
+// A call refused because the credentials expired is made once more with
+// fresh ones.
+func (a *awsProvider) call(ctx context.Context, service, region, target string, body, out any) error {
+	err := a.callOnce(ctx, service, region, target, body, out)
+	if t := awsErrorType(err); t == "ExpiredToken" || t == "ExpiredTokenException" {
+		a.auth.forget()
+		err = a.callOnce(ctx, service, region, target, body, out)
+	}
+	return err
+}
+
+func (a *awsProvider) callOnce(ctx context.Context, service, region, target string, body, out any) error {
+	creds, err := a.auth.get(ctx, a.client)
+	if err != nil {
+		return err
+	}
+	endpoint := a.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	header := http.Header{
+		"Content-Type": {"application/x-amz-json-1.1"},
+		"X-Amz-Target": {target},
+	}
+	header = creds.sign(http.MethodPost, u, header, payload, service, region, time.Now())
+	if awsRead(target) {
+		ctx = Idempotent(ctx)
+	}
+	return a.client.Send(ctx, http.MethodPost, endpoint, header, payload, out)
+}
+
+// awsRead reports whether target only reads, so that it may be retried.
+func awsRead(target string) bool {
+	_, action, _ := strings.Cut(target, ".")
+	return strings.HasPrefix(action, "Get") || strings.HasPrefix(action, "List") || strings.HasPrefix(action, "Describe")
+}
+
+// awsErrorType returns the exception name of a failed AWS call, "" for
+// other errors.
+func awsErrorType(err error) string {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		return ""
+	}
+	var body struct {
+		Type string `json:"__type"`
+	}
+	json.Unmarshal([]byte(he.Body), &body)
+	_, name, _ := strings.Cut(body.Type, "#")
+	if name == "" {
+		name = body.Type
+	}
+	return name
+}
+
+// ssmKey names the variable for a parameter under prefix:
+// /myapp/prod/db/password is DB_PASSWORD.
+func ssmKey(prefix, name string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+	return strings.ToUpper(strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(rel))
+}
+
+// ssmName names a new parameter for a variable under prefix, inverting
+// ssmKey: DB_PASSWORD is /myapp/prod/db/password.
+func ssmName(prefix, key string) string {
+	return prefix + "/" + strings.ReplaceAll(strings.ToLower(key), "_", "/")
+}
+
+type ssmParameter struct {
+	Name  string `json:"Name"`
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// ssmPage fetches one page of the parameters under prefix, decrypted.
+func (a *awsProvider) ssmPage(ctx context.Context, ap awsPath, cursor string, limit int) ([]ssmParameter, string, error) {
+	body := map[string]any{
+		"Path":           cmp.Or(ap.Prefix, "/"),
+		"Recursive":      true,
+		"WithDecryption": true,
+		"MaxResults":     min(limit, ssmPage),
+	}
+	if cursor != "" {
+		body["NextToken"] = cursor
+	}
+	var resp struct {
+		Parameters []ssmParameter `json:"Parameters"`
+		NextToken  string         `json:"NextToken"`
+	}
+	if err := a.call(ctx, "ssm", ap.Region, "AmazonSSM.GetParametersByPath", body, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Parameters, resp.NextToken, nil
+}
+
+// ssmAll fetches every parameter under prefix.
+func (a *awsProvider) ssmAll(ctx context.Context, ap awsPath) ([]ssmParameter, error) {
+	var out []ssmParameter
+	cursor := ""
+	for {
+		params, next, err := a.ssmPage(ctx, ap, cursor, ssmPage)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, params...)
+		ReportProgress(ctx, len(out), 0)
+		if cursor = next; cursor == "" {
+			return out, nil
+		}
+	}
+}
+
+// Page fetches a page of the parameters under the path; the cursor is the
+// API's NextToken. A Secrets Manager path is a single page.
+func (a *awsProvider) Page(ctx context.Context, p, cursor string, limit int) (Page, error) {
+	ap, err := parseAWSPath(p)
+	if err != nil {
+		return Page{}, err
+	}
+	if ap.Secret != "" {
+		entries, err := a.secret(ctx, ap)
+		return Page{Entries: entries, Total: len(entries)}, err
+	}
+	params, next, err := a.ssmPage(ctx, ap, cursor, limit)
+	if err != nil {
+		return Page{}, err
+	}
+	pg := Page{Next: next}
+	for _, prm := range params {
+		pg.Entries = append(pg.Entries, Entry{Key: ssmKey(ap.Prefix, prm.Name), Value: prm.Value})
+	}
+	return pg, nil
+}
+
+func (a *awsProvider) Pull(ctx context.Context, p string) ([]Entry, error) {
+	ap, err := parseAWSPath(p)
+	if err != nil {
+		return nil, err
+	}
+	if ap.Secret != "" {
+		return a.secret(ctx, ap)
+	}
+	params, err := a.ssmAll(ctx, ap)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, len(params))
+	for i, prm := range params {
+		out[i] = Entry{Key: ssmKey(ap.Prefix, prm.Name), Value: prm.Value}
+	}
+	return out, nil
+}
+
+// secret reads a Secrets Manager secret as variables.
+func (a *awsProvider) secret(ctx context.Context, ap awsPath) ([]Entry, error) {
+	var resp struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := a.call(ctx, "secretsmanager", ap.Region, "secretsmanager.GetSecretValue", map[string]string{"SecretId": ap.Secret}, &resp); err != nil {
+		return nil, fmt.Errorf("%s: %w", ap.Secret, err)
+	}
+	var obj map[string]any
+	if json.Unmarshal([]byte(resp.SecretString), &obj) != nil {
+		return []Entry{{Key: ssmKey("", ap.Secret[strings.LastIndex(ap.Secret, "/")+1:]), Value: resp.SecretString}}, nil
+	}
+	out := make([]Entry, 0, len(obj))
+	for k, v := range obj {
+		s, ok := v.(string)
+		if !ok {
+			b, _ := json.Marshal(v)
+			s = string(b)
+		}
+		out = append(out, Entry{Key: k, Value: s})
+	}
+	slices.SortFunc(out, func(x, y Entry) int { return strings.Compare(x.Key, y.Key) })
+	return out, nil
+}
+
+func (a *awsProvider) Push(ctx context.Context, p string, entries []Entry) error {
+	return a.push(ctx, p, entries, nil)
+}
+
+// PushCAS writes only if the remote values are still those in base. The
+// check and the writes are separate calls, so a change landing between
+// them is not caught.
+func (a *awsProvider) PushCAS(ctx context.Context, p string, entries []Entry, base map[string]string) error {
+	if base == nil {
+		base = map[string]string{}
+	}
+	return a.push(ctx, p, entries, base)
+}
+
+// push writes entries under the path. Parameters whose value is already
+// right are left alone, so they keep their version; new parameters are
+// created as SecureString and existing ones keep their type. With a
+// non-nil base, nothing is written when a remote value differs from it.
+func (a *awsProvider) push(ctx context.Context, p string, entries []Entry, base map[string]string) error {
+	ap, err := parseAWSPath(p)
+	if err != nil {
+		return err
+	}
+	if ap.Secret != "" {
+		return a.pushSecret(ctx, ap, entries, base)
+	}
+	params, err := a.ssmAll(ctx, ap)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]ssmParameter, len(params))
+	for _, prm := range params {
+		have[ssmKey(ap.Prefix, prm.Name)] = prm
+	}
+	if base != nil {
+		if err := casConflicts(entries, base, func(k string) (string, bool) {
+			prm, ok := have[k]
+			return prm.Value, ok
+		}); err != nil {
+			return err
+		}
+	}
+	var todo []Entry
+	for _, e := range entries {
+		if prm, ok := have[e.Key]; !ok || prm.Value != e.Value {
+			todo = append(todo, e)
+		}
+	}
+	for i, e := range todo {
+		prm, ok := have[e.Key]
+		if !ok {
+			prm = ssmParameter{Name: ssmName(ap.Prefix, e.Key), Type: "SecureString"}
+		}
+		body := map[string]any{"Name": prm.Name, "Value": e.Value, "Type": prm.Type, "Overwrite": true}
+		if err := a.call(ctx, "ssm", ap.Region, "AmazonSSM.PutParameter", body, nil); err != nil {
+			return fmt.Errorf("%s: %w", prm.Name, err)
+		}
+		ReportProgress(ctx, i+1, len(todo))
+	}
+	return nil
+}
+
+// pushSecret replaces a Secrets Manager secret's value with entries as a
+// JSON object, creating the secret when it does not exist.
+func (a *awsProvider) pushSecret(ctx context.Context, ap awsPath, entries []Entry, base map[string]string) error {
+	if base != nil {
+		current, err := a.secret(ctx, ap)
+		if err != nil && awsErrorType(err) != "ResourceNotFoundException" {
+			return err
+		}
+		values := make(map[string]string, len(current))
+		for _, e := range current {
+			values[e.Key] = e.Value
+		}
+		if err := casConflicts(entries, base, func(k string) (string, bool) {
+			v, ok := values[k]
+			return v, ok
+		}); err != nil {
+			return err
+		}
+	}
+	obj := make(map[string]string, len(entries))
+	for _, e := range entries {
+		obj[e.Key] = e.Value
+	}
+	value, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	err = a.call(ctx, "secretsmanager", ap.Region, "secretsmanager.PutSecretValue",
+		map[string]string{"SecretId": ap.Secret, "SecretString": string(value)}, nil)
+	if awsErrorType(err) == "ResourceNotFoundException" {
+		err = a.call(ctx, "secretsmanager", ap.Region, "secretsmanager.CreateSecret",
+			map[string]string{"Name": ap.Secret, "SecretString": string(value)}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", ap.Secret, err)
+	}
+	ReportProgress(ctx, 1, 1)
+	return nil
+}
+
+// casConflicts returns a *ConflictError naming the entries whose remote
+// value, looked up by get, is no longer the one in base.
+func casConflicts(entries []Entry, base map[string]string, get func(key string) (string, bool)) error {
+	var changed []string
+	for _, e := range entries {
+		want, had := base[e.Key]
+		got, has := get(e.Key)
+		if had != has || got != want {
+			changed = append(changed, e.Key)
+		}
+	}
+	if len(changed) > 0 {
+		return &ConflictError{Keys: changed}
+	}
+	return nil
+}
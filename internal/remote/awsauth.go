@@ -0,0 +1,263 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// awsRefresh is how long before temporary credentials expire they are
+// fetched again.
+const awsRefresh = 5 * time.Minute
+
+// awsCreds are an access key pair, with a session token and expiry for
+// temporary credentials.
+type awsCreds struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// awsAuth finds credentials and the region the way the AWS CLI does,
+// without the SDK: the environment, the shared credentials and config
+// files for $AWS_PROFILE, then `aws configure export-credentials` (which
+// covers SSO and assumed roles), then the instance metadata service. The
+// first success is cached until shortly before it expires, or until
+// forget drops it.
+type awsAuth struct {
+	mu    sync.Mutex
+	creds *awsCreds
+}
+
+func awsProfile() string {
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+func awsFile(env, name string) string {
+	if p := os.Getenv(env); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", name)
+}
+
+// iniSection returns the keys of section in an AWS-style INI file.
+func iniSection(path, section string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var out map[string]string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	in := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[':
+			in = strings.TrimSpace(strings.Trim(line, "[]")) == section
+		case in:
+			if k, v, ok := strings.Cut(line, "="); ok {
+				if out == nil {
+					out = make(map[string]string)
+				}
+				out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+	return out
+}
+
+// awsRegion is the region from the environment or the profile's config.
+func awsRegion() (string, error) {
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if r := os.Getenv(env); r != "" {
+			return r, nil
+		}
+	}
+	section := "profile " + awsProfile()
+	if awsProfile() == "default" {
+		section = "default"
+	}
+	if r := iniSection(awsFile("AWS_CONFIG_FILE", "config"), section)["region"]; r != "" {
+		return r, nil
+	}
+	return "", errors.New("no AWS region (set AWS_REGION or add ?region= to the path)")
+}
+
+func (a *awsAuth) get(ctx context.Context, c *Client) (awsCreds, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.creds != nil && (a.creds.Expiration.IsZero() || time.Until(a.creds.Expiration) > awsRefresh) {
+		return *a.creds, nil
+	}
+	creds, err := a.find(ctx, c)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	a.creds = &creds
+	return creds, nil
+}
+
+// forget drops the cached credentials, for a request AWS refused as
+// expired.
+func (a *awsAuth) forget() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.creds = nil
+}
+
+func (a *awsAuth) find(ctx context.Context, c *Client) (awsCreds, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCreds{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if s := iniSection(awsFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"), awsProfile()); s["aws_access_key_id"] != "" {
+		return awsCreds{AccessKeyID: s["aws_access_key_id"], SecretAccessKey: s["aws_secret_access_key"], SessionToken: s["aws_session_token"]}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudTimeout)
+	defer cancel()
+	var errs []error
+	if _, err := exec.LookPath("aws"); err == nil {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "aws", "configure", "export-credentials", "--format", "process", "--profile", awsProfile())
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		var creds awsCreds
+		if err == nil && json.Unmarshal(out, &creds) == nil && creds.AccessKeyID != "" {
+			return creds, nil
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" && err != nil {
+			msg = err.Error()
+		}
+		errs = append(errs, fmt.Errorf("aws: %s", msg))
+	}
+	creds, err := awsMetadataCreds(ctx, c)
+	if err == nil {
+		return creds, nil
+	}
+	errs = append(errs, fmt.Errorf("metadata: %w", err))
+	return awsCreds{}, fmt.Errorf("no aws credentials (set AWS_ACCESS_KEY_ID, configure profile %s or log in with aws sso login): %w",
+		awsProfile(), errors.Join(errs...))
+}
+
+// awsMetadataCreds reads the instance role's credentials through IMDSv2,
+// which answers only on EC2; elsewhere it fails fast.
+func awsMetadataCreds(ctx context.Context, c *Client) (awsCreds, error) {
+	const base = "http://169.254.169.254/latest"
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	get := func(method, url string, header http.Header) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return "", responseError(resp)
+		}
+		var b bytes.Buffer
+		_, err = b.ReadFrom(resp.Body)
+		return strings.TrimSpace(b.String()), err
+	}
+	tok, err := get(http.MethodPut, base+"/api/token", http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"300"}})
+	if err != nil {
+		return awsCreds{}, err
+	}
+	h := http.Header{"X-Aws-Ec2-Metadata-Token": {tok}}
+	role, err := get(http.MethodGet, base+"/meta-data/iam/security-credentials/", h)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	role, _, _ = strings.Cut(role, "\n")
+	out, err := get(http.MethodGet, base+"/meta-data/iam/security-credentials/"+url.PathEscape(role), h)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	var creds struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal([]byte(out), &creds); err != nil {
+		return awsCreds{}, err
+	}
+	return awsCreds{AccessKeyID: creds.AccessKeyID, SecretAccessKey: creds.SecretAccessKey, SessionToken: creds.Token, Expiration: creds.Expiration}, nil
+}
+
+// sign returns the headers that authenticate a request with Signature
+// Version 4, given the headers it is sent with.
+func (c awsCreds) sign(method string, u *url.URL, header http.Header, payload []byte, service, region string, now time.Time) http.Header {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	out := header.Clone()
+	out.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		out.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	signed := map[string]string{"host": u.Host}
+	for k, v := range out {
+		signed[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	var canon strings.Builder
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	fmt.Fprintf(&canon, "%s\n%s\n%s\n", method, path, u.Query().Encode())
+	for _, k := range names {
+		fmt.Fprintf(&canon, "%s:%s\n", k, signed[k])
+	}
+	sum := sha256.Sum256(payload)
+	fmt.Fprintf(&canon, "\n%s\n%s", strings.Join(names, ";"), hex.EncodeToString(sum[:]))
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	canonSum := sha256.Sum256([]byte(canon.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonSum[:])
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	out.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, strings.Join(names, ";"), hex.EncodeToString(hmacSHA256(key, toSign))))
+	return out
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
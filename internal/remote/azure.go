@@ -0,0 +1,182 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("azure", newAzure)
+}
+
+const (
+	azureAPIVersion = "7.4"
+	azurePage       = 25 // the most secrets Key Vault lists at once
+)
+
+// azure reads and writes Azure Key Vault secrets. Paths look like
+// "my-vault", "my-vault/api-token@<version>" or "my-vault?env=prod", where
+// labels match secret tags. A scope containing a dot is a full vault host
+// name. Secret names map to variables as in pass: api-token is API_TOKEN.
+type azure struct {
+	scheme string
+	token  *ambientToken
+	client *Client
+}
+
+func newAzure(cfg Config) (Provider, error) {
+	const resource = "https://vault.azure.net"
+	return &azure{
+		scheme: "https",
+		client: cfg.Client,
+		token: &ambientToken{
+			provider: "azure",
+			creds:    cfg.Creds,
+			env:      "AZURE_ACCESS_TOKEN",
+			cli:      []string{"az", "account", "get-access-token", "--resource", resource, "-o", "json"},
+			metadata: func(ctx context.Context, c *Client) (string, time.Time, error) {
+				return metadataToken(ctx, c,
+					"http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource="+url.QueryEscape(resource),
+					http.Header{"Metadata": {"true"}})
+			},
+		},
+	}, nil
+}
+
+func (az *azure) do(ctx context.Context, method, url string, body, out any) error {
+	tok, err := az.token.get(ctx, az.client)
+	if err != nil {
+		return err
+	}
+	err = az.client.JSON(ctx, method, url, http.Header{"Authorization": {"Bearer " + tok}}, body, out)
+	if isUnauthorized(err) {
+		az.token.forget()
+		if tok, err = az.token.get(ctx, az.client); err != nil {
+			return err
+		}
+		err = az.client.JSON(ctx, method, url, http.Header{"Authorization": {"Bearer " + tok}}, body, out)
+	}
+	return err
+}
+
+func (az *azure) vaultURL(vault string) string {
+	if !strings.Contains(vault, ".") {
+		vault += ".vault.azure.net"
+	}
+	return az.scheme + "://" + vault
+}
+
+func (az *azure) secretURL(vault, name, version string) string {
+	u := az.vaultURL(vault) + "/secrets/" + url.PathEscape(name)
+	if version != "" {
+		u += "/" + url.PathEscape(version)
+	}
+	return u + "?api-version=" + azureAPIVersion
+}
+
+func (az *azure) get(ctx context.Context, vault, name, version string) (string, error) {
+	var resp struct {
+		Value string `json:"value"`
+	}
+	if err := az.do(ctx, http.MethodGet, az.secretURL(vault, name, version), nil, &resp); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return resp.Value, nil
+}
+
+// Page lists a page of the vault's enabled secrets carrying the path's
+// tags and reads their current versions. The cursor is the API's nextLink.
+func (az *azure) Page(ctx context.Context, p, cursor string, limit int) (Page, error) {
+	sp, err := parseSecretPath(p)
+	if err != nil {
+		return Page{}, err
+	}
+	if sp.Name != "" {
+		v, err := az.get(ctx, sp.Scope, sp.Name, sp.Version)
+		if err != nil {
+			return Page{}, err
+		}
+		return Page{Entries: []Entry{{Key: PassKey(sp.Name), Value: v}}, Total: 1}, nil
+	}
+	list := cursor
+	if list == "" {
+		list = fmt.Sprintf("%s/secrets?api-version=%s&maxresults=%d", az.vaultURL(sp.Scope), azureAPIVersion, min(limit, azurePage))
+	} else if !strings.HasPrefix(list, az.vaultURL(sp.Scope)+"/") {
+		// The token goes only to the vault itself.
+		return Page{}, fmt.Errorf("bad page cursor %q", cursor)
+	}
+	var resp struct {
+		Value []struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Enabled bool `json:"enabled"`
+			} `json:"attributes"`
+			Tags map[string]string `json:"tags"`
+		} `json:"value"`
+		NextLink string `json:"nextLink"`
+	}
+	if err := az.do(ctx, http.MethodGet, list, nil, &resp); err != nil {
+		return Page{}, err
+	}
+	pg := Page{Next: resp.NextLink}
+	for _, s := range resp.Value {
+		if !s.Attributes.Enabled || !hasLabels(s.Tags, sp.Labels) {
+			continue
+		}
+		name := path.Base(s.ID)
+		v, err := az.get(ctx, sp.Scope, name, "")
+		if err != nil {
+			return Page{}, err
+		}
+		pg.Entries = append(pg.Entries, Entry{Key: PassKey(name), Value: v})
+	}
+	return pg, nil
+}
+
+func (az *azure) Pull(ctx context.Context, path string) ([]Entry, error) {
+	var out []Entry
+	cursor := ""
+	for {
+		pg, err := az.Page(ctx, path, cursor, azurePage)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pg.Entries...)
+		ReportProgress(ctx, len(out), 0)
+		if cursor = pg.Next; cursor == "" {
+			return out, nil
+		}
+	}
+}
+
+// Push sets each secret, adding a new version, and tags it with the
+// path's labels.
+func (az *azure) Push(ctx context.Context, p string, entries []Entry) error {
+	sp, err := parseSecretPath(p)
+	if err != nil {
+		return err
+	}
+	if sp.Name != "" {
+		return fmt.Errorf("push to a vault, not the secret %s", sp.Name)
+	}
+	for i, e := range entries {
+		name := PassEntryName(e.Key)
+		if strings.ContainsFunc(name, func(r rune) bool { return !(r == '-' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') }) {
+			return fmt.Errorf("%s: Key Vault names allow only letters, digits and dashes", e.Key)
+		}
+		body := map[string]any{"value": e.Value}
+		if len(sp.Labels) > 0 {
+			body["tags"] = sp.Labels
+		}
+		if err := az.do(ctx, http.MethodPut, az.secretURL(sp.Scope, name, ""), body, nil); err != nil {
+			return fmt.Errorf("%s: %w", e.Key, err)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
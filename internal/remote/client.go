@@ -0,0 +1,205 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Client runs provider requests with rate limiting, a per-request timeout
+// and retries with exponential backoff.
+type Client struct {
+	HTTP    *http.Client
+	Limiter *Limiter
+	Timeout time.Duration // per attempt
+	Retries int           // extra attempts after the first
+	Backoff time.Duration // first retry delay, doubled each time
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTP:    &http.Client{},
+		Limiter: NewLimiter(10),
+		Timeout: 15 * time.Second,
+		Retries: 4,
+		Backoff: 250 * time.Millisecond,
+	}
+}
+
+// Do runs fn until it succeeds, fails permanently, runs out of retries or
+// ctx is cancelled. fn must be safe to run more than once.
+func (c *Client) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.do(ctx, c.Retries, fn)
+}
+
+func (c *Client) do(ctx context.Context, retries int, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+		actx, cancel := context.WithTimeout(ctx, c.Timeout)
+		err := fn(actx)
+		cancel()
+		if err == nil || ctx.Err() != nil || attempt >= retries || !Retryable(err) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wait := c.delay(attempt)
+		slog.Warn("remote request failed, retrying", "attempt", attempt+1, "wait", wait, "err", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) delay(attempt int) time.Duration {
+	d := c.Backoff << attempt
+	if d > 10*time.Second {
+		d = 10 * time.Second
+	}
+	// Full jitter keeps parallel callers from retrying in lockstep.
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+// JSON performs an HTTP request through Do, encoding body and decoding the
+// response into out when they are non-nil.
+func (c *Client) JSON(ctx context.Context, method, url string, header http.Header, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.Send(ctx, method, url, header, payload, out)
+}
+
+// idempotentKey marks a context whose requests may be replayed.
+type idempotentKey struct{}
+
+// Idempotent marks the requests made with ctx as safe to replay, as
+// reads sent with POST are.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// Send is JSON with a body that is already encoded. A nil payload sends no
+// body; a Content-Type in header overrides application/json. Only GET and
+// HEAD requests, and those made with an Idempotent context, are retried: a
+// write that timed out may still have been applied.
+func (c *Client) Send(ctx context.Context, method, url string, header http.Header, payload []byte, out any) error {
+	retries := 0
+	if method == http.MethodGet || method == http.MethodHead || ctx.Value(idempotentKey{}) != nil {
+		retries = c.Retries
+	}
+	return c.do(ctx, retries, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if payload != nil && req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		slog.Debug("remote request", "method", method, "url", req.URL.Redacted())
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return responseError(resp)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	})
+}
+
+// responseError reads the start of a failed response's body into an
+// HTTPError.
+func responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+}
+
+// HTTPError is a non-2xx response.
+type HTTPError struct {
+	Status int
+	Body   string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Body)
+}
+
+// Retryable reports whether err is worth another attempt: throttling,
+// server errors and timeouts.
+func Retryable(err error) bool {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Status == http.StatusTooManyRequests || he.Status >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Limiter spaces calls evenly at a fixed rate.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func NewLimiter(perSecond float64) *Limiter {
+	return &Limiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next slot or until ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
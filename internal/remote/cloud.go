@@ -0,0 +1,203 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// cloudTimeout bounds the CLI and metadata lookups of an ambient token.
+const cloudTimeout = 20 * time.Second
+
+// secretPath addresses secrets in a cloud secret manager:
+// "<scope>[/<name>[@<version>]][?label=value&...]", where the scope is the
+// vault or project. Without a name every secret in the scope carrying the
+// labels is meant; labels are also set on secrets a push creates.
+type secretPath struct {
+	Scope   string
+	Name    string
+	Version string // "" for the latest
+	Labels  map[string]string
+}
+
+func parseSecretPath(path string) (secretPath, error) {
+	var sp secretPath
+	path, query, _ := strings.Cut(path, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return sp, fmt.Errorf("bad labels %q: %v", query, err)
+	}
+	for k, v := range q {
+		if sp.Labels == nil {
+			sp.Labels = make(map[string]string)
+		}
+		sp.Labels[k] = v[len(v)-1]
+	}
+	rest, version, hasVersion := strings.Cut(strings.Trim(path, "/"), "@")
+	sp.Scope, sp.Name, _ = strings.Cut(rest, "/")
+	sp.Version = version
+	switch {
+	case sp.Scope == "":
+		return sp, errors.New("path must start with the vault or project")
+	case hasVersion && (sp.Name == "" || version == ""):
+		return sp, fmt.Errorf("a version needs a secret name: %q", path)
+	}
+	return sp, nil
+}
+
+// hasLabels reports whether labels include every one of want.
+func hasLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedLabels lists labels as k=v, sorted, for filters and messages.
+func sortedLabels(labels map[string]string) []string {
+	out := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		out = append(out, k+"="+labels[k])
+	}
+	return out
+}
+
+// ambientToken finds a bearer token the way the cloud SDKs do, without
+// them: a token stored with :auth under provider, then the environment
+// variable env, then the cloud's CLI, then the instance metadata endpoint.
+// The first success is cached until shortly before it expires, or until
+// forget drops it.
+type ambientToken struct {
+	provider string
+	creds    cred.Store
+	env      string
+	cli      []string
+	metadata func(ctx context.Context, c *Client) (string, time.Time, error)
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // zero for a token that does not expire on its own
+}
+
+// tokenRefresh is how long before a token expires it is fetched again.
+const tokenRefresh = 5 * time.Minute
+
+// cliTokenLife is how long a token from a CLI that does not say when it
+// expires is used; the CLI keeps its own cache, so asking again is cheap.
+const cliTokenLife = 10 * time.Minute
+
+// forget drops the cached token, for a request refused as unauthorized.
+func (t *ambientToken) forget() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token, t.expires = "", time.Time{}
+}
+
+func (t *ambientToken) get(ctx context.Context, c *Client) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && (t.expires.IsZero() || time.Until(t.expires) > tokenRefresh) {
+		return t.token, nil
+	}
+	t.token, t.expires = "", time.Time{}
+	if tok, err := cred.Lookup(t.creds, t.provider); err == nil {
+		t.token = tok
+		return tok, nil
+	}
+	if tok := os.Getenv(t.env); tok != "" {
+		t.token = tok
+		return tok, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudTimeout)
+	defer cancel()
+	var errs []error
+	if _, err := exec.LookPath(t.cli[0]); err == nil {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, t.cli[0], t.cli[1:]...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if tok, expires := cliToken(out); err == nil && tok != "" {
+			t.token, t.expires = tok, expires
+			return tok, nil
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" && err != nil {
+			msg = err.Error()
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", t.cli[0], msg))
+	}
+	tok, expires, err := t.metadata(ctx, c)
+	if err == nil {
+		t.token, t.expires = tok, expires
+		return tok, nil
+	}
+	errs = append(errs, fmt.Errorf("metadata: %w", err))
+	return "", fmt.Errorf("no %s credentials (use :auth %s, set %s or log in with %s): %w",
+		t.provider, t.provider, t.env, t.cli[0], errors.Join(errs...))
+}
+
+// cliToken reads the token a CLI printed: the token alone, or az's JSON
+// with when it expires. A token with no expiry is used for cliTokenLife.
+func cliToken(out []byte) (string, time.Time) {
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+		ExpiresOn   string `json:"expiresOn"`  // local time
+		ExpiresUnix int64  `json:"expires_on"` // newer az versions
+	}
+	if json.Unmarshal(out, &resp) != nil || resp.AccessToken == "" {
+		return strings.TrimSpace(string(out)), time.Now().Add(cliTokenLife)
+	}
+	expires := time.Now().Add(cliTokenLife)
+	if resp.ExpiresUnix > 0 {
+		expires = time.Unix(resp.ExpiresUnix, 0)
+	} else if t, err := time.ParseInLocation("2006-01-02 15:04:05.999999", resp.ExpiresOn, time.Local); err == nil {
+		expires = t
+	}
+	return resp.AccessToken, expires
+}
+
+// metadataToken reads an access token and when it expires from an
+// instance metadata endpoint, which answers only on cloud machines;
+// elsewhere it fails fast.
+func metadataToken(ctx context.Context, c *Client, url string, header http.Header) (string, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var resp struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"` // a string from Azure
+	}
+	client := &Client{HTTP: c.HTTP, Timeout: 2 * time.Second}
+	if err := client.JSON(ctx, http.MethodGet, url, header, nil, &resp); err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.AccessToken == "" {
+		return "", time.Time{}, errors.New("no access token in response")
+	}
+	var expires time.Time
+	if secs, err := resp.ExpiresIn.Int64(); err == nil {
+		expires = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return resp.AccessToken, expires, nil
+}
+
+// isUnauthorized reports whether err is a 401 response, which a cached
+// token that expired early gets.
+func isUnauthorized(err error) bool {
+	var he *HTTPError
+	return errors.As(err, &he) && he.Status == http.StatusUnauthorized
+}
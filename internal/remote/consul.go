@@ -0,0 +1,221 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+func init() {
+	Register("consul", newConsul)
+}
+
+// consulWait is how long a watch's blocking query waits for a change. It
+// stays under the client's per-attempt timeout.
+const consulWait = "10s"
+
+// consulTxnOps is the most operations Consul accepts in one transaction.
+const consulTxnOps = 64
+
+// consul maps a Consul KV tree to variables. The path is a key prefix;
+// keys below it are named by the configured KVKeys.
+type consul struct {
+	addr   string
+	token  string
+	keys   KVKeys
+	client *Client
+}
+
+func newConsul(cfg Config) (Provider, error) {
+	addr := os.Getenv("CONSUL_HTTP_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:8500"
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	// Without ACLs Consul needs no token.
+	tok, err := cred.Lookup(cfg.Creds, "consul")
+	if err != nil && !errors.Is(err, cred.ErrNotFound) {
+		return nil, err
+	}
+	return &consul{addr: strings.TrimRight(addr, "/"), token: tok, keys: cfg.KV, client: cfg.Client}, nil
+}
+
+func (c *consul) header() http.Header {
+	h := http.Header{}
+	if c.token != "" {
+		h.Set("X-Consul-Token", c.token)
+	}
+	return h
+}
+
+type consulKV struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+// list reads the tree under prefix. With a non-zero index it is a
+// blocking query that returns once the tree changes past index or the wait
+// ends. It also returns the index to block on next.
+func (c *consul) list(ctx context.Context, prefix string, index uint64) ([]consulKV, uint64, error) {
+	q := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", consulWait)
+	}
+	u := c.addr + "/v1/kv/" + (&url.URL{Path: prefix}).EscapedPath() + "?" + q.Encode()
+	var kvs []consulKV
+	var next uint64
+	err := c.client.Do(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header = c.header()
+		resp, err := c.client.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		next, _ = strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			kvs = nil
+			return nil
+		case resp.StatusCode >= 300:
+			return responseError(resp)
+		}
+		return json.NewDecoder(resp.Body).Decode(&kvs)
+	})
+	return kvs, next, err
+}
+
+// entries names the keys under prefix, leaving out folders.
+func (c *consul) entries(prefix string, kvs []consulKV) []Entry {
+	out := make([]Entry, 0, len(kvs))
+	for _, kv := range kvs {
+		if strings.HasSuffix(kv.Key, "/") {
+			continue
+		}
+		out = append(out, Entry{Key: c.keys.Name(strings.TrimPrefix(kv.Key, prefix)), Value: string(kv.Value)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (c *consul) Pull(ctx context.Context, path string) ([]Entry, error) {
+	prefix := kvPrefix(path)
+	kvs, _, err := c.list(ctx, prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := c.entries(prefix, kvs)
+	ReportProgress(ctx, len(out), len(out))
+	return out, nil
+}
+
+func (c *consul) keyURL(key string) string {
+	return c.addr + "/v1/kv/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// Push writes each entry unconditionally.
+func (c *consul) Push(ctx context.Context, path string, entries []Entry) error {
+	prefix := kvPrefix(path)
+	for i, e := range entries {
+		var ok bool
+		if err := c.client.Send(ctx, http.MethodPut, c.keyURL(c.keys.Key(prefix, e.Key)), c.header(), []byte(e.Value), &ok); err != nil {
+			return fmt.Errorf("%s: %w", e.Key, err)
+		}
+		if !ok {
+			return fmt.Errorf("%s: consul refused the write", e.Key)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
+
+// PushCAS checks every key against base, then writes them in check-and-set
+// transactions on the indexes read, so a change landing in between fails
+// the write too. Each transaction holds up to consulTxnOps keys; when a
+// later one conflicts, the keys of those before it are reported written.
+func (c *consul) PushCAS(ctx context.Context, path string, entries []Entry, base map[string]string) error {
+	prefix := kvPrefix(path)
+	kvs, _, err := c.list(ctx, prefix, 0)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]uint64, len(kvs))
+	for _, kv := range kvs {
+		index[c.keys.Name(strings.TrimPrefix(kv.Key, prefix))] = kv.ModifyIndex
+	}
+	if keys := kvConflicts(entries, kvMap(c.entries(prefix, kvs)), base); len(keys) > 0 {
+		return &ConflictError{Keys: keys}
+	}
+
+	type txnKV struct {
+		Verb  string
+		Key   string
+		Value []byte
+		Index uint64
+	}
+	for start := 0; start < len(entries); start += consulTxnOps {
+		chunk := entries[start:min(start+consulTxnOps, len(entries))]
+		ops := make([]map[string]txnKV, len(chunk))
+		for i, e := range chunk {
+			// Index 0 writes only if the key does not exist yet.
+			ops[i] = map[string]txnKV{"KV": {Verb: "cas", Key: c.keys.Key(prefix, e.Key), Value: []byte(e.Value), Index: index[e.Key]}}
+		}
+		err := c.client.JSON(ctx, http.MethodPut, c.addr+"/v1/txn", c.header(), ops, nil)
+		var he *HTTPError
+		if errors.As(err, &he) && he.Status == http.StatusConflict {
+			// Something changed after the check; name it if it still
+			// differs.
+			if kvs, _, lerr := c.list(ctx, prefix, 0); lerr == nil {
+				if keys := kvConflicts(entries[start:], kvMap(c.entries(prefix, kvs)), base); len(keys) > 0 {
+					return &ConflictError{Keys: keys, Written: entryKeys(entries[:start])}
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		ReportProgress(ctx, start+len(chunk), len(entries))
+	}
+	return nil
+}
+
+// Watch follows the tree with blocking queries and calls fn when its
+// contents change.
+func (c *consul) Watch(ctx context.Context, path string, fn func([]Entry)) error {
+	prefix := kvPrefix(path)
+	var last map[string]string
+	var index uint64
+	for {
+		kvs, next, err := c.list(ctx, prefix, index)
+		if err != nil {
+			return err
+		}
+		entries := c.entries(prefix, kvs)
+		if m := kvMap(entries); last == nil || !maps.Equal(m, last) {
+			last = m
+			fn(entries)
+		}
+		// An index going backwards means the raft log was reset.
+		if next < index {
+			next = 0
+		}
+		index = max(next, 1)
+	}
+}
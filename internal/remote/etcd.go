@@ -0,0 +1,289 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+func init() {
+	Register("etcd", newEtcd)
+}
+
+// etcdTxnOps keeps a transaction under etcd's default limit of 128
+// operations.
+const etcdTxnOps = 64
+
+// etcd maps an etcd v3 key range to variables through the JSON gateway.
+// The path is a key prefix; keys below it are named by the configured
+// KVKeys.
+type etcd struct {
+	addr   string
+	token  string
+	keys   KVKeys
+	client *Client
+}
+
+func newEtcd(cfg Config) (Provider, error) {
+	addr, _, _ := strings.Cut(os.Getenv("ETCDCTL_ENDPOINTS"), ",")
+	if addr == "" {
+		addr = "127.0.0.1:2379"
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	// A token from /v3/auth/authenticate, needed only with auth enabled.
+	tok, err := cred.Lookup(cfg.Creds, "etcd")
+	if err != nil && !errors.Is(err, cred.ErrNotFound) {
+		return nil, err
+	}
+	return &etcd{addr: strings.TrimRight(addr, "/"), token: tok, keys: cfg.KV, client: cfg.Client}, nil
+}
+
+func (e *etcd) header() http.Header {
+	h := http.Header{}
+	if e.token != "" {
+		h.Set("Authorization", e.token)
+	}
+	return h
+}
+
+// etcdRange is a key range; the gateway base64-encodes bytes and quotes
+// 64-bit integers.
+type etcdRange struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+}
+
+type etcdKV struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+// prefixRange is the range holding every key that starts with prefix.
+func prefixRange(prefix string) etcdRange {
+	if prefix == "" {
+		return etcdRange{Key: []byte{0}, RangeEnd: []byte{0}}
+	}
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return etcdRange{Key: []byte(prefix), RangeEnd: end[:i+1]}
+		}
+	}
+	return etcdRange{Key: []byte(prefix), RangeEnd: []byte{0}}
+}
+
+// list reads the range under prefix and the revision it was read at.
+func (e *etcd) list(ctx context.Context, prefix string) ([]Entry, int64, error) {
+	var resp struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdKV   `json:"kvs"`
+	}
+	if err := e.client.JSON(Idempotent(ctx), http.MethodPost, e.addr+"/v3/kv/range", e.header(), prefixRange(prefix), &resp); err != nil {
+		return nil, 0, err
+	}
+	out := make([]Entry, 0, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		rel := strings.TrimPrefix(string(kv.Key), prefix)
+		out = append(out, Entry{Key: e.keys.Name(rel), Value: string(kv.Value)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, resp.Header.Revision, nil
+}
+
+func (e *etcd) Pull(ctx context.Context, path string) ([]Entry, error) {
+	out, _, err := e.list(ctx, kvPrefix(path))
+	if err != nil {
+		return nil, err
+	}
+	ReportProgress(ctx, len(out), len(out))
+	return out, nil
+}
+
+// Push writes each entry unconditionally.
+func (e *etcd) Push(ctx context.Context, path string, entries []Entry) error {
+	prefix := kvPrefix(path)
+	for i, en := range entries {
+		put := etcdKV{Key: []byte(e.keys.Key(prefix, en.Key)), Value: []byte(en.Value)}
+		if err := e.client.JSON(ctx, http.MethodPost, e.addr+"/v3/kv/put", e.header(), put, nil); err != nil {
+			return fmt.Errorf("%s: %w", en.Key, err)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
+
+// PushCAS writes in transactions that compare each key's value with base,
+// or check that a key missing from base was never created. All keys are
+// checked first; each transaction then holds up to etcdTxnOps keys, and
+// when a later one conflicts, the keys of those before it are reported
+// written.
+func (e *etcd) PushCAS(ctx context.Context, path string, entries []Entry, base map[string]string) error {
+	prefix := kvPrefix(path)
+	type compare struct {
+		Key            []byte `json:"key"`
+		Target         string `json:"target"`
+		Result         string `json:"result"`
+		Value          []byte `json:"value,omitempty"`
+		CreateRevision *int64 `json:"create_revision,omitempty"`
+	}
+	type op struct {
+		Put etcdKV `json:"request_put"`
+	}
+	cur, _, err := e.list(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if keys := kvConflicts(entries, kvMap(cur), base); len(keys) > 0 {
+		return &ConflictError{Keys: keys}
+	}
+	var never int64
+	for start := 0; start < len(entries); start += etcdTxnOps {
+		chunk := entries[start:min(start+etcdTxnOps, len(entries))]
+		var txn struct {
+			Compare []compare `json:"compare"`
+			Success []op      `json:"success"`
+		}
+		for _, en := range chunk {
+			key := []byte(e.keys.Key(prefix, en.Key))
+			c := compare{Key: key, Target: "CREATE", Result: "EQUAL", CreateRevision: &never}
+			if v, ok := base[en.Key]; ok {
+				c = compare{Key: key, Target: "VALUE", Result: "EQUAL", Value: []byte(v)}
+			}
+			txn.Compare = append(txn.Compare, c)
+			txn.Success = append(txn.Success, op{Put: etcdKV{Key: key, Value: []byte(en.Value)}})
+		}
+		var resp struct {
+			Succeeded bool `json:"succeeded"`
+		}
+		if err := e.client.JSON(ctx, http.MethodPost, e.addr+"/v3/kv/txn", e.header(), txn, &resp); err != nil {
+			return err
+		}
+		if !resp.Succeeded {
+			cur, _, err := e.list(ctx, prefix)
+			if err != nil {
+				return err
+			}
+			keys := kvConflicts(chunk, kvMap(cur), base)
+			if len(keys) == 0 {
+				return errors.New("etcd refused the transaction")
+			}
+			return &ConflictError{Keys: keys, Written: entryKeys(entries[:start])}
+		}
+		ReportProgress(ctx, start+len(chunk), len(entries))
+	}
+	return nil
+}
+
+// Watch reads the range, then follows it on a watch stream, reading it
+// again after each batch of events. A broken stream is reopened from the
+// last revision seen, backing off like a failed request.
+func (e *etcd) Watch(ctx context.Context, path string, fn func([]Entry)) error {
+	prefix := kvPrefix(path)
+	entries, rev, err := e.list(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	fn(entries)
+	changed := func() error {
+		entries, r, err := e.list(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		rev = r
+		fn(entries)
+		return nil
+	}
+	for attempt := 0; ; attempt++ {
+		err := e.stream(ctx, prefix, rev+1, func() error {
+			attempt = 0
+			return changed()
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= e.client.Retries {
+			return err
+		}
+		wait := e.client.delay(attempt)
+		slog.Warn("etcd watch broke, reopening", "attempt", attempt+1, "wait", wait, "err", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stream opens a watch on the range from revision start and calls changed
+// for each batch of events until the stream ends. It never returns nil.
+func (e *etcd) stream(ctx context.Context, prefix string, start int64, changed func() error) error {
+	r := prefixRange(prefix)
+	body, err := json.Marshal(map[string]any{"create_request": map[string]any{
+		"key": r.Key, "range_end": r.RangeEnd, "start_revision": start,
+	}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.addr+"/v3/watch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = e.header()
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Events       []json.RawMessage `json:"events"`
+				Canceled     bool              `json:"canceled"`
+				CancelReason string            `json:"cancel_reason"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		switch {
+		case msg.Error != nil:
+			return fmt.Errorf("etcd watch: %s", msg.Error.Message)
+		case msg.Result.Canceled:
+			// Usually a compacted start revision: catch up by reading
+			// the range again before the stream is reopened.
+			if err := changed(); err != nil {
+				return err
+			}
+			return fmt.Errorf("etcd watch cancelled: %s", msg.Result.CancelReason)
+		case len(msg.Result.Events) > 0:
+			if err := changed(); err != nil {
+				return err
+			}
+		}
+	}
+}
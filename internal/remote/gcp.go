@@ -0,0 +1,179 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("gcp", newGCP)
+}
+
+// gcpPage is how many secrets a pull lists per request.
+const gcpPage = 250
+
+// gcp reads and writes Google Secret Manager secrets. Paths look like
+// "my-project", "my-project/API_KEY@3" or "my-project?env=prod"; secret
+// IDs are used as variable names unchanged.
+type gcp struct {
+	api    string
+	token  *ambientToken
+	client *Client
+}
+
+func newGCP(cfg Config) (Provider, error) {
+	return &gcp{
+		api:    "https://secretmanager.googleapis.com/v1",
+		client: cfg.Client,
+		token: &ambientToken{
+			provider: "gcp",
+			creds:    cfg.Creds,
+			env:      "GOOGLE_OAUTH_ACCESS_TOKEN",
+			cli:      []string{"gcloud", "auth", "print-access-token"},
+			metadata: func(ctx context.Context, c *Client) (string, time.Time, error) {
+				return metadataToken(ctx, c,
+					"http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token",
+					http.Header{"Metadata-Flavor": {"Google"}})
+			},
+		},
+	}, nil
+}
+
+func (g *gcp) do(ctx context.Context, method, url string, body, out any) error {
+	tok, err := g.token.get(ctx, g.client)
+	if err != nil {
+		return err
+	}
+	err = g.client.JSON(ctx, method, url, http.Header{"Authorization": {"Bearer " + tok}}, body, out)
+	if isUnauthorized(err) {
+		g.token.forget()
+		if tok, err = g.token.get(ctx, g.client); err != nil {
+			return err
+		}
+		err = g.client.JSON(ctx, method, url, http.Header{"Authorization": {"Bearer " + tok}}, body, out)
+	}
+	return err
+}
+
+func (g *gcp) secretURL(project, name string) string {
+	return fmt.Sprintf("%s/projects/%s/secrets/%s", g.api, url.PathEscape(project), url.PathEscape(name))
+}
+
+// access reads one version of a secret, "" meaning the latest.
+func (g *gcp) access(ctx context.Context, project, name, version string) (string, error) {
+	if version == "" {
+		version = "latest"
+	}
+	var resp struct {
+		Payload struct {
+			Data []byte `json:"data"`
+		} `json:"payload"`
+	}
+	if err := g.do(ctx, http.MethodGet, g.secretURL(project, name)+"/versions/"+url.PathEscape(version)+":access", nil, &resp); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(resp.Payload.Data), nil
+}
+
+// Page lists a page of the project's secrets carrying the path's labels
+// and reads their latest versions. The cursor is the API's page token.
+func (g *gcp) Page(ctx context.Context, p, cursor string, limit int) (Page, error) {
+	sp, err := parseSecretPath(p)
+	if err != nil {
+		return Page{}, err
+	}
+	if sp.Name != "" {
+		v, err := g.access(ctx, sp.Scope, sp.Name, sp.Version)
+		if err != nil {
+			return Page{}, err
+		}
+		return Page{Entries: []Entry{{Key: sp.Name, Value: v}}, Total: 1}, nil
+	}
+	q := url.Values{"pageSize": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		q.Set("pageToken", cursor)
+	}
+	if len(sp.Labels) > 0 {
+		filters := sortedLabels(sp.Labels)
+		for i, l := range filters {
+			filters[i] = "labels." + l
+		}
+		q.Set("filter", strings.Join(filters, " AND "))
+	}
+	var resp struct {
+		Secrets []struct {
+			Name string `json:"name"`
+		} `json:"secrets"`
+		NextPageToken string `json:"nextPageToken"`
+		TotalSize     int    `json:"totalSize"`
+	}
+	list := fmt.Sprintf("%s/projects/%s/secrets?%s", g.api, url.PathEscape(sp.Scope), q.Encode())
+	if err := g.do(ctx, http.MethodGet, list, nil, &resp); err != nil {
+		return Page{}, err
+	}
+	pg := Page{Next: resp.NextPageToken, Total: resp.TotalSize}
+	for _, s := range resp.Secrets {
+		name := path.Base(s.Name)
+		v, err := g.access(ctx, sp.Scope, name, "")
+		if err != nil {
+			return Page{}, err
+		}
+		pg.Entries = append(pg.Entries, Entry{Key: name, Value: v})
+	}
+	return pg, nil
+}
+
+func (g *gcp) Pull(ctx context.Context, path string) ([]Entry, error) {
+	var out []Entry
+	cursor := ""
+	for {
+		pg, err := g.Page(ctx, path, cursor, gcpPage)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pg.Entries...)
+		ReportProgress(ctx, len(out), pg.Total)
+		if cursor = pg.Next; cursor == "" {
+			return out, nil
+		}
+	}
+}
+
+// Push adds a version to each secret, creating missing secrets with the
+// path's labels.
+func (g *gcp) Push(ctx context.Context, p string, entries []Entry) error {
+	sp, err := parseSecretPath(p)
+	if err != nil {
+		return err
+	}
+	if sp.Name != "" {
+		return fmt.Errorf("push to a project, not the secret %s", sp.Name)
+	}
+	for i, e := range entries {
+		body := map[string]any{"payload": map[string][]byte{"data": []byte(e.Value)}}
+		err := g.do(ctx, http.MethodPost, g.secretURL(sp.Scope, e.Key)+":addVersion", body, nil)
+		var he *HTTPError
+		if errors.As(err, &he) && he.Status == http.StatusNotFound {
+			create := map[string]any{"replication": map[string]any{"automatic": struct{}{}}}
+			if len(sp.Labels) > 0 {
+				create["labels"] = sp.Labels
+			}
+			u := fmt.Sprintf("%s/projects/%s/secrets?secretId=%s", g.api, url.PathEscape(sp.Scope), url.QueryEscape(e.Key))
+			if err = g.do(ctx, http.MethodPost, u, create, nil); err == nil {
+				err = g.do(ctx, http.MethodPost, g.secretURL(sp.Scope, e.Key)+":addVersion", body, nil)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Key, err)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// HTTPSource describes a generic HTTP or GraphQL service in the config, so
+// it can be pulled from, and optionally pushed to, like a built-in
+// provider. URL, Body and header values are Go templates over the path
+// (.Path) and the token stored with :auth under the source's name
+// (.Token); a json function quotes a value for use inside a body.
+//
+// Items locates the entries in the response: an object becomes one entry
+// per member, an array one entry per element, with Key and Value locating
+// each element's key and value. Locations are dotted paths such as
+// "data.config.items" or "$.items[0].value".
+type HTTPSource struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"` // GET, or POST when Body is set
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
+	Items   string            `json:"items"`
+	Key     string            `json:"key"`
+	Value   string            `json:"value"`
+
+	// Push writes entries back. Without it the source is read-only.
+	Push *HTTPPush `json:"push"`
+}
+
+// HTTPPush is the request that writes entries back. Its templates also see
+// .Entries; with Each set one request is made per entry, which sees .Key
+// and .Value instead.
+type HTTPPush struct {
+	URL    string `json:"url"` // the source's URL when empty
+	Method string `json:"method"`
+	Body   string `json:"body"`
+	Each   bool   `json:"each"`
+}
+
+// httpSource is an HTTPSource opened as a Provider.
+type httpSource struct {
+	name   string
+	src    HTTPSource
+	token  string
+	client *Client
+}
+
+func newHTTPSource(name string, src HTTPSource, cfg Config) (Provider, error) {
+	if src.URL == "" {
+		return nil, fmt.Errorf("source %s has no url", name)
+	}
+	tok, err := cred.Lookup(cfg.Creds, name)
+	if err != nil && usesToken(src) {
+		return nil, fmt.Errorf("no %s token (use :auth %s)", name, name)
+	}
+	return &httpSource{name: name, src: src, token: tok, client: cfg.Client}, nil
+}
+
+func usesToken(src HTTPSource) bool {
+	texts := []string{src.URL, src.Body}
+	for _, v := range src.Headers {
+		texts = append(texts, v)
+	}
+	if src.Push != nil {
+		texts = append(texts, src.Push.URL, src.Push.Body)
+	}
+	for _, t := range texts {
+		if strings.Contains(t, ".Token") {
+			return true
+		}
+	}
+	return false
+}
+
+// httpData is what the templates see.
+type httpData struct {
+	Path    string
+	Token   string
+	Entries []Entry
+	Key     string
+	Value   string
+}
+
+var httpFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func expand(name, text string, data httpData) (string, error) {
+	t, err := template.New(name).Funcs(httpFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// request expands a request's templates and sends it, decoding a JSON
+// response into out when it is non-nil.
+func (h *httpSource) request(ctx context.Context, method, url, body string, data httpData, out any) error {
+	data.Token = h.token
+	url, err := expand("url", url, data)
+	if err != nil {
+		return fmt.Errorf("source %s url: %w", h.name, err)
+	}
+	var payload []byte
+	if body != "" {
+		s, err := expand("body", body, data)
+		if err != nil {
+			return fmt.Errorf("source %s body: %w", h.name, err)
+		}
+		payload = []byte(s)
+	}
+	header := http.Header{}
+	for k, v := range h.src.Headers {
+		s, err := expand("header", v, data)
+		if err != nil {
+			return fmt.Errorf("source %s header %s: %w", h.name, k, err)
+		}
+		header.Set(k, s)
+	}
+	if method == "" {
+		method = http.MethodGet
+		if payload != nil {
+			method = http.MethodPost
+		}
+	}
+	return h.client.Send(ctx, strings.ToUpper(method), url, header, payload, out)
+}
+
+func (h *httpSource) Pull(ctx context.Context, path string) ([]Entry, error) {
+	var resp any
+	if err := h.request(ctx, h.src.Method, h.src.URL, h.src.Body, httpData{Path: path}, &resp); err != nil {
+		return nil, err
+	}
+	if err := graphQLError(resp); err != nil {
+		return nil, err
+	}
+	out, err := h.extract(resp)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", h.name, err)
+	}
+	ReportProgress(ctx, len(out), len(out))
+	return out, nil
+}
+
+// extract maps a decoded response to entries.
+func (h *httpSource) extract(resp any) ([]Entry, error) {
+	items, err := lookupJSON(resp, h.src.Items)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	var out []Entry
+	switch items := items.(type) {
+	case map[string]any:
+		for k, v := range items {
+			out = append(out, Entry{Key: k, Value: jsonText(v)})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	case []any:
+		if h.src.Key == "" {
+			return nil, errors.New("items is a list; set key and value")
+		}
+		for i, it := range items {
+			k, err := lookupJSON(it, h.src.Key)
+			if err != nil {
+				return nil, fmt.Errorf("item %d key: %w", i, err)
+			}
+			v, err := lookupJSON(it, h.src.Value)
+			if err != nil {
+				return nil, fmt.Errorf("item %d value: %w", i, err)
+			}
+			out = append(out, Entry{Key: jsonText(k), Value: jsonText(v)})
+		}
+	default:
+		return nil, fmt.Errorf("items is a %s, not an object or list", jsonKind(items))
+	}
+	return out, nil
+}
+
+func (h *httpSource) Push(ctx context.Context, path string, entries []Entry) error {
+	p := h.src.Push
+	if p == nil {
+		return fmt.Errorf("source %s is read-only (no push request configured)", h.name)
+	}
+	url := p.URL
+	if url == "" {
+		url = h.src.URL
+	}
+	if !p.Each {
+		if err := h.request(ctx, p.Method, url, p.Body, httpData{Path: path, Entries: entries}, nil); err != nil {
+			return err
+		}
+		ReportProgress(ctx, len(entries), len(entries))
+		return nil
+	}
+	for i, e := range entries {
+		data := httpData{Path: path, Entries: entries, Key: e.Key, Value: e.Value}
+		if err := h.request(ctx, p.Method, url, p.Body, data, nil); err != nil {
+			return fmt.Errorf("%s: %w", e.Key, err)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
+
+// graphQLError reports the first error of a GraphQL response, which comes
+// with a 200 status.
+func graphQLError(resp any) error {
+	m, ok := resp.(map[string]any)
+	if !ok {
+		return nil
+	}
+	errs, ok := m["errors"].([]any)
+	if !ok || len(errs) == 0 {
+		return nil
+	}
+	if e, ok := errs[0].(map[string]any); ok {
+		if msg, ok := e["message"].(string); ok {
+			return fmt.Errorf("graphql: %s", msg)
+		}
+	}
+	return fmt.Errorf("graphql: %s", jsonText(errs[0]))
+}
+
+// lookupJSON follows a dotted path with optional [n] indexes through a
+// decoded JSON value. A leading "$" and an empty path mean v itself.
+func lookupJSON(v any, path string) (any, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(path, "[", ".[")
+	if path == "" {
+		return v, nil
+	}
+	for _, step := range strings.Split(path, ".") {
+		if step == "" || step == "[*]" {
+			continue
+		}
+		if idx, ok := strings.CutPrefix(step, "["); ok {
+			n, err := strconv.Atoi(strings.TrimSuffix(idx, "]"))
+			list, isList := v.([]any)
+			if err != nil || !isList {
+				return nil, fmt.Errorf("cannot index %s with %s", jsonKind(v), step)
+			}
+			if n < 0 || n >= len(list) {
+				return nil, fmt.Errorf("index %d out of range (%d items)", n, len(list))
+			}
+			v = list[n]
+			continue
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("no %q in %s", step, jsonKind(v))
+		}
+		if v, ok = m[step]; !ok {
+			return nil, fmt.Errorf("no %q", step)
+		}
+	}
+	return v, nil
+}
+
+// jsonText renders a decoded JSON value as a variable value: strings as
+// they are, null as empty and anything else as compact JSON.
+func jsonText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "list"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "number"
+}
@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"slices"
+	"strings"
+)
+
+// KVKeys maps the keys of a KV tree under a prefix to variable names: the
+// prefix is dropped and each "/" becomes Separator, "_" when empty, so
+// app/db/host under app/ is DB_HOST with Upper set. Pushing maps names
+// back the same way, which cannot restore case Upper folded.
+type KVKeys struct {
+	Separator string `json:"separator"`
+	Upper     bool   `json:"upper"`
+}
+
+func (k KVKeys) sep() string {
+	if k.Separator == "" {
+		return "_"
+	}
+	return k.Separator
+}
+
+// kvPrefix turns a path into the key prefix it addresses.
+func kvPrefix(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// Name maps the key at prefix+rel to a variable name.
+func (k KVKeys) Name(rel string) string {
+	name := strings.ReplaceAll(strings.Trim(rel, "/"), "/", k.sep())
+	if k.Upper {
+		name = strings.ToUpper(name)
+	}
+	return name
+}
+
+// Key maps a variable name back to a key under prefix.
+func (k KVKeys) Key(prefix, name string) string {
+	if k.Upper {
+		name = strings.ToLower(name)
+	}
+	return prefix + strings.ReplaceAll(name, k.sep(), "/")
+}
+
+// kvConflicts lists the keys of entries whose current remote value, in
+// cur, is not their value in base.
+func kvConflicts(entries []Entry, cur, base map[string]string) []string {
+	var keys []string
+	for _, e := range entries {
+		was, pulled := base[e.Key]
+		now, exists := cur[e.Key]
+		if pulled != exists || was != now {
+			keys = append(keys, e.Key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// kvMap indexes entries by key.
+func kvMap(entries []Entry) map[string]string {
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		m[e.Key] = e.Value
+	}
+	return m
+}
@@ -0,0 +1,171 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("pass", newPass)
+}
+
+// PassScheme prefixes values that refer to a password-store entry.
+const PassScheme = "pass://"
+
+// pass maps password-store entries to variables: the entries directly
+// under path become keys named after them, so work/api-token is API_TOKEN.
+type pass struct {
+	dir string
+}
+
+func newPass(Config) (Provider, error) {
+	if _, err := exec.LookPath("pass"); err != nil {
+		return nil, errors.New("pass is not installed")
+	}
+	return &pass{dir: passDir()}, nil
+}
+
+func passDir() string {
+	if d := os.Getenv("PASSWORD_STORE_DIR"); d != "" {
+		return d
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".password-store")
+}
+
+func (p *pass) Pull(ctx context.Context, path string) ([]Entry, error) {
+	path = strings.Trim(path, "/")
+	names, err := p.names(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.show(ctx, path, names, 0, len(names))
+}
+
+// Page lists the entries under path, which is cheap, and decrypts only
+// the requested ones. The cursor is the index of the first entry.
+func (p *pass) Page(ctx context.Context, path, cursor string, limit int) (Page, error) {
+	path = strings.Trim(path, "/")
+	names, err := p.names(path)
+	if err != nil {
+		return Page{}, err
+	}
+	start := 0
+	if cursor != "" {
+		if start, err = strconv.Atoi(cursor); err != nil || start < 0 || start > len(names) {
+			return Page{}, fmt.Errorf("bad page cursor %q", cursor)
+		}
+	}
+	end := min(start+limit, len(names))
+	entries, err := p.show(ctx, path, names[start:end], start, len(names))
+	if err != nil {
+		return Page{}, err
+	}
+	pg := Page{Entries: entries, Total: len(names)}
+	if end < len(names) {
+		pg.Next = strconv.Itoa(end)
+	}
+	return pg, nil
+}
+
+// names lists the entries directly under path, sorted.
+func (p *pass) names(path string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(p.dir, path))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if name, ok := strings.CutSuffix(f.Name(), ".gpg"); ok && !f.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// show decrypts names, reporting progress as done+i of total.
+func (p *pass) show(ctx context.Context, path string, names []string, done, total int) ([]Entry, error) {
+	out := make([]Entry, 0, len(names))
+	for i, name := range names {
+		v, err := PassShow(ctx, pathJoin(path, name))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Entry{Key: PassKey(name), Value: v})
+		ReportProgress(ctx, done+i+1, total)
+	}
+	return out, nil
+}
+
+// Push inserts each entry under path, overwriting existing entries.
+func (p *pass) Push(ctx context.Context, path string, entries []Entry) error {
+	path = strings.Trim(path, "/")
+	for i, e := range entries {
+		if err := PassInsert(ctx, pathJoin(path, PassEntryName(e.Key)), e.Value); err != nil {
+			return fmt.Errorf("%s: %w", e.Key, err)
+		}
+		ReportProgress(ctx, i+1, len(entries))
+	}
+	return nil
+}
+
+func pathJoin(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// PassShow returns the first line of a password-store entry, which by
+// convention holds the secret.
+func PassShow(ctx context.Context, entry string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "pass", "show", entry)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return line, nil
+}
+
+// PassInsert writes value to entry, replacing it.
+func PassInsert(ctx context.Context, entry, value string) error {
+	if strings.Contains(value, "\n") {
+		return errors.New("multi-line values cannot be stored in pass")
+	}
+	cmd := exec.CommandContext(ctx, "pass", "insert", "--multiline", "--force", entry)
+	cmd.Stdin = strings.NewReader(value + "\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// PassKey names the variable for an entry: api-token becomes API_TOKEN.
+func PassKey(entry string) string {
+	name := entry[strings.LastIndex(entry, "/")+1:]
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// PassEntryName is the inverse of PassKey: API_TOKEN becomes api-token.
+func PassEntryName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
@@ -0,0 +1,164 @@
+// Package remote pulls and pushes variables held by remote services.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// Entry is one remote key/value pair.
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Provider is a remote backend. Path addresses a group of values in the
+// provider's own terms (a Vault secret, an SSM path prefix, ...).
+type Provider interface {
+	Pull(ctx context.Context, path string) ([]Entry, error)
+	Push(ctx context.Context, path string, entries []Entry) error
+}
+
+// Pager is implemented by providers that can list a path a page at a
+// time, for backends holding more entries than are worth pulling at once.
+type Pager interface {
+	// Page fetches up to limit entries starting at cursor, "" for the
+	// first page.
+	Page(ctx context.Context, path, cursor string, limit int) (Page, error)
+}
+
+// Page is one page of entries. Next is the cursor of the following page,
+// "" after the last; Total is the number of entries under the path, 0 when
+// the backend cannot tell.
+type Page struct {
+	Entries []Entry
+	Next    string
+	Total   int
+}
+
+// CASPusher is implemented by providers that can write conditionally, so
+// a push does not clobber changes made since the values were pulled.
+type CASPusher interface {
+	// PushCAS writes entries only if each key's remote value is still its
+	// value in base; a key missing from base must not exist remotely.
+	// A *ConflictError says which keys differ. Nothing is written then,
+	// unless the backend took a push too large for one transaction in
+	// several and an earlier one went through; the error's Written lists
+	// what it wrote.
+	PushCAS(ctx context.Context, path string, entries []Entry, base map[string]string) error
+}
+
+// ConflictError lists the keys a conditional push found changed remotely.
+type ConflictError struct {
+	Keys    []string
+	Written []string // keys written before the conflict was found
+}
+
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("%d keys changed remotely: %s", len(e.Keys), strings.Join(e.Keys, ", "))
+	if len(e.Written) > 0 {
+		msg += fmt.Sprintf(" (%d keys were written before: %s)", len(e.Written), strings.Join(e.Written, ", "))
+	}
+	return msg
+}
+
+// entryKeys returns the keys of entries.
+func entryKeys(entries []Entry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Watcher is implemented by providers that can follow changes under a
+// path.
+type Watcher interface {
+	// Watch calls fn with every entry under path each time they change,
+	// until ctx is done or the watch fails for good.
+	Watch(ctx context.Context, path string, fn func([]Entry)) error
+}
+
+// FirstPage fetches the first page from p, or pulls everything as a single
+// page from a provider that is not a Pager.
+func FirstPage(ctx context.Context, p Provider, path string, limit int) (Page, error) {
+	if pg, ok := p.(Pager); ok {
+		return pg.Page(ctx, path, "", limit)
+	}
+	entries, err := p.Pull(ctx, path)
+	return Page{Entries: entries, Total: len(entries)}, err
+}
+
+// Config is handed to provider factories.
+type Config struct {
+	Creds  cred.Store
+	Client *Client
+
+	// Sources are the HTTP sources from the config, opened by name when
+	// no provider is registered under it.
+	Sources map[string]HTTPSource
+
+	// KV maps the keys of KV stores such as Consul and etcd to names.
+	KV KVKeys
+}
+
+type Factory func(cfg Config) (Provider, error)
+
+var providers = map[string]Factory{}
+
+// Register makes a provider available under name.
+func Register(name string, f Factory) {
+	providers[name] = f
+}
+
+// Names lists registered providers.
+func Names() []string {
+	out := make([]string, 0, len(providers))
+	for n := range providers {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Open instantiates the named provider or configured source. A nil Client
+// gets the defaults.
+func Open(name string, cfg Config) (Provider, error) {
+	if cfg.Client == nil {
+		cfg.Client = NewClient()
+	}
+	name = strings.ToLower(name)
+	if f, ok := providers[name]; ok {
+		return f(cfg)
+	}
+	for n, src := range cfg.Sources {
+		if strings.ToLower(n) == name {
+			return newHTTPSource(name, src, cfg)
+		}
+	}
+	have := Names()
+	for n := range cfg.Sources {
+		have = append(have, strings.ToLower(n))
+	}
+	sort.Strings(have)
+	return nil, fmt.Errorf("unknown provider %q (have %s)", name, strings.Join(have, ", "))
+}
+
+type progressKey struct{}
+
+// WithProgress attaches a progress callback to ctx.
+func WithProgress(ctx context.Context, fn func(done, total int)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress calls the callback attached to ctx, if any. total is 0 when
+// unknown.
+func ReportProgress(ctx context.Context, done, total int) {
+	if fn, ok := ctx.Value(progressKey{}).(func(done, total int)); ok {
+		fn(done, total)
+	}
+}
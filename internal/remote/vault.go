@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+func init() {
+	Register("vault", newVault)
+}
+
+// vault reads and writes KV version 2 secrets. Paths look like
+// "secret/myapp", where the first element is the mount.
+type vault struct {
+	addr   string
+	token  string
+	client *Client
+}
+
+func newVault(cfg Config) (Provider, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "http://127.0.0.1:8200"
+	}
+	tok, err := cred.Lookup(cfg.Creds, "vault")
+	if err != nil {
+		return nil, errors.New("no vault token (use :auth vault)")
+	}
+	return &vault{addr: strings.TrimRight(addr, "/"), token: tok, client: cfg.Client}, nil
+}
+
+func (v *vault) url(path string) (string, error) {
+	mount, rest, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("vault path must be <mount>/<secret>, got %q", path)
+	}
+	return fmt.Sprintf("%s/v1/%s/data/%s", v.addr, mount, rest), nil
+}
+
+func (v *vault) header() http.Header {
+	return http.Header{"X-Vault-Token": {v.token}}
+}
+
+func (v *vault) Pull(ctx context.Context, path string) ([]Entry, error) {
+	url, err := v.url(path)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.client.JSON(ctx, http.MethodGet, url, v.header(), nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(resp.Data.Data))
+	for k, val := range resp.Data.Data {
+		out = append(out, Entry{Key: k, Value: fmt.Sprint(val)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	ReportProgress(ctx, len(out), len(out))
+	return out, nil
+}
+
+// Push writes entries as a new version of the secret, replacing its data.
+func (v *vault) Push(ctx context.Context, path string, entries []Entry) error {
+	url, err := v.url(path)
+	if err != nil {
+		return err
+	}
+	data := make(map[string]string, len(entries))
+	for _, e := range entries {
+		data[e.Key] = e.Value
+	}
+	body := map[string]any{"data": data}
+	if err := v.client.JSON(ctx, http.MethodPost, url, v.header(), body, nil); err != nil {
+		return err
+	}
+	ReportProgress(ctx, len(entries), len(entries))
+	return nil
+}
@@ -0,0 +1,28 @@
+package secmem
+
+import "syscall"
+
+// alloc returns n bytes of memory that is never swapped out, falling back
+// to the heap when the kernel refuses.
+func alloc(n int) []byte {
+	size := (n + syscall.Getpagesize() - 1) &^ (syscall.Getpagesize() - 1)
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return make([]byte, n)
+	}
+	_ = syscall.Mlock(b)
+	return b[:n]
+}
+
+func free(b []byte) {
+	b = b[:cap(b)]
+	if len(b)%syscall.Getpagesize() != 0 {
+		return // from the heap
+	}
+	_ = syscall.Munlock(b)
+	_ = syscall.Munmap(b)
+}
+
+// DisableCoreDumps does nothing: macOS writes no core dumps unless asked
+// to, and lowering RLIMIT_CORE would reach the commands envoy runs.
+func DisableCoreDumps() error { return nil }
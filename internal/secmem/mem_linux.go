@@ -0,0 +1,39 @@
+package secmem
+
+import "syscall"
+
+const madvDontDump = 16 // missing from package syscall
+
+// alloc returns n bytes of memory that is never swapped out and is left
+// out of core dumps, falling back to the heap when the kernel refuses.
+func alloc(n int) []byte {
+	size := (n + syscall.Getpagesize() - 1) &^ (syscall.Getpagesize() - 1)
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return make([]byte, n)
+	}
+	// RLIMIT_MEMLOCK may forbid locking; leaving it out of dumps still helps.
+	_ = syscall.Mlock(b)
+	_ = syscall.Madvise(b, madvDontDump)
+	return b[:n]
+}
+
+func free(b []byte) {
+	b = b[:cap(b)]
+	if len(b)%syscall.Getpagesize() != 0 {
+		return // from the heap
+	}
+	_ = syscall.Munlock(b)
+	_ = syscall.Munmap(b)
+}
+
+// DisableCoreDumps keeps the process from dumping core, which would
+// capture every secret in the clear, and from being read through ptrace
+// by other processes of the same user. Unlike RLIMIT_CORE the setting is
+// not inherited by the commands envoy runs.
+func DisableCoreDumps() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_DUMPABLE, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
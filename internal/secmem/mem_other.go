@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package secmem
+
+// alloc returns n bytes from the heap, not locked here.
+func alloc(n int) []byte { return make([]byte, n) }
+
+func free([]byte) {}
+
+// DisableCoreDumps does nothing here.
+func DisableCoreDumps() error { return nil }
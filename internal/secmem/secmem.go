@@ -0,0 +1,145 @@
+// Package secmem keeps secret values out of core dumps and swap as far as
+// Go allows. A value is sealed with AES-GCM under a process key that lives
+// in locked memory the kernel leaves out of core dumps; the ciphertext may
+// sit anywhere. Opening a value yields a short-lived string, which the
+// garbage collector, not this package, disposes of: the aim is that no
+// long-lived copy of a secret exists in the clear.
+package secmem
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+const keySize = 32
+
+var (
+	mu     sync.Mutex
+	page   []byte // locked memory holding the key, nil until first use
+	purged bool
+)
+
+// ErrPurged is returned by OpenBytes once Purge has scrubbed the key.
+var ErrPurged = errors.New("secret memory purged")
+
+// Sealed is an encrypted value. The zero value and nil are both empty.
+type Sealed struct {
+	ct []byte // nonce followed by ciphertext
+}
+
+// Seal encrypts s.
+func Seal(s string) *Sealed {
+	b := []byte(s)
+	defer Wipe(b)
+	return SealBytes(b)
+}
+
+// SealBytes encrypts b, leaving it untouched.
+func SealBytes(b []byte) *Sealed {
+	mu.Lock()
+	defer mu.Unlock()
+	aead := gcmLocked()
+	if aead == nil {
+		return &Sealed{}
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(b)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic("secmem: " + err.Error())
+	}
+	s := &Sealed{ct: aead.Seal(nonce, nonce, b, nil)}
+	runtime.AddCleanup(s, Wipe, s.ct)
+	return s
+}
+
+// Open decrypts the value, returning "" after Purge or for an empty value.
+func (s *Sealed) Open() string {
+	b, err := s.OpenBytes()
+	if err != nil {
+		return ""
+	}
+	defer Wipe(b)
+	return string(b)
+}
+
+// OpenBytes decrypts the value into a new slice the caller should Wipe.
+func (s *Sealed) OpenBytes() ([]byte, error) {
+	if s == nil || len(s.ct) == 0 {
+		return nil, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	aead := gcmLocked()
+	if aead == nil {
+		return nil, ErrPurged
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, s.ct[:n], s.ct[n:], nil)
+}
+
+// Clone returns a copy of the value sealed apart from s, so that either
+// can be destroyed without the other.
+func (s *Sealed) Clone() *Sealed {
+	if s == nil {
+		return nil
+	}
+	c := &Sealed{ct: slices.Clone(s.ct)}
+	runtime.AddCleanup(c, Wipe, c.ct)
+	return c
+}
+
+// Destroy scrubs the ciphertext. Values no longer referenced are scrubbed
+// by the garbage collector in any case.
+func (s *Sealed) Destroy() {
+	if s != nil {
+		Wipe(s.ct)
+		s.ct = nil
+	}
+}
+
+// Wipe zeroes b.
+func Wipe(b []byte) {
+	clear(b)
+	runtime.KeepAlive(b)
+}
+
+// Purge scrubs the key, making every sealed value unreadable, and
+// releases its memory. Call it on the way out.
+func Purge() {
+	mu.Lock()
+	defer mu.Unlock()
+	if page != nil {
+		Wipe(page)
+		free(page)
+		page = nil
+	}
+	purged = true
+}
+
+// gcmLocked returns a cipher under the key, creating the key on first
+// use, or nil after Purge. The cipher is built per call so that its key
+// schedule, which lives on the heap, is short-lived too.
+func gcmLocked() cipher.AEAD {
+	if purged {
+		return nil
+	}
+	if page == nil {
+		page = alloc(keySize)
+		if _, err := rand.Read(page); err != nil {
+			panic("secmem: " + err.Error())
+		}
+	}
+	block, err := aes.NewCipher(page[:keySize])
+	if err != nil {
+		panic("secmem: " + err.Error())
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic("secmem: " + err.Error())
+	}
+	return aead
+}
@@ -0,0 +1,75 @@
+// Package session keeps UI state that outlives one run, such as the pane
+// layout.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Zoom values for Layout.Zoom.
+const (
+	ZoomTable = "table"
+	ZoomSide  = "side"
+)
+
+// Layout is the arrangement of the main window's panes.
+type Layout struct {
+	Inspector bool   `json:"inspector"` // inspector pane shown
+	Side      int    `json:"side"`      // side pane width in columns; 0 is a third of the window
+	Zoom      string `json:"zoom"`      // pane filling the window, or ""
+}
+
+// Session is the state saved between runs.
+type Session struct {
+	Layout   Layout              `json:"layout"`
+	Recent   map[string][]string `json:"recent,omitempty"`   // keys added lately, newest first, per project directory
+	Searches []string            `json:"searches,omitempty"` // / searches, newest first
+}
+
+// Path is the session file, next to the journals.
+func Path() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "envoy-session.json")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "envoy", "session.json")
+}
+
+// Load reads the session file. A missing file yields the zero Session.
+func Load(path string) (Session, error) {
+	var s Session
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Session{}, err
+	}
+	return s, nil
+}
+
+// Save replaces the session file.
+func Save(path string, s Session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,130 @@
+// Package shellhist finds secrets typed into shell history files and
+// redacts them in place.
+package shellhist
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces a secret in a scrubbed line.
+const Redacted = "<redacted>"
+
+// Hit is a history line holding one or more secrets.
+type Hit struct {
+	Path string
+	Line int      // 1-based
+	Text string   // the line as it stands
+	Keys []string // the variables whose values it holds
+}
+
+// Files lists the history files of the usual shells that exist: $HISTFILE,
+// bash, zsh, fish and PowerShell's PSReadLine.
+func Files() []string {
+	home, _ := os.UserHomeDir()
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+	candidates := []string{
+		os.Getenv("HISTFILE"),
+		filepath.Join(home, ".bash_history"),
+		filepath.Join(home, ".zsh_history"),
+		filepath.Join(home, ".zhistory"),
+		filepath.Join(data, "fish", "fish_history"),
+		filepath.Join(data, "powershell", "PSReadLine", "ConsoleHost_history.txt"),
+	}
+	if runtime.GOOS == "windows" {
+		candidates = append(candidates, filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "PowerShell", "PSReadLine", "ConsoleHost_history.txt"))
+	}
+	var out []string
+	for _, p := range candidates {
+		if p == "" || slices.Contains(out, p) {
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Scan returns the lines of path holding any of secrets, which maps each
+// value to the variable it belongs to.
+func Scan(path string, secrets map[string]string) ([]Hit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hits []Hit
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		var keys []string
+		for v, k := range secrets {
+			if strings.Contains(line, v) && !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) > 0 {
+			slices.Sort(keys)
+			hits = append(hits, Hit{Path: path, Line: n, Text: line, Keys: keys})
+		}
+	}
+	return hits, sc.Err()
+}
+
+// Scrub replaces every occurrence of values in path with Redacted and
+// returns how many lines changed. The file is replaced atomically and
+// keeps its permissions; no copy of the old contents is left behind.
+func Scrub(path string, values []string) (int, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	// Longer values first, so one that contains another is replaced whole.
+	values = slices.Clone(values)
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	changed := 0
+	for i, l := range lines {
+		orig := l
+		for _, v := range values {
+			l = bytes.ReplaceAll(l, []byte(v), []byte(Redacted))
+		}
+		if !bytes.Equal(l, orig) {
+			lines[i] = l
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".scrub-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bytes.Join(lines, nil)); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return changed, os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,128 @@
+// Package sign makes and checks detached GnuPG signatures for env files, so
+// teams distributing a canonical file can tell when a copy was altered.
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Ext is appended to a file's path to name its signature.
+const Ext = ".asc"
+
+// SigPath is the detached signature of path.
+func SigPath(path string) string { return path + Ext }
+
+// Signed reports whether path has a signature next to it.
+func Signed(path string) bool {
+	_, err := os.Stat(SigPath(path))
+	return err == nil
+}
+
+// Sign writes an armored detached signature of path, made with key or
+// gpg's default key when key is "".
+func Sign(ctx context.Context, path, key string) error {
+	args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--output", SigPath(path)}
+	if key != "" {
+		args = append(args, "--local-user", key)
+	}
+	args = append(args, "--", path)
+	_, err := gpg(ctx, args...)
+	return err
+}
+
+// Result is the outcome of checking a signature.
+type Result struct {
+	Signed bool   // a signature file exists
+	Good   bool   // it matches the file
+	Signer string // user ID of the signing key, when known
+	KeyID  string
+	Reason string // why a signature is not good
+}
+
+// Verify checks path against its signature. A file without one yields a
+// Result with Signed false and no error; an error means gpg could not run.
+func Verify(ctx context.Context, path string) (Result, error) {
+	if !Signed(path) {
+		return Result{}, nil
+	}
+	r := Result{Signed: true}
+	out, err := gpg(ctx, "--batch", "--status-fd", "1", "--verify", "--", SigPath(path), path)
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		return r, err
+	}
+
+	// gpg reports through "[GNUPG:] KEYWORD args" status lines.
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(strings.TrimPrefix(sc.Text(), "[GNUPG:] "))
+		if len(fields) == 0 {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[0] {
+		case "GOODSIG":
+			r.Good = true
+			r.KeyID, r.Signer = arg(1), strings.Join(fields[2:], " ")
+		case "BADSIG":
+			r.KeyID, r.Signer = arg(1), strings.Join(fields[2:], " ")
+			r.Reason = "the file does not match its signature; it may have been tampered with"
+		case "EXPKEYSIG":
+			r.KeyID, r.Signer = arg(1), strings.Join(fields[2:], " ")
+			r.Reason = "signed with an expired key"
+		case "REVKEYSIG":
+			r.KeyID, r.Signer = arg(1), strings.Join(fields[2:], " ")
+			r.Reason = "signed with a revoked key"
+		case "NO_PUBKEY":
+			r.KeyID = arg(1)
+			r.Reason = "the signing key " + arg(1) + " is not in your keyring"
+		}
+	}
+	if r.Reason != "" {
+		r.Good = false
+	}
+	if !r.Good && r.Reason == "" {
+		r.Reason = "gpg could not verify the signature"
+	}
+	return r, nil
+}
+
+func gpg(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "gpg", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); msg != "" && errors.As(err, &exit) {
+			// Keep the exit error for callers that inspect status output.
+			return out, &gpgError{exit: exit, msg: lastLine(msg)}
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// gpgError carries gpg's last line of complaint.
+type gpgError struct {
+	exit *exec.ExitError
+	msg  string
+}
+
+func (e *gpgError) Error() string { return "gpg: " + strings.TrimPrefix(e.msg, "gpg: ") }
+func (e *gpgError) Unwrap() error { return e.exit }
+
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
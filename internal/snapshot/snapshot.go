@@ -0,0 +1,209 @@
+// Package snapshot records the machine's environment in timestamped files
+// and compares them, to answer "what changed since yesterday?".
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snapshot is the environment at one moment.
+type Snapshot struct {
+	Name  string            `json:"name,omitempty"`
+	Taken time.Time         `json:"taken"`
+	Host  string            `json:"host,omitempty"`
+	Vars  map[string]string `json:"vars"`
+
+	Path string `json:"-"` // file it was read from
+}
+
+// stampLayout prefixes snapshot file names so they sort by time.
+const stampLayout = "20060102-150405"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Dir is where snapshots are kept.
+func Dir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "envoy-snapshots")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "envoy", "snapshots")
+}
+
+// New captures environ, a list in os.Environ form.
+func New(name string, environ []string) Snapshot {
+	host, _ := os.Hostname()
+	s := Snapshot{Name: name, Taken: time.Now(), Host: host, Vars: make(map[string]string, len(environ))}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			s.Vars[k] = v
+		}
+	}
+	return s
+}
+
+// Save writes s into dir as "<time>[-name].json", readable only by the
+// user since values may be secret, and returns the path.
+func Save(dir string, s Snapshot) (string, error) {
+	if s.Name != "" && !validName.MatchString(s.Name) {
+		return "", fmt.Errorf("bad snapshot name %q: use letters, digits, '.', '_' and '-'", s.Name)
+	}
+	file := s.Taken.Format(stampLayout)
+	if s.Name != "" {
+		file += "-" + s.Name
+	}
+	path := filepath.Join(dir, file+".json")
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Load reads a snapshot file.
+func Load(path string) (Snapshot, error) {
+	var s Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// List returns the snapshots in dir, oldest first.
+func List(dir string) ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Snapshot
+	for _, p := range paths {
+		s, err := Load(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	slices.SortStableFunc(out, func(a, b Snapshot) int { return a.Taken.Compare(b.Taken) })
+	return out, nil
+}
+
+// Find resolves ref to a snapshot in dir: a file path, a snapshot name
+// (the newest of that name), "latest", or "latest~N" for the Nth before
+// the newest.
+func Find(dir, ref string) (Snapshot, error) {
+	if strings.ContainsRune(ref, os.PathSeparator) || strings.HasSuffix(ref, ".json") {
+		return Load(ref)
+	}
+	all, err := List(dir)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if back, ok := strings.CutPrefix(ref, "latest"); ok && (back == "" || strings.HasPrefix(back, "~")) {
+		n := 0
+		if back != "" {
+			if _, err := fmt.Sscanf(back, "~%d", &n); err != nil || n < 0 {
+				return Snapshot{}, fmt.Errorf("bad snapshot reference %q", ref)
+			}
+		}
+		if n >= len(all) {
+			return Snapshot{}, fmt.Errorf("%s: only %d snapshots in %s", ref, len(all), dir)
+		}
+		return all[len(all)-1-n], nil
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Name == ref || strings.TrimSuffix(filepath.Base(all[i].Path), ".json") == ref {
+			return all[i], nil
+		}
+	}
+	return Snapshot{}, errors.New("no snapshot named " + ref)
+}
+
+// Label names s for output.
+func (s Snapshot) Label() string {
+	label := s.Taken.Local().Format("2006-01-02 15:04:05")
+	if s.Name != "" {
+		label = s.Name + " (" + label + ")"
+	}
+	return label
+}
+
+// ChangeKind says how a variable differs between two snapshots.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+// Change is one variable that differs.
+type Change struct {
+	Key      string
+	Kind     ChangeKind
+	Old, New string
+}
+
+// Diff lists the variables that differ from a to b, sorted by key.
+func Diff(a, b map[string]string) []Change {
+	var out []Change
+	for k, old := range a {
+		cur, ok := b[k]
+		switch {
+		case !ok:
+			out = append(out, Change{Key: k, Kind: Removed, Old: old})
+		case cur != old:
+			out = append(out, Change{Key: k, Kind: Changed, Old: old, New: cur})
+		}
+	}
+	for k, cur := range b {
+		if _, ok := a[k]; !ok {
+			out = append(out, Change{Key: k, Kind: Added, New: cur})
+		}
+	}
+	slices.SortFunc(out, func(x, y Change) int { return strings.Compare(x.Key, y.Key) })
+	return out
+}
+
+// ListDiff compares two values as lists split on sep, such as PATH
+// entries, returning the entries only in old and only in cur. It reports
+// false when neither value is a list.
+func ListDiff(old, cur, sep string) (removed, added []string, ok bool) {
+	if !strings.Contains(old, sep) && !strings.Contains(cur, sep) {
+		return nil, nil, false
+	}
+	o, c := strings.Split(old, sep), strings.Split(cur, sep)
+	for _, e := range o {
+		if !slices.Contains(c, e) {
+			removed = append(removed, e)
+		}
+	}
+	for _, e := range c {
+		if !slices.Contains(o, e) {
+			added = append(added, e)
+		}
+	}
+	return removed, added, true
+}
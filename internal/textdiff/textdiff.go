@@ -0,0 +1,304 @@
+// Package textdiff computes character- and word-level differences between
+// two strings.
+package textdiff
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Kind says what an Edit does.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Insert
+	Delete
+)
+
+// Edit is a run of text kept, inserted or deleted.
+type Edit struct {
+	Kind Kind
+	Text string
+}
+
+// maxCost bounds the search; beyond it the changed middle is reported as
+// one deletion and one insertion.
+const maxCost = 500
+
+// shortEqual is the longest unchanged run between two changes that is
+// folded into them; splitting a change around one or two matching
+// characters makes it harder to read.
+const shortEqual = 2
+
+// Algorithm names a diff algorithm.
+type Algorithm string
+
+const (
+	// Myers finds a shortest edit script.
+	Myers Algorithm = "myers"
+	// Histogram anchors on the rarest common tokens first, which keeps
+	// reordered or repeated parts of a value together.
+	Histogram Algorithm = "histogram"
+)
+
+// Algorithms lists the supported algorithms.
+var Algorithms = []Algorithm{Myers, Histogram}
+
+// ParseAlgorithm looks up an algorithm by name; "" is Myers, as is an
+// unknown name, for which ok is false.
+func ParseAlgorithm(name string) (alg Algorithm, ok bool) {
+	if name == "" {
+		return Myers, true
+	}
+	a := Algorithm(strings.ToLower(name))
+	if !slices.Contains(Algorithms, a) {
+		return Myers, false
+	}
+	return a, true
+}
+
+// Options selects how Diff compares two strings.
+type Options struct {
+	Algorithm Algorithm
+	Words     bool // compare whole words rather than single characters
+}
+
+// Chars returns the edits turning a into b, rune by rune, using Myers'
+// algorithm.
+func Chars(a, b string) []Edit { return Diff(a, b, Options{}) }
+
+// Words returns the edits turning a into b, word by word, using Myers'
+// algorithm.
+func Words(a, b string) []Edit { return Diff(a, b, Options{Words: true}) }
+
+// Diff returns the edits turning a into b. The common prefix and suffix
+// are trimmed before the algorithm runs, and changes separated by a very
+// short unchanged run are merged.
+func Diff(a, b string, opt Options) []Edit {
+	split := chars
+	if opt.Words {
+		split = words
+	}
+	ta, tb := split(a), split(b)
+	p := 0
+	for p < len(ta) && p < len(tb) && ta[p] == tb[p] {
+		p++
+	}
+	s := 0
+	for s < len(ta)-p && s < len(tb)-p && ta[len(ta)-1-s] == tb[len(tb)-1-s] {
+		s++
+	}
+
+	var out []Edit
+	add := func(k Kind, t []string) {
+		if len(t) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Kind == k {
+			out[n-1].Text += strings.Join(t, "")
+			return
+		}
+		out = append(out, Edit{Kind: k, Text: strings.Join(t, "")})
+	}
+	diff := myers
+	if opt.Algorithm == Histogram {
+		diff = histogram
+	}
+	add(Equal, ta[:p])
+	for _, e := range diff(ta[p:len(ta)-s], tb[p:len(tb)-s]) {
+		add(e.kind, e.text)
+	}
+	add(Equal, ta[len(ta)-s:])
+	return coalesce(out)
+}
+
+// chars splits s into runes.
+func chars(s string) []string {
+	out := make([]string, 0, len(s))
+	for _, r := range s {
+		out = append(out, string(r))
+	}
+	return out
+}
+
+// words splits s into runs of letters and digits, with every other rune a
+// token of its own, so "a.b,c" is five tokens.
+func words(s string) []string {
+	var out []string
+	start := -1
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			out = append(out, s[start:i])
+			start = -1
+		}
+		out = append(out, string(r))
+	}
+	if start >= 0 {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+// coalesce folds short equal runs between changes into them, leaving each
+// change as one deletion followed by one insertion.
+func coalesce(edits []Edit) []Edit {
+	var out []Edit
+	var del, ins string
+	flush := func() {
+		if del != "" {
+			out = append(out, Edit{Delete, del})
+		}
+		if ins != "" {
+			out = append(out, Edit{Insert, ins})
+		}
+		del, ins = "", ""
+	}
+	for i, e := range edits {
+		switch {
+		case e.Kind == Delete:
+			del += e.Text
+		case e.Kind == Insert:
+			ins += e.Text
+		case i > 0 && i < len(edits)-1 && len([]rune(e.Text)) <= shortEqual:
+			del += e.Text
+			ins += e.Text
+		default:
+			flush()
+			out = append(out, e)
+		}
+	}
+	flush()
+	return out
+}
+
+type step struct {
+	kind Kind
+	text []string
+}
+
+func myers(a, b []string) []step {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return []step{{Delete, a}, {Insert, b}}
+	}
+	limit := min(n+m, maxCost)
+	off := limit + 1
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down: insertion
+			} else {
+				x = v[off+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return []step{{Delete, a}, {Insert, b}}
+	}
+
+	// Walk the trace back from the end, collecting steps in reverse.
+	var rev []step
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[off+pk]
+		py := px - pk
+		for x > px && y > py {
+			rev = append(rev, step{Equal, a[x-1 : x]})
+			x, y = x-1, y-1
+		}
+		if x == px {
+			rev = append(rev, step{Insert, b[y-1 : y]})
+		} else {
+			rev = append(rev, step{Delete, a[x-1 : x]})
+		}
+		x, y = px, py
+	}
+	for x > 0 && y > 0 {
+		rev = append(rev, step{Equal, a[x-1 : x]})
+		x, y = x-1, y-1
+	}
+
+	out := make([]step, len(rev))
+	for i, s := range rev {
+		out[len(rev)-1-i] = s
+	}
+	return out
+}
+
+// maxChain is how often a token may occur in a before histogram diff stops
+// considering it as an anchor.
+const maxChain = 64
+
+// histogram splits a and b around their longest common run containing the
+// rarest token of a and recurses on each side, falling back to Myers when
+// no anchor is left.
+func histogram(a, b []string) []step {
+	if len(a) == 0 || len(b) == 0 {
+		return []step{{Delete, a}, {Insert, b}}
+	}
+	count := make(map[string]int, len(a))
+	for _, t := range a {
+		count[t]++
+	}
+	inB := make(map[string][]int, len(b))
+	for j, t := range b {
+		inB[t] = append(inB[t], j)
+	}
+
+	bestCount, bestLen := maxChain+1, 0
+	var as, bs int
+	for i, t := range a {
+		if count[t] > bestCount {
+			continue
+		}
+		for _, j := range inB[t] {
+			s, e := 0, 1
+			for i-s > 0 && j-s > 0 && a[i-s-1] == b[j-s-1] {
+				s++
+			}
+			for i+e < len(a) && j+e < len(b) && a[i+e] == b[j+e] {
+				e++
+			}
+			if n := s + e; count[t] < bestCount || n > bestLen {
+				bestCount, bestLen, as, bs = count[t], n, i-s, j-s
+			}
+		}
+	}
+	if bestLen == 0 {
+		return myers(a, b)
+	}
+
+	out := histogram(a[:as], b[:bs])
+	out = append(out, step{Equal, a[as : as+bestLen]})
+	return append(out, histogram(a[as+bestLen:], b[bs+bestLen:])...)
+}
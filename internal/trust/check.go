@@ -0,0 +1,109 @@
+package trust
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// loaderKeys make every program started afterwards load or search code the
+// file chooses; a file setting one always needs confirmation.
+var loaderKeys = []string{
+	"LD_PRELOAD", "LD_LIBRARY_PATH", "LD_AUDIT",
+	"DYLD_INSERT_LIBRARIES", "DYLD_LIBRARY_PATH", "DYLD_FRAMEWORK_PATH",
+}
+
+// searchKeys change which programs or modules run, and are worth pointing
+// out when a file is not trusted.
+var searchKeys = []string{
+	"PATH", "PYTHONPATH", "PYTHONSTARTUP", "NODE_OPTIONS", "NODE_PATH",
+	"PERL5LIB", "PERL5OPT", "RUBYLIB", "RUBYOPT", "BASH_ENV", "ENV",
+	"PROMPT_COMMAND", "GIT_SSH", "GIT_SSH_COMMAND", "EDITOR", "PAGER",
+}
+
+// Report describes where an env file lives and why it may not be trusted.
+type Report struct {
+	Path     string
+	Mode     fs.FileMode
+	Owner    string   // user name or uid; "" when unknown
+	Problems []string // reasons to confirm before loading
+	Risky    []string // other keys set that change what programs run
+}
+
+// Trusted reports whether the file can be loaded without asking.
+func (r Report) Trusted() bool { return len(r.Problems) == 0 }
+
+// String describes the report over a few lines.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s", r.Path, r.Mode)
+	if r.Owner != "" {
+		fmt.Fprintf(&b, "  owner %s", r.Owner)
+	}
+	for _, p := range r.Problems {
+		fmt.Fprintf(&b, "\n  - %s", p)
+	}
+	if len(r.Risky) > 0 {
+		fmt.Fprintf(&b, "\n  also sets %s", strings.Join(r.Risky, ", "))
+	}
+	return b.String()
+}
+
+// CheckFile inspects path, whose parsed keys are keys. A file is suspect
+// when it lies outside the home and working directories, when it or its
+// directory is writable by anyone, when another user owns it, or when it
+// sets a loader variable such as LD_PRELOAD.
+func CheckFile(path string, keys []string) (Report, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Report{}, err
+	}
+	r := Report{Path: abs}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return r, err
+	}
+	r.Mode = fi.Mode().Perm()
+
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		roots = append(roots, wd)
+	}
+	if !slices.ContainsFunc(roots, func(root string) bool { return within(root, abs) }) {
+		r.Problems = append(r.Problems, "outside your home and working directories")
+	}
+	if r.Mode&0o002 != 0 {
+		r.Problems = append(r.Problems, "writable by every user")
+	}
+	if di, err := os.Stat(filepath.Dir(abs)); err == nil && di.Mode().Perm()&0o002 != 0 {
+		r.Problems = append(r.Problems, "in a directory every user can write to")
+	}
+	if uid, name, ok := owner(fi); ok {
+		r.Owner = name
+		if uid != os.Getuid() && uid != 0 {
+			r.Problems = append(r.Problems, "owned by another user")
+		}
+	}
+
+	for _, k := range keys {
+		switch {
+		case slices.Contains(loaderKeys, k):
+			r.Problems = append(r.Problems, "sets "+k+", which injects code into every program started later")
+		case slices.Contains(searchKeys, k):
+			r.Risky = append(r.Risky, k)
+		}
+	}
+	return r, nil
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
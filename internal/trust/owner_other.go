@@ -0,0 +1,8 @@
+//go:build !unix
+
+package trust
+
+import "io/fs"
+
+// owner is unknown where files have no Unix owner.
+func owner(fs.FileInfo) (int, string, bool) { return 0, "", false }
@@ -0,0 +1,24 @@
+//go:build unix
+
+package trust
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// owner returns the uid and user name owning fi.
+func owner(fi fs.FileInfo) (int, string, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, "", false
+	}
+	uid := int(st.Uid)
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	return uid, name, true
+}
@@ -0,0 +1,158 @@
+// Package trust remembers which directories' env files the user agreed to
+// load, like direnv's allow list, so files from a freshly cloned repository
+// are never loaded silently.
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Decision is the remembered answer for one directory. An allow holds only
+// while the files' contents hash to Sum; a deny holds until revoked.
+type Decision struct {
+	Dir   string    `json:"dir"`
+	Allow bool      `json:"allow"`
+	Sum   string    `json:"sum,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// DB is the set of decisions, keyed by directory hash.
+type DB struct {
+	path string
+	Dirs map[string]Decision `json:"dirs"`
+}
+
+// Path is the decisions file, next to the journals.
+func Path() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "envoy-allow.json")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "envoy", "allow.json")
+}
+
+// Load reads the decisions at path. A missing file yields an empty DB.
+func Load(path string) (*DB, error) {
+	db := &DB{path: path, Dirs: make(map[string]Decision)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return db, err
+	}
+	if err := json.Unmarshal(data, db); err != nil {
+		return db, err
+	}
+	if db.Dirs == nil {
+		db.Dirs = make(map[string]Decision)
+	}
+	return db, nil
+}
+
+// DirID hashes a directory's absolute path.
+func DirID(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Lookup returns the decision for dir's files hashing to sum. ok is false
+// when there is none, or the files changed since they were allowed.
+func (db *DB) Lookup(dir, sum string) (allow, ok bool) {
+	d, found := db.Dirs[DirID(dir)]
+	switch {
+	case !found:
+		return false, false
+	case d.Allow && d.Sum != sum:
+		return false, false
+	}
+	return d.Allow, true
+}
+
+// Set records a decision for dir and saves the file.
+func (db *DB) Set(dir, sum string, allow bool) error {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	d := Decision{Dir: dir, Allow: allow, At: time.Now()}
+	if allow {
+		d.Sum = sum
+	}
+	db.Dirs[DirID(dir)] = d
+	return db.save()
+}
+
+// Forget drops the decision for dir and saves the file.
+func (db *DB) Forget(dir string) error {
+	delete(db.Dirs, DirID(dir))
+	return db.save()
+}
+
+func (db *DB) save() error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(db.path), 0o700); err != nil {
+		return err
+	}
+	tmp := db.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, db.path)
+}
+
+// templateSuffixes mark env files holding placeholders rather than values.
+var templateSuffixes = []string{".example", ".sample", ".template", ".dist"}
+
+// EnvFiles lists the env files in dir that would be loaded, in name order
+// so .env comes first, skipping templates such as .env.example.
+func EnvFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || (name != ".env" && !strings.HasPrefix(name, ".env.")) {
+			continue
+		}
+		if slices.ContainsFunc(templateSuffixes, func(s string) bool { return strings.HasSuffix(name, s) }) {
+			continue
+		}
+		out = append(out, filepath.Join(dir, name))
+	}
+	return out
+}
+
+// Sum hashes the names and contents of files.
+func Sum(files []string) (string, error) {
+	h := sha256.New()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(filepath.Base(f) + "\x00"))
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// ageCommand handles ":age": it asks for the identity .age files are
+// decrypted with and the recipients they are encrypted to.
+func (a *App) ageCommand(args []string) string {
+	if len(args) > 0 {
+		return "Usage: :age"
+	}
+	a.askAgeKeys(nil)
+	return ""
+}
+
+// ageRetry asks for age keys when err is for want of them, then runs
+// retry, whose status it shows. It reports whether it asked.
+func (a *App) ageRetry(err error, retry func() string) bool {
+	if !errors.Is(err, env.ErrAgeIdentity) && !errors.Is(err, env.ErrAgeRecipients) {
+		return false
+	}
+	a.askAgeKeys(retry)
+	return true
+}
+
+// askAgeKeys opens a form for the age identity file and recipients, filled
+// in with the keys set or, failing those, the identity age and sops users
+// keep and its own public key. Saving sets them on every buffer and runs
+// then, when not nil.
+func (a *App) askAgeKeys(then func() string) {
+	keys := a.Store.AgeKeys()
+	identity := keys.Identity
+	if identity == "" {
+		identity = env.AgeIdentityPath()
+	}
+	recipients := keys.Recipients
+	if len(recipients) == 0 && identity != "" {
+		if r, err := env.AgeRecipient(identity); err == nil {
+			recipients = []string{r}
+		}
+	}
+
+	form := tview.NewForm().
+		AddInputField("Identity file", identity, 60, nil, nil).
+		AddInputField("Recipients", strings.Join(recipients, " "), 60, nil, nil)
+	done := func(msg string) {
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+		a.updateStatusInline(msg)
+	}
+	form.AddButton("Save", func() {
+		k := env.AgeKeys{
+			Identity:   expandHome(strings.TrimSpace(form.GetFormItemByLabel("Identity file").(*tview.InputField).GetText())),
+			Recipients: strings.Fields(form.GetFormItemByLabel("Recipients").(*tview.InputField).GetText()),
+		}
+		for _, b := range a.buffers {
+			b.store.SetAgeKeys(k)
+		}
+		if then == nil {
+			done(fmt.Sprintf("age: %d recipients", len(k.Recipients)))
+			return
+		}
+		done(then())
+	}).
+		AddButton("Cancel", func() { done("age: cancelled") })
+	form.SetBorder(true).
+		SetTitle(" age keys (recipients: public keys, space-separated) ").
+		SetTitleAlign(tview.AlignLeft)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(&pasteCapture{Primitive: form, singleLine: true}, 80, 9), true, true)
+	a.App.SetFocus(form)
+	a.refreshStatus()
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// Accessibility mode describes the selected row in the status line in
+// place of the key hints, puts the terminal cursor on the selection for
+// screen readers that follow it, and announces selection changes, focus
+// changes and status messages as plain lines, optionally to a file or
+// named pipe an external screen reader reads.
+
+// announceQueue is how many announcements wait for a slow reader before
+// new ones are dropped.
+const announceQueue = 64
+
+// markWords names the gutter symbols in announcements.
+var markWords = map[rune]string{
+	markModified:    "edited",
+	markAdded:       "added",
+	markBroken:      "broken or expired",
+	markPlaceholder: "placeholder",
+	markExpiring:    "expiring soon",
+	markSecret:      "live credential",
+	markShadowed:    "shadowed",
+}
+
+// announcer writes announcements to path, one per line. The file is
+// opened in the background: a named pipe blocks until a reader opens it.
+type announcer struct {
+	ch chan string
+}
+
+func newAnnouncer(path string) *announcer {
+	n := &announcer{ch: make(chan string, announceQueue)}
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			slog.Warn("announce", "path", path, "err", err)
+			return
+		}
+		defer f.Close()
+		for s := range n.ch {
+			if _, err := fmt.Fprintln(f, s); err != nil {
+				slog.Warn("announce", "path", path, "err", err)
+				return
+			}
+		}
+	}()
+	return n
+}
+
+// styleTag matches tview color and style tags.
+var styleTag = regexp.MustCompile(`\[[a-zA-Z0-9#]*(?::[a-zA-Z0-9#]*(?::[a-zA-Z-]*)?)?\]|\[-(?::-?(?::-?)?)?\]`)
+
+// say announces s, without tags, when accessibility mode is on. A repeat
+// of the last announcement is dropped.
+func (a *App) say(s string) {
+	if !a.accessible {
+		return
+	}
+	s = strings.TrimSpace(styleTag.ReplaceAllString(s, ""))
+	if s == "" || s == a.lastSaid {
+		return
+	}
+	a.lastSaid = s
+	if a.announce != nil {
+		select {
+		case a.announce.ch <- s:
+		default:
+		}
+	}
+}
+
+// selectionText describes the selected row: key, value (hidden when it
+// looks secret), position and the states the gutter shows.
+func (a *App) selectionText() string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "no variables"
+	}
+	it, _ := a.Store.Get(key)
+	value := it.Value
+	switch {
+	case env.LooksSecret(key, value):
+		value = "secret, hidden"
+	case value == "":
+		value = "empty"
+	case len([]rune(value)) > 80:
+		value = string([]rune(value)[:80]) + "…"
+	}
+	parts := []string{key + " = " + value, fmt.Sprintf("row %d of %d", a.selRow, a.Store.Count())}
+	for _, m := range a.gutter(key, it, a.Store.Process()) {
+		if w, ok := markWords[m]; ok {
+			parts = append(parts, w)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// focusText names a focused primitive: a form field or button by its
+// label, anything else by its title.
+func focusText(p tview.Primitive) string {
+	switch p := p.(type) {
+	case interface{ GetLabel() string }:
+		return strings.TrimSpace(p.GetLabel())
+	case interface{ GetTitle() string }:
+		return strings.TrimSpace(p.GetTitle())
+	}
+	return ""
+}
+
+// announceFocus announces the focused primitive when it has changed.
+func (a *App) announceFocus() {
+	if p := a.App.GetFocus(); p != a.lastFocus {
+		a.lastFocus = p
+		if t := focusText(p); t != "" {
+			a.say("Focus: " + t)
+		}
+	}
+}
+
+// afterDraw checks for a focus change and, on the main table, moves the
+// terminal cursor to the selected row. It runs with the application
+// locked, so the focus is looked up from the event loop afterwards.
+func (a *App) afterDraw(screen tcell.Screen) {
+	if !a.accessible {
+		return
+	}
+	go a.App.QueueUpdate(a.announceFocus)
+	if !a.Table.HasFocus() || a.selRow < 1 {
+		return
+	}
+	x, y, _, h := a.Table.GetInnerRect()
+	top, _ := a.Table.GetOffset()
+	if row := a.tableRow(a.selRow) - top; row >= 1 && row < h {
+		screen.ShowCursor(x, y+row)
+	}
+}
+
+func (a *App) accessibleOption() string {
+	if a.accessible {
+		return "accessible"
+	}
+	return "noaccessible"
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/rivo/tview"
+)
+
+// applyStatus is the status-line segment counting edits not yet applied to
+// the running process, or "".
+func (a *App) applyStatus() string {
+	if n := len(a.Store.Pending()); n > 0 {
+		return fmt.Sprintf(" | [yellow]%d not applied[-]", n)
+	}
+	return ""
+}
+
+// applyCommand handles ":apply [KEY...|%]": the selected key by default,
+// every pending change with %. It previews the sets and unsets and asks
+// before touching the process.
+func (a *App) applyCommand(args []string) string {
+	var keys []string
+	switch {
+	case len(args) == 1 && args[0] == "%":
+	case len(args) > 0:
+		keys = args
+	default:
+		k, ok := a.selectedKey()
+		if !ok {
+			return "Usage: :apply [KEY...|%]"
+		}
+		keys = []string{k}
+	}
+
+	ops := a.Store.Pending()
+	if len(keys) > 0 {
+		want := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			want[k] = true
+		}
+		var sel []env.Op
+		for _, op := range ops {
+			if want[op.Key] {
+				sel = append(sel, op)
+			}
+		}
+		ops = sel
+	}
+	if len(ops) == 0 {
+		return "Nothing to apply: the process already matches"
+	}
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(describeOps(ops))
+	form := tview.NewForm().
+		AddButton("Apply", func() {
+			a.closeModal()
+			a.applyOps(ops)
+		}).
+		AddButton("Cancel", a.closeModal)
+	form.SetButtonsAlign(tview.AlignCenter)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Apply %d changes to the running process ", len(ops))).
+		SetTitleAlign(tview.AlignLeft)
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 100, min(len(ops)+6, 28)), true, true)
+	a.App.SetFocus(form)
+	return ""
+}
+
+func (a *App) applyOps(ops []env.Op) {
+	keys := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = op.Key
+	}
+	done, err := a.Store.Apply(keys)
+	slog.Info("apply", "requested", len(ops), "applied", len(done), "err", err)
+
+	var b strings.Builder
+	b.WriteString(describeOps(done))
+	if err != nil {
+		fmt.Fprintf(&b, "[red]Stopped: %s[-]\nThe process environment was left as it was.\n", tview.Escape(err.Error()))
+	}
+	a.refreshStatus()
+	a.showText(fmt.Sprintf("Applied %d of %d", len(done), len(ops)), b.String())
+}
+
+// describeOps lists process changes one per line.
+func describeOps(ops []env.Op) string {
+	var b strings.Builder
+	for _, op := range ops {
+		key := tview.Escape(op.Key)
+		switch {
+		case op.Delete:
+			fmt.Fprintf(&b, "[red]unset[-] %s\n", key)
+		case op.Prev == nil:
+			fmt.Fprintf(&b, "[green]set[-]   %s=%s\n", key, tview.Escape(op.Value))
+		default:
+			fmt.Fprintf(&b, "[yellow]set[-]   %s=%s  [gray](was %s)[-]\n", key, tview.Escape(op.Value), tview.Escape(op.Prev.Value))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+
+	"github.com/rivo/tview"
+)
+
+// authCommand handles ":auth <provider> [--delete]".
+func (a *App) authCommand(args []string) string {
+	if len(args) < 1 {
+		return "Usage: :auth <provider> [--delete]"
+	}
+	provider := strings.ToLower(args[0])
+	remove := len(args) > 1 && args[1] == "--delete"
+
+	file, isFile := a.Creds.(*cred.File)
+	needPass := isFile && file.Passphrase == ""
+	if remove && !needPass {
+		if err := a.Creds.Delete(provider); err != nil {
+			return fmt.Sprintf("Auth: %v", err)
+		}
+		return fmt.Sprintf("Removed credentials for %s", provider)
+	}
+
+	form := tview.NewForm()
+	if !remove {
+		form.AddPasswordField("Token", "", 60, '*', nil)
+	}
+	if needPass {
+		form.AddPasswordField("Passphrase", "", 60, '*', nil)
+	}
+
+	save := func() {
+		if needPass {
+			file.Passphrase = form.GetFormItemByLabel("Passphrase").(*tview.InputField).GetText()
+		}
+		var err error
+		var msg string
+		if remove {
+			err = a.Creds.Delete(provider)
+			msg = fmt.Sprintf("Removed credentials for %s", provider)
+		} else {
+			tok := strings.TrimSpace(form.GetFormItemByLabel("Token").(*tview.InputField).GetText())
+			if tok == "" {
+				a.updateStatusInline("Token cannot be empty")
+				return
+			}
+			err = a.Creds.Set(provider, tok)
+			msg = fmt.Sprintf("Stored credentials for %s", provider)
+		}
+		if err != nil {
+			if needPass {
+				file.Passphrase = ""
+			}
+			a.updateStatusInline(fmt.Sprintf("Auth: %v", err))
+			return
+		}
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+		a.updateStatusInline(msg)
+	}
+
+	form.AddButton("Save", save).
+		AddButton("Cancel", func() {
+			a.closeModal()
+			a.Vim.Mode = ModeNormal
+			a.refreshStatus()
+		})
+	backend := "OS keychain"
+	if isFile {
+		backend = file.Path
+	}
+	form.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Credentials for %s (%s) ", provider, backend)).
+		SetTitleAlign(tview.AlignLeft)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(&pasteCapture{Primitive: form, singleLine: true}, 80, 9), true, true)
+	a.App.SetFocus(form)
+	a.refreshStatus()
+	return ""
+}
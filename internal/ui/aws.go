@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+// awsCommand handles ":aws pull <path>" and ":aws push [path] [--force]",
+// shorthands for :pull and :push with the aws provider. Push defaults to
+// the path last pulled and sends the visible keys pulled from it or
+// edited in this session, so the rest of the environment stays out of
+// Parameter Store.
+func (a *App) awsCommand(args []string) string {
+	const usage = "Usage: :aws pull <path> | :aws push [path] [--force]"
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "pull":
+		if len(args) != 2 {
+			return usage
+		}
+		a.awsPath = args[1]
+		return a.pullCommand([]string{"aws", args[1]})
+	case "push":
+		args = args[1:]
+		force := false
+		if i := slices.Index(args, "--force"); i >= 0 {
+			force, args = true, slices.Delete(args, i, i+1)
+		}
+		path := a.awsPath
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if path == "" {
+			return usage
+		}
+		source := "aws:" + path
+		var entries []remote.Entry
+		for _, k := range a.Store.ListKeys() {
+			it, _ := a.Store.Get(k)
+			pulled := slices.ContainsFunc(a.Store.Layers(k), func(l env.Layer) bool { return l.Source == source })
+			if pulled || it.Modified {
+				entries = append(entries, remote.Entry{Key: k, Value: it.Value})
+			}
+		}
+		if len(entries) == 0 {
+			return "Nothing to push to " + source
+		}
+		return a.pushEntries("aws", path, entries, force)
+	}
+	return usage
+}
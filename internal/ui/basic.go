@@ -0,0 +1,269 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/textdiff"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Input modes selectable with :set inputmode= and the "input" config key.
+const (
+	inputVim   = "vim"
+	inputBasic = "basic"
+)
+
+// Table orders selectable with :set sort= and the "sort" config key.
+const (
+	sortAlpha   = "alpha"
+	sortNatural = "natural"
+)
+
+const basicHints = "F1 Help  F2/Enter Edit  F3 Find  F4/Ins Add  F8/Del Delete  F10 Menu  ^S Save  ^Q Quit"
+
+// basicKeys describes the basic-mode bindings for F1.
+var basicKeys = []keyHint{
+	{"↑ ↓ PgUp PgDn Home End", "move"},
+	{"Enter, F2", "edit the selected variable"},
+	{"Insert, F4", "add a variable"},
+	{"Delete, F8", "delete the selected variable"},
+	{"F3", "find (filter the table)"},
+	{"Ctrl-T", "go to a key by fuzzy name"},
+	{"Ctrl-R", "reveal the selected secret for a moment"},
+	{"Ctrl-W then w < > = z", "switch, resize or zoom panes"},
+	{"Esc", "clear the filter"},
+	{"F10", "menu"},
+	{"Ctrl-Z, Ctrl-Y", "undo, redo"},
+	{"Ctrl-S", "save to .env"},
+	{"Ctrl-Q", "quit"},
+	{":", "command line"},
+}
+
+// handleBasic processes a table key in basic mode. It returns nil when the
+// key was consumed.
+func (a *App) handleBasic(ev *tcell.EventKey) *tcell.EventKey {
+	switch ev.Key() {
+	case tcell.KeyEnter, tcell.KeyF2:
+		a.openEditForm(false)
+	case tcell.KeyInsert, tcell.KeyF4:
+		a.openAddForm()
+	case tcell.KeyDelete, tcell.KeyF8:
+		a.confirmDelete()
+	case tcell.KeyF3:
+		a.enterSearch(a.lastFilter)
+	case tcell.KeyF1:
+		a.showBasicHelp()
+	case tcell.KeyF10:
+		a.openMenu()
+	case tcell.KeyCtrlZ:
+		a.updateStatusInline(a.undo(false, 1))
+	case tcell.KeyCtrlY:
+		a.updateStatusInline(a.undo(true, 1))
+	case tcell.KeyCtrlR:
+		a.updateStatusInline(a.revealSelected())
+	case tcell.KeyCtrlS:
+		a.runAction("w")
+	case tcell.KeyCtrlQ:
+		a.runAction("q")
+	case tcell.KeyEsc:
+		if a.lastFilter != "" {
+			a.applySearch("")
+		}
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case ':':
+			a.enterCommand("")
+		case '/':
+			a.enterSearch("")
+		}
+	default:
+		// Arrows and paging are the table's own.
+		return ev
+	}
+	return nil
+}
+
+// runAction runs a command as if typed on the command line.
+func (a *App) runAction(cmd string) {
+	if out := a.execCommand(cmd); out != "" {
+		a.updateStatusInline(out)
+	}
+}
+
+func (a *App) showBasicHelp() {
+	var b strings.Builder
+	for _, h := range basicKeys {
+		fmt.Fprintf(&b, "[yellow]%-24s[-] %s\n", tview.Escape(h.key), tview.Escape(h.desc))
+	}
+	b.WriteString("\nType :set inputmode=vim for vim bindings.")
+	a.showText("Keys", b.String())
+}
+
+// openMenu shows the basic-mode action menu.
+func (a *App) openMenu() {
+	list := tview.NewList().ShowSecondaryText(false)
+	item := func(label string, shortcut rune, fn func()) {
+		list.AddItem(label, "", shortcut, func() {
+			a.closeModal()
+			fn()
+		})
+	}
+	item("Edit variable", 'e', func() { a.openEditForm(false) })
+	item("Add variable", 'a', a.openAddForm)
+	item("Add several…", 'A', a.openBatchForm)
+	item("Delete variable", 'd', a.confirmDelete)
+	item("Find", 'f', func() { a.enterSearch(a.lastFilter) })
+	item("Go to key…", 'g', a.openFinder)
+	item("Save", 's', func() { a.runAction("w") })
+	item("Save as…", 'S', func() { a.enterCommand("w ") })
+	item("Import file…", 'i', func() { a.enterCommand("import ") })
+	item("Apply to process…", 'p', func() { a.runAction("apply %") })
+	item("Inspector", 'I', a.toggleInspector)
+	item("Statistics", 't', a.showStats)
+	item("Command line", ':', func() { a.enterCommand("") })
+	item("Switch to vim keys", 'v', func() { a.runAction("set inputmode=vim") })
+	item("Quit", 'q', func() { a.runAction("q") })
+	list.SetDoneFunc(a.closeModal)
+	list.SetBorder(true).SetTitle(" Menu ").SetTitleAlign(tview.AlignLeft)
+
+	a.Pages.AddPage(pageModal, centerPrimitive(list, 40, list.GetItemCount()+2), true, true)
+	a.App.SetFocus(list)
+}
+
+// setCommand handles ":set", ":set option?" and ":set option=value".
+func (a *App) setCommand(args []string) string {
+	if len(args) == 0 {
+		return "inputmode=" + a.inputMode() + " sort=" + a.sortMode() + " " + a.typesOption() +
+			" diff=" + string(a.diff.Algorithm) + " diffunit=" + a.diffUnit() + " palette=" + a.paletteName + " " + a.maskOption() + " " + a.accessibleOption() + " " + a.idleOption() + " " + a.sectionsOption()
+	}
+	name, value, assign := strings.Cut(args[0], "=")
+	name = strings.TrimSuffix(name, "?")
+	switch name {
+	case "inputmode", "im":
+		if !assign {
+			return "inputmode=" + a.inputMode()
+		}
+		switch value {
+		case inputBasic:
+			a.basic = true
+		case inputVim:
+			a.basic = false
+		default:
+			return fmt.Sprintf("Unknown inputmode %q (vim or basic)", value)
+		}
+		a.Vim.resetPrefix()
+		a.refreshStatus()
+		return "inputmode=" + value
+	case "sort":
+		if !assign {
+			return "sort=" + a.sortMode()
+		}
+		switch value {
+		case sortAlpha, sortNatural:
+		default:
+			return fmt.Sprintf("Unknown sort %q (alpha or natural)", value)
+		}
+		key, _ := a.selectedKey()
+		a.Store.SetNaturalSort(value == sortNatural)
+		a.renderTable()
+		a.selectKey(key)
+		return "sort=" + value
+	case "diff":
+		if !assign {
+			return "diff=" + string(a.diff.Algorithm)
+		}
+		alg, ok := textdiff.ParseAlgorithm(value)
+		if !ok {
+			return fmt.Sprintf("Unknown diff %q (myers or histogram)", value)
+		}
+		a.diff.Algorithm = alg
+		return "diff=" + string(alg)
+	case "diffunit":
+		if !assign {
+			return "diffunit=" + a.diffUnit()
+		}
+		switch value {
+		case diffChar, diffWord:
+		default:
+			return fmt.Sprintf("Unknown diffunit %q (char or word)", value)
+		}
+		a.diff.Words = value == diffWord
+		return "diffunit=" + value
+	case "palette":
+		if !assign {
+			return "palette=" + a.paletteName
+		}
+		return a.setPalette(value)
+	case "idle":
+		if !assign {
+			return a.idleOption()
+		}
+		return a.setIdle(value)
+	case "accessible", "noaccessible":
+		if strings.HasSuffix(args[0], "?") {
+			return a.accessibleOption()
+		}
+		a.accessible = name == "accessible"
+		a.refreshStatus()
+		return a.accessibleOption()
+	case "mask", "nomask", "unmask":
+		if strings.HasSuffix(args[0], "?") {
+			return a.maskOption()
+		}
+		a.unmask = name != "mask"
+		a.revealed = ""
+		a.renderTable()
+		return a.maskOption()
+	case "statusline", "stl":
+		if !assign {
+			return "statusline=" + a.statusFormat
+		}
+		// The command line was split on spaces; put them back.
+		a.statusFormat = cmp.Or(strings.Join(append([]string{value}, args[1:]...), " "), defaultStatusLine)
+		a.refreshStatus()
+		return ""
+	case "sections", "nosections":
+		if strings.HasSuffix(args[0], "?") {
+			return a.sectionsOption()
+		}
+		key, _ := a.selectedKey()
+		a.Store.SetSectionSort(name == "sections")
+		a.renderTable()
+		a.selectKey(key)
+		return a.sectionsOption()
+	case "types", "notypes":
+		if strings.HasSuffix(args[0], "?") {
+			return a.typesOption()
+		}
+		a.types = name == "types"
+		a.renderTable()
+		return a.typesOption()
+	default:
+		return fmt.Sprintf("Unknown option: %s", name)
+	}
+}
+
+func (a *App) typesOption() string {
+	if a.types {
+		return "types"
+	}
+	return "notypes"
+}
+
+func (a *App) sortMode() string {
+	if a.Store.NaturalSort() {
+		return sortNatural
+	}
+	return sortAlpha
+}
+
+func (a *App) inputMode() string {
+	if a.basic {
+		return inputBasic
+	}
+	return inputVim
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// batchCheck validates the batch text: the parser's line issues plus keys
+// the key policy rejects, values the key's picker rejects and keys that
+// already exist.
+func (a *App) batchCheck(text string) ([]env.Item, map[int]env.LineIssue) {
+	items, lines, issues := env.CheckLines(text)
+	byLine := make(map[int]env.LineIssue)
+	add := func(is env.LineIssue) {
+		// One marker per line: an error outranks a warning.
+		if prev, ok := byLine[is.Line]; ok && (prev.Error || !is.Error) {
+			return
+		}
+		byLine[is.Line] = is
+	}
+	for _, is := range issues {
+		add(is)
+	}
+	for i, it := range items {
+		_, exists := a.Store.Get(it.Key)
+		if ke, ok := a.Store.CheckKey(it.Key).(*env.KeyError); ok && ke.Rejected && !exists {
+			add(env.LineIssue{Line: lines[i], Msg: ke.Problem + keyFix(ke), Error: true})
+		} else if msg := invalidChoice(it.Key, it.Value); msg != "" {
+			add(env.LineIssue{Line: lines[i], Msg: msg, Error: true})
+		} else if exists {
+			add(env.LineIssue{Line: lines[i], Msg: "replaces the current value"})
+		}
+	}
+	return items, byLine
+}
+
+// openBatchForm is a text area for typing or pasting many KEY=VALUE lines,
+// checked as they are typed, with a marker beside each line. C-s adds them
+// all in one transaction once no line has an error.
+func (a *App) openBatchForm() {
+	text := tview.NewTextArea().
+		SetWrap(false).
+		SetPlaceholder("KEY=VALUE, one per line")
+	gutter := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	report := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+
+	done := func() {
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+	}
+	var items []env.Item
+	var issues map[int]env.LineIssue
+	update := func() {
+		items, issues = a.batchCheck(text.GetText())
+		row, _ := text.GetOffset()
+		_, _, _, height := text.GetInnerRect()
+		lines := strings.Split(text.GetText(), "\n")
+		var g strings.Builder
+		for r := row; r < row+max(height, 1) && r < len(lines); r++ {
+			is, bad := issues[r+1]
+			trimmed := strings.TrimSpace(lines[r])
+			switch {
+			case bad && is.Error:
+				g.WriteString("[red]✗[-]\n")
+			case bad:
+				g.WriteString("[yellow]![-]\n")
+			case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+				g.WriteString("\n")
+			default:
+				g.WriteString("[green]✓[-]\n")
+			}
+		}
+		gutter.SetText(g.String())
+
+		// Errors first: the report has room for a few lines only.
+		errs := 0
+		var b strings.Builder
+		for _, wantErr := range []bool{true, false} {
+			for n := 1; n <= len(lines); n++ {
+				is, ok := issues[n]
+				if !ok || is.Error != wantErr {
+					continue
+				}
+				color := "yellow"
+				if is.Error {
+					color = "red"
+					errs++
+				}
+				fmt.Fprintf(&b, "[%s]line %d: %s[-]\n", color, n, tview.Escape(is.Msg))
+			}
+		}
+		summary := fmt.Sprintf("%d variables", len(items))
+		if errs > 0 {
+			summary += fmt.Sprintf(", [red]%d lines to fix[-]", errs)
+		}
+		report.SetText(summary + "\n" + b.String())
+	}
+
+	submit := func() {
+		update()
+		for _, is := range issues {
+			if is.Error {
+				a.updateStatusInline("Fix the lines marked ✗ first")
+				return
+			}
+		}
+		if len(items) == 0 {
+			a.updateStatusInline("Nothing to add")
+			return
+		}
+		updates := 0
+		a.Store.Begin()
+		for _, it := range items {
+			if _, ok := a.Store.Get(it.Key); ok {
+				updates++
+			}
+			if err := a.Store.Upsert(it.Key, it.Value); err != nil {
+				a.Store.Rollback()
+				a.updateStatusInline(fmt.Sprintf("Nothing added: %s: %v", it.Key, err))
+				return
+			}
+		}
+		_ = a.Store.Commit()
+		done()
+		a.renderTable()
+		a.selectKey(items[0].Key)
+		a.updateStatusInline(fmt.Sprintf("Added %d vars (%d updated)", len(items)-updates, updates))
+	}
+
+	form := tview.NewForm().
+		AddButton("Add all", submit).
+		AddButton("Cancel", done)
+	form.SetButtonsAlign(tview.AlignCenter).SetCancelFunc(done)
+
+	text.SetChangedFunc(update)
+	text.SetMovedFunc(update)
+	text.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyCtrlS:
+			submit()
+			return nil
+		case tcell.KeyTab:
+			a.App.SetFocus(form)
+			return nil
+		case tcell.KeyEsc:
+			done()
+			return nil
+		}
+		return ev
+	})
+
+	editor := tview.NewFlex().
+		AddItem(gutter, 2, 0, false).
+		AddItem(text, 0, 1, true)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(editor, 0, 1, true).
+		AddItem(report, 5, 0, false).
+		AddItem(form, 3, 0, false)
+	layout.SetBorder(true).
+		SetTitle(" Add variables — one KEY=VALUE per line; C-s add all, Tab buttons, ESC cancel ").
+		SetTitleAlign(tview.AlignLeft)
+	update()
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 90, 22), true, true)
+	a.App.SetFocus(text)
+	a.refreshStatus()
+}
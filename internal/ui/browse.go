@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// browsePage is how many entries one fetch asks for; browseMargin is how
+// close to the last loaded row the cursor gets before the next page is
+// fetched.
+const (
+	browsePage   = 100
+	browseMargin = 20
+)
+
+// browseCommand handles ":browse <provider> <path>", which lists a remote
+// path page by page as the cursor nears the end rather than pulling it all
+// first. Providers that cannot page load in one go.
+func (a *App) browseCommand(args []string) string {
+	if len(args) < 2 {
+		return "Usage: :browse <provider> <path>"
+	}
+	name, path := args[0], args[1]
+	p, err := a.openProvider(name)
+	if err != nil {
+		return fmt.Sprintf("Browse failed: %v", err)
+	}
+	slog.Info("browse", "provider", name, "path", path)
+	a.showBrowse(name, path, p)
+	return ""
+}
+
+// showBrowse opens the browser. m loads the next page, F fetches the rest,
+// i imports the selected entry and I every loaded one; ESC closes and
+// cancels any fetch in flight.
+func (a *App) showBrowse(name, path string, p remote.Provider) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := name + ":" + path
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("VALUE"))
+	table.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+
+	var (
+		entries  []remote.Entry
+		next     string
+		total    int
+		started  bool
+		loading  bool
+		fetchAll bool
+		failed   error
+	)
+	title := func() {
+		count := fmt.Sprintf("%d loaded", len(entries))
+		switch {
+		case total > 0:
+			count = fmt.Sprintf("%d of %d loaded", len(entries), total)
+		case next != "":
+			count += ", more available"
+		}
+		state := ""
+		switch {
+		case loading:
+			state = " — loading…"
+		case failed != nil:
+			state = " — " + remoteError("Fetch", failed)
+		}
+		table.SetTitle(fmt.Sprintf(" %s: %s%s — m more, F fetch all, i import, I import loaded, ESC close ", source, count, state))
+	}
+
+	var load func()
+	load = func() {
+		if loading || (started && next == "") {
+			return
+		}
+		loading, failed = true, nil
+		title()
+		cursor, first := next, !started
+		go func() {
+			var pg remote.Page
+			var err error
+			if first {
+				pg, err = remote.FirstPage(ctx, p, path, browsePage)
+			} else {
+				pg, err = p.(remote.Pager).Page(ctx, path, cursor, browsePage)
+			}
+			a.App.QueueUpdateDraw(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				loading = false
+				if err != nil {
+					slog.Error("browse failed", "source", source, "err", err)
+					failed, fetchAll = err, false
+					title()
+					return
+				}
+				started, next, total = true, pg.Next, pg.Total
+				for _, e := range pg.Entries {
+					row := len(entries) + 1
+					table.SetCell(row, 0, tview.NewTableCell(tview.Escape(e.Key)).SetExpansion(1))
+					table.SetCell(row, 1, tview.NewTableCell(tview.Escape(e.Value)).SetExpansion(3).SetMaxWidth(80))
+					entries = append(entries, e)
+				}
+				if first && len(entries) > 0 {
+					table.Select(1, 0)
+				}
+				title()
+				if fetchAll {
+					load()
+				}
+			})
+		}()
+	}
+
+	importEntries := func(es []remote.Entry) {
+		if len(es) == 0 {
+			return
+		}
+		items := make([]env.Item, len(es))
+		for i, e := range es {
+			items[i] = env.Item{Key: e.Key, Value: e.Value}
+		}
+		n := a.Store.Merge(source, items)
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Imported %d vars from %s", n, source))
+	}
+
+	table.SetSelectionChangedFunc(func(row, _ int) {
+		if row >= len(entries)-browseMargin {
+			load()
+		}
+	})
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			cancel()
+			a.closeModal()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Rune() {
+		case 'm':
+			load()
+			return nil
+		case 'F':
+			fetchAll = true
+			load()
+			return nil
+		case 'i':
+			if row, _ := table.GetSelection(); row >= 1 && row <= len(entries) {
+				importEntries(entries[row-1 : row])
+			}
+			return nil
+		case 'I':
+			importEntries(entries)
+			return nil
+		}
+		return ev
+	})
+
+	load()
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 120, 26), true, true)
+	a.App.SetFocus(table)
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/rivo/tview"
+)
+
+// budgetCommand handles ":budget [name|off]". Naming a budget turns on
+// budget mode: the status line shows usage of the visible variables and
+// the keys that push them over are highlighted.
+func (a *App) budgetCommand(args []string) string {
+	if len(args) == 0 {
+		if a.budget == nil {
+			return "Usage: :budget <" + strings.Join(env.BudgetNames(), "|") + "|off>"
+		}
+		a.showBudget()
+		return ""
+	}
+	if args[0] == "off" {
+		a.budget = nil
+		a.renderTable()
+		return "Budget mode off"
+	}
+	b, ok := env.BudgetFor(args[0])
+	if !ok {
+		return fmt.Sprintf("Unknown budget %q (%s)", args[0], strings.Join(env.BudgetNames(), ", "))
+	}
+	a.budget = &b
+	a.renderTable()
+	a.showBudget()
+	return ""
+}
+
+func (a *App) budgetReport() (env.BudgetReport, bool) {
+	if a.budget == nil {
+		return env.BudgetReport{}, false
+	}
+	return a.Store.MeasureBudget(*a.budget, a.Store.ListKeys()), true
+}
+
+// overBudget lists the keys budget mode highlights.
+func (a *App) overBudget() []string {
+	r, ok := a.budgetReport()
+	if !ok {
+		return nil
+	}
+	return append(r.Pushers, r.Oversize...)
+}
+
+// budgetStatus is the status-line segment for budget mode, or "".
+func (a *App) budgetStatus() string {
+	r, ok := a.budgetReport()
+	if !ok {
+		return ""
+	}
+	if r.Over() {
+		return " | [red]" + r.Summary() + "[-]"
+	}
+	return " | " + r.Summary()
+}
+
+func (a *App) showBudget() {
+	r, _ := a.budgetReport()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[::b]%s[::-]\n%s\n\n", tview.Escape(r.Name), tview.Escape(r.Desc))
+	fmt.Fprintf(&b, "%d variables, %d bytes", len(r.Vars), r.Used)
+	if r.Total > 0 {
+		fmt.Fprintf(&b, " of %d", r.Total)
+		if r.Used > r.Total {
+			fmt.Fprintf(&b, " — [red]over by %d[-]", r.Used-r.Total)
+		}
+	}
+	b.WriteString("\n")
+	if r.MaxVars > 0 && len(r.Vars) > r.MaxVars {
+		fmt.Fprintf(&b, "[red]%d variables over the limit of %d[-]\n", len(r.Vars)-r.MaxVars, r.MaxVars)
+	}
+	if len(r.Oversize) > 0 {
+		fmt.Fprintf(&b, "[red]Over %d bytes each:[-] %s\n", r.PerVar, tview.Escape(strings.Join(r.Oversize, ", ")))
+	}
+	if len(r.Pushers) > 0 {
+		fmt.Fprintf(&b, "Removing %s would fit the budget.\n", tview.Escape(strings.Join(r.Pushers, ", ")))
+	}
+
+	b.WriteString("\n[::b]Largest[::-]\n")
+	for i, v := range r.Vars {
+		if i == 20 {
+			break
+		}
+		note := ""
+		if v.Secret {
+			note = "  secret: move to a secret manager"
+		}
+		mark := " "
+		if slices.Contains(r.Pushers, v.Key) || slices.Contains(r.Oversize, v.Key) {
+			mark = "[red]*[-]"
+		}
+		fmt.Fprintf(&b, "%s %-32s %6d%s\n", mark, tview.Escape(v.Key), v.Size, note)
+	}
+	a.showText("Budget", b.String())
+}
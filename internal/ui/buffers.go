@@ -0,0 +1,331 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/journal"
+	"github.com/rivethorn/envoy/internal/trust"
+)
+
+// processBuffer names the buffer holding the process environment.
+const processBuffer = "[process]"
+
+// buffer is one open environment: the process environment the session
+// started with, or an env file opened from the command line or with
+// :e <path>. Each has its own store, and so its own dirty state and filter.
+// Sources and :apply belong to the process buffer.
+type buffer struct {
+	name     string
+	path     string // file written by :w; "" for the process buffer
+	store    Store
+	filter   string
+	row, col int
+	marks    map[string]string // key each m{a-z} mark is on, see markKey
+
+	journal *journal.Journal   // this session's record of unsaved changes
+	orphans []*journal.Journal // left by crashed sessions, not yet recovered
+}
+
+// openFiles opens the files named on the command line in buffers of their
+// own, one after the other, shows the first and calls then. Problems are
+// added to errs and reported once all are open.
+func (a *App) openFiles(paths, errs []string, then func()) {
+	if len(paths) == 0 {
+		if len(a.buffers) > 1 {
+			a.switchBuffer(1)
+			a.setSelection(1, 0)
+		}
+		if len(errs) > 0 {
+			a.updateStatusInline(strings.Join(append(a.startupErrs, errs...), "; "))
+		}
+		then()
+		return
+	}
+	path, rest := paths[0], paths[1:]
+	if _, ok := a.bufferFor(path); ok {
+		a.openFiles(rest, errs, then)
+		return
+	}
+	items, err := a.readBuffer(path)
+	if err != nil {
+		a.openFiles(rest, append(errs, fmt.Sprintf("Open %s: %v", path, err)), then)
+		return
+	}
+	a.openChecked(path, items, func(b *buffer, _ string) {
+		if b == nil {
+			errs = append(errs, "Not opened: "+path)
+		} else if note := a.orphanNote(b); note != "" {
+			errs = append(errs, path+": "+note)
+		}
+		a.openFiles(rest, errs, then)
+	})
+}
+
+// openChecked opens path, read as items, in a new buffer once the file has
+// passed the trust check and been locked, asking first when it is suspect
+// or held by another session. done gets the buffer and the check's note,
+// or nil when the user declined.
+func (a *App) openChecked(path string, items []env.Item, done func(b *buffer, note string)) {
+	r, note := checkFile(path, items)
+	open := func() {
+		a.bindFileOr(path, func() {
+			b := a.addBuffer(path, items)
+			a.journalBuffer(b, path)
+			done(b, note)
+		}, func() { done(nil, "") })
+	}
+	if r.Trusted() {
+		open()
+		return
+	}
+	a.confirmUntrusted("Open "+path+"?", "Open anyway", []trust.Report{r}, func(ok bool) {
+		if !ok {
+			done(nil, "")
+			return
+		}
+		slog.Info("open despite trust problems", "path", path, "problems", r.Problems)
+		open()
+	})
+}
+
+// bufferFor returns the buffer already holding path.
+func (a *App) bufferFor(path string) (*buffer, bool) {
+	for _, b := range a.buffers {
+		if b.path != "" && sameFile(b.path, path) {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// addBuffer opens a new buffer on path holding items.
+func (a *App) addBuffer(path string, items []env.Item) *buffer {
+	b := &buffer{name: path, path: path}
+	a.loadBuffer(b, items)
+	a.buffers = append(a.buffers, b)
+	return b
+}
+
+// readBuffer parses the file at path; a missing one reads as empty.
+func (a *App) readBuffer(path string) ([]env.Item, error) {
+	items, err := env.ParseFS(env.AgeFS(env.OSFS{}, a.Store.AgeKeys()), path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return items, nil
+}
+
+// loadBuffer gives b a fresh store holding items, unmodified. A journaled
+// buffer's journal follows the new store and starts over.
+func (a *App) loadBuffer(b *buffer, items []env.Item) {
+	s := env.NewStoreFromItems(items)
+	s.SetNaturalSort(a.Store.NaturalSort())
+	s.SetSectionSort(a.Store.SectionSort())
+	_ = s.SetKeyPolicy(a.Store.KeyPolicy())
+	s.SetAgeKeys(a.Store.AgeKeys())
+	b.store = s
+	if b.journal != nil {
+		a.hookJournal(b)
+		a.clearJournal(b)
+	}
+}
+
+func sameFile(a, b string) bool {
+	pa, errA := filepath.Abs(a)
+	pb, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && pa == pb
+}
+
+// processStore is the process buffer's store, which sources load into
+// whichever buffer is shown.
+func (a *App) processStore() Store {
+	return a.buffers[0].store
+}
+
+// buf is the buffer shown.
+func (a *App) buf() *buffer {
+	return a.buffers[a.cur]
+}
+
+// switchBuffer shows buffer i, keeping the filter and cursor of the one
+// left.
+func (a *App) switchBuffer(i int) {
+	if i == a.cur {
+		return
+	}
+	old := a.buf()
+	old.filter, old.row, old.col = a.lastFilter, a.selRow, a.selCol
+	a.cur = i
+	b := a.buf()
+	a.Store = b.store
+	a.lastFilter = b.filter
+	a.selRow, a.selCol = b.row, b.col
+	a.Table.SetTitle(bufferTitle(b))
+	a.renderTable()
+}
+
+func bufferTitle(b *buffer) string {
+	if b.path == "" {
+		return " Environment variables "
+	}
+	return " " + b.name + " "
+}
+
+// bufferCommand handles :ls, :bnext, :bprev and :b <n|name>.
+func (a *App) bufferCommand(cmd string, args []string) string {
+	n := len(a.buffers)
+	switch cmd {
+	case "ls", "buffers":
+		return a.listBuffers()
+	case "bn", "bnext":
+		a.switchBuffer((a.cur + 1) % n)
+	case "bp", "bprev", "bprevious":
+		a.switchBuffer((a.cur - 1 + n) % n)
+	default:
+		if len(args) != 1 {
+			return "Usage: :b <number|name>"
+		}
+		i, err := a.findBuffer(args[0])
+		if err != nil {
+			return err.Error()
+		}
+		a.switchBuffer(i)
+	}
+	return a.bufferStatus()
+}
+
+// findBuffer resolves a buffer number as :ls shows it, an exact name or a
+// unique part of one.
+func (a *App) findBuffer(arg string) (int, error) {
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(a.buffers) {
+			return 0, fmt.Errorf("No buffer %d", n)
+		}
+		return n - 1, nil
+	}
+	var matches []int
+	for i, b := range a.buffers {
+		if b.name == arg {
+			return i, nil
+		}
+		if strings.Contains(b.name, arg) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("No buffer matching %q", arg)
+	case 1:
+		return matches[0], nil
+	}
+	return 0, fmt.Errorf("More than one buffer matches %q", arg)
+}
+
+// listBuffers describes every buffer on the status line, vim style: %
+// marks the one shown and + one with unsaved changes.
+func (a *App) listBuffers() string {
+	var parts []string
+	for i, b := range a.buffers {
+		flags := " "
+		if i == a.cur {
+			flags = "%"
+		}
+		if b.store.Dirty() {
+			flags += "+"
+		}
+		parts = append(parts, fmt.Sprintf("%d%s %s", i+1, flags, b.name))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (a *App) bufferStatus() string {
+	b := a.buf()
+	s := fmt.Sprintf("Buffer %d/%d: %s, %d vars", a.cur+1, len(a.buffers), b.name, b.store.Count())
+	if b.store.Dirty() {
+		s += ", modified"
+	}
+	return s
+}
+
+// editCommand handles ":e [path]" and ":e!". With a path it opens the file
+// in a buffer, or switches to the buffer holding it; without one it reloads
+// the buffer shown, which :e refuses to do over unsaved changes and :e!
+// does anyway, as in vim.
+func (a *App) editCommand(args []string, force bool) string {
+	if len(args) == 0 {
+		b := a.buf()
+		if b.store.Dirty() && !force {
+			return "No write since last change (:e! reloads anyway)"
+		}
+		if b.path == "" {
+			a.Store.LoadFromProcess()
+			a.clearJournal(b)
+			a.renderTable()
+			return "Reloaded from process environment"
+		}
+		items, err := a.readBuffer(b.path)
+		if a.ageRetry(err, func() string { return a.editCommand(args, force) }) {
+			return ""
+		}
+		if err != nil {
+			return fmt.Sprintf("Reload %s: %v", b.path, err)
+		}
+		return a.loadTrusted("Reload", b.path, items, func() string {
+			a.loadBuffer(b, items)
+			a.Store = b.store
+			a.Store.Filter(a.lastFilter)
+			a.renderTable()
+			return "Reloaded " + b.path
+		})
+	}
+	path := expandHome(strings.Join(args, " "))
+	if b, ok := a.bufferFor(path); ok {
+		a.showBuffer(b)
+		return a.bufferStatus()
+	}
+	items, err := a.readBuffer(path)
+	if a.ageRetry(err, func() string { return a.editCommand(args, force) }) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("Open %s: %v", path, err)
+	}
+	a.openChecked(path, items, func(b *buffer, note string) {
+		if b == nil {
+			a.updateStatusInline("Open cancelled")
+			return
+		}
+		a.showBuffer(b)
+		msg := a.bufferStatus() + note
+		if orphans := a.orphanNote(b); orphans != "" {
+			msg += "; " + orphans
+		}
+		a.updateStatusInline(msg)
+	})
+	return ""
+}
+
+// showBuffer switches to b.
+func (a *App) showBuffer(b *buffer) {
+	for i := range a.buffers {
+		if a.buffers[i] == b {
+			a.switchBuffer(i)
+		}
+	}
+}
+
+// writeDefault is the file :w writes without a path: the buffer's file, or
+// .env for the process buffer.
+func (a *App) writeDefault() string {
+	if p := a.buf().path; p != "" {
+		return p
+	}
+	return defaultWritePath
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/bundle"
+	"github.com/rivethorn/envoy/internal/explain"
+	"github.com/rivethorn/envoy/internal/update"
+)
+
+// bundleCommand handles ":bundle save <path>" and ":bundle load <path>".
+func (a *App) bundleCommand(args []string) string {
+	if len(args) < 2 || (args[0] != "save" && args[0] != "load") {
+		return "Usage: :bundle save|load <path>"
+	}
+	path := expandHome(strings.Join(args[1:], " "))
+	if args[0] == "save" {
+		return a.saveBundle(path)
+	}
+	return a.loadBundle(path)
+}
+
+func (a *App) saveBundle(path string) string {
+	b := &bundle.Bundle{
+		Manifest: bundle.Manifest{Version: bundle.Version, Created: time.Now().UTC(), Envoy: update.Current()},
+		Vars:     a.Store.State(),
+		Explain:  a.Config.Explain,
+		Meta:     a.Store.Meta(),
+	}
+	// Bundles hold plain values; keep them private like the env files.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Sprintf("Bundle failed: %v", err)
+	}
+	err = bundle.Write(f, b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Sprintf("Bundle failed: %v", err)
+	}
+	slog.Info("bundle saved", "path", path, "vars", len(b.Vars))
+	return fmt.Sprintf("Saved %d vars and %d descriptions to %s", len(b.Vars), len(b.Explain), path) + metaNote(b.Meta)
+}
+
+func (a *App) loadBundle(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("Bundle failed: %v", err)
+	}
+	defer f.Close()
+	b, err := bundle.Read(f)
+	if err != nil {
+		return fmt.Sprintf("Bundle failed: %v", err)
+	}
+
+	n := a.Store.Restore(b.Vars)
+	for name, v := range b.Meta {
+		if err := a.Store.SetMeta(name, v); err != nil {
+			slog.Warn("bundle meta", "name", name, "err", err)
+		}
+	}
+	if len(b.Explain) > 0 {
+		// Descriptions apply to this session; copy them into the config
+		// file to keep them.
+		if a.Config.Explain == nil {
+			a.Config.Explain = make(map[string]explain.Entry)
+		}
+		maps.Copy(a.Config.Explain, b.Explain)
+		a.explain = explain.New(a.Config.Explain)
+	}
+	a.renderTable()
+	slog.Info("bundle loaded", "path", path, "vars", n, "created", b.Created)
+	return fmt.Sprintf("Loaded %d vars and %d descriptions from %s (saved %s)",
+		n, len(b.Explain), path, b.Created.Local().Format(time.DateTime)) + metaNote(b.Meta) + a.expiryWarning()
+}
+
+func metaNote(meta map[string]string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("; %d meta-variables", len(meta))
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// caseWarning returns a status suffix when keys collide by case, or "".
+func (a *App) caseWarning() string {
+	if n := len(a.Store.CaseCollisions()); n > 0 {
+		return fmt.Sprintf("; %d case collisions (:casecheck)", n)
+	}
+	return ""
+}
+
+// showCaseCollisions lists keys differing only by case, grouped. m or Enter
+// keeps the selected key and deletes the rest of its group.
+func (a *App) showCaseCollisions() string {
+	if len(a.Store.CaseCollisions()) == 0 {
+		return "No case collisions"
+	}
+
+	var keys []string
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("GROUP"))
+	table.SetCell(0, 1, headerCell("KEY"))
+	table.SetCell(0, 2, headerCell("VALUE"))
+
+	fill := func() {
+		keys = keys[:0]
+		for r := table.GetRowCount() - 1; r > 0; r-- {
+			table.RemoveRow(r)
+		}
+		for _, group := range a.Store.CaseCollisions() {
+			for i, k := range group {
+				row := len(keys) + 1
+				name := ""
+				if i == 0 {
+					name = strings.ToUpper(k)
+				}
+				it, _ := a.Store.Get(k)
+				table.SetCell(row, 0, tview.NewTableCell(name).SetExpansion(1))
+				table.SetCell(row, 1, tview.NewTableCell(k).SetExpansion(1))
+				table.SetCell(row, 2, tview.NewTableCell(it.Value).SetExpansion(2))
+				keys = append(keys, k)
+			}
+		}
+	}
+	fill()
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(" Case collisions — m/Enter keep this key, ESC close ").
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+			a.renderTable()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() != tcell.KeyEnter && ev.Rune() != 'm' {
+			return ev
+		}
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(keys) {
+			return nil
+		}
+		keep := keys[row-1]
+		dropped := a.Store.MergeCase(keep)
+		a.updateStatusInline(fmt.Sprintf("Kept %s, removed %s", keep, strings.Join(dropped, ", ")))
+		if len(a.Store.CaseCollisions()) == 0 {
+			a.closeModal()
+			a.renderTable()
+			return nil
+		}
+		fill()
+		table.Select(1, 0)
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/snapshot"
+	"github.com/rivo/tview"
+)
+
+// loginShellTimeout bounds a login shell's startup files.
+const loginShellTimeout = 15 * time.Second
+
+// compareShell handles ":compare-shell [shell]": it starts a fresh login
+// shell and lists how its environment differs from this process's.
+func (a *App) compareShell(args []string) string {
+	shell := env.LoginShell()
+	if len(args) > 0 {
+		shell = args[0]
+	}
+	var login map[string]string
+	a.runRemote("Starting a login shell ("+filepath.Base(shell)+")", func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, loginShellTimeout)
+		defer cancel()
+		var err error
+		login, err = env.LoginEnviron(ctx, shell)
+		return err
+	}, func(err error) {
+		if err != nil {
+			slog.Warn("compare-shell", "shell", shell, "err", err)
+			a.updateStatusInline(fmt.Sprintf("Login shell failed: %v", err))
+			return
+		}
+		cur := a.Store.Process()
+		for _, k := range env.ShellVolatile {
+			delete(cur, k)
+		}
+		a.showShellDiff(filepath.Base(shell), snapshot.Diff(login, cur))
+	})
+	return ""
+}
+
+// showShellDiff lists the differences, variables set only in this session
+// first. Enter jumps to the key.
+func (a *App) showShellDiff(shell string, changes []snapshot.Change) {
+	if len(changes) == 0 {
+		a.updateStatusInline("This environment matches a fresh " + shell + " login shell")
+		return
+	}
+	rank := map[snapshot.ChangeKind]int{snapshot.Added: 0, snapshot.Changed: 1, snapshot.Removed: 2}
+	slices.SortStableFunc(changes, func(x, y snapshot.Change) int { return rank[x.Kind] - rank[y.Kind] })
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("HERE"))
+	table.SetCell(0, 2, headerCell("LOGIN SHELL"))
+	table.SetCell(0, 3, headerCell(""))
+	counts := make(map[snapshot.ChangeKind]int)
+	for i, c := range changes {
+		counts[c.Kind]++
+		var note string
+		switch c.Kind {
+		case snapshot.Added:
+			note = "only here"
+		case snapshot.Removed:
+			note = "only in login shell"
+		case snapshot.Changed:
+			note = "differs"
+		}
+		mark, color := a.changeMark(c.Kind == snapshot.Added, c.Kind == snapshot.Removed)
+		table.SetCell(i+1, 0, tview.NewTableCell(mark+c.Key).SetTextColor(color).SetExpansion(1))
+		here := tview.Escape(c.New)
+		if c.Kind == snapshot.Changed {
+			here = a.valueDiff(c.Old, c.New)
+		}
+		table.SetCell(i+1, 1, tview.NewTableCell(here).SetExpansion(2).SetMaxWidth(50))
+		table.SetCell(i+1, 2, tview.NewTableCell(c.Old).SetExpansion(2).SetMaxWidth(50))
+		table.SetCell(i+1, 3, tview.NewTableCell(note).SetTextColor(tcell.ColorGray))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" This session vs a fresh %s login shell: %d only here, %d differ, %d only there — Enter go to key, ESC close ",
+			shell, counts[snapshot.Added], counts[snapshot.Changed], counts[snapshot.Removed])).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(changes) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(changes[row-1].Key)
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 130, 26), true, true)
+	a.App.SetFocus(table)
+}
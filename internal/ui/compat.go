@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// compatCommand handles ":compat [key]": how the dotenv line written for
+// key, the selected one by default, is read back by docker, compose, bash
+// and Ruby dotenv.
+func (a *App) compatCommand(args []string) string {
+	key, ok := a.selectedKey()
+	if len(args) > 0 {
+		key = args[0]
+	} else if !ok {
+		return "Usage: :compat [key]"
+	}
+	it, ok := a.Store.Get(key)
+	if !ok {
+		return "No variable " + key
+	}
+	line, results := env.Compat(key, it.Value, func(k string) (string, bool) {
+		v, ok := a.Store.Get(k)
+		return v.Value, ok
+	})
+	hide := a.masked(key, it.Value)
+	show := func(v string) string {
+		if hide {
+			return maskText
+		}
+		return tview.Escape(strconv.Quote(v))
+	}
+
+	var b strings.Builder
+	b.WriteString("Written to .env as\n\n")
+	if hide {
+		line = key + "=" + maskText
+	}
+	for _, l := range strings.Split(line, "\n") {
+		fmt.Fprintf(&b, "  %s\n", tview.Escape(l))
+	}
+	b.WriteString("\n")
+	differ := 0
+	for _, r := range results {
+		switch {
+		case r.Same:
+			fmt.Fprintf(&b, "[green]✓ %-8s[-] reads it back unchanged\n", r.Tool)
+		case !r.Found:
+			differ++
+			fmt.Fprintf(&b, "[red]✗ %-8s[-] does not set %s\n", r.Tool, tview.Escape(key))
+		default:
+			differ++
+			fmt.Fprintf(&b, "[red]✗ %-8s[-] reads %s\n", r.Tool, show(r.Value))
+		}
+		for _, n := range r.Notes {
+			fmt.Fprintf(&b, "             [yellow]%s[-]\n", tview.Escape(n))
+		}
+	}
+	if differ == 0 {
+		b.WriteString("\nEvery tool reads the value as written.")
+	} else {
+		fmt.Fprintf(&b, "\n%d of %d tools read something else.", differ, len(results))
+	}
+	a.showText("Compatibility of "+key, b.String())
+	return ""
+}
@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/trust"
+	"github.com/rivo/tview"
+)
+
+// dirEnvFiles lists the working directory's env files not already given
+// as -layer sources.
+func dirEnvFiles(explicit []Source) []string {
+	var out []string
+	for _, f := range trust.EnvFiles(".") {
+		given := slices.ContainsFunc(explicit, func(s Source) bool {
+			return !s.Remote && filepath.Clean(expandHome(s.Spec)) == f
+		})
+		if !given {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// withDirEnv calls load with sources, preceded by the working directory's
+// env files when the user allows them. A remembered decision is applied
+// without asking; otherwise a prompt offers to load them once, always or
+// never.
+func (a *App) withDirEnv(sources []Source, load func([]Source)) {
+	files := dirEnvFiles(sources)
+	if len(files) == 0 {
+		load(sources)
+		return
+	}
+	db, err := trust.Load(trust.Path())
+	if err != nil {
+		slog.Warn("load allow list", "err", err)
+	}
+	sum, err := trust.Sum(files)
+	if err != nil {
+		slog.Warn("hash env files", "err", err)
+		load(sources)
+		return
+	}
+	withFiles := func() []Source {
+		out := make([]Source, 0, len(files)+len(sources))
+		for _, f := range files {
+			out = append(out, Source{Spec: f})
+		}
+		return append(out, sources...)
+	}
+	if allow, ok := db.Lookup(".", sum); ok {
+		slog.Info("dir env remembered", "allow", allow, "files", files)
+		if allow {
+			load(withFiles())
+		} else {
+			load(sources)
+		}
+		return
+	}
+
+	remember := func(allow bool) {
+		if err := db.Set(".", sum, allow); err != nil {
+			slog.Warn("save allow list", "err", err)
+		}
+	}
+	m := tview.NewModal().
+		SetText(fmt.Sprintf("This directory has env files:\n\n%s\n\nLoad them as layers? Only do so if you trust where they came from.",
+			strings.Join(files, "\n"))).
+		AddButtons([]string{"Load", "Always", "Never", "Not now"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			switch label {
+			case "Always":
+				remember(true)
+				load(withFiles())
+			case "Load":
+				load(withFiles())
+			case "Never":
+				remember(false)
+				load(sources)
+			default:
+				load(sources)
+			}
+		})
+	a.Pages.AddPage(pageModal, m, true, true)
+	a.App.SetFocus(m)
+}
+
+// allowCommand handles ":allow" and ":deny", remembering the answer for
+// the working directory's env files. :allow also loads them now.
+func (a *App) allowCommand(allow bool) string {
+	files := trust.EnvFiles(".")
+	if len(files) == 0 {
+		return "No env files in this directory"
+	}
+	db, err := trust.Load(trust.Path())
+	if err != nil {
+		return fmt.Sprintf("Allow list: %v", err)
+	}
+	sum, err := trust.Sum(files)
+	if err != nil {
+		return fmt.Sprintf("Allow list: %v", err)
+	}
+	if err := db.Set(".", sum, allow); err != nil {
+		return fmt.Sprintf("Allow list: %v", err)
+	}
+	if !allow {
+		return "Env files in this directory will not be loaded"
+	}
+	n := 0
+	for _, f := range files {
+		items, err := a.Store.ParseFile(f)
+		if err != nil {
+			return fmt.Sprintf("Load %s: %v", f, err)
+		}
+		n += a.Store.Merge(f, items)
+	}
+	a.renderTable()
+	return fmt.Sprintf("Allowed; loaded %d vars from %s", n, strings.Join(files, ", "))
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivethorn/envoy/internal/config"
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Driver runs an App on a simulation screen so vim flows can be exercised
+// end to end without a terminal. Input is dispatched through the
+// application's update queue, so every call returns after the event has been
+// fully handled.
+type Driver struct {
+	App    *App
+	screen tcell.SimulationScreen
+	done   chan error
+}
+
+// NewDriver starts an App on a width×height simulation screen. When
+// opts.Store is nil the store is a detached, empty one on an in-memory
+// filesystem, and when opts.Config is nil the defaults apply rather than
+// the user's config file, so nothing leaks in from or out to the real
+// environment.
+func NewDriver(opts Options, width, height int) *Driver {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	opts.Screen = screen
+	if opts.Store == nil {
+		store := env.NewStoreFrom(nil)
+		store.SetFS(env.NewMemFS())
+		opts.Store = store
+	}
+	if opts.Config == nil {
+		opts.Config = &config.Config{}
+	}
+	a := NewApp(opts)
+	screen.SetSize(width, height)
+
+	d := &Driver{App: a, screen: screen, done: make(chan error, 1)}
+	go func() { d.done <- a.App.Run() }()
+	return d
+}
+
+// do runs f on the UI goroutine and waits for it.
+func (d *Driver) do(f func()) {
+	done := make(chan struct{})
+	d.App.App.QueueUpdateDraw(func() {
+		f()
+		close(done)
+	})
+	<-done
+}
+
+// SendKey delivers one key event the way the event loop would.
+func (d *Driver) SendKey(ev *tcell.EventKey) {
+	d.do(func() {
+		if capture := d.App.App.GetInputCapture(); capture != nil {
+			if ev = capture(ev); ev == nil {
+				return
+			}
+		}
+		if handler := d.App.Pages.InputHandler(); handler != nil {
+			handler(ev, func(p tview.Primitive) { d.App.App.SetFocus(p) })
+		}
+	})
+}
+
+// SendKeys types keys in vim notation: plain characters, plus <Esc>, <CR>
+// (or <Enter>), <Tab>, <BS>, <Del>, <Ins>, <Up>, <Down>, <Left>, <Right>,
+// <lt>, <F1>…<F12> and <C-x> for control keys.
+func (d *Driver) SendKeys(keys string) {
+	for keys != "" {
+		if keys[0] == '<' {
+			if end := strings.IndexByte(keys, '>'); end > 0 {
+				if ev := namedKey(keys[1:end]); ev != nil {
+					d.SendKey(ev)
+					keys = keys[end+1:]
+					continue
+				}
+			}
+		}
+		r, size := utf8.DecodeRuneInString(keys)
+		d.SendKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		keys = keys[size:]
+	}
+}
+
+func namedKey(name string) *tcell.EventKey {
+	switch strings.ToLower(name) {
+	case "esc":
+		return tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone)
+	case "cr", "enter":
+		return tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModNone)
+	case "tab":
+		return tcell.NewEventKey(tcell.KeyTab, '\t', tcell.ModNone)
+	case "bs":
+		return tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+	case "up":
+		return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+	case "down":
+		return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+	case "left":
+		return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+	case "right":
+		return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+	case "del":
+		return tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone)
+	case "ins":
+		return tcell.NewEventKey(tcell.KeyInsert, 0, tcell.ModNone)
+	case "lt":
+		return tcell.NewEventKey(tcell.KeyRune, '<', tcell.ModNone)
+	}
+	if rest, ok := strings.CutPrefix(strings.ToLower(name), "f"); ok {
+		if n, err := strconv.Atoi(rest); err == nil && n >= 1 && n <= 12 {
+			return tcell.NewEventKey(tcell.KeyF1+tcell.Key(n-1), 0, tcell.ModNone)
+		}
+	}
+	if len(name) == 3 && strings.HasPrefix(strings.ToLower(name), "c-") {
+		c := name[2] | 0x20 // lower-case letter
+		if c >= 'a' && c <= 'z' {
+			k := tcell.KeyCtrlA + tcell.Key(c-'a')
+			return tcell.NewEventKey(k, rune(k), tcell.ModCtrl)
+		}
+	}
+	return nil
+}
+
+// Paste delivers text as a single bracketed paste.
+func (d *Driver) Paste(text string) {
+	d.do(func() {
+		if handler := d.App.Pages.PasteHandler(); handler != nil {
+			handler(text, func(p tview.Primitive) { d.App.App.SetFocus(p) })
+		}
+	})
+}
+
+// Snapshot redraws and returns the screen as text, one line per row with
+// trailing spaces trimmed.
+func (d *Driver) Snapshot() string {
+	var out string
+	d.do(func() {
+		d.App.App.ForceDraw()
+		cells, w, h := d.screen.GetContents()
+		lines := make([]string, h)
+		for y := 0; y < h; y++ {
+			var b strings.Builder
+			for x := 0; x < w; x++ {
+				c := cells[y*w+x]
+				if len(c.Runes) == 0 {
+					b.WriteByte(' ')
+					continue
+				}
+				b.WriteString(string(c.Runes))
+			}
+			lines[y] = strings.TrimRight(b.String(), " ")
+		}
+		out = strings.Join(lines, "\n")
+	})
+	return out
+}
+
+// Stop ends the application and returns Run's error.
+func (d *Driver) Stop() error {
+	d.App.App.Stop()
+	return <-d.done
+}
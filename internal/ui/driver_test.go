@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDriverAddEditUndo(t *testing.T) {
+	d := NewDriver(Options{}, 100, 30)
+	defer d.Stop()
+
+	d.SendKeys("AGREETING<Tab>hello<Tab><CR>")
+	if s := d.Snapshot(); !strings.Contains(s, "GREETING") || !strings.Contains(s, "hello") {
+		t.Fatalf("after add:\n%s", s)
+	}
+
+	d.SendKeys("i<Tab><C-u>world<Tab><CR>")
+	s := d.Snapshot()
+	if !strings.Contains(s, "world") || strings.Contains(s, "hello") {
+		t.Fatalf("after edit:\n%s", s)
+	}
+
+	d.SendKeys("u")
+	s = d.Snapshot()
+	if !strings.Contains(s, "hello") || strings.Contains(s, "world") {
+		t.Fatalf("after undo:\n%s", s)
+	}
+}
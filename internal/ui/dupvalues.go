@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showDuplicateValues lists groups of keys sharing one value. n and N move
+// between groups; Enter jumps to the selected key.
+func (a *App) showDuplicateValues() string {
+	groups := a.Store.DuplicateValues()
+	if len(groups) == 0 {
+		return "No two keys share a value"
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("GROUP"))
+	table.SetCell(0, 1, headerCell("KEY"))
+	table.SetCell(0, 2, headerCell("VALUE"))
+
+	var keys []string
+	var starts []int // first row of each group
+	shared := 0
+	for g, group := range groups {
+		starts = append(starts, len(keys)+1)
+		shared += len(group)
+		for i, k := range group {
+			row := len(keys) + 1
+			name, value := "", ""
+			if i == 0 {
+				it, _ := a.Store.Get(k)
+				name = fmt.Sprintf("%d (%d keys)", g+1, len(group))
+				value = a.shownValue(k, it.Value)
+			}
+			table.SetCell(row, 0, tview.NewTableCell(name).SetTextColor(tcell.ColorGray))
+			table.SetCell(row, 1, tview.NewTableCell(k).SetTextColor(tcell.ColorYellow).SetExpansion(1))
+			table.SetCell(row, 2, tview.NewTableCell(value).SetExpansion(2).SetMaxWidth(60))
+			keys = append(keys, k)
+		}
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %d values shared by %d keys — n/N next/previous group, Enter go to key, ESC close ", len(groups), shared)).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(keys) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(keys[row-1])
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Rune() != 'n' && ev.Rune() != 'N' {
+			return ev
+		}
+		row, _ := table.GetSelection()
+		g := 0
+		for g+1 < len(starts) && starts[g+1] <= row {
+			g++
+		}
+		if ev.Rune() == 'n' {
+			g = (g + 1) % len(starts)
+		} else {
+			g = (g - 1 + len(starts)) % len(starts)
+		}
+		table.Select(starts[g], 0)
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
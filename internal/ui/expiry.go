@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// expiryWarnWindow is how far ahead a deadline counts as soon.
+const expiryWarnWindow = 14 * 24 * time.Hour
+
+// expiryMark is the gutter symbol for an entry past (markBroken) or near
+// (markExpiring) its deadline, or 0 when it has none or it is far off.
+func (a *App) expiryMark(key string) rune {
+	t, ok := a.Store.Expiry(key)
+	switch {
+	case !ok:
+		return 0
+	case time.Now().After(t):
+		return markBroken
+	case time.Until(t) < expiryWarnWindow:
+		return markExpiring
+	}
+	return 0
+}
+
+// expiryColor is the key colour for an entry past or near its deadline,
+// or 0 when it has none or it is far off.
+func (a *App) expiryColor(key string) tcell.Color {
+	switch a.expiryMark(key) {
+	case markBroken:
+		return a.pal.Expired
+	case markExpiring:
+		return a.pal.Expiring
+	}
+	return 0
+}
+
+// expiryWarning returns a status suffix when keys are due for rotation, or
+// "".
+func (a *App) expiryWarning() string {
+	if n := len(a.Store.Expiring(expiryWarnWindow)); n > 0 {
+		return fmt.Sprintf("; %d keys expiring (:expiring)", n)
+	}
+	return ""
+}
+
+// expiresIn describes a deadline relative to now.
+func expiresIn(t time.Time) string {
+	d := time.Until(t)
+	if d < 0 {
+		return "expired " + age(-d) + " ago"
+	}
+	return "in " + age(d)
+}
+
+// expiresCommand handles ":expires <date|none>" for the selected key.
+func (a *App) expiresCommand(args []string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	if len(args) != 1 {
+		if t, ok := a.Store.Expiry(key); ok {
+			return fmt.Sprintf("%s expires %s (%s)", key, t.Format(time.DateOnly), expiresIn(t))
+		}
+		return "Usage: :expires <YYYY-MM-DD|+days|none>"
+	}
+	var t time.Time
+	switch arg := args[0]; {
+	case arg == "none":
+	case strings.HasPrefix(arg, "+"):
+		days, err := strconv.Atoi(strings.TrimSuffix(arg[1:], "d"))
+		if err != nil {
+			return fmt.Sprintf("Bad day count %q", arg)
+		}
+		y, m, d := time.Now().AddDate(0, 0, days).Date()
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	default:
+		var err error
+		if t, err = env.ParseExpiry(arg); err != nil {
+			return fmt.Sprintf("Bad date %q: use YYYY-MM-DD", arg)
+		}
+	}
+	a.Store.SetExpiry(key, t)
+	a.lastChange = func() string { return a.expiresCommand(args) }
+	a.renderTable()
+	if t.IsZero() {
+		return fmt.Sprintf("Cleared expiry of %s", key)
+	}
+	return fmt.Sprintf("%s expires %s (%s)", key, t.Format(time.DateOnly), expiresIn(t))
+}
+
+// showExpiring lists keys past or near their deadline; with a day count,
+// those due within that many days. Enter jumps to the key.
+func (a *App) showExpiring(args []string) string {
+	window := expiryWarnWindow
+	if len(args) > 0 {
+		days, err := strconv.Atoi(args[0])
+		if err != nil || days < 0 {
+			return "Usage: :expiring [days]"
+		}
+		window = time.Duration(days) * 24 * time.Hour
+	}
+	list := a.Store.Expiring(window)
+	if len(list) == 0 {
+		return "No keys expiring"
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("EXPIRES"))
+	table.SetCell(0, 2, headerCell(""))
+	for i, e := range list {
+		color := tcell.ColorOrange
+		if time.Now().After(e.At) {
+			color = tcell.ColorOrangeRed
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(e.Key).SetExpansion(2).SetTextColor(color))
+		table.SetCell(i+1, 1, tview.NewTableCell(e.At.Format(time.DateOnly)).SetExpansion(1))
+		table.SetCell(i+1, 2, tview.NewTableCell(expiresIn(e.At)).SetExpansion(1))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(" Expiring — Enter go to key, ESC close ").
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(list) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(list[row-1].Key)
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 80, 20), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/snapshot"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// diffCommand handles ":diff [-u] [path]", which compares the store with a
+// dotenv file, by default the one :w writes. -u starts in the unified
+// layout.
+func (a *App) diffCommand(args []string) string {
+	unified := false
+	if len(args) > 0 && args[0] == "-u" {
+		unified, args = true, args[1:]
+	}
+	path := a.writeDefault()
+	if len(args) > 0 {
+		path = expandHome(strings.Join(args, " "))
+	}
+	changes, err := a.fileChanges(path)
+	if err != nil {
+		return fmt.Sprintf("Diff %s: %v", path, err)
+	}
+	if len(changes) == 0 {
+		return "No differences from " + path
+	}
+	a.showFileDiff(path, unified)
+	return ""
+}
+
+// fileChanges lists how path differs from the store: Added keys are only
+// in the file, Removed keys only in the store, and Old is always the
+// store's value.
+func (a *App) fileChanges(path string) ([]snapshot.Change, error) {
+	items, err := a.Store.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := make(map[string]string, len(items))
+	for _, it := range items {
+		file[it.Key] = it.Value
+	}
+	return snapshot.Diff(a.Store.Vars(), file), nil
+}
+
+// acceptChanges makes the store match the file for each change, in one
+// undoable step, or for none of them when one is refused.
+func (a *App) acceptChanges(changes []snapshot.Change) error {
+	a.Store.Begin()
+	for _, c := range changes {
+		if c.Kind == snapshot.Removed {
+			a.Store.Delete(c.Key)
+		} else if err := a.Store.Upsert(c.Key, c.New); err != nil {
+			a.Store.Rollback()
+			return fmt.Errorf("%s: %w", c.Key, err)
+		}
+	}
+	_ = a.Store.Commit()
+	a.renderTable()
+	return nil
+}
+
+// showFileDiff lists the differences between the store and path, side by
+// side or as unified -/+ lines. a takes the file's side of the selected
+// change into the store, A takes all of them, t switches the layout and
+// Enter jumps to the key.
+func (a *App) showFileDiff(path string, unified bool) {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	var changes []snapshot.Change
+	var rows []int // change index of each row after the header
+
+	fill := func() error {
+		var err error
+		if changes, err = a.fileChanges(path); err != nil {
+			return err
+		}
+		table.Clear()
+		rows = rows[:0]
+		if unified {
+			table.SetCell(0, 0, headerCell("--- store  +++ "+path))
+			line := func(i int, mark rune, color tcell.Color, key, value string) {
+				rows = append(rows, i)
+				text := string(mark) + " " + tview.Escape(key+"="+value)
+				table.SetCell(len(rows), 0, tview.NewTableCell(text).SetTextColor(color).SetExpansion(1))
+			}
+			for i, c := range changes {
+				if c.Kind != snapshot.Added {
+					line(i, markRemoved, a.pal.Removed, c.Key, c.Old)
+				}
+				if c.Kind != snapshot.Removed {
+					line(i, markAdded, a.pal.Added, c.Key, c.New)
+				}
+			}
+		} else {
+			table.SetCell(0, 0, headerCell("KEY"))
+			table.SetCell(0, 1, headerCell("STORE"))
+			table.SetCell(0, 2, headerCell("FILE"))
+			for i, c := range changes {
+				rows = append(rows, i)
+				mark, color := a.changeMark(c.Kind == snapshot.Added, c.Kind == snapshot.Removed)
+				file := tview.Escape(c.New)
+				if c.Kind == snapshot.Changed {
+					file = a.valueDiff(c.Old, c.New)
+				}
+				table.SetCell(i+1, 0, tview.NewTableCell(mark+c.Key).SetTextColor(color).SetExpansion(1))
+				table.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(c.Old)).SetExpansion(2).SetMaxWidth(50))
+				table.SetCell(i+1, 2, tview.NewTableCell(file).SetExpansion(2).SetMaxWidth(50))
+			}
+		}
+		table.SetTitle(fmt.Sprintf(" %d differences from %s — a accept, A accept all, t layout, Enter go to key, ESC close ",
+			len(changes), path))
+		return nil
+	}
+	fill()
+	table.Select(1, 0)
+
+	table.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	selected := func() (snapshot.Change, bool) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(rows) {
+			return snapshot.Change{}, false
+		}
+		return changes[rows[row-1]], true
+	}
+	accept := func(cs ...snapshot.Change) {
+		row, _ := table.GetSelection()
+		if err := a.acceptChanges(cs); err != nil {
+			a.updateStatusInline(fmt.Sprintf("Not accepted: %v", err))
+			return
+		}
+		switch err := fill(); {
+		case err != nil:
+			a.closeModal()
+			a.updateStatusInline(fmt.Sprintf("Diff %s: %v", path, err))
+			return
+		case len(changes) == 0:
+			a.closeModal()
+			a.updateStatusInline("Store now matches " + path)
+			return
+		}
+		table.Select(min(row, len(rows)), 0)
+		a.updateStatusInline(fmt.Sprintf("Took %d from %s; %d differences left", len(cs), path, len(changes)))
+	}
+	table.SetSelectedFunc(func(int, int) {
+		if c, ok := selected(); ok && c.Kind != snapshot.Added {
+			a.closeModal()
+			a.selectKey(c.Key)
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Rune() {
+		case 'a':
+			if c, ok := selected(); ok {
+				accept(c)
+			}
+			return nil
+		case 'A':
+			accept(changes...)
+			return nil
+		case 't':
+			unified = !unified
+			fill()
+			table.Select(1, 0)
+			return nil
+		}
+		return ev
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 130, 26), true, true)
+	a.App.SetFocus(table)
+}
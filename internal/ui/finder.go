@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/fuzzy"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	finderLimit    = 200 // results listed
+	finderValueLen = 60  // value runes shown per row
+	finderKeyBonus = 4   // a key match outranks an equal value match
+)
+
+// finderHit is one ranked key in the finder.
+type finderHit struct {
+	key            string
+	score          int
+	keyPos, valPos []int
+}
+
+// rankKeys ranks every key against q, and values too when withValues is
+// set. Each key appears once, under its better match.
+func (a *App) rankKeys(q string, withValues bool) []finderHit {
+	keys := a.Store.AllKeys()
+	hits := make(map[int]*finderHit)
+	for _, r := range fuzzy.Rank(q, keys) {
+		hits[r.Index] = &finderHit{key: keys[r.Index], score: r.Score + finderKeyBonus, keyPos: r.Pos}
+	}
+	if withValues && q != "" {
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			it, _ := a.Store.Get(k)
+			values[i] = it.Value
+		}
+		for _, r := range fuzzy.Rank(q, values) {
+			if h, ok := hits[r.Index]; ok && h.score >= r.Score {
+				continue
+			}
+			hits[r.Index] = &finderHit{key: keys[r.Index], score: r.Score, valPos: r.Pos}
+		}
+	}
+
+	out := make([]finderHit, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, *h)
+	}
+	slices.SortFunc(out, func(x, y finderHit) int {
+		if x.score != y.score {
+			return y.score - x.score
+		}
+		if len(x.key) != len(y.key) {
+			return len(x.key) - len(y.key)
+		}
+		return strings.Compare(x.key, y.key)
+	})
+	return out[:min(len(out), finderLimit)]
+}
+
+// openFinder opens the fuzzy key finder. Typing ranks keys live; Tab
+// toggles matching values too; Enter jumps to the key, C-e edits it.
+func (a *App) openFinder() {
+	input := tview.NewInputField().SetLabel("> ")
+	list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(list, 0, 1, false)
+	layout.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+
+	withValues := false
+	var hits []finderHit
+	fill := func() {
+		scope := "keys"
+		if withValues {
+			scope = "keys and values"
+		}
+		hits = a.rankKeys(input.GetText(), withValues)
+		layout.SetTitle(fmt.Sprintf(" Find %s (%d) — Enter jump, C-e edit, Tab values, ESC cancel ", scope, len(hits)))
+		list.Clear()
+		width := 0
+		for _, h := range hits {
+			width = max(width, len(h.key))
+		}
+		for _, h := range hits {
+			it, _ := a.Store.Get(h.key)
+			val := []rune(it.Value)
+			if len(val) > finderValueLen {
+				val = append(val[:finderValueLen-1], '…')
+			}
+			line := highlight([]rune(h.key), h.keyPos, "-") +
+				strings.Repeat(" ", width-len(h.key)+2) +
+				"[gray]" + highlight(val, h.valPos, "gray") + "[-]"
+			list.AddItem(line, "", 0, nil)
+		}
+	}
+	fill()
+
+	done := func() {
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+	}
+	jump := func() (string, bool) {
+		i := list.GetCurrentItem()
+		if i < 0 || i >= len(hits) {
+			return "", false
+		}
+		key := hits[i].key
+		done()
+		// A filter that hides the key would leave nothing to select.
+		if !slices.Contains(a.Store.ListKeys(), key) {
+			a.applySearch("")
+		}
+		a.selectKey(key)
+		return key, true
+	}
+
+	input.SetChangedFunc(func(string) { fill() })
+	input.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			done()
+		case tcell.KeyEnter:
+			if key, ok := jump(); ok {
+				a.updateStatusInline("Jumped to " + key)
+			}
+		case tcell.KeyCtrlE:
+			if _, ok := jump(); ok {
+				a.openEditForm(false)
+			}
+		case tcell.KeyTab:
+			withValues = !withValues
+			fill()
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyCtrlN, tcell.KeyCtrlP:
+			// Move through the results while the input keeps focus.
+			switch ev.Key() {
+			case tcell.KeyCtrlN:
+				ev = tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case tcell.KeyCtrlP:
+				ev = tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+			if h := list.InputHandler(); h != nil {
+				h(ev, func(p tview.Primitive) {})
+			}
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 100, 24), true, true)
+	a.App.SetFocus(input)
+	a.refreshStatus()
+}
+
+// highlight renders r with the runes at pos in yellow, returning to base
+// after each run.
+func highlight(r []rune, pos []int, base string) string {
+	var b strings.Builder
+	flush := func(start, end int, hit bool) {
+		if start >= end {
+			return
+		}
+		seg := tview.Escape(string(r[start:end]))
+		if hit {
+			seg = "[yellow]" + seg + "[" + base + "]"
+		}
+		b.WriteString(seg)
+	}
+	start, p := 0, 0
+	for start < len(r) {
+		hit := p < len(pos) && pos[p] == start
+		end := start + 1
+		if hit {
+			p++
+			for p < len(pos) && pos[p] == end && end < len(r) {
+				p++
+				end++
+			}
+		} else {
+			for end < len(r) && (p >= len(pos) || pos[p] != end) {
+				end++
+			}
+		}
+		flush(start, end, hit)
+		start = end
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/config"
+	"github.com/rivo/tview"
+)
+
+// rowRule is a compiled config.Highlight.
+type rowRule struct {
+	key, value *regexp.Regexp // nil matches anything
+	fg, bg     tcell.Color    // 0 leaves the color alone
+	attrs      tcell.AttrMask
+	keyCell    bool
+	valueCell  bool
+}
+
+// colorAliases maps the terminal names of colors tcell knows by their W3C
+// names.
+var colorAliases = map[string]string{"cyan": "aqua", "magenta": "fuchsia"}
+
+var highlightAttrs = map[string]tcell.AttrMask{
+	"bold":      tcell.AttrBold,
+	"dim":       tcell.AttrDim,
+	"italic":    tcell.AttrItalic,
+	"underline": tcell.AttrUnderline,
+	"blink":     tcell.AttrBlink,
+	"reverse":   tcell.AttrReverse,
+}
+
+// compileHighlights checks and compiles the config's highlight rules,
+// leaving out and reporting the ones that are wrong.
+func compileHighlights(rules []config.Highlight) ([]rowRule, []string) {
+	var out []rowRule
+	var errs []string
+	for i, r := range rules {
+		h, err := compileHighlight(r)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Highlight rule %d: %v", i+1, err))
+			continue
+		}
+		out = append(out, h)
+	}
+	return out, errs
+}
+
+func compileHighlight(r config.Highlight) (rowRule, error) {
+	var h rowRule
+	var err error
+	if r.Key != "" {
+		if h.key, err = regexp.Compile(r.Key); err != nil {
+			return h, fmt.Errorf("key: %v", err)
+		}
+	}
+	if r.Value != "" {
+		if h.value, err = regexp.Compile(r.Value); err != nil {
+			return h, fmt.Errorf("value: %v", err)
+		}
+	}
+	for _, c := range []struct {
+		name string
+		to   *tcell.Color
+	}{{r.Color, &h.fg}, {r.Background, &h.bg}} {
+		if c.name == "" {
+			continue
+		}
+		if *c.to = tcell.GetColor(cmp.Or(colorAliases[c.name], c.name)); *c.to == tcell.ColorDefault {
+			return h, fmt.Errorf("unknown color %q", c.name)
+		}
+	}
+	for _, s := range strings.Split(r.Style, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		attr, ok := highlightAttrs[s]
+		if !ok {
+			return h, fmt.Errorf("unknown style %q", s)
+		}
+		h.attrs |= attr
+	}
+	switch r.Cell {
+	case "":
+		h.keyCell, h.valueCell = true, true
+	case "key":
+		h.keyCell = true
+	case "value":
+		h.valueCell = true
+	default:
+		return h, fmt.Errorf("cell %q is not key or value", r.Cell)
+	}
+	return h, nil
+}
+
+// highlightCells applies the highlight rules matching key and value to
+// their cells. Without a palette only the styles are applied.
+func (a *App) highlightCells(key, value string, keyCell, valCell *tview.TableCell) {
+	for _, h := range a.highlights {
+		if h.key != nil && !h.key.MatchString(key) || h.value != nil && !h.value.MatchString(value) {
+			continue
+		}
+		for _, c := range []struct {
+			cell *tview.TableCell
+			on   bool
+		}{{keyCell, h.keyCell}, {valCell, h.valueCell}} {
+			if !c.on {
+				continue
+			}
+			if a.paletteName != paletteNone {
+				if h.fg != 0 {
+					c.cell.SetTextColor(h.fg)
+				}
+				if h.bg != 0 {
+					c.cell.SetBackgroundColor(h.bg)
+				}
+			}
+			addAttrs(c.cell, h.attrs)
+		}
+	}
+}
+
+// addAttrs adds attrs to those cell is drawn with.
+func addAttrs(cell *tview.TableCell, attrs tcell.AttrMask) {
+	if attrs != 0 {
+		_, _, has := cell.Style.Decompose()
+		cell.SetAttributes(has | attrs)
+	}
+}
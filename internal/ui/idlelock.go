@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const pageLock = "lock"
+
+// idleCheck is how often the idle timer is looked at.
+const idleCheck = 10 * time.Second
+
+// watchIdle locks the screen once no key has been pressed for a.idle. It
+// runs for the life of the application.
+func (a *App) watchIdle() {
+	for range time.Tick(idleCheck) {
+		a.App.QueueUpdateDraw(func() {
+			if a.idle > 0 && !a.locked && time.Since(a.lastInput) >= a.idle {
+				a.lockScreen()
+			}
+		})
+	}
+}
+
+// lockCommand handles ":lock", which blanks the screen now.
+func (a *App) lockCommand(args []string) string {
+	if len(args) > 0 {
+		return "Usage: :lock"
+	}
+	a.lockScreen()
+	return ""
+}
+
+// lockScreen covers everything, modals included, until a key is pressed
+// or, when the config names one, the passphrase is typed.
+func (a *App) lockScreen() {
+	if a.locked {
+		return
+	}
+	a.locked = true
+	a.lockedFocus = a.App.GetFocus()
+
+	var prompt tview.Primitive
+	if a.lockHash == nil {
+		text := tview.NewTextView().
+			SetTextAlign(tview.AlignCenter).
+			SetText("Envoy is locked.\n\nPress any key to unlock.")
+		text.SetInputCapture(func(*tcell.EventKey) *tcell.EventKey {
+			a.unlockScreen()
+			return nil
+		})
+		prompt = text
+	} else {
+		field := tview.NewInputField().
+			SetLabel("Passphrase ").
+			SetMaskCharacter('*').
+			SetFieldWidth(30)
+		field.SetDoneFunc(func(key tcell.Key) {
+			if key != tcell.KeyEnter {
+				return
+			}
+			if a.passphraseOK(field.GetText()) {
+				a.unlockScreen()
+				return
+			}
+			field.SetText("")
+			field.SetLabel("Wrong passphrase ")
+		})
+		prompt = field
+	}
+
+	mid := tview.NewFlex().
+		AddItem(tview.NewBox(), 0, 1, false).
+		AddItem(prompt, 50, 0, true).
+		AddItem(tview.NewBox(), 0, 1, false)
+	screen := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewBox(), 0, 1, false).
+		AddItem(mid, 3, 0, true).
+		AddItem(tview.NewBox(), 0, 1, false)
+	a.Pages.AddPage(pageLock, screen, true, true)
+	a.App.SetFocus(prompt)
+	a.say("Envoy is locked")
+}
+
+func (a *App) unlockScreen() {
+	a.Pages.RemovePage(pageLock)
+	a.locked = false
+	a.lastInput = time.Now()
+	if a.lockedFocus != nil {
+		a.App.SetFocus(a.lockedFocus)
+	}
+	a.say("Unlocked")
+}
+
+// lockHash decodes the configured passphrase digest. A malformed one is an
+// error rather than a lock nothing opens.
+func lockHash(digest string) ([]byte, error) {
+	if digest == "" {
+		return nil, nil
+	}
+	h, err := hex.DecodeString(strings.TrimSpace(digest))
+	if err != nil || len(h) != sha256.Size {
+		return nil, fmt.Errorf("lock passphrase_sha256 is not a SHA-256 hex digest; any key unlocks")
+	}
+	return h, nil
+}
+
+// passphraseOK compares the SHA-256 digest of p with the configured one in
+// constant time.
+func (a *App) passphraseOK(p string) bool {
+	got := sha256.Sum256([]byte(p))
+	return subtle.ConstantTimeCompare(got[:], a.lockHash) == 1
+}
+
+func (a *App) idleOption() string {
+	return fmt.Sprintf("idle=%d", int(a.idle/time.Minute))
+}
+
+// setIdle handles ":set idle=N", minutes without a key press before the
+// screen locks; 0 turns the timer off.
+func (a *App) setIdle(value string) string {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Sprintf("Invalid idle %q: minutes, 0 for never", value)
+	}
+	a.idle = time.Duration(n) * time.Minute
+	a.lastInput = time.Now()
+	return a.idleOption()
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+func (a *App) toggleInspector() {
+	a.inspecting = !a.inspecting
+	a.layoutChanged()
+	a.updateInspector()
+}
+
+func (a *App) selectedKey() (string, bool) {
+	item, ok := a.Store.GetByIndex(a.selRow - 1)
+	return item.Key, ok
+}
+
+func (a *App) updateInspector() {
+	if !a.inspecting {
+		return
+	}
+	key, ok := a.selectedKey()
+	if !ok {
+		a.Inspector.SetText("")
+		return
+	}
+	a.Inspector.SetText(a.describe(key)).ScrollToBeginning()
+}
+
+// describe renders what is known about key: its value, where it came from
+// and, when the explain database has an entry, what it is for.
+func (a *App) describe(key string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[::b]%s[::-]\n", tview.Escape(key))
+	if it, ok := a.Store.Get(key); ok {
+		fmt.Fprintf(&b, "%s\n", tview.Escape(a.shownValue(key, it.Value)))
+		if v, missing := a.Store.Resolve(it.Value); len(missing) > 0 {
+			fmt.Fprintf(&b, "[red]undefined: @%s[-]\n", strings.Join(missing, ", @"))
+		} else if v != it.Value && !a.masked(key, it.Value) {
+			fmt.Fprintf(&b, "[green]→ %s[-]\n", tview.Escape(v))
+		}
+		var tags []string
+		if k := env.Infer(key, it.Value); k != env.KindNone && k != env.KindSecret {
+			tags = append(tags, k.String())
+		}
+		if it.Modified {
+			tags = append(tags, "[yellow]modified[-]")
+		}
+		if env.LooksSecret(key, it.Value) {
+			tags = append(tags, "probable secret")
+		}
+		if env.LooksLikePath(key, it.Value) && !env.PathExists(it.Value) {
+			tags = append(tags, "[red]path missing[-]")
+		}
+		if len(tags) > 0 {
+			fmt.Fprintf(&b, "\n%s\n", strings.Join(tags, ", "))
+		}
+		b.WriteString(a.describeProbe(key))
+	}
+	if p, ok := a.Store.Provenance(key); ok {
+		fmt.Fprintf(&b, "\nFrom %s", tview.Escape(p.Source))
+		if !p.Fetched.IsZero() {
+			fmt.Fprintf(&b, ", fetched %s (%s ago)", p.Fetched.Local().Format(time.DateTime), age(time.Since(p.Fetched)))
+		}
+		b.WriteString("\n")
+	}
+	if t, ok := a.Store.Expiry(key); ok {
+		fmt.Fprintf(&b, "Expires %s (%s)\n", t.Format(time.DateOnly), expiresIn(t))
+	}
+	if layers := a.Store.Layers(key); len(layers) > 1 {
+		b.WriteString("\n[::b]Layers[::-] (lowest first)\n")
+		for _, l := range layers {
+			fmt.Fprintf(&b, "  %s: %s\n", tview.Escape(l.Source), tview.Escape(l.Value))
+		}
+	}
+
+	e, ok := a.explain.Lookup(key)
+	if !ok {
+		b.WriteString("\n[gray]No description. Add one under \"explain\" in the config.[-]\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n%s\n", tview.Escape(e.Summary))
+	for _, n := range e.Notes {
+		fmt.Fprintf(&b, "  • %s\n", tview.Escape(n))
+	}
+	if len(e.Links) > 0 {
+		b.WriteString("\n")
+		for _, l := range e.Links {
+			fmt.Fprintf(&b, "[blue]%s[-]\n", tview.Escape(l))
+		}
+	}
+	return b.String()
+}
+
+// age renders d coarsely: minutes, hours or days.
+func age(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// showExplain opens the description of key, or of the selected key.
+func (a *App) showExplain(key string) string {
+	if key == "" {
+		var ok bool
+		if key, ok = a.selectedKey(); !ok {
+			return "No key selected"
+		}
+	}
+	a.showText("Explain "+key, a.describe(key))
+	return ""
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/journal"
+)
+
+// openJournal starts journaling the process buffer's mutations, and those
+// of every file buffer opened after it. Journals left by sessions that are
+// no longer running are kept until they are recovered.
+func (a *App) openJournal(recover bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	a.journaling = true
+	a.journalKey = journal.Key(a.Creds)
+	a.recoverOnLoad = recover
+	a.journalBuffer(a.buffers[0], cwd)
+}
+
+// journalBuffer journals b's mutations under name, its file path or the
+// working directory, in a journal of this session's own, and finds those
+// earlier sessions left for it. File buffers whose journals are found are
+// recovered at once with -recover.
+func (a *App) journalBuffer(b *buffer, name string) {
+	if !a.journaling {
+		return
+	}
+	id := journal.ID(name)
+	b.orphans = journal.Orphans(id, a.journalKey)
+	j, err := journal.Open(id, a.journalKey)
+	if err != nil {
+		slog.Warn("journal disabled", "buffer", b.name, "err", err)
+		return
+	}
+	b.journal = j
+	a.hookJournal(b)
+}
+
+// hookJournal appends the mutations of b's store to b's journal. It is
+// called again whenever the buffer is given a new store.
+func (a *App) hookJournal(b *buffer) {
+	j := b.journal
+	b.store.OnChange(func(ops []env.Op) {
+		if a.loadingSource {
+			return
+		}
+		if err := j.Append(ops); err != nil {
+			slog.Error("journal append failed", "buffer", b.name, "err", err)
+		}
+	})
+}
+
+// orphanNote recovers b's orphaned journals with -recover, or else returns
+// how to, or "" when there are none.
+func (a *App) orphanNote(b *buffer) string {
+	if len(b.orphans) == 0 {
+		return ""
+	}
+	if a.recoverOnLoad {
+		return a.recoverBuffer(b)
+	}
+	return "Unsaved changes from an earlier session were found: :recover to replay them"
+}
+
+// afterLoad runs once every startup source has been applied.
+func (a *App) afterLoad() {
+	if a.wrap != nil && a.wrap.runs == 0 {
+		a.updateStatusInline(a.launchWrapped())
+	}
+	if note := a.orphanNote(a.buffers[0]); note != "" {
+		a.updateStatusInline(note)
+	}
+}
+
+// recoverJournal replays the changes journaled by crashed sessions onto
+// the buffer shown.
+func (a *App) recoverJournal() string {
+	return a.recoverBuffer(a.buf())
+}
+
+// recoverBuffer replays b's orphaned journals onto it. The changes are
+// journaled again as this session's, and the old journals deleted.
+func (a *App) recoverBuffer(b *buffer) string {
+	if len(b.orphans) == 0 {
+		return "Nothing to recover"
+	}
+	var entries []journal.Entry
+	for _, j := range b.orphans {
+		es, err := j.Entries()
+		if err != nil {
+			return fmt.Sprintf("Recover failed: %v", err)
+		}
+		entries = append(entries, es...)
+	}
+	n, withheld := 0, 0
+	s := b.store
+	s.Begin()
+	for _, e := range entries {
+		switch {
+		case e.Expires != nil:
+			s.SetExpiry(e.Key, *e.Expires)
+		case e.Delete:
+			s.Delete(e.Key)
+		case e.Withheld:
+			withheld++
+			continue
+		default:
+			s.Upsert(e.Key, e.Value)
+		}
+		n++
+	}
+	_ = s.Commit()
+	for _, j := range b.orphans {
+		slog.Info("recovered journal", "path", j.Path())
+		_ = j.Remove()
+	}
+	b.orphans = nil
+	a.renderTable()
+	msg := fmt.Sprintf("Recovered %d changes", n)
+	if withheld > 0 {
+		msg += fmt.Sprintf("; %d secret values were not journaled and are lost", withheld)
+	}
+	return msg
+}
+
+// journalSaved marks the shown buffer's journaled changes as persisted
+// after a write.
+func (a *App) journalSaved() {
+	if a.tutor != nil {
+		return
+	}
+	a.clearJournal(a.buf())
+}
+
+// clearJournal empties b's journal once its changes are written or
+// discarded.
+func (a *App) clearJournal(b *buffer) {
+	if b.journal == nil {
+		return
+	}
+	if err := b.journal.Reset(); err != nil {
+		slog.Warn("journal reset failed", "buffer", b.name, "err", err)
+	}
+}
+
+// closeJournal deletes this session's journals on a clean exit and keeps
+// them otherwise. Unrecovered journals of earlier sessions are kept.
+func (a *App) closeJournal(clean bool) {
+	for _, b := range a.buffers {
+		if b.journal != nil {
+			if clean {
+				_ = b.journal.Remove()
+			} else {
+				_ = b.journal.Close()
+			}
+		}
+		for _, j := range b.orphans {
+			_ = j.Close()
+		}
+	}
+}
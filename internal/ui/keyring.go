@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// keyringService is the keychain service :keyring stores entries under
+// when given no name.
+const keyringService = "envoy"
+
+// keyringCommand handles ":keyring [service/account]": it moves the
+// selected key's value into the OS keychain and leaves a keyring://
+// reference in its place, which :w resolves on export.
+func (a *App) keyringCommand(args []string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	it, _ := a.Store.Get(key)
+	if cred.IsRef(it.Value) {
+		return fmt.Sprintf("%s is already a secret reference", key)
+	}
+	if it.Value == "" {
+		return fmt.Sprintf("%s is empty", key)
+	}
+	service, account := keyringService, key
+	if len(args) > 0 {
+		s, acc, ok := cred.ParseRef(cred.RefScheme + strings.TrimPrefix(args[0], cred.RefScheme))
+		if !ok {
+			return "Usage: :keyring [service/account]"
+		}
+		service, account = s, acc
+	}
+
+	var ref string
+	a.runRemote("Storing "+key+" in the keychain", func(context.Context) error {
+		var err error
+		ref, err = cred.StoreRef(service, account, it.Value)
+		return err
+	}, func(err error) {
+		if err != nil {
+			slog.Error("keyring store failed", "key", key, "err", err)
+			a.updateStatusInline(remoteError("Keyring", err))
+			return
+		}
+		if err := a.Store.Upsert(key, ref); err != nil {
+			a.updateStatusInline("Not saved: " + err.Error())
+			return
+		}
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Stored %s in the keychain as %s", key, ref))
+	})
+	return ""
+}
+
+// resolveRefs replaces the keyring:// and op:// references in vars with
+// the secrets they name, for a child process.
+func resolveRefs(vars map[string]string) error {
+	for k, v := range vars {
+		if !cred.IsRef(v) {
+			continue
+		}
+		s, err := cred.ResolveRef(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		vars[k] = s
+	}
+	return nil
+}
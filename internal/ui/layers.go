@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// layerSummary describes shadowing, case and expiry problems left by
+// loading several layers, or returns "".
+func (a *App) layerSummary() string {
+	msg := ""
+	if n := len(a.Store.Overrides()); n > 0 {
+		msg = fmt.Sprintf("%d keys shadowed across layers (:overrides)", n)
+	}
+	if warn := a.caseWarning() + a.expiryWarning(); warn != "" {
+		msg = strings.TrimPrefix(msg+warn, "; ")
+	}
+	return msg
+}
+
+// showOverrides lists keys whose value differs between layers. Each layer is
+// one row; p or Enter promotes that layer's value to the current one.
+func (a *App) showOverrides() string {
+	overrides := a.Store.Overrides()
+	if len(overrides) == 0 {
+		return "No keys shadowed across layers"
+	}
+
+	type ref struct {
+		key   string
+		layer env.Layer
+	}
+	var rows []ref
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("SOURCE"))
+	table.SetCell(0, 2, headerCell("VALUE"))
+
+	fill := func() {
+		rows = rows[:0]
+		for r := table.GetRowCount() - 1; r > 0; r-- {
+			table.RemoveRow(r)
+		}
+		for _, o := range a.Store.Overrides() {
+			cur, _ := a.Store.Get(o.Key)
+			for i, l := range o.Chain {
+				row := len(rows) + 1
+				key := ""
+				if i == 0 {
+					key = o.Key
+				}
+				mark := "  "
+				color := tcell.ColorDefault
+				value := a.valueDiff(cur.Value, l.Value)
+				if l.Value == cur.Value {
+					mark = "* "
+					color = tcell.ColorGreen
+					value = tview.Escape(l.Value)
+				}
+				table.SetCell(row, 0, tview.NewTableCell(key).SetExpansion(1))
+				table.SetCell(row, 1, tview.NewTableCell(mark+l.Source).SetTextColor(color))
+				table.SetCell(row, 2, tview.NewTableCell(value).SetExpansion(2).SetTextColor(color))
+				rows = append(rows, ref{key: o.Key, layer: l})
+			}
+		}
+	}
+	fill()
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(" Overrides (* = current; others diffed against it) — p/Enter promote, ESC close ").
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+			a.renderTable()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() != tcell.KeyEnter && ev.Rune() != 'p' {
+			return ev
+		}
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(rows) {
+			return nil
+		}
+		r := rows[row-1]
+		a.Store.Upsert(r.key, r.layer.Value)
+		fill()
+		table.Select(row, 0)
+		a.updateStatusInline(fmt.Sprintf("Promoted %s from %s", r.key, r.layer.Source))
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/session"
+	"github.com/rivo/tview"
+)
+
+// sideShare is a side pane's share of the body next to the table's 2 until
+// the panes are resized.
+const sideShare = 1
+
+// Side pane width limits, in columns, when resizing.
+const (
+	minSideWidth  = 12
+	minTableWidth = 20
+)
+
+// windowPrefix is the pending-op name of Ctrl-W.
+const windowPrefix = "^W"
+
+// sidePanes returns the panes shown next to the table, in order.
+func (a *App) sidePanes() []tview.Primitive {
+	var out []tview.Primitive
+	if a.inspecting {
+		out = append(out, a.Inspector)
+	}
+	if a.tutor != nil {
+		out = append(out, a.tutor.pane)
+	}
+	if a.wrap != nil {
+		out = append(out, a.wrap.pane)
+	}
+	return out
+}
+
+// layoutBody rebuilds the body from the table, the side panes and the
+// layout's width and zoom.
+func (a *App) layoutBody() {
+	side := a.sidePanes()
+	zoom := a.layout.Zoom
+	if len(side) == 0 {
+		zoom = ""
+	}
+	a.Body.Clear()
+	if zoom != session.ZoomSide {
+		a.Body.AddItem(a.tablePane, 0, 2, true)
+	}
+	if zoom != session.ZoomTable {
+		for _, p := range side {
+			switch {
+			case zoom == session.ZoomSide:
+				a.Body.AddItem(p, 0, 1, false)
+			case a.layout.Side > 0:
+				a.Body.AddItem(p, a.layout.Side, 0, false)
+			default:
+				a.Body.AddItem(p, 0, sideShare, false)
+			}
+		}
+	}
+
+	// Keep focus on a pane that is still shown.
+	f := a.App.GetFocus()
+	switch {
+	case zoom == session.ZoomSide && !slices.Contains(side, f):
+		a.App.SetFocus(side[0])
+	case zoom == session.ZoomTable && slices.Contains(side, f):
+		a.App.SetFocus(a.Table)
+	case f == a.Inspector && !a.inspecting:
+		a.App.SetFocus(a.Table)
+	}
+}
+
+func (a *App) sideFocused() bool {
+	return slices.Contains(a.sidePanes(), a.App.GetFocus())
+}
+
+// windowKeys handles Ctrl-W and the key after it for the table and the side
+// panes. It reports whether ev was consumed.
+func (a *App) windowKeys(ev *tcell.EventKey) bool {
+	if a.Vim.PendingOp == windowPrefix {
+		n := a.Vim.countOrDefault()
+		a.Vim.resetPrefix()
+		a.updateStatusInline(a.windowCommand(ev, n))
+		return true
+	}
+	if ev.Key() != tcell.KeyCtrlW {
+		return false
+	}
+	a.Vim.PendingOp = windowPrefix
+	a.updateStatusInline("-- " + a.Vim.prefixText())
+	a.scheduleWhichKey(windowPrefix)
+	return true
+}
+
+// windowCommand runs the Ctrl-W command ev, n times where that applies.
+func (a *App) windowCommand(ev *tcell.EventKey, n int) string {
+	side := a.sidePanes()
+	if len(side) == 0 {
+		return "No side pane (K shows the inspector)"
+	}
+	if ev.Key() == tcell.KeyCtrlW {
+		return a.cyclePane()
+	}
+	switch ev.Rune() {
+	case 'w', 'h', 'l':
+		return a.cyclePane()
+	case '>':
+		return a.resizeSide(n)
+	case '<':
+		return a.resizeSide(-n)
+	case '=':
+		a.layout.Side = 0
+		a.layoutChanged()
+		return "Panes reset"
+	case 'z', 'o':
+		if a.layout.Zoom != "" {
+			a.layout.Zoom = ""
+		} else if a.sideFocused() {
+			a.layout.Zoom = session.ZoomSide
+		} else {
+			a.layout.Zoom = session.ZoomTable
+		}
+		a.layoutChanged()
+		if a.layout.Zoom == "" {
+			return "Zoom off"
+		}
+		return "Zoomed (Ctrl-W z to restore)"
+	}
+	return ""
+}
+
+// resizeSide widens the focused pane by n columns: the side panes when one
+// has focus, the table otherwise.
+func (a *App) resizeSide(n int) string {
+	if a.layout.Zoom != "" {
+		return "Zoomed: Ctrl-W z first"
+	}
+	if !a.sideFocused() {
+		n = -n
+	}
+	width := a.layout.Side
+	if width == 0 {
+		_, _, width, _ = a.sidePanes()[0].GetRect()
+	}
+	_, _, total, _ := a.Body.GetRect()
+	limit := max(minSideWidth, (total-minTableWidth)/len(a.sidePanes()))
+	a.layout.Side = min(max(width+n, minSideWidth), limit)
+	a.layoutChanged()
+	return fmt.Sprintf("Side pane width %d", a.layout.Side)
+}
+
+// cyclePane moves focus between the table and the side panes.
+func (a *App) cyclePane() string {
+	if a.layout.Zoom != "" {
+		return "Zoomed: Ctrl-W z first"
+	}
+	// The table, then each side pane, then back to the table.
+	side := a.sidePanes()
+	i := slices.Index(side, a.App.GetFocus())
+	if i+1 == len(side) {
+		a.App.SetFocus(a.Table)
+		return ""
+	}
+	a.App.SetFocus(side[i+1])
+	return "Pane focused: Esc returns to the table"
+}
+
+// paneInput is the input capture of the side panes: Ctrl-W commands, and
+// Esc back to the table. Other keys scroll the pane.
+func (a *App) paneInput(ev *tcell.EventKey) *tcell.EventKey {
+	a.whichKeyInput()
+	if a.windowKeys(ev) {
+		return nil
+	}
+	if ev.Key() == tcell.KeyF5 && a.wrap != nil {
+		a.updateStatusInline(a.launchWrapped())
+		return nil
+	}
+	if ev.Key() == tcell.KeyEsc {
+		if a.layout.Zoom == session.ZoomSide {
+			a.layout.Zoom = ""
+			a.layoutChanged()
+		}
+		a.App.SetFocus(a.Table)
+		a.refreshStatus()
+		return nil
+	}
+	return ev
+}
+
+// layoutChanged applies the layout and saves it to the session file.
+func (a *App) layoutChanged() {
+	a.layoutBody()
+	a.saveSession()
+}
+
+func (a *App) saveSession() {
+	if a.sessionPath == "" {
+		return
+	}
+	a.layout.Inspector = a.inspecting
+	if err := session.Save(a.sessionPath, session.Session{Layout: a.layout, Recent: a.recent, Searches: a.searches}); err != nil {
+		slog.Warn("save session", "path", a.sessionPath, "err", err)
+	}
+}
+
+// loadSession restores the layout saved by an earlier run and keeps saving
+// changes to path.
+func (a *App) loadSession(path string) {
+	s, err := session.Load(path)
+	if err != nil {
+		slog.Warn("load session", "path", path, "err", err)
+	}
+	a.sessionPath = path
+	a.layout, a.recent, a.searches = s.Layout, s.Recent, s.Searches
+	a.inspecting = s.Layout.Inspector
+	a.layoutBody()
+	a.updateInspector()
+}
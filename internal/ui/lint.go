@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivethorn/envoy/internal/lint"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var severityColor = map[lint.Severity]tcell.Color{
+	lint.Error:   tcell.ColorRed,
+	lint.Warning: tcell.ColorOrange,
+	lint.Info:    tcell.ColorGray,
+}
+
+// showLint checks every variable against the built-in, user and project
+// rules and lists the findings. Enter jumps to the key.
+func (a *App) showLint() string {
+	rs, err := lint.Default()
+	if err != nil {
+		return fmt.Sprintf("Lint failed: %v", err)
+	}
+	findings := rs.Check(a.Store.Vars())
+	if len(findings) == 0 {
+		return fmt.Sprintf("Lint: no findings (%d rules)", rs.Len())
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	for i, h := range []string{"SEVERITY", "KEY", "RULE", "MESSAGE"} {
+		table.SetCell(0, i, headerCell(h))
+	}
+	for i, f := range findings {
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(string(f.Severity)).SetTextColor(severityColor[f.Severity]))
+		table.SetCell(row, 1, tview.NewTableCell(tview.Escape(f.Key)).SetExpansion(1))
+		table.SetCell(row, 2, tview.NewTableCell(tview.Escape(f.Rule)).SetExpansion(1))
+		table.SetCell(row, 3, tview.NewTableCell(tview.Escape(f.Message)).SetExpansion(3))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Lint — %d findings, %d errors; Enter go to key, ESC close ", len(findings), lint.Errors(findings))).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(findings) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(findings[row-1].Key)
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 120, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
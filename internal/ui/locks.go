@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/rivethorn/envoy/internal/lock"
+
+	"github.com/rivo/tview"
+)
+
+// bindFile locks path for this session before then runs. When another
+// session holds it the user chooses to open it read-only, take the lock
+// over, or cancel (then is not run).
+func (a *App) bindFile(path string, then func()) {
+	a.bindFileOr(path, then, nil)
+}
+
+// bindFileOr is bindFile calling cancelled, when not nil, if the user
+// cancels.
+func (a *App) bindFileOr(path string, then, cancelled func()) {
+	if !a.locking || a.tutor != nil {
+		then()
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if _, ok := a.locks[abs]; ok || a.readOnly[abs] != nil {
+		then()
+		return
+	}
+	l, holder, err := lock.Acquire(abs, false)
+	switch {
+	case err == nil:
+		a.locks[abs] = l
+		then()
+	case errors.Is(err, lock.ErrLocked):
+		a.confirmLocked(abs, holder, then, cancelled)
+	default:
+		// A lock that cannot be created (read-only directory, ...) must not
+		// stop the edit.
+		slog.Warn("lock failed", "path", abs, "err", err)
+		then()
+	}
+}
+
+func (a *App) confirmLocked(abs string, holder *lock.Info, then, cancelled func()) {
+	m := tview.NewModal().
+		SetText(fmt.Sprintf("%s is being edited by %s.\n\nWriting it now may overwrite their changes.", abs, holder)).
+		AddButtons([]string{"Read-only", "Take over", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			switch label {
+			case "Read-only":
+				a.readOnly[abs] = holder
+				then()
+			case "Take over":
+				l, _, err := lock.Acquire(abs, true)
+				if err != nil {
+					a.updateStatusInline(fmt.Sprintf("Take over failed: %v", err))
+					if cancelled != nil {
+						cancelled()
+					}
+					return
+				}
+				slog.Info("took over lock", "path", abs, "holder", holder.String())
+				a.locks[abs] = l
+				then()
+			default:
+				a.writePending = false
+				a.quitAfterWrite = false
+				a.updateStatusInline("Cancelled")
+				if cancelled != nil {
+					cancelled()
+				}
+			}
+		})
+	a.Pages.AddPage(pageModal, m, true, true)
+	a.App.SetFocus(m)
+}
+
+// lockedOut explains why path may not be written, or returns "".
+func (a *App) lockedOut(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if holder := a.readOnly[abs]; holder != nil {
+		return fmt.Sprintf("%s is read-only: locked by %s", path, holder)
+	}
+	return ""
+}
+
+func (a *App) releaseLocks() {
+	for path, l := range a.locks {
+		if err := l.Release(); err != nil {
+			slog.Warn("lock release failed", "path", path, "err", err)
+		}
+	}
+	a.locks = make(map[string]*lock.Lock)
+}
+
+// takeOver claims path's lock regardless of its holder, as :w! does.
+func (a *App) takeOver(path string) error {
+	if !a.locking || a.tutor != nil {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if _, ok := a.locks[abs]; ok {
+		return nil
+	}
+	l, _, err := lock.Acquire(abs, true)
+	if err != nil {
+		return err
+	}
+	delete(a.readOnly, abs)
+	a.locks[abs] = l
+	return nil
+}
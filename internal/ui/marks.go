@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// lastJumpMark holds the key selected before the latest jump to a mark;
+// jumping to it with ' goes back.
+const lastJumpMark = "'"
+
+// markKey handles m{a-z}, marking the selected key. Marks hold the key
+// rather than its row, so they follow it through sorting and filtering.
+func (a *App) markKey(name string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	b := a.buf()
+	if b.marks == nil {
+		b.marks = make(map[string]string)
+	}
+	b.marks[name] = key
+	return fmt.Sprintf("Mark %s set on %s", name, key)
+}
+
+// jumpMark handles '{a-z}, selecting the key mark name is on. A filter
+// hiding it is cleared and a section folding it opened.
+func (a *App) jumpMark(name string) string {
+	key, ok := a.buf().marks[name]
+	if !ok {
+		return fmt.Sprintf("Mark %s not set", name)
+	}
+	if _, ok := a.Store.Get(key); !ok {
+		return fmt.Sprintf("Mark %s: %s no longer exists", name, key)
+	}
+	if cur, ok := a.selectedKey(); ok {
+		a.buf().marks[lastJumpMark] = cur
+	}
+	if !slices.Contains(a.Store.ListKeys(), key) {
+		a.applySearch("")
+	}
+	a.selectKey(key)
+	return fmt.Sprintf("Mark %s: %s", name, key)
+}
+
+// listMarks handles ":marks".
+func (a *App) listMarks() string {
+	marks := a.buf().marks
+	if len(marks) == 0 {
+		return "No marks set"
+	}
+	var parts []string
+	for name, key := range marks {
+		parts = append(parts, name+" "+key)
+	}
+	slices.Sort(parts)
+	return "Marks: " + strings.Join(parts, " | ")
+}
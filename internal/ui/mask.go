@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+)
+
+// maskText stands in for a secret value in the table and inspector.
+const maskText = "••••••••"
+
+// revealFor is how long s shows the selected secret before masking it
+// again; moving off the row masks it sooner.
+const revealFor = 10 * time.Second
+
+// masked reports whether key's value is shown as maskText: it was masked
+// in visual mode, or it looks secret and masking is on, and the row is not
+// revealed.
+func (a *App) masked(key, value string) bool {
+	return key != a.revealed && (a.maskKeys[key] || !a.unmask && env.LooksSecret(key, value))
+}
+
+// shownValue is value as the table shows it.
+func (a *App) shownValue(key, value string) string {
+	if a.masked(key, value) {
+		return maskText
+	}
+	return value
+}
+
+// revealSelected unmasks the selected row until the selection moves or
+// revealFor passes.
+func (a *App) revealSelected() string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return ""
+	}
+	it, _ := a.Store.Get(key)
+	if !a.masked(key, it.Value) {
+		return key + " is not masked"
+	}
+	a.revealed = key
+	a.revealGen++
+	gen := a.revealGen
+	a.setValueCell(key, it.Value)
+	a.updateInspector()
+	time.AfterFunc(revealFor, func() {
+		a.App.QueueUpdateDraw(func() {
+			if gen == a.revealGen {
+				a.hideRevealed()
+			}
+		})
+	})
+	return fmt.Sprintf("Revealed %s for %s", key, revealFor)
+}
+
+// hideRevealed masks the revealed row again.
+func (a *App) hideRevealed() {
+	key := a.revealed
+	if key == "" {
+		return
+	}
+	a.revealed = ""
+	a.revealGen++
+	if it, ok := a.Store.Get(key); ok {
+		a.setValueCell(key, it.Value)
+	}
+	a.updateInspector()
+}
+
+// setValueCell redraws the value cell of key's row, if shown.
+func (a *App) setValueCell(key, value string) {
+	for i, k := range a.Store.ListKeys() {
+		if k == key {
+			if r := a.tableRow(i + 1); r > 0 {
+				a.Table.GetCell(r, 1).SetText(a.shownValue(key, value))
+			}
+			return
+		}
+	}
+}
+
+func (a *App) maskOption() string {
+	if a.unmask {
+		return "nomask"
+	}
+	return "mask"
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// metaCommand handles ":meta", which lists the meta-variables,
+// ":meta name=value" and ":meta delete <name>".
+func (a *App) metaCommand(args []string) string {
+	if len(args) == 0 {
+		a.showMeta()
+		return ""
+	}
+	if args[0] == "delete" {
+		if len(args) != 2 {
+			return "Usage: :meta delete <name>"
+		}
+		return a.deleteMeta(strings.TrimPrefix(args[1], "@"))
+	}
+	name, value, ok := strings.Cut(strings.Join(args, " "), "=")
+	if !ok {
+		return "Usage: :meta [@name=value | delete <name>]"
+	}
+	return a.setMeta(strings.TrimPrefix(strings.TrimSpace(name), "@"), value)
+}
+
+func (a *App) setMeta(name, value string) string {
+	if err := a.Store.SetMeta(name, value); err != nil {
+		return err.Error()
+	}
+	a.renderTable()
+	return fmt.Sprintf("@%s=%s; %d values use it", name, value, len(a.Store.MetaUsers(name)))
+}
+
+func (a *App) deleteMeta(name string) string {
+	if _, ok := a.Store.Meta()[name]; !ok {
+		return "No meta-variable @" + name
+	}
+	a.Store.DeleteMeta(name)
+	a.renderTable()
+	if n := len(a.Store.MetaUsers(name)); n > 0 {
+		return fmt.Sprintf("Deleted @%s; %d values still refer to it and will not export", name, n)
+	}
+	return "Deleted @" + name
+}
+
+// showMeta lists the meta-variables with the keys that use them. Enter
+// changes the selected value, a adds one and d deletes one.
+func (a *App) showMeta() {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	var names []string
+	fill := func() {
+		table.Clear()
+		table.SetCell(0, 0, headerCell("NAME"))
+		table.SetCell(0, 1, headerCell("VALUE"))
+		table.SetCell(0, 2, headerCell("USED BY"))
+		meta := a.Store.Meta()
+		names = slices.Sorted(maps.Keys(meta))
+		for i, n := range names {
+			users := a.Store.MetaUsers(n)
+			table.SetCell(i+1, 0, tview.NewTableCell("@"+n).SetTextColor(tcell.ColorYellow))
+			table.SetCell(i+1, 1, tview.NewTableCell(meta[n]).SetExpansion(1))
+			table.SetCell(i+1, 2, tview.NewTableCell(strings.Join(users, ", ")).SetExpansion(2).SetMaxWidth(60))
+		}
+		if len(names) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("No meta-variables: a adds one; values refer to it as ${@name}").
+				SetTextColor(tcell.ColorGray).SetSelectable(false))
+		}
+	}
+	fill()
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(" Meta-variables — Enter change, a add, d delete, ESC close ").
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	selected := func() (string, bool) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(names) {
+			return "", false
+		}
+		return names[row-1], true
+	}
+	table.SetSelectedFunc(func(int, int) {
+		if name, ok := selected(); ok {
+			a.openMetaForm(name)
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Rune() {
+		case 'a':
+			a.openMetaForm("")
+			return nil
+		case 'd':
+			if name, ok := selected(); ok {
+				a.updateStatusInline(a.deleteMeta(name))
+				fill()
+			}
+			return nil
+		}
+		return ev
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 100, 18), true, true)
+	a.App.SetFocus(table)
+}
+
+// openMetaForm sets a meta-variable's value, or defines a new one when name
+// is "". Closing it returns to the list.
+func (a *App) openMetaForm(name string) {
+	form := tview.NewForm()
+	if name == "" {
+		form.AddInputField("Name", "", 30, nil, nil)
+	}
+	form.AddInputField("Value", a.Store.Meta()[name], 60, nil, nil)
+
+	back := func() {
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+		a.showMeta()
+	}
+	form.AddButton("Set", func() {
+		n := name
+		if n == "" {
+			n = strings.TrimPrefix(strings.TrimSpace(form.GetFormItemByLabel("Name").(*tview.InputField).GetText()), "@")
+		}
+		if !env.ValidMetaName(n) {
+			a.updateStatusInline(fmt.Sprintf("Invalid name %q: letters, digits and _", n))
+			return
+		}
+		value := form.GetFormItemByLabel("Value").(*tview.InputField).GetText()
+		msg := a.setMeta(n, value)
+		back()
+		a.updateStatusInline(msg)
+	}).
+		AddButton("Cancel", back)
+	form.SetCancelFunc(back)
+	title := " New meta-variable "
+	if name != "" {
+		title = " @" + name + " "
+	}
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignLeft)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(form, 80, 9), true, true)
+	a.App.SetFocus(form)
+	a.refreshStatus()
+}
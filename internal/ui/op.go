@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+)
+
+// opDefaultField is the item field :op stores a value in when given none.
+const opDefaultField = "password"
+
+// opCommand handles ":op [vault[/item[/field]]]": it moves the selected
+// key's value into 1Password and leaves an op:// reference in its place,
+// which :resolve, :wrap and :w read back through the op CLI. The vault
+// defaults to $OP_VAULT, the item to the key and the field to "password".
+func (a *App) opCommand(args []string) string {
+	const usage = "Usage: :op vault[/item[/field]] (or set OP_VAULT)"
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	it, _ := a.Store.Get(key)
+	if cred.IsRef(it.Value) {
+		return fmt.Sprintf("%s is already a secret reference", key)
+	}
+	if it.Value == "" {
+		return fmt.Sprintf("%s is empty", key)
+	}
+	vault, item, field := os.Getenv("OP_VAULT"), key, opDefaultField
+	if len(args) > 0 {
+		parts := strings.Split(strings.TrimPrefix(args[0], cred.OpScheme), "/")
+		if len(parts) > 3 || slices.Contains(parts, "") {
+			return usage
+		}
+		vault = parts[0]
+		if len(parts) > 1 {
+			item = parts[1]
+		}
+		if len(parts) > 2 {
+			field = parts[2]
+		}
+	}
+	if vault == "" {
+		return usage
+	}
+
+	var ref string
+	a.runRemote("Storing "+key+" in 1Password", func(ctx context.Context) error {
+		var err error
+		ref, err = cred.StoreOp(ctx, vault, item, field, it.Value)
+		return err
+	}, func(err error) {
+		if err != nil {
+			slog.Error("op store failed", "key", key, "err", err)
+			a.updateStatusInline(remoteError("1Password", err))
+			return
+		}
+		if err := a.Store.Upsert(key, ref); err != nil {
+			a.updateStatusInline("Not saved: " + err.Error())
+			return
+		}
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Stored %s in 1Password as %s", key, ref))
+	})
+	return ""
+}
@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// Palettes selectable with :set palette= and the "palette" config key.
+// "none" is also what --no-color and NO_COLOR select.
+const (
+	paletteDefault      = "default"
+	paletteHighContrast = "high-contrast"
+	paletteColorblind   = "colorblind"
+	paletteNone         = "none"
+)
+
+// palette holds the colors of the states the main table and the diff
+// views show. Each state also has a gutter symbol, so none of them is
+// signalled by color alone.
+type palette struct {
+	Modified    tcell.Color
+	Added       tcell.Color
+	Removed     tcell.Color
+	Broken      tcell.Color // missing path, unreachable URL
+	Placeholder tcell.Color
+	Secret      tcell.Color // probable live credential
+	Shadowed    tcell.Color
+	Expired     tcell.Color
+	Expiring    tcell.Color
+	Visual      tcell.Color // background of the visual mode range
+}
+
+// The colorblind palette uses the Okabe-Ito colors, which stay apart under
+// the common forms of color vision deficiency: blue and orange take the
+// place of green and red.
+var palettes = map[string]palette{
+	paletteDefault: {
+		Modified:    tcell.ColorYellow,
+		Added:       tcell.ColorGreen,
+		Removed:     tcell.ColorRed,
+		Broken:      tcell.ColorRed,
+		Placeholder: tcell.ColorDarkOrange,
+		Secret:      tcell.ColorHotPink,
+		Shadowed:    tcell.ColorFuchsia,
+		Expired:     tcell.ColorOrangeRed,
+		Expiring:    tcell.ColorOrange,
+		Visual:      tcell.ColorDarkSlateGray,
+	},
+	paletteHighContrast: {
+		Modified:    tcell.ColorYellow,
+		Added:       tcell.ColorLime,
+		Removed:     tcell.ColorRed,
+		Broken:      tcell.ColorRed,
+		Placeholder: tcell.ColorAqua,
+		Secret:      tcell.ColorRed,
+		Shadowed:    tcell.ColorFuchsia,
+		Expired:     tcell.ColorRed,
+		Expiring:    tcell.ColorYellow,
+		Visual:      tcell.ColorNavy,
+	},
+	paletteColorblind: {
+		Modified:    tcell.NewHexColor(0xF0E442),
+		Added:       tcell.NewHexColor(0x56B4E9),
+		Removed:     tcell.NewHexColor(0xD55E00),
+		Broken:      tcell.NewHexColor(0xD55E00),
+		Placeholder: tcell.NewHexColor(0xE69F00),
+		Secret:      tcell.NewHexColor(0xD55E00),
+		Shadowed:    tcell.NewHexColor(0xCC79A7),
+		Expired:     tcell.NewHexColor(0xD55E00),
+		Expiring:    tcell.NewHexColor(0xE69F00),
+		Visual:      tcell.NewHexColor(0x0072B2),
+	},
+	paletteNone: {},
+}
+
+// paletteNames lists the palettes, sorted.
+func paletteNames() []string { return slices.Sorted(maps.Keys(palettes)) }
+
+// tag is c as a color tag, "-" (the default) for no color.
+func tag(c tcell.Color) string {
+	if c == tcell.ColorDefault {
+		return "-"
+	}
+	return c.CSS()
+}
+
+// Gutter symbols. The first column shows how a row was edited, the second
+// what needs attention, the most urgent first.
+const (
+	markModified    = '*'
+	markAdded       = '+'
+	markRemoved     = '-'
+	markBroken      = '!'
+	markPlaceholder = '?'
+	markExpiring    = '~'
+	markSecret      = '$'
+	markShadowed    = '^'
+)
+
+// gutterLegend explains the gutter symbols for :legend.
+var gutterLegend = []keyHint{
+	{string(markModified), "edited"},
+	{string(markAdded), "added (not in the process environment)"},
+	{string(markRemoved), "removed (in diff views)"},
+	{string(markBroken), "missing path, unreachable URL or expired"},
+	{string(markExpiring), "due for rotation soon"},
+	{string(markPlaceholder), "placeholder value (:todos)"},
+	{string(markSecret), "looks like a live credential (:secrets)"},
+	{string(markShadowed), "shadowed across layers (:overrides)"},
+}
+
+// gutter returns the two symbols shown before key in the main table.
+func (a *App) gutter(key string, item env.Item, process map[string]string) string {
+	edit := ' '
+	if item.Modified {
+		edit = markModified
+		if _, ok := process[key]; !ok {
+			edit = markAdded
+		}
+	}
+	attention := ' '
+	expiry := a.expiryMark(key)
+	switch {
+	case a.broken(key, item.Value) || expiry == markBroken:
+		attention = markBroken
+	case env.IsPlaceholder(item.Value):
+		attention = markPlaceholder
+	case expiry == markExpiring:
+		attention = markExpiring
+	case a.flagged(key, item.Value):
+		attention = markSecret
+	case a.Store.Shadowed(key):
+		attention = markShadowed
+	}
+	return string(edit) + string(attention) + " "
+}
+
+// flagged reports whether key's value looks like a live credential.
+func (a *App) flagged(key, value string) bool {
+	_, ok := env.ScanSecret(key, value)
+	return ok
+}
+
+// broken reports whether key names a missing path or an unreachable URL.
+func (a *App) broken(key, value string) bool {
+	if env.LooksLikePath(key, value) && !env.PathExists(value) {
+		return true
+	}
+	r, ok := a.probeResult(key)
+	return ok && !r.Reachable()
+}
+
+// changeMark is a diff view's gutter symbol and color for a change.
+func (a *App) changeMark(added, removed bool) (string, tcell.Color) {
+	switch {
+	case added:
+		return string(markAdded) + " ", a.pal.Added
+	case removed:
+		return string(markRemoved) + " ", a.pal.Removed
+	}
+	return string(markModified) + " ", a.pal.Modified
+}
+
+// setPalette switches to the named palette.
+func (a *App) setPalette(name string) string {
+	p, ok := palettes[name]
+	if !ok {
+		return fmt.Sprintf("Unknown palette %q (%s)", name, strings.Join(paletteNames(), ", "))
+	}
+	a.pal, a.paletteName = p, name
+	a.renderTable()
+	return "palette=" + name
+}
+
+func (a *App) showLegend() {
+	var b strings.Builder
+	for _, h := range gutterLegend {
+		fmt.Fprintf(&b, "[::b]%s[::-]  %s\n", h.key, tview.Escape(h.desc))
+	}
+	b.WriteString("\nThe first column shows edits, the second what needs attention.")
+	a.showText("Gutter", b.String())
+}
+
+// monoScreen draws without color, for --no-color and NO_COLOR. Text keeps
+// its attributes; a selected or focused background turns into reverse
+// video and a field or button background into an underline, so the cursor
+// and focus still show.
+type monoScreen struct {
+	tcell.Screen
+}
+
+func (s monoScreen) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	_, bg, attr := style.Decompose()
+	switch bg {
+	case tcell.ColorDefault, tview.Styles.PrimitiveBackgroundColor:
+	case tview.Styles.ContrastBackgroundColor, tview.Styles.MoreContrastBackgroundColor:
+		attr |= tcell.AttrUnderline
+	default:
+		attr |= tcell.AttrReverse
+	}
+	s.Screen.SetContent(x, y, primary, combining, tcell.StyleDefault.Attributes(attr))
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+// passDefaultDir is where :store puts entries when given no name.
+const passDefaultDir = "envoy"
+
+// resolveCommand handles ":resolve [entry|op://ref]". With an argument it
+// sets the selected key from the password store or 1Password; without one
+// it replaces every pass:// and op:// reference with the value it names.
+func (a *App) resolveCommand(args []string) string {
+	refs := make(map[string]string) // key -> pass:// or op:// reference
+	if len(args) > 0 {
+		key, ok := a.selectedKey()
+		if !ok {
+			return "No key selected"
+		}
+		ref := args[0]
+		if !cred.IsOpRef(ref) {
+			ref = remote.PassScheme + strings.TrimPrefix(ref, remote.PassScheme)
+		}
+		refs[key] = ref
+	} else {
+		for _, k := range a.Store.ListKeys() {
+			it, _ := a.Store.Get(k)
+			if strings.HasPrefix(it.Value, remote.PassScheme) || cred.IsOpRef(it.Value) {
+				refs[k] = it.Value
+			}
+		}
+		if len(refs) == 0 {
+			return "No pass:// or op:// references to resolve"
+		}
+	}
+
+	values := make(map[string]string)
+	a.runRemote(fmt.Sprintf("Resolving %d references", len(refs)), func(ctx context.Context) error {
+		n := 0
+		for k, ref := range refs {
+			v, err := fetchRef(ctx, ref)
+			if err != nil {
+				return err
+			}
+			values[k] = v
+			n++
+			remote.ReportProgress(ctx, n, len(refs))
+		}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			slog.Error("resolve failed", "err", err)
+			a.updateStatusInline(remoteError("Resolve", err))
+			return
+		}
+		a.Store.Begin()
+		for k, v := range values {
+			a.Store.Upsert(k, v)
+		}
+		_ = a.Store.Commit()
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Resolved %d references", len(values)))
+	})
+	return ""
+}
+
+// fetchRef reads the secret a pass:// or op:// reference names.
+func fetchRef(ctx context.Context, ref string) (string, error) {
+	entry, ok := strings.CutPrefix(ref, remote.PassScheme)
+	if !ok {
+		return cred.ResolveOp(ctx, ref)
+	}
+	v, err := remote.PassShow(ctx, entry)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", entry, err)
+	}
+	return v, nil
+}
+
+// storeCommand handles ":store [entry]": it moves the selected key's value
+// into the password store and leaves a pass:// reference in its place.
+func (a *App) storeCommand(args []string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	it, _ := a.Store.Get(key)
+	if strings.HasPrefix(it.Value, remote.PassScheme) {
+		return fmt.Sprintf("%s is already a pass reference", key)
+	}
+	entry := passDefaultDir + "/" + remote.PassEntryName(key)
+	if len(args) > 0 {
+		entry = strings.TrimPrefix(args[0], remote.PassScheme)
+	}
+
+	a.runRemote("Storing "+key+" in pass", func(ctx context.Context) error {
+		return remote.PassInsert(ctx, entry, it.Value)
+	}, func(err error) {
+		if err != nil {
+			slog.Error("store failed", "key", key, "err", err)
+			a.updateStatusInline(remoteError("Store", err))
+			return
+		}
+		a.Store.Upsert(key, remote.PassScheme+entry)
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Stored %s in pass as %s", key, entry))
+	})
+	return ""
+}
@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/clipboard"
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/rivo/tview"
+)
+
+// pasteCapture wraps a primitive and offers pasted text to fn before the
+// wrapped primitive sees it. fn returns true if it consumed the paste.
+// With singleLine set, newlines are flattened before forwarding so a paste
+// can't smuggle line breaks into an input field.
+type pasteCapture struct {
+	tview.Primitive
+	fn         func(text string) bool
+	singleLine bool
+}
+
+func (p *pasteCapture) PasteHandler() func(pastedText string, setFocus func(p tview.Primitive)) {
+	return func(text string, setFocus func(p tview.Primitive)) {
+		if p.fn != nil && p.fn(text) {
+			return
+		}
+		if p.singleLine {
+			text = flattenPaste(text)
+		}
+		if handler := p.Primitive.PasteHandler(); handler != nil {
+			handler(text, setFocus)
+		}
+	}
+}
+
+func flattenPaste(text string) string {
+	text = strings.TrimRight(text, "\r\n")
+	text = strings.ReplaceAll(text, "\r\n", " ")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// tablePaste handles text pasted while the table has focus. Bracketed paste
+// keeps the characters from being read as normal-mode keys; here the text is
+// offered to the bulk parser instead.
+func (a *App) tablePaste(text string) bool {
+	if a.Vim.Mode != ModeNormal {
+		return true
+	}
+	if !a.smartPaste(text) {
+		a.updateStatusInline("Paste ignored: no KEY=VALUE pairs found")
+	}
+	return true
+}
+
+// isBulkText reports whether pasted text looks like more than a single value.
+func isBulkText(text string) bool {
+	t := strings.TrimSpace(text)
+	return strings.Contains(t, "\n") || strings.HasPrefix(t, "{")
+}
+
+// smartPaste parses text and opens the bulk-add preview. It returns false if
+// nothing could be parsed, leaving the text to the caller.
+func (a *App) smartPaste(text string) bool {
+	items, err := env.ParseBulk(text)
+	if err != nil {
+		return false
+	}
+	a.previewBulk(items)
+	return true
+}
+
+// importClipboard handles ":import-clipboard", offering the clipboard's
+// text to the bulk parser.
+func (a *App) importClipboard() string {
+	text, err := clipboard.Read()
+	if err != nil {
+		return fmt.Sprintf("Clipboard: %v", err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "Clipboard is empty"
+	}
+	items, err := env.ParseBulk(text)
+	if err != nil {
+		return fmt.Sprintf("Clipboard: %v", err)
+	}
+	a.previewBulk(items)
+	return ""
+}
+
+func (a *App) openPasteForm() {
+	form := tview.NewForm().
+		AddTextArea("Text", "", 0, 12, 0, nil)
+
+	parseBtn := func() {
+		text := form.GetFormItemByLabel("Text").(*tview.TextArea).GetText()
+		items, err := env.ParseBulk(text)
+		if err != nil {
+			a.updateStatusInline(fmt.Sprintf("Paste: %v", err))
+			return
+		}
+		a.previewBulk(items)
+	}
+
+	form.AddButton("Parse", parseBtn).
+		AddButton("Cancel", func() {
+			a.closeModal()
+			a.Vim.Mode = ModeNormal
+			a.refreshStatus()
+		})
+	form.SetBorder(true).SetTitle(" Paste KEY=VALUE lines, export statements or JSON ").SetTitleAlign(tview.AlignLeft)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(form, 90, 18), true, true)
+	a.App.SetFocus(form)
+	a.refreshStatus()
+}
+
+func (a *App) previewBulk(items []env.Item) {
+	var b strings.Builder
+	updates := 0
+	for _, it := range items {
+		tag := "[green]new[-]      "
+		if _, ok := a.Store.Get(it.Key); ok {
+			tag = "[yellow]overwrite[-]"
+			updates++
+		} else if ke, ok := a.Store.CheckKey(it.Key).(*env.KeyError); ok && ke.Rejected {
+			tag = "[red]skip[-]     "
+		}
+		fmt.Fprintf(&b, "%s  %s=%s\n", tag, tview.Escape(it.Key), tview.Escape(it.Value))
+	}
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(b.String())
+
+	form := tview.NewForm().
+		AddButton("Add all", func() {
+			var skipped []string
+			a.Store.Begin()
+			for _, it := range items {
+				if a.Store.Upsert(it.Key, it.Value) != nil {
+					skipped = append(skipped, it.Key)
+				}
+			}
+			_ = a.Store.Commit()
+			a.closeModal()
+			a.renderTable()
+			a.selectKey(items[0].Key)
+			a.Vim.Mode = ModeNormal
+			a.refreshStatus()
+			msg := fmt.Sprintf("Added %d vars (%d updated)", len(items)-updates-len(skipped), updates)
+			if len(skipped) > 0 {
+				msg += "; skipped keys the key policy rejects: " + strings.Join(skipped, ", ")
+			}
+			a.updateStatusInline(msg)
+		}).
+		AddButton("Cancel", func() {
+			a.closeModal()
+			a.Vim.Mode = ModeNormal
+			a.refreshStatus()
+		})
+
+	box := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	box.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Add %d variables? ", len(items))).
+		SetTitleAlign(tview.AlignLeft)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(box, 90, 20), true, true)
+	a.App.SetFocus(form)
+	a.refreshStatus()
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// invalidChoice explains why val cannot be used for key, or returns "".
+func invalidChoice(key, val string) string {
+	p := env.PickerFor(key)
+	if env.ValidChoice(p, val) {
+		return ""
+	}
+	what := "locale"
+	if p == env.PickZone {
+		what = "time zone"
+	}
+	return fmt.Sprintf("%s: %q is not an installed %s", key, val, what)
+}
+
+// openPicker chooses item's value from choices. Typing filters the list;
+// Enter picks, C-e falls back to the text form, ESC cancels.
+func (a *App) openPicker(item env.Item, choices []string, appending bool) {
+	filter := tview.NewInputField().SetLabel("Filter ")
+	list := tview.NewList().ShowSecondaryText(false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(filter, 1, 0, true).
+		AddItem(list, 0, 1, false)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %s — Enter pick, C-e edit as text, ESC cancel ", item.Key)).
+		SetTitleAlign(tview.AlignLeft)
+
+	var shown []string
+	fill := func(q string) {
+		list.Clear()
+		shown = shown[:0]
+		q = strings.ToLower(q)
+		sel := 0
+		for _, c := range choices {
+			if q != "" && !strings.Contains(strings.ToLower(c), q) {
+				continue
+			}
+			if c == item.Value {
+				sel = len(shown)
+			}
+			shown = append(shown, c)
+			list.AddItem(c, "", 0, nil)
+		}
+		list.SetCurrentItem(sel)
+	}
+	fill("")
+
+	done := func() {
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+	}
+	pick := func() {
+		i := list.GetCurrentItem()
+		if i < 0 || i >= len(shown) {
+			return
+		}
+		val := shown[i]
+		a.Store.Upsert(item.Key, val)
+		done()
+		a.renderTable()
+		a.selectKey(item.Key)
+		a.updateStatusInline(fmt.Sprintf("Saved %s=%s", item.Key, val))
+	}
+
+	filter.SetChangedFunc(fill)
+	capture := func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			done()
+		case tcell.KeyEnter:
+			pick()
+		case tcell.KeyCtrlE:
+			a.closeModal()
+			a.openTextEdit(item, appending)
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+			// Move through the list while the filter keeps focus.
+			if h := list.InputHandler(); h != nil {
+				h(ev, func(p tview.Primitive) {})
+			}
+		default:
+			return ev
+		}
+		return nil
+	}
+	filter.SetInputCapture(capture)
+
+	a.Vim.Mode = ModeInsert
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 60, 20), true, true)
+	a.App.SetFocus(filter)
+	a.refreshStatus()
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/probe"
+	"github.com/rivethorn/envoy/internal/remote"
+	"github.com/rivo/tview"
+)
+
+// maxProbes bounds concurrent probes for ":probe %".
+const maxProbes = 8
+
+// probeCommand handles ":probe [KEY...|%]": it requests each URL-valued key,
+// the selected one by default, and shows the outcome in the inspector.
+func (a *App) probeCommand(args []string) string {
+	var keys []string
+	switch {
+	case len(args) == 1 && args[0] == "%":
+		for _, k := range a.Store.AllKeys() {
+			if _, ok := a.probeURL(k); ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			return "No http or https URLs to probe"
+		}
+	case len(args) > 0:
+		keys = args
+	default:
+		k, ok := a.selectedKey()
+		if !ok {
+			return "No variable selected"
+		}
+		keys = []string{k}
+	}
+	urls := make(map[string]string, len(keys))
+	for _, k := range keys {
+		u, ok := a.probeURL(k)
+		if !ok {
+			return fmt.Sprintf("%s does not hold an http or https URL", k)
+		}
+		urls[k] = u
+	}
+
+	results := make(map[string]probe.Result, len(keys))
+	var mu sync.Mutex
+	a.runRemote(fmt.Sprintf("Probing %d URLs", len(keys)), func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		var done atomic.Int32
+		sem := make(chan struct{}, maxProbes)
+		for k, u := range urls {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				r := probe.Probe(ctx, u)
+				slog.Info("probe", "key", k, "status", r.Status, "latency", r.Latency, "err", r.Err)
+				mu.Lock()
+				results[k] = r
+				mu.Unlock()
+				remote.ReportProgress(ctx, int(done.Add(1)), len(urls))
+			}()
+		}
+		wg.Wait()
+		return ctx.Err()
+	}, func(err error) {
+		if err != nil {
+			a.updateStatusInline("Probe cancelled")
+			return
+		}
+		if a.probes == nil {
+			a.probes = make(map[string]probe.Result)
+		}
+		var down []string
+		for k, r := range results {
+			a.probes[k] = r
+			if !r.Reachable() {
+				down = append(down, k)
+			}
+		}
+		a.renderTable()
+		if !a.inspecting {
+			a.toggleInspector()
+		}
+		a.updateInspector()
+		switch {
+		case len(keys) == 1:
+			a.updateStatusInline(keys[0] + ": " + results[keys[0]].String())
+		case len(down) > 0:
+			a.updateStatusInline(fmt.Sprintf("Probed %d URLs: %d unreachable (%s)", len(keys), len(down), strings.Join(down, ", ")))
+		default:
+			a.updateStatusInline(fmt.Sprintf("Probed %d URLs: all answered", len(keys)))
+		}
+	})
+	return ""
+}
+
+// probeURL returns key's value, with meta-variables resolved, when it is
+// an http or https URL.
+func (a *App) probeURL(key string) (string, bool) {
+	it, ok := a.Store.Get(key)
+	if !ok {
+		return "", false
+	}
+	u, _ := a.Store.Resolve(it.Value)
+	return u, probe.Probable(u)
+}
+
+// probeResult returns the latest probe of key while its value is unchanged.
+func (a *App) probeResult(key string) (probe.Result, bool) {
+	r, ok := a.probes[key]
+	if !ok {
+		return r, false
+	}
+	u, _ := a.probeURL(key)
+	return r, u == r.URL
+}
+
+// describeProbe is the inspector's line for key's latest probe, or "".
+func (a *App) describeProbe(key string) string {
+	r, ok := a.probeResult(key)
+	if !ok {
+		return ""
+	}
+	color := "green"
+	switch {
+	case !r.Reachable() || r.Status >= 500:
+		color = "red"
+	case r.Status >= 400:
+		color = "yellow"
+	}
+	return fmt.Sprintf("\nProbe: [%s]%s[-] (%s ago)\n", color, tview.Escape(r.String()), age(time.Since(r.When)))
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showProxyCheck lists problems with the proxy variables. s or Enter
+// copies the selected key's value to both cases of its family.
+func (a *App) showProxyCheck() string {
+	if len(a.Store.ProxyIssues()) == 0 {
+		return "Proxy variables are consistent"
+	}
+
+	var keys []string
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("VALUE"))
+	table.SetCell(0, 2, headerCell("PROBLEM"))
+
+	fill := func() {
+		keys = keys[:0]
+		for r := table.GetRowCount() - 1; r > 0; r-- {
+			table.RemoveRow(r)
+		}
+		for _, is := range a.Store.ProxyIssues() {
+			key := is.Key
+			it, _ := a.Store.Get(key)
+			row := len(keys) + 1
+			table.SetCell(row, 0, tview.NewTableCell(key).SetExpansion(1))
+			table.SetCell(row, 1, tview.NewTableCell(it.Value).SetExpansion(2))
+			table.SetCell(row, 2, tview.NewTableCell(is.Problem).SetExpansion(3))
+			keys = append(keys, key)
+		}
+	}
+	fill()
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(" Proxy check — s/Enter sync family to this value, ESC close ").
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+			a.renderTable()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() != tcell.KeyEnter && ev.Rune() != 's' {
+			return ev
+		}
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(keys) {
+			return nil
+		}
+		key := keys[row-1]
+		written := a.Store.SyncProxy(key)
+		if len(written) == 0 {
+			a.updateStatusInline(fmt.Sprintf("%s family already in sync", strings.ToLower(key)))
+		} else {
+			a.updateStatusInline(fmt.Sprintf("Synced %s from %s", strings.Join(written, ", "), key))
+		}
+		if len(a.Store.ProxyIssues()) == 0 {
+			a.closeModal()
+			a.renderTable()
+			return nil
+		}
+		fill()
+		table.Select(1, 0)
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
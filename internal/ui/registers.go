@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+)
+
+// unnamedRegister is the register every yank fills and p reads by default.
+const unnamedRegister = `"`
+
+// register holds what was yanked: KEY=VALUE pairs, or with valueOnly just
+// the value of the first.
+type register struct {
+	items     []env.Item
+	valueOnly bool
+}
+
+// setRegister stores r in the register named name, and in the unnamed one.
+// An uppercase name appends to its lowercase register instead.
+func (a *App) setRegister(name string, r register) {
+	if lower := strings.ToLower(name); lower != name {
+		name = lower
+		if old, ok := a.registers[name]; ok && !old.valueOnly && !r.valueOnly {
+			r.items = append(slices.Clone(old.items), r.items...)
+		}
+	}
+	if name != "" {
+		a.registers[name] = r
+	}
+	a.registers[unnamedRegister] = r
+}
+
+// yank copies the items of keys to the register a " prefix named, or the
+// unnamed one.
+func (a *App) yank(keys []string) string {
+	var items []env.Item
+	for _, k := range keys {
+		if it, ok := a.Store.Get(k); ok {
+			items = append(items, env.Item{Key: k, Value: it.Value})
+		}
+	}
+	if len(items) == 0 {
+		return "Nothing to yank"
+	}
+	a.setRegister(a.Vim.Register, register{items: items})
+	return fmt.Sprintf("Yanked %d variables%s", len(items), registerSuffix(a.Vim.Register))
+}
+
+// yankRows handles yy, yanking n rows from the selected one, and yv,
+// yanking the selected value alone.
+func (a *App) yankRows(valueOnly bool, n int) string {
+	keys := a.Store.ListKeys()
+	if a.selRow < 1 || a.selRow > len(keys) {
+		return "Nothing to yank"
+	}
+	if !valueOnly {
+		return a.yank(keys[a.selRow-1 : min(a.selRow-1+n, len(keys))])
+	}
+	key := keys[a.selRow-1]
+	it, _ := a.Store.Get(key)
+	a.setRegister(a.Vim.Register, register{items: []env.Item{{Key: key, Value: it.Value}}, valueOnly: true})
+	return fmt.Sprintf("Yanked the value of %s%s", key, registerSuffix(a.Vim.Register))
+}
+
+// paste handles p, adding the pairs of register name as new rows, and P,
+// putting its value over the selected row's. A key already in the table is
+// pasted as a copy, KEY_COPY; a value-only register opens the add form for
+// p.
+func (a *App) paste(name string, over bool) string {
+	name = cmp.Or(strings.ToLower(name), unnamedRegister)
+	r, ok := a.registers[name]
+	if !ok || len(r.items) == 0 {
+		return fmt.Sprintf("Register %s is empty", name)
+	}
+	if over {
+		key, ok := a.selectedKey()
+		if !ok {
+			return "No row selected"
+		}
+		if len(r.items) > 1 {
+			return fmt.Sprintf("P pastes one value; register %s holds %d variables", name, len(r.items))
+		}
+		if err := a.Store.Upsert(key, r.items[0].Value); err != nil {
+			return "Not pasted: " + err.Error()
+		}
+		a.lastChange = func() string { return a.paste(name, true) }
+		a.renderTable()
+		return fmt.Sprintf("Pasted over %s", key)
+	}
+	if r.valueOnly {
+		a.addDraft = &env.Item{Value: r.items[0].Value}
+		a.openAddForm()
+		return ""
+	}
+	var added []string
+	a.Store.Begin()
+	for _, it := range r.items {
+		key := a.copyKey(it.Key)
+		if err := a.Store.Upsert(key, it.Value); err != nil {
+			a.Store.Rollback()
+			a.renderTable()
+			return fmt.Sprintf("Not pasted: %s: %v", key, err)
+		}
+		added = append(added, key)
+	}
+	_ = a.Store.Commit()
+	a.lastChange = func() string { return a.paste(name, false) }
+	a.renderTable()
+	a.selectKey(added[0])
+	if len(added) == 1 {
+		return fmt.Sprintf("Pasted %s", added[0])
+	}
+	return fmt.Sprintf("Pasted %d variables", len(added))
+}
+
+// copySuffix matches the suffix copyKey adds, so a copy of a copy is
+// numbered rather than suffixed twice.
+var copySuffix = regexp.MustCompile(`_COPY\d*$`)
+
+// copyKey returns key, or when the buffer has it already the first free
+// KEY_COPY, KEY_COPY2, ….
+func (a *App) copyKey(key string) string {
+	if _, ok := a.Store.Get(key); !ok {
+		return key
+	}
+	key = copySuffix.ReplaceAllString(key, "")
+	for i := 1; ; i++ {
+		c := key + "_COPY"
+		if i > 1 {
+			c += fmt.Sprint(i)
+		}
+		if _, ok := a.Store.Get(c); !ok {
+			return c
+		}
+	}
+}
+
+func registerSuffix(name string) string {
+	switch lower := strings.ToLower(name); {
+	case name == "" || name == unnamedRegister:
+		return ""
+	case lower != name:
+		return " onto register " + lower
+	default:
+		return " into register " + name
+	}
+}
+
+// registersCommand handles ":registers", listing what each register holds
+// with values masked as the table masks them.
+func (a *App) registersCommand() string {
+	names := make([]string, 0, len(a.registers))
+	for name := range a.registers {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "No registers"
+	}
+	slices.Sort(names)
+	var parts []string
+	for _, name := range names {
+		r := a.registers[name]
+		var items []string
+		for _, it := range r.items {
+			v := a.shownValue(it.Key, it.Value)
+			if r.valueOnly {
+				items = append(items, v)
+			} else {
+				items = append(items, it.Key+"="+v)
+			}
+		}
+		parts = append(parts, fmt.Sprintf(`"%s %s`, name, strings.Join(items, " ")))
+	}
+	return strings.Join(parts, " | ")
+}
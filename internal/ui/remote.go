@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+
+	"github.com/rivo/tview"
+)
+
+// runRemote runs fn in the background behind a progress dialog with a Cancel
+// button. done is called on the UI goroutine with fn's error.
+func (a *App) runRemote(title string, fn func(ctx context.Context) error, done func(err error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := tview.NewModal().
+		SetText(title + "…").
+		AddButtons([]string{"Cancel"}).
+		SetDoneFunc(func(int, string) { cancel() })
+	a.Pages.AddPage(pageModal, m, true, true)
+	a.App.SetFocus(m)
+
+	ctx = remote.WithProgress(ctx, func(n, total int) {
+		text := fmt.Sprintf("%s… %d", title, n)
+		if total > 0 {
+			text = fmt.Sprintf("%s… %d/%d", title, n, total)
+		}
+		a.App.QueueUpdateDraw(func() { m.SetText(text) })
+	})
+
+	go func() {
+		err := fn(ctx)
+		cancel()
+		a.App.QueueUpdateDraw(func() {
+			a.closeModal()
+			done(err)
+		})
+	}()
+}
+
+func (a *App) openProvider(name string) (remote.Provider, error) {
+	return remote.Open(name, a.providerConfig())
+}
+
+// providerConfig is what providers are opened with, taken on the UI
+// goroutine for those opened off it.
+func (a *App) providerConfig() remote.Config {
+	return remote.Config{Creds: a.Creds, Sources: a.Config.Sources, KV: a.Config.KV}
+}
+
+// pullCommand handles ":pull <provider> <path>".
+func (a *App) pullCommand(args []string) string {
+	if len(args) < 2 {
+		return "Usage: :pull <provider> <path>"
+	}
+	name, path := args[0], args[1]
+	p, err := a.openProvider(name)
+	if err != nil {
+		return fmt.Sprintf("Pull failed: %v", err)
+	}
+
+	var entries []remote.Entry
+	slog.Info("pull", "provider", name, "path", path)
+	a.runRemote("Pulling "+name+":"+path, func(ctx context.Context) error {
+		entries, err = p.Pull(ctx, path)
+		return err
+	}, func(err error) {
+		if err != nil {
+			slog.Error("pull failed", "provider", name, "path", path, "err", err)
+			a.updateStatusInline(remoteError("Pull", err))
+			return
+		}
+		items := make([]env.Item, len(entries))
+		for i, e := range entries {
+			items[i] = env.Item{Key: e.Key, Value: e.Value}
+		}
+		n := a.Store.Merge(name+":"+path, items)
+		a.renderTable()
+		a.updateStatusInline(fmt.Sprintf("Pulled %d vars from %s:%s", n, name, path))
+	})
+	return ""
+}
+
+// pushCommand handles ":push <provider> <path> [--force]", sending the
+// variables currently visible in the table. A provider that can write
+// conditionally only does so if the remote values are still those last
+// pulled; --force overwrites regardless.
+func (a *App) pushCommand(args []string) string {
+	force := false
+	if i := slices.Index(args, "--force"); i >= 0 {
+		force, args = true, slices.Delete(args, i, i+1)
+	}
+	if len(args) < 2 {
+		return "Usage: :push <provider> <path> [--force]"
+	}
+	var entries []remote.Entry
+	for _, k := range a.Store.ListKeys() {
+		it, _ := a.Store.Get(k)
+		entries = append(entries, remote.Entry{Key: k, Value: it.Value})
+	}
+	return a.pushEntries(args[0], args[1], entries, force)
+}
+
+// pushEntries sends entries to path of the named provider.
+func (a *App) pushEntries(name, path string, entries []remote.Entry, force bool) string {
+	p, err := a.openProvider(name)
+	if err != nil {
+		return fmt.Sprintf("Push failed: %v", err)
+	}
+	source := name + ":" + path
+	push := func(ctx context.Context) error { return p.Push(ctx, path, entries) }
+	if cp, ok := p.(remote.CASPusher); ok && !force {
+		base := a.pushBase(source, entries)
+		push = func(ctx context.Context) error { return cp.PushCAS(ctx, path, entries, base) }
+	}
+	slog.Info("push", "provider", name, "path", path, "count", len(entries), "force", force)
+	a.runRemote(fmt.Sprintf("Pushing %d vars to %s", len(entries), source), push, func(err error) {
+		if err != nil {
+			slog.Error("push failed", "provider", name, "path", path, "err", err)
+			if msg, ok := conflictNote(err); ok {
+				a.updateStatusInline(msg)
+				return
+			}
+			a.updateStatusInline(remoteError("Push", err))
+			return
+		}
+		a.updateStatusInline(fmt.Sprintf("Pushed %d vars to %s", len(entries), source))
+	})
+	return ""
+}
+
+func remoteError(op string, err error) string {
+	if errors.Is(err, context.Canceled) {
+		return op + " cancelled"
+	}
+	return fmt.Sprintf("%s failed: %v", op, err)
+}
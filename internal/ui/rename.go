@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// renamePrefixCommand handles ":renameprefix OLD_ NEW_". It previews the
+// renames and the values whose references are rewritten, and asks first;
+// a rename onto an existing key is refused.
+func (a *App) renamePrefixCommand(args []string) string {
+	if len(args) != 2 {
+		return "Usage: :renameprefix OLD_ NEW_"
+	}
+	from, to := args[0], args[1]
+	if from == to {
+		return "Prefixes are the same"
+	}
+	p := a.Store.PlanPrefixRename(from, to)
+	if len(p.Renames) == 0 {
+		return "No keys start with " + from
+	}
+
+	var b strings.Builder
+	if len(p.Collisions) > 0 {
+		fmt.Fprintf(&b, "[red]Refused: would overwrite %s[-]\n\n", tview.Escape(strings.Join(p.Collisions, ", ")))
+	}
+	width := 0
+	for _, r := range p.Renames {
+		width = max(width, len(r.From))
+	}
+	for _, r := range p.Renames {
+		fmt.Fprintf(&b, "%-*s → [green]%s[-]\n", width, tview.Escape(r.From), tview.Escape(r.To))
+	}
+	if len(p.Refs) > 0 {
+		fmt.Fprintf(&b, "\n[yellow]References rewritten in[-] %s\n", tview.Escape(strings.Join(p.Refs, ", ")))
+	}
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(b.String())
+	form := tview.NewForm()
+	if len(p.Collisions) == 0 {
+		form.AddButton("Rename", func() {
+			a.closeModal()
+			done, err := a.Store.RenamePrefix(from, to)
+			if err != nil {
+				a.updateStatusInline("Rename failed: " + err.Error())
+				return
+			}
+			a.lastChange = func() string { return a.renameSelectedPrefix(to) }
+			a.renderTable()
+			a.selectKey(done.Renames[0].To)
+			a.updateStatusInline(fmt.Sprintf("Renamed %d keys from %s to %s", len(done.Renames), from, to))
+		})
+	}
+	form.AddButton("Cancel", a.closeModal)
+	form.SetCancelFunc(a.closeModal)
+	form.SetButtonsAlign(tview.AlignCenter)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Rename %d keys from %s to %s ", len(p.Renames), from, to)).
+		SetTitleAlign(tview.AlignLeft)
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 100, min(len(p.Renames)+10, 28)), true, true)
+	a.App.SetFocus(form)
+	return ""
+}
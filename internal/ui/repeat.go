@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// repeatChange handles ., running the latest change again on the selected
+// row n times. It returns a status.
+func (a *App) repeatChange(n int) string {
+	if a.lastChange == nil {
+		return "No change to repeat"
+	}
+	var msg string
+	for range n {
+		msg = a.lastChange()
+	}
+	return msg
+}
+
+// deleteRows deletes n rows from the selected one as one change, without
+// asking: it is what . repeats after x or a visual d, which asked.
+func (a *App) deleteRows(n int) string {
+	keys := a.Store.ListKeys()
+	if a.selRow < 1 || a.selRow > len(keys) {
+		return "No key selected"
+	}
+	keys = keys[a.selRow-1 : min(a.selRow-1+n, len(keys))]
+	a.Store.Begin()
+	for _, k := range keys {
+		a.Store.Delete(k)
+	}
+	_ = a.Store.Commit()
+	a.renderTable()
+	if a.selRow > a.Store.Count() {
+		a.setSelection(max(a.Store.Count(), 1), a.selCol)
+	}
+	if len(keys) == 1 {
+		return fmt.Sprintf("Deleted %s", keys[0])
+	}
+	return fmt.Sprintf("Deleted %d variables", len(keys))
+}
+
+// setSelectedValue sets the selected key's value, as . repeats an edit.
+func (a *App) setSelectedValue(val string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	if err := a.Store.Upsert(key, val); err != nil {
+		return "Not saved: " + err.Error()
+	}
+	a.renderTable()
+	return fmt.Sprintf("Saved %s", key)
+}
+
+// renameSelectedPrefix previews renaming the prefix of the selected key,
+// up to its first "_", to to, as . repeats a :renameprefix.
+func (a *App) renameSelectedPrefix(to string) string {
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	i := strings.IndexByte(key, '_')
+	if i <= 0 {
+		return fmt.Sprintf("%s has no prefix", key)
+	}
+	return a.renamePrefixCommand([]string{key[:i+1], to})
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/shellhist"
+	"github.com/rivo/tview"
+)
+
+// scrubMinLen is the shortest value :scrub-history looks for; shorter ones
+// match too much unrelated history.
+const scrubMinLen = 8
+
+// historySecrets maps the store's secret-looking values to their keys.
+func (a *App) historySecrets() map[string]string {
+	secrets := make(map[string]string)
+	for _, k := range a.Store.AllKeys() {
+		it, _ := a.Store.Get(k)
+		if len(it.Value) >= scrubMinLen && env.LooksSecret(k, it.Value) {
+			secrets[it.Value] = k
+		}
+	}
+	return secrets
+}
+
+// scrubHistoryCommand handles ":scrub-history [file...]": it lists the
+// shell history lines holding the store's secrets, from the given files
+// or the usual shells' history files, and offers to redact them.
+func (a *App) scrubHistoryCommand(args []string) string {
+	secrets := a.historySecrets()
+	if len(secrets) == 0 {
+		return "No secret-looking values to look for"
+	}
+	files := shellhist.Files()
+	if len(args) > 0 {
+		files = nil
+		for _, f := range args {
+			files = append(files, expandHome(f))
+		}
+	}
+	if len(files) == 0 {
+		return "No shell history files found"
+	}
+	var hits []shellhist.Hit
+	for _, f := range files {
+		h, err := shellhist.Scan(f, secrets)
+		if err != nil {
+			return fmt.Sprintf("Scan failed: %v", err)
+		}
+		hits = append(hits, h...)
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No secrets found in %s", strings.Join(files, ", "))
+	}
+
+	// Lines show with the secrets masked: the point is to get them off
+	// the screen as much as out of the file.
+	var pairs []string
+	for v := range secrets {
+		pairs = append(pairs, v, maskText)
+	}
+	hide := strings.NewReplacer(pairs...)
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("FILE"))
+	table.SetCell(0, 1, headerCell("LINE"))
+	table.SetCell(0, 2, headerCell("KEYS"))
+	table.SetCell(0, 3, headerCell("COMMAND"))
+	perFile := make(map[string]int)
+	for i, h := range hits {
+		perFile[h.Path]++
+		table.SetCell(i+1, 0, tview.NewTableCell(h.Path).SetTextColor(tcell.ColorGray).SetMaxWidth(30))
+		table.SetCell(i+1, 1, tview.NewTableCell(fmt.Sprint(h.Line)).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 2, tview.NewTableCell(strings.Join(h.Keys, ",")).SetTextColor(a.pal.Secret))
+		table.SetCell(i+1, 3, tview.NewTableCell(hide.Replace(h.Text)).SetExpansion(1))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %d history lines in %d files hold secrets — r redact them, ESC close ", len(hits), len(perFile))).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Rune() != 'r' {
+			return ev
+		}
+		a.confirmScrub(perFile, slices.Collect(maps.Keys(secrets)))
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 120, 24), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
+
+// confirmScrub asks before redacting values in the files of perFile.
+func (a *App) confirmScrub(perFile map[string]int, values []string) {
+	n := 0
+	for _, c := range perFile {
+		n += c
+	}
+	text := fmt.Sprintf("Replace the secrets on %d lines in %d history files with %s?\n\nA shell still running may write its own history back when it exits.", n, len(perFile), shellhist.Redacted)
+	m := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Redact", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			if label != "Redact" {
+				return
+			}
+			changed := 0
+			for _, f := range slices.Sorted(maps.Keys(perFile)) {
+				c, err := shellhist.Scrub(f, values)
+				if err != nil {
+					slog.Error("scrub history", "path", f, "err", err)
+					a.updateStatusInline(fmt.Sprintf("Scrub failed after %d lines: %v", changed, err))
+					return
+				}
+				changed += c
+			}
+			slog.Info("scrub history", "files", len(perFile), "lines", changed)
+			a.updateStatusInline(fmt.Sprintf("Redacted %d history lines", changed))
+		})
+	a.Pages.AddPage(pageModal, centerPrimitive(m, 64, 11), true, true)
+	a.App.SetFocus(m)
+}
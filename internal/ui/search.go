@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+)
+
+// searchMax bounds the search history kept in the session file.
+const searchMax = 50
+
+// rememberSearch makes q the latest search, for n and N and the history.
+func (a *App) rememberSearch(q string) {
+	if q == "" {
+		return
+	}
+	a.lastSearch = q
+	searches := slices.DeleteFunc(slices.Clone(a.searches), func(s string) bool { return s == q })
+	searches = append([]string{q}, searches...)
+	a.searches = searches[:min(len(searches), searchMax)]
+	a.saveSession()
+}
+
+// stepSearchHistory shows the search by older searches back in the history
+// in the minibuffer, as Up and Down do; past the newest it returns to what
+// was typed.
+func (a *App) stepSearchHistory(by int) {
+	at := a.searchAt + by
+	if at < -1 || at >= len(a.searches) {
+		return
+	}
+	if a.searchAt == -1 {
+		a.searchText = a.Cmd.GetText()
+	}
+	a.searchAt = at
+	if at == -1 {
+		a.Cmd.SetText(a.searchText)
+		return
+	}
+	a.Cmd.SetText(a.searches[at])
+}
+
+// searchAgain moves to the next key, or with prev the previous one, whose
+// name or value holds the latest search, as n and N do once its filter is
+// cleared. It returns a status.
+func (a *App) searchAgain(prev bool) string {
+	q := strings.ToLower(a.lastSearch)
+	if q == "" {
+		return "No previous search"
+	}
+	keys := a.Store.ListKeys()
+	vars := a.Store.Vars()
+	n := len(keys)
+	dir := 1
+	if prev {
+		dir = -1
+	}
+	for i := 1; i <= n; i++ {
+		r := a.selRow + i*dir
+		wrapped := r < 1 || r > n
+		r = ((r-1)%n+n)%n + 1
+		k := keys[r-1]
+		if !strings.Contains(strings.ToLower(k), q) && !strings.Contains(strings.ToLower(vars[k]), q) {
+			continue
+		}
+		a.setSelection(r, a.selCol)
+		switch {
+		case wrapped && prev:
+			return "/" + a.lastSearch + ": search hit TOP, continuing at BOTTOM"
+		case wrapped:
+			return "/" + a.lastSearch + ": search hit BOTTOM, continuing at TOP"
+		}
+		return "/" + a.lastSearch
+	}
+	return "Pattern not found: " + a.lastSearch
+}
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// secretFindings scans the visible values for live credentials, in table
+// order.
+func (a *App) secretFindings() []env.Finding {
+	var out []env.Finding
+	for i, k := range a.Store.ListKeys() {
+		it, _ := a.Store.GetByIndex(i)
+		if f, ok := env.ScanSecret(k, it.Value); ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// secretWarning returns a status suffix when an unredacted write holds
+// values that look like live credentials, or "".
+func (a *App) secretWarning(eo env.ExportOptions) string {
+	if eo.Redact != nil {
+		return ""
+	}
+	if n := len(a.secretFindings()); n > 0 {
+		return fmt.Sprintf("; %d values look like live credentials (:secrets)", n)
+	}
+	return ""
+}
+
+// showSecrets lists the values that look like live credentials and the
+// rule that flagged each. Enter jumps to the selected key.
+func (a *App) showSecrets() string {
+	findings := a.secretFindings()
+	if len(findings) == 0 {
+		return "No values look like live credentials"
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("RULE"))
+	table.SetCell(0, 2, headerCell("VALUE"))
+	for i, f := range findings {
+		it, _ := a.Store.Get(f.Key)
+		table.SetCell(i+1, 0, tview.NewTableCell(f.Key).SetTextColor(a.pal.Secret).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(f.Reason).SetTextColor(tcell.ColorGray))
+		table.SetCell(i+1, 2, tview.NewTableCell(a.shownValue(f.Key, it.Value)).SetExpansion(2).SetMaxWidth(50))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %d probable live credentials — Enter go to key, ESC close ", len(findings))).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(findings) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(findings[row-1].Key)
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 20), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
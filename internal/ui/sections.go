@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Section headers take table rows of their own, so a.selRow, which counts
+// keys, and the table row of a key differ once any are shown. rowOf and
+// keyOf, rebuilt by renderTable, map between the two.
+
+// tableRow is the table row showing the key at selRow-style row r, or 0
+// when the key is hidden in a folded section.
+func (a *App) tableRow(r int) int {
+	if a.rowOf == nil {
+		return r
+	}
+	if r < 1 || r > len(a.rowOf) {
+		return 0
+	}
+	return a.rowOf[r-1]
+}
+
+// keyRow is the selRow-style row of the key table row t shows, or 0 for
+// the header and section rows.
+func (a *App) keyRow(t int) int {
+	if a.keyOf == nil {
+		return t
+	}
+	if t < 0 || t >= len(a.keyOf) {
+		return 0
+	}
+	return a.keyOf[t]
+}
+
+// selectTable moves the table's selection to a.selRow.
+func (a *App) selectTable() {
+	a.Table.Select(a.tableRow(a.selRow), a.selCol)
+}
+
+// visibleRow returns the row nearest r, looking first in direction dir,
+// whose key is not folded away; 0 when every key is.
+func (a *App) visibleRow(r, dir int) int {
+	n := a.Store.Count()
+	if dir == 0 {
+		dir = 1
+	}
+	for _, d := range []int{dir, -dir} {
+		for i := r; i >= 1 && i <= n; i += d {
+			if a.tableRow(i) > 0 {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// sectionCells fills table row row with the header of section name, which
+// holds n keys in the table.
+func (a *App) sectionCells(row int, name string, n int) {
+	mark, note := "▾", ""
+	if a.folded[name] {
+		mark, note = "▸", fmt.Sprintf(" (%d hidden)", n)
+	}
+	a.Table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%s [::b]%s[::-]%s", mark, tview.Escape(name), note)).
+		SetTextColor(tcell.ColorSteelBlue).
+		SetSelectable(false))
+	a.Table.SetCell(row, 1, tview.NewTableCell(strings.Repeat("─", 40)).
+		SetTextColor(tcell.ColorSteelBlue).
+		SetSelectable(false))
+	if a.types {
+		a.Table.SetCell(row, 2, tview.NewTableCell("").SetSelectable(false))
+	}
+}
+
+// fold changes which sections are folded, as the vim z commands do: "a"
+// toggles the selected key's section, "o" opens and "c" closes it, "R"
+// opens and "M" closes them all. It returns a status.
+func (a *App) fold(op string) string {
+	if !a.Store.SectionSort() {
+		return "Sections are off (:set sections)"
+	}
+	switch op {
+	case "R":
+		clear(a.folded)
+		a.renderTable()
+		return "Opened all sections"
+	case "M":
+		for _, name := range a.Store.Sections() {
+			a.folded[name] = true
+		}
+		a.renderTable()
+		return "Folded all sections"
+	}
+	key, ok := a.selectedKey()
+	if !ok {
+		return "No key selected"
+	}
+	name := a.Store.Section(key)
+	if name == "" {
+		return fmt.Sprintf("%s is not in a section", key)
+	}
+	return a.setFolded(name, op == "c" || op == "a" && !a.folded[name])
+}
+
+// setFolded folds or opens section name.
+func (a *App) setFolded(name string, folded bool) string {
+	if folded {
+		a.folded[name] = true
+	} else {
+		delete(a.folded, name)
+	}
+	a.renderTable()
+	if folded {
+		return "Folded " + name
+	}
+	return "Opened " + name
+}
+
+// foldCommand handles ":fold [section|all|none]": with no argument it
+// toggles the selected key's section, with a name the section whose name
+// starts with it.
+func (a *App) foldCommand(args []string) string {
+	if len(args) == 0 {
+		return a.fold("a")
+	}
+	switch arg := strings.Join(args, " "); arg {
+	case "all":
+		return a.fold("M")
+	case "none":
+		return a.fold("R")
+	default:
+		if !a.Store.SectionSort() {
+			return "Sections are off (:set sections)"
+		}
+		for _, name := range a.Store.Sections() {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(arg)) {
+				return a.setFolded(name, !a.folded[name])
+			}
+		}
+		return fmt.Sprintf("No section %q", arg)
+	}
+}
+
+func (a *App) sectionsOption() string {
+	if a.Store.SectionSort() {
+		return "sections"
+	}
+	return "nosections"
+}
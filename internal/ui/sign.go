@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/sign"
+)
+
+// gpgTimeout bounds one gpg run, including a passphrase prompt.
+const gpgTimeout = time.Minute
+
+// signWanted resolves --sign against export.sign in the config.
+func (a *App) signWanted(o writeOpts) bool {
+	if o.sign != nil {
+		return *o.sign
+	}
+	return a.Config.Export.Sign
+}
+
+// signFiles signs each written file when want is set. Otherwise it points
+// out signatures the write has left stale. It returns a status suffix.
+func (a *App) signFiles(paths []string, want bool) string {
+	if !want {
+		for _, p := range paths {
+			if sign.Signed(p) {
+				return fmt.Sprintf("; %s no longer matches (--sign to re-sign)", sign.SigPath(p))
+			}
+		}
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gpgTimeout)
+	defer cancel()
+	for _, p := range paths {
+		if err := sign.Sign(ctx, p, a.Config.Export.SignKey); err != nil {
+			slog.Error("sign", "path", p, "err", err)
+			return fmt.Sprintf("; signing %s failed: %v", p, err)
+		}
+		slog.Info("signed", "path", p, "sig", sign.SigPath(p))
+	}
+	return ", signed"
+}
+
+// verifyFile checks path's signature, if it has one. problem is set when
+// the signature does not hold; note describes a good one for the status
+// line.
+func verifyFile(path string) (note, problem string) {
+	if !sign.Signed(path) {
+		return "", ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gpgTimeout)
+	defer cancel()
+	r, err := sign.Verify(ctx, path)
+	if err != nil {
+		slog.Warn("verify", "path", path, "err", err)
+		return "", fmt.Sprintf("signature could not be checked: %v", err)
+	}
+	slog.Info("verify", "path", path, "good", r.Good, "key", r.KeyID, "reason", r.Reason)
+	if !r.Good {
+		return "", "signature check failed: " + r.Reason
+	}
+	return "; signature good (" + r.Signer + ")", ""
+}
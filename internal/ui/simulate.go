@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// simNote explains how v reaches the child, with the color to show it in;
+// "" means it arrives exactly as in the table.
+func simNote(v env.SimVar) (string, tcell.Color) {
+	switch v.Change {
+	case env.SimUnset:
+		return "unset by env", tcell.ColorRed
+	case env.SimDropped:
+		return "dropped: not a shell identifier", tcell.ColorRed
+	case env.SimSet:
+		if v.Table != "" && v.Table != v.Value {
+			return "set by the command, was " + v.Table, tcell.ColorGreen
+		}
+		return "set by the command", tcell.ColorGreen
+	}
+	switch {
+	case strings.HasPrefix(v.Value, remote.PassScheme):
+		return "unresolved pass reference (:resolve)", tcell.ColorOrange
+	case cred.IsOpRef(v.Value):
+		return "1Password reference, resolved by :wrap, :w and :resolve", tcell.ColorOrange
+	case cred.IsRef(v.Value):
+		return "keyring reference, resolved by :wrap and :w", tcell.ColorOrange
+	case v.Literal:
+		return "$ reference passed literally, not expanded", tcell.ColorOrange
+	}
+	return "", 0
+}
+
+// showSimulation opens a read-only view of the environment a child process
+// would receive, optionally through the given command line. d toggles
+// showing only the variables that differ from the table.
+func (a *App) showSimulation(cmd string) string {
+	sim, err := a.Store.Simulate(cmd)
+	if err != nil {
+		return fmt.Sprintf("Simulate failed: %v", err)
+	}
+
+	differ, gone := 0, 0
+	for _, v := range sim.Vars {
+		if note, _ := simNote(v); note != "" {
+			differ++
+		}
+		if v.Gone() {
+			gone++
+		}
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	onlyDiff := false
+	fill := func() {
+		table.Clear()
+		table.SetCell(0, 0, headerCell("KEY"))
+		table.SetCell(0, 1, headerCell("CHILD VALUE"))
+		table.SetCell(0, 2, headerCell("NOTE"))
+		row := 1
+		for _, v := range sim.Vars {
+			note, color := simNote(v)
+			if onlyDiff && note == "" {
+				continue
+			}
+			key := tview.NewTableCell(tview.Escape(v.Key)).SetExpansion(1)
+			val := tview.NewTableCell(tview.Escape(v.Value)).SetExpansion(2)
+			if v.Gone() {
+				val.SetText("").SetAttributes(tcell.AttrDim)
+				key.SetAttributes(tcell.AttrStrikeThrough)
+			}
+			if color != 0 {
+				key.SetTextColor(color)
+			}
+			table.SetCell(row, 0, key)
+			table.SetCell(row, 1, val)
+			table.SetCell(row, 2, tview.NewTableCell(tview.Escape(note)).SetExpansion(2).SetTextColor(color))
+			row++
+		}
+		table.Select(1, 0)
+	}
+	fill()
+
+	target := "a child process"
+	if sim.Command != "" {
+		target = sim.Command
+	}
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Environment of %s — %d vars, %d differ from the table; d differences only, ESC close ",
+			tview.Escape(target), len(sim.Vars)-gone, differ)).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Rune() {
+		case 'd':
+			onlyDiff = !onlyDiff
+			fill()
+		case 'q':
+			a.closeModal()
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 120, 28), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+// maxLoaders bounds how many sources are fetched at once.
+const maxLoaders = 4
+
+// Source is a layer loaded at startup: a dotenv file or, with Remote set, a
+// "provider:path" spec.
+type Source struct {
+	Spec   string
+	Remote bool
+
+	items []env.Item // the file's content, once withTrusted has read it
+	read  bool
+}
+
+type sourceState int
+
+const (
+	sourcePending sourceState = iota
+	sourceLoading
+	sourceLoaded
+	sourceFailed
+)
+
+func (s sourceState) String() string {
+	return [...]string{"pending", "loading", "loaded", "failed"}[s]
+}
+
+// sourceStatus is the load state of one startup source. It is only touched
+// on the UI goroutine.
+type sourceStatus struct {
+	Source
+	state sourceState
+	items []env.Item
+	err   error
+	took  time.Duration
+}
+
+// loadSources fetches sources concurrently and merges them into the store in
+// precedence order as soon as every earlier source has arrived, so the table
+// fills in progressively without a later layer being overridden by an
+// earlier one that happened to be slower.
+func (a *App) loadSources(sources []Source) {
+	if len(sources) == 0 {
+		a.afterLoad()
+		return
+	}
+	a.sources = make([]*sourceStatus, len(sources))
+	for i, s := range sources {
+		a.sources[i] = &sourceStatus{Source: s}
+	}
+	a.updateStatusInline(fmt.Sprintf("Loading %d sources…", len(sources)))
+
+	// The fetches run off the UI goroutine, so they get the process store
+	// and provider config now rather than reading a.Store, which switching
+	// buffers replaces.
+	store, cfg := a.processStore(), a.providerConfig()
+	sem := make(chan struct{}, maxLoaders)
+	for i, s := range sources {
+		go func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			a.App.QueueUpdateDraw(func() { a.sources[i].state = sourceLoading })
+
+			start := time.Now()
+			items, err := fetchSource(store, cfg, s)
+			took := time.Since(start)
+
+			if err != nil {
+				slog.Error("source failed", "source", s.Spec, "err", err)
+			} else {
+				slog.Info("source loaded", "source", s.Spec, "count", len(items), "took", took)
+			}
+			a.App.QueueUpdateDraw(func() {
+				st := a.sources[i]
+				st.items, st.err, st.took = items, err, took
+				st.state = sourceLoaded
+				if err != nil {
+					st.state = sourceFailed
+				}
+				a.applyLoadedSources()
+			})
+		}()
+	}
+}
+
+// fetchSource returns the items of s: those withTrusted read, or else
+// read with store's file settings or a provider opened with cfg.
+func fetchSource(store Store, cfg remote.Config, s Source) ([]env.Item, error) {
+	if s.read {
+		return s.items, nil
+	}
+	if !s.Remote {
+		return store.ParseFile(expandHome(s.Spec))
+	}
+	name, path, ok := strings.Cut(s.Spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("remote source must be provider:path")
+	}
+	p, err := remote.Open(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := p.Pull(context.Background(), path)
+	items := make([]env.Item, len(entries))
+	for i, e := range entries {
+		items[i] = env.Item{Key: e.Key, Value: e.Value}
+	}
+	return items, err
+}
+
+// applyLoadedSources merges the longest finished prefix of sources not yet
+// applied.
+func (a *App) applyLoadedSources() {
+	for a.sourcesApplied < len(a.sources) {
+		st := a.sources[a.sourcesApplied]
+		if st.state == sourcePending || st.state == sourceLoading {
+			break
+		}
+		if st.state == sourceLoaded {
+			a.loadingSource = true
+			a.processStore().Merge(st.Spec, st.items)
+			a.loadingSource = false
+		}
+		st.items = nil
+		a.sourcesApplied++
+	}
+	a.renderTable()
+
+	if a.sourcesApplied < len(a.sources) {
+		a.updateStatusInline(fmt.Sprintf("Loading sources %d/%d…", a.sourcesApplied, len(a.sources)))
+		return
+	}
+	failed := 0
+	for _, st := range a.sources {
+		if st.state == sourceFailed {
+			failed++
+		}
+	}
+	msg := fmt.Sprintf("Loaded %d sources", len(a.sources)-failed)
+	if failed > 0 {
+		msg += fmt.Sprintf(", %d failed (:sources)", failed)
+	}
+	if sum := a.layerSummary(); sum != "" {
+		msg += "; " + sum
+	}
+	a.updateStatusInline(msg)
+	a.afterLoad()
+}
+
+func (a *App) showSources() string {
+	if len(a.sources) == 0 {
+		return "No startup sources"
+	}
+	var b strings.Builder
+	for _, st := range a.sources {
+		kind := "file"
+		if st.Remote {
+			kind = "remote"
+		}
+		fmt.Fprintf(&b, "%-7s %-8s %s", kind, st.state, st.Spec)
+		switch st.state {
+		case sourceLoaded:
+			fmt.Fprintf(&b, "  (%s)", st.took.Round(time.Millisecond))
+		case sourceFailed:
+			fmt.Fprintf(&b, "  [red]%v[-]", st.err)
+		}
+		b.WriteString("\n")
+	}
+	a.showText("Sources", b.String())
+	return ""
+}
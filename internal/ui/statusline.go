@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// defaultStatusLine is the status line layout when the config sets none.
+const defaultStatusLine = " %m | %R vars%a%u | %h"
+
+// statusLine expands the segments of format for the status line:
+//
+//	%m  mode
+//	%f  buffer name: the file shown, or [process]
+//	%n  buffer number and count, as 2/3
+//	%r  row of the selected key
+//	%R  number of keys shown
+//	%k  selected key
+//	%q  search filter
+//	%d  [+] when there are unsaved changes
+//	%a  changes not applied to the process, after a | separator
+//	%u  size budget, after a | separator
+//	%h  key hints
+//	%%  a literal %
+//
+// An unknown segment is kept as written. Text from the table is escaped,
+// the template itself is not, so it may carry color tags.
+func (a *App) statusLine(format, mode, hints string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 == len(format) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'm':
+			b.WriteString(mode)
+		case 'f':
+			b.WriteString(tview.Escape(a.buf().name))
+		case 'n':
+			fmt.Fprintf(&b, "%d/%d", a.cur+1, len(a.buffers))
+		case 'r':
+			b.WriteString(strconv.Itoa(a.selRow))
+		case 'R':
+			b.WriteString(strconv.Itoa(a.Store.Count()))
+		case 'k':
+			if key, ok := a.selectedKey(); ok {
+				b.WriteString(tview.Escape(key))
+			}
+		case 'q':
+			b.WriteString(tview.Escape(a.lastFilter))
+		case 'd':
+			if a.Store.Dirty() {
+				b.WriteString(tview.Escape("[+]"))
+			}
+		case 'a':
+			b.WriteString(a.applyStatus())
+		case 'u':
+			b.WriteString(a.budgetStatus())
+		case 'h':
+			b.WriteString(hints)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
+
+// selectionInStatus reports whether the status line shows the selection,
+// and so is redrawn as it moves.
+func (a *App) selectionInStatus() bool {
+	return strings.Contains(a.statusFormat, "%r") || strings.Contains(a.statusFormat, "%k")
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+)
+
+// Store is the environment model the UI drives. *env.Store implements it;
+// tests can supply a detached store (env.NewStoreFrom) or their own fake.
+type Store interface {
+	ListKeys() []string
+	AllKeys() []string
+	SetNaturalSort(on bool)
+	NaturalSort() bool
+	SetSectionSort(on bool)
+	SectionSort() bool
+	Section(key string) string
+	Sections() []string
+	Vars() map[string]string
+	Count() int
+	GetByIndex(idx int) (env.Item, bool)
+	Get(key string) (env.Item, bool)
+	Upsert(key, val string) error
+	CheckKey(key string) error
+	SetKeyPolicy(p env.KeyPolicy) error
+	KeyPolicy() env.KeyPolicy
+	Delete(key string)
+	Filter(query string)
+	Dirty() bool
+	LoadFromProcess()
+
+	Begin()
+	Commit() error
+	Rollback()
+	OnChange(fn func(ops []env.Op))
+	Pending() []env.Op
+	Undo() ([]env.Op, bool)
+	Redo() ([]env.Op, bool)
+	Process() map[string]string
+	Apply(keys []string) ([]env.Op, error)
+
+	ReadImport(path string) (env.ImportFile, error)
+	MergeFile(f env.ImportFile) int
+	Encoding(path string) (env.Encoding, bool)
+	SourceFormat(path string) (string, bool)
+	ParseFile(path string) ([]env.Item, error)
+	SetAgeKeys(k env.AgeKeys)
+	AgeKeys() env.AgeKeys
+	ParseCompose(path, service string) ([]env.Item, []string, error)
+	Merge(source string, items []env.Item) int
+	ExportWith(path string, o env.ExportOptions) error
+	ExportTargets(dir string, targets []string, o env.ExportOptions) ([]string, error)
+
+	State() []env.KeyState
+	Restore(states []env.KeyState) int
+
+	Layers(key string) []env.Layer
+	Provenance(key string) (env.Provenance, bool)
+	SetExpiry(key string, t time.Time)
+	Expiry(key string) (time.Time, bool)
+	Expiring(d time.Duration) []env.Expiry
+	Overrides() []env.Override
+	Shadowed(key string) bool
+	CaseCollisions() [][]string
+	DuplicateValues() [][]string
+	SetMeta(name, value string) error
+	DeleteMeta(name string)
+	Meta() map[string]string
+	MetaUsers(name string) []string
+	Resolve(value string) (string, []string)
+	MergeCase(key string) []string
+	PlanPrefixRename(from, to string) env.RenamePlan
+	RenamePrefix(from, to string) (env.RenamePlan, error)
+	ProxyIssues() []env.ProxyIssue
+	SyncProxy(key string) []string
+	Simulate(cmd string) (env.Simulation, error)
+	Stats() env.Stats
+	MeasureBudget(b env.Budget, keys []string) env.BudgetReport
+}
+
+var _ Store = (*env.Store)(nil)
@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/lint"
+	"github.com/rivo/tview"
+)
+
+const (
+	keySuggestMax = 10 // entries in the add form's key drop-down
+	recentMax     = 20 // added keys remembered per project
+)
+
+// keySuggestion is a key the add form offers, where it was found and, for
+// a sibling env file, the value it has there.
+type keySuggestion struct {
+	key, source, value string
+}
+
+// usageScan holds the keys the code under dir reads; keys stays nil until
+// the background scan finishes.
+type usageScan struct {
+	dir  string
+	keys []string
+}
+
+// projectDir is the directory of the file :w writes by default.
+func (a *App) projectDir() string {
+	dir, err := filepath.Abs(filepath.Dir(a.writeDefault()))
+	if err != nil {
+		return "."
+	}
+	return dir
+}
+
+// rememberKey records key as the latest added in the current project.
+func (a *App) rememberKey(key string) {
+	dir := a.projectDir()
+	if a.recent == nil {
+		a.recent = make(map[string][]string)
+	}
+	keys := slices.DeleteFunc(slices.Clone(a.recent[dir]), func(k string) bool { return k == key })
+	keys = append([]string{key}, keys...)
+	a.recent[dir] = keys[:min(len(keys), recentMax)]
+	a.saveSession()
+}
+
+// usedKeys returns the keys the project's code reads, starting a
+// background scan the first time and nil until it is done.
+func (a *App) usedKeys() []string {
+	dir := a.projectDir()
+	if u := a.usage; u != nil && u.dir == dir {
+		return u.keys
+	}
+	u := &usageScan{dir: dir}
+	a.usage = u
+	go func() {
+		keys := env.UsedKeys(dir)
+		a.App.QueueUpdate(func() { u.keys = keys })
+	}()
+	return nil
+}
+
+// schemaKeys lists the keys the export schema for the default file and the
+// lint rules name.
+func (a *App) schemaKeys() []string {
+	keys := slices.Clone(a.Config.ExportFor(a.writeDefault()).Keys)
+	if rs, err := lint.Default(); err == nil {
+		keys = append(keys, rs.Keys()...)
+	}
+	return keys
+}
+
+// siblingSuggestions lists the keys of the env files next to the default
+// file, with their values there.
+func (a *App) siblingSuggestions() []keySuggestion {
+	var out []keySuggestion
+	for _, f := range env.SiblingFiles(a.writeDefault()) {
+		items, err := env.ParseFile(f)
+		if err != nil {
+			continue
+		}
+		for _, it := range items {
+			out = append(out, keySuggestion{key: it.Key, source: filepath.Base(f), value: it.Value})
+		}
+	}
+	return out
+}
+
+// suggestKeys gives the add form's key field a drop-down of keys missing
+// here: recently added in this project, named by the schema, read by the
+// code and set in sibling env files, in that order. Picking a key from a
+// sibling file also fills in its value there when the value is empty.
+func (a *App) suggestKeys(keyField, valField *tview.InputField) {
+	recent := a.recent[a.projectDir()]
+	schema := a.schemaKeys()
+	siblings := a.siblingSuggestions()
+	a.usedKeys() // start the scan while the user types
+	var shown []keySuggestion
+
+	keyField.SetAutocompleteUseTags(true)
+	keyField.SetAutocompleteFunc(func(text string) []string {
+		text = strings.ToUpper(strings.TrimSpace(text))
+		if text == "" {
+			return nil
+		}
+		var all []keySuggestion
+		for _, k := range recent {
+			all = append(all, keySuggestion{key: k, source: "recent"})
+		}
+		for _, k := range schema {
+			all = append(all, keySuggestion{key: k, source: "schema"})
+		}
+		for _, k := range a.usedKeys() {
+			all = append(all, keySuggestion{key: k, source: "code"})
+		}
+		all = append(all, siblings...)
+
+		// Keys starting with the text come before those merely holding it.
+		var prefix, inner []keySuggestion
+		seen := make(map[string]bool)
+		for _, s := range all {
+			up := strings.ToUpper(s.key)
+			if seen[s.key] || up == text || !strings.Contains(up, text) {
+				continue
+			}
+			seen[s.key] = true
+			if _, ok := a.Store.Get(s.key); ok {
+				continue
+			}
+			if strings.HasPrefix(up, text) {
+				prefix = append(prefix, s)
+			} else {
+				inner = append(inner, s)
+			}
+		}
+		shown = append(prefix, inner...)
+		shown = shown[:min(len(shown), keySuggestMax)]
+		entries := make([]string, len(shown))
+		for i, s := range shown {
+			entries[i] = fmt.Sprintf("%s [gray]%s[-]", tview.Escape(s.key), tview.Escape(s.source))
+		}
+		return entries
+	})
+	keyField.SetAutocompletedFunc(func(_ string, index, source int) bool {
+		if source == tview.AutocompletedNavigate || index >= len(shown) {
+			return false
+		}
+		s := shown[index]
+		keyField.SetText(s.key)
+		if s.value != "" && valField.GetText() == "" {
+			valField.SetText(s.value)
+		}
+		return true
+	})
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/dbconn"
+	"github.com/rivethorn/envoy/internal/remote"
+	"github.com/rivo/tview"
+)
+
+// testConnCommand handles ":testconn [KEY...|%]": it connects to the
+// database each key names, the selected key's by default.
+func (a *App) testConnCommand(args []string) string {
+	var keys []string
+	switch {
+	case len(args) == 1 && args[0] == "%":
+		for _, k := range a.Store.AllKeys() {
+			if _, ok := a.connTarget(k); ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			return "No database URLs or DSNs to test"
+		}
+	case len(args) > 0:
+		keys = args
+	default:
+		k, ok := a.selectedKey()
+		if !ok {
+			return "No variable selected"
+		}
+		keys = []string{k}
+	}
+	targets := make([]dbconn.Target, len(keys))
+	for i, k := range keys {
+		t, ok := a.connTarget(k)
+		if !ok {
+			return fmt.Sprintf("%s does not hold a postgres, mysql, redis or mongodb connection string", k)
+		}
+		targets[i] = t
+	}
+
+	results := make([]dbconn.Result, len(keys))
+	a.runRemote(fmt.Sprintf("Connecting to %d databases", len(keys)), func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		var done atomic.Int32
+		sem := make(chan struct{}, maxProbes)
+		for i, t := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i] = dbconn.Test(ctx, t)
+				slog.Info("testconn", "key", keys[i], "kind", t.Kind, "addr", t.Addr(), "ok", results[i].OK, "err", results[i].Err)
+				remote.ReportProgress(ctx, int(done.Add(1)), len(targets))
+			}()
+		}
+		wg.Wait()
+		return ctx.Err()
+	}, func(err error) {
+		if err != nil {
+			a.updateStatusInline("Connection test cancelled")
+			return
+		}
+		if len(keys) == 1 {
+			a.updateStatusInline(keys[0] + ": " + results[0].String())
+			return
+		}
+		a.showConnResults(keys, results)
+	})
+	return ""
+}
+
+// connTarget parses key's value, with meta-variables resolved, as a
+// database connection string.
+func (a *App) connTarget(key string) (dbconn.Target, bool) {
+	it, ok := a.Store.Get(key)
+	if !ok {
+		return dbconn.Target{}, false
+	}
+	v, _ := a.Store.Resolve(it.Value)
+	return dbconn.Parse(v)
+}
+
+// showConnResults lists the outcome per key. Enter jumps to the key.
+func (a *App) showConnResults(keys []string, results []dbconn.Result) {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("DATABASE"))
+	table.SetCell(0, 2, headerCell("ADDRESS"))
+	table.SetCell(0, 3, headerCell("RESULT"))
+	failed := 0
+	for i, r := range results {
+		color := tcell.ColorGreen
+		if !r.OK {
+			color = tcell.ColorRed
+			failed++
+		}
+		kind := r.Target.Kind
+		if dbconn.HasDriver(kind) {
+			kind += " (driver)"
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(keys[i]).SetTextColor(color).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(kind))
+		table.SetCell(i+1, 2, tview.NewTableCell(r.Target.Addr()).SetExpansion(1))
+		table.SetCell(i+1, 3, tview.NewTableCell(r.String()).SetExpansion(3).SetMaxWidth(70))
+	}
+	table.Select(1, 0)
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Connection tests: %d of %d failed — Enter go to key, ESC close ", failed, len(results))).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(keys) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(keys[row-1])
+	})
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 130, 16), true, true)
+	a.App.SetFocus(table)
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// todoKeys lists the visible keys whose values are placeholders, in table
+// order.
+func (a *App) todoKeys() []string {
+	var keys []string
+	for i, k := range a.Store.ListKeys() {
+		if it, ok := a.Store.GetByIndex(i); ok && env.IsPlaceholder(it.Value) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// todoWarning returns a status suffix when values are still placeholders,
+// or "".
+func (a *App) todoWarning() string {
+	if n := len(a.todoKeys()); n > 0 {
+		return fmt.Sprintf("; %d placeholder values (:todos)", n)
+	}
+	return ""
+}
+
+// nextTodo moves to the next placeholder value after the selected row, or
+// the previous one before it, wrapping around.
+func (a *App) nextTodo(prev bool) {
+	var rows []int
+	for i := range a.Store.ListKeys() {
+		if it, ok := a.Store.GetByIndex(i); ok && env.IsPlaceholder(it.Value) {
+			rows = append(rows, i+1)
+		}
+	}
+	if len(rows) == 0 {
+		a.updateStatusInline("No placeholder values")
+		return
+	}
+	target := rows[0]
+	if prev {
+		target = rows[len(rows)-1]
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i] < a.selRow {
+				target = rows[i]
+				break
+			}
+		}
+	} else {
+		for _, r := range rows {
+			if r > a.selRow {
+				target = r
+				break
+			}
+		}
+	}
+	a.setSelection(target, 1)
+}
+
+// showTodos lists the placeholder values. Enter jumps to the selected key.
+func (a *App) showTodos() string {
+	keys := a.todoKeys()
+	if len(keys) == 0 {
+		return "No placeholder values"
+	}
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("VALUE"))
+	for i, k := range keys {
+		it, _ := a.Store.Get(k)
+		table.SetCell(i+1, 0, tview.NewTableCell(k).SetTextColor(tcell.ColorYellow).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(it.Value).SetTextColor(a.pal.Placeholder).SetExpansion(2).SetMaxWidth(60))
+	}
+	table.Select(1, 0)
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %d placeholder values — Enter go to key, ESC close ", len(keys))).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(keys) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(keys[row-1])
+	})
+
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 100, 20), true, true)
+	a.App.SetFocus(table)
+	return ""
+}
@@ -0,0 +1,230 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/trust"
+	"github.com/rivo/tview"
+)
+
+// checkFile reports on an env file about to be loaded with items. A
+// signature that does not hold is one of the problems; a good one is
+// described in note.
+func checkFile(path string, items []env.Item) (r trust.Report, note string) {
+	keys := make([]string, len(items))
+	for i, it := range items {
+		keys[i] = it.Key
+	}
+	r, err := trust.CheckFile(path, keys)
+	if err != nil {
+		// An unreadable file fails on load with a clearer error.
+		slog.Debug("trust check", "path", path, "err", err)
+		r = trust.Report{Path: path}
+	}
+	note, problem := verifyFile(path)
+	if problem != "" {
+		r.Problems = append(r.Problems, problem)
+	}
+	return r, note
+}
+
+// confirmUntrusted shows why files are suspect and calls then with the
+// user's answer.
+func (a *App) confirmUntrusted(title, yes string, reports []trust.Report, then func(load bool)) {
+	var b strings.Builder
+	for i, r := range reports {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(tview.Escape(r.String()))
+	}
+	b.WriteString("\n\n[yellow]Env files can point PATH or the dynamic loader at code of their choosing; anything you :apply reaches every program started afterwards.[-]")
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true).
+		SetScrollable(true).
+		SetText(b.String())
+	cancel := func() {
+		a.closeModal()
+		then(false)
+	}
+	form := tview.NewForm().
+		AddButton(yes, func() {
+			a.closeModal()
+			then(true)
+		}).
+		AddButton("Cancel", cancel)
+	form.SetButtonsAlign(tview.AlignCenter).SetCancelFunc(cancel)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	layout.SetBorder(true).SetTitle(" " + title + " ").SetTitleAlign(tview.AlignLeft)
+	a.Pages.AddPage(pageModal, centerPrimitive(layout, 90, 20), true, true)
+	a.App.SetFocus(form)
+}
+
+// importCommand handles ":import <path>" and ":import compose <file>
+// [service]", asking first when the file is suspect.
+func (a *App) importCommand(args []string) string {
+	if len(args) < 1 {
+		return "Usage: :import <path> | :import compose <file> [service] | :import chamber <file>"
+	}
+	if args[0] == "compose" && len(args) > 1 {
+		return a.importCompose(args[1:])
+	}
+	if args[0] == "chamber" && len(args) > 1 {
+		return a.importChamber(expandHome(strings.Join(args[1:], " ")))
+	}
+	path := expandHome(strings.Join(args, " "))
+	f, err := a.Store.ReadImport(path)
+	if a.ageRetry(err, func() string { return a.importCommand(args) }) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("Import failed: %v", err)
+	}
+	// The items checked are the ones merged: reading the file again would
+	// decrypt it or run its CLI twice, and could load other content.
+	return a.importTrusted(path, f.Items, func() string { return a.importFile(f) })
+}
+
+// importTrusted runs load once path, about to be loaded with items, has
+// been checked, asking first when it is suspect.
+func (a *App) importTrusted(path string, items []env.Item, load func() string) string {
+	return a.loadTrusted("Import", path, items, load)
+}
+
+// loadTrusted is importTrusted for loads other than an import, named by
+// verb in the question.
+func (a *App) loadTrusted(verb, path string, items []env.Item, load func() string) string {
+	r, note := checkFile(path, items)
+	if !r.Trusted() {
+		a.confirmUntrusted(verb+" "+path+"?", verb+" anyway", []trust.Report{r}, func(ok bool) {
+			if !ok {
+				a.updateStatusInline(verb + " cancelled")
+				return
+			}
+			slog.Info("load despite trust problems", "verb", verb, "path", path, "problems", r.Problems)
+			a.updateStatusInline(load())
+		})
+		return ""
+	}
+	return load() + note
+}
+
+// importCompose merges the environment a docker-compose service would
+// start with, its ${VAR} references resolved against the store.
+func (a *App) importCompose(args []string) string {
+	path, service := expandHome(args[0]), ""
+	if len(args) > 1 {
+		service = args[1]
+	}
+	items, unset, err := a.Store.ParseCompose(path, service)
+	if err != nil {
+		return fmt.Sprintf("Import failed: %v", err)
+	}
+	source := "compose:" + path
+	if service != "" {
+		source += "/" + service
+	}
+	return a.importTrusted(path, items, func() string {
+		n := a.Store.Merge(source, items)
+		a.renderTable()
+		msg := fmt.Sprintf("Imported %d vars from %s", n, source)
+		if len(unset) > 0 {
+			msg += fmt.Sprintf("; unset, so empty: %s", strings.Join(unset, ", "))
+		}
+		return msg + a.caseWarning() + a.todoWarning()
+	})
+}
+
+// importChamber merges a file chamber export wrote, as JSON or dotenv,
+// naming each key as chamber exec would: db-password is DB_PASSWORD.
+func (a *App) importChamber(path string) string {
+	items, err := a.Store.ParseFile(path)
+	if err != nil {
+		return fmt.Sprintf("Import failed: %v", err)
+	}
+	items = env.ChamberItems(items)
+	source := "chamber:" + path
+	return a.importTrusted(path, items, func() string {
+		n := a.Store.Merge(source, items)
+		a.renderTable()
+		return fmt.Sprintf("Imported %d vars from %s", n, source) + a.caseWarning() + a.todoWarning()
+	})
+}
+
+func (a *App) importFile(f env.ImportFile) string {
+	path := f.Path
+	n := a.Store.MergeFile(f)
+	a.renderTable()
+	// Lock it now so a clash is reported before any editing.
+	a.bindFile(path, func() {})
+	return fmt.Sprintf("Imported %d vars from %s", n, path) + a.formatNote(path) + a.encodingWarning(path) + a.caseWarning() + a.expiryWarning() + a.todoWarning()
+}
+
+// sourceNames are the formats an import reports having read a file in.
+var sourceNames = map[string]string{
+	env.SourceJSON:       "JSON",
+	env.SourceYAML:       "YAML",
+	env.SourceManifest:   "Kubernetes manifests",
+	env.SourceTOML:       "TOML",
+	env.SourceXML:        "XML properties",
+	env.SourceProperties: "Java properties",
+	env.SourceVault:      "dotenv-vault",
+}
+
+// formatNote names the format path was read in, when it was not dotenv.
+func (a *App) formatNote(path string) string {
+	f, ok := a.Store.SourceFormat(path)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" (read as %s)", cmp.Or(sourceNames[f], f))
+}
+
+// withTrusted calls load with sources, leaving out suspect layer files
+// unless the user confirms them. Layer files are read here, once, so that
+// what is loaded is what was checked.
+func (a *App) withTrusted(sources []Source, load func([]Source)) {
+	var reports []trust.Report
+	suspect := make(map[string]bool)
+	sources = slices.Clone(sources)
+	for i, s := range sources {
+		if s.Remote {
+			continue
+		}
+		items, err := a.processStore().ParseFile(expandHome(s.Spec))
+		if err != nil {
+			continue
+		}
+		sources[i].items, sources[i].read = items, true
+		if r, _ := checkFile(expandHome(s.Spec), items); !r.Trusted() {
+			reports = append(reports, r)
+			suspect[s.Spec] = true
+		}
+	}
+	if len(reports) == 0 {
+		load(sources)
+		return
+	}
+	a.confirmUntrusted(fmt.Sprintf("%d layer files need confirmation", len(reports)), "Load anyway", reports, func(ok bool) {
+		if ok {
+			load(sources)
+			return
+		}
+		var kept []Source
+		for _, s := range sources {
+			if !suspect[s.Spec] {
+				kept = append(kept, s)
+			}
+		}
+		load(kept)
+	})
+}
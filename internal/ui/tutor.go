@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivethorn/envoy/internal/env"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// tutorEnv is the sandbox buffer :tutor loads.
+var tutorEnv = []string{
+	"APP_NAME=envoy-tutor",
+	"APP_PORT=8080",
+	"DATABASE_URL=postgres://localhost:5432/tutor",
+	"DB_POOL_SIZE=10",
+	"LOG_LEVEL=info",
+	"OLD_TOKEN=delete-me",
+	"REDIS_URL=redis://localhost:6379",
+	"TUTOR_NAME=change-me",
+	"ZONE=eu-west-1",
+}
+
+// tutorFile is where the last lesson writes, inside the sandbox.
+const tutorFile = "tutor.env"
+
+type lesson struct {
+	text string
+	done func(a *App) bool
+}
+
+var lessons = []lesson{
+	{"Move down with [::b]j[::-] (or the down arrow). Move down three rows; a count works too: [::b]3j[::-].",
+		func(a *App) bool { return a.selRow >= 4 }},
+	{"Jump to the last row with [::b]G[::-].",
+		func(a *App) bool { return a.selRow == a.Store.Count() }},
+	{"Jump back to the first row with [::b]gg[::-].",
+		func(a *App) bool { return a.selRow == 1 }},
+	{"Search with [::b]/[::-]: type [::b]/db[::-] and press Enter. The table filters as you type.",
+		func(a *App) bool { return a.lastFilter != "" && a.Store.Count() < len(tutorEnv) }},
+	{"Clear the filter: press [::b]/[::-] then Enter on an empty search.",
+		func(a *App) bool { return a.lastFilter == "" && a.Store.Count() >= len(tutorEnv)-1 }},
+	{"Move to TUTOR_NAME, press [::b]i[::-] to edit, replace change-me with your name and choose Save.",
+		func(a *App) bool {
+			it, ok := a.Store.Get("TUTOR_NAME")
+			return ok && it.Value != "change-me"
+		}},
+	{"Add a variable with [::b]A[::-]: key GREETING, any value.",
+		func(a *App) bool { _, ok := a.Store.Get("GREETING"); return ok }},
+	{"Move to OLD_TOKEN and delete it with [::b]x[::-].",
+		func(a *App) bool { _, ok := a.Store.Get("OLD_TOKEN"); return !ok }},
+	{"Commands start with [::b]:[::-]. Write the buffer with [::b]:w " + tutorFile + "[::-] (the sandbox keeps it in memory).",
+		func(a *App) bool { return a.tutor != nil && a.tutor.wrote() }},
+}
+
+// tutorState is a running :tutor session.
+type tutorState struct {
+	saved Store // the user's store, restored on exit
+	fs    *env.MemFS
+	step  int
+	pane  *tview.TextView
+}
+
+func (t *tutorState) wrote() bool {
+	_, err := t.fs.ReadFile(tutorFile)
+	return err == nil
+}
+
+// tutorCommand handles ":tutor" and ":tutor off".
+func (a *App) tutorCommand(args []string) string {
+	if len(args) > 0 && args[0] == "off" {
+		return a.endTutor()
+	}
+	if a.tutor != nil {
+		return "Tutor already running (:tutor off to leave)"
+	}
+	store := env.NewStoreFrom(tutorEnv)
+	fsys := env.NewMemFS()
+	store.SetFS(fsys)
+
+	pane := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	pane.SetBorder(true).SetBorderColor(tcell.ColorYellow).SetTitleAlign(tview.AlignLeft)
+	pane.SetInputCapture(a.paneInput)
+	a.tutor = &tutorState{saved: a.Store, fs: fsys, pane: pane}
+	a.Store = store
+	a.lastFilter = ""
+	a.layoutBody()
+	a.selRow, a.selCol = 1, 0
+	a.renderTable()
+	a.showLesson()
+	return "Tutor: a sandbox buffer; nothing here touches your environment"
+}
+
+func (a *App) endTutor() string {
+	if a.tutor == nil {
+		return "Tutor not running"
+	}
+	a.Store = a.tutor.saved
+	a.tutor = nil
+	a.layoutBody()
+	a.App.SetFocus(a.Table)
+	a.lastFilter = ""
+	a.Store.Filter("")
+	a.renderTable()
+	return "Left the tutor"
+}
+
+func (a *App) showLesson() {
+	t := a.tutor
+	if t.step >= len(lessons) {
+		t.pane.SetTitle(" Tutor — done ")
+		t.pane.SetText("That's the basics.\n\nMore: [::b]:help[::-] lists every command, [::b]K[::-] opens the inspector.\n\nLeave with [::b]:tutor off[::-].")
+		return
+	}
+	t.pane.SetTitle(fmt.Sprintf(" Tutor %d/%d ", t.step+1, len(lessons)))
+	t.pane.SetText(lessons[t.step].text + "\n\n[gray]:tutor off to leave[-]")
+}
+
+// tutorCheck advances the tutor when the current lesson is complete. It
+// runs after selection changes and redraws.
+func (a *App) tutorCheck() {
+	t := a.tutor
+	if t == nil || t.step >= len(lessons) || !lessons[t.step].done(a) {
+		return
+	}
+	t.step++
+	a.showLesson()
+}
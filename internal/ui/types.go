@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivo/tview"
+)
+
+// kindIcons are the type column's markers, one or two cells wide.
+var kindIcons = map[env.Kind]struct {
+	icon  string
+	color tcell.Color
+}{
+	env.KindString: {"s", tcell.ColorGray},
+	env.KindInt:    {"#", tcell.ColorAqua},
+	env.KindBool:   {"?", tcell.ColorGreen},
+	env.KindURL:    {"@", tcell.ColorBlue},
+	env.KindPath:   {"/", tcell.ColorTeal},
+	env.KindSecret: {"*", tcell.ColorRed},
+	env.KindJSON:   {"{}", tcell.ColorPurple},
+}
+
+// kindCell is the type column's cell for key. It is not selectable, so the
+// cursor keeps to the key and value columns.
+func kindCell(key, value string) *tview.TableCell {
+	c := tview.NewTableCell("").SetSelectable(false)
+	if k, ok := kindIcons[env.Infer(key, value)]; ok {
+		c.SetText(k.icon).SetTextColor(k.color)
+	}
+	return c
+}
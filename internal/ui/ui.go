@@ -1,12 +1,27 @@
 package ui
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/rivethorn/envoy/internal/clipboard"
+	"github.com/rivethorn/envoy/internal/config"
+	"github.com/rivethorn/envoy/internal/cred"
 	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/explain"
+	"github.com/rivethorn/envoy/internal/lock"
+	"github.com/rivethorn/envoy/internal/probe"
+	"github.com/rivethorn/envoy/internal/session"
+	"github.com/rivethorn/envoy/internal/textdiff"
+	"github.com/rivethorn/envoy/internal/update"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -25,23 +40,176 @@ type App struct {
 	Cmd    *tview.InputField
 	Layout *tview.Flex
 
-	Store *env.Store
-	Vim   *VimState
+	Body      *tview.Flex // table, plus the inspector when shown
+	Inspector *tview.TextView
+
+	tablePane   tview.Primitive // the table as placed in Body
+	layout      session.Layout
+	recent      map[string][]string // keys added per project, see session.Session
+	sessionPath string              // where layout changes are saved, or ""
+
+	Store  Store
+	Vim    *VimState
+	Creds  cred.Store
+	Config *config.Config
 
 	selRow     int // 1-based (0 is header)
 	selCol     int // 0=KEY, 1=VALUE
 	lastFilter string
+	lastChange func() string // what . repeats on the selected row; see repeatChange
+	// statusFormat is the status line template; see statusLine.
+	statusFormat string
+
+	visualFrom int                 // row the visual range is anchored at
+	rangeKeys  []string            // keys a ":'<,'>" command line acts on
+	onlyKeys   []string            // keys a ranged :w writes
+	registers  map[string]register // what y yanked, by name; see setRegister
+	maskKeys   map[string]bool     // values masked by m whatever they look like
+	repainting bool
+	lastSearch string   // latest / search entered, kept when its filter is cleared
+	searches   []string // search history, newest first, see session.Session
+	searchAt   int      // history entry shown in the minibuffer, -1 for the draft
+	searchText string   // what was typed before stepping into the history
+
+	folded map[string]bool // sections collapsed in the table
+	rowOf  []int           // table row per key index, 0 when folded away
+	keyOf  []int           // selRow per table row, 0 for header rows
+
+	sources        []*sourceStatus
+	sourcesApplied int
+
+	pal         palette
+	highlights  []rowRule // the config's row coloring rules
+	paletteName string
+
+	accessible bool       // describe the selection and announce changes
+	announce   *announcer // where announcements go besides the status line
+	lastSaid   string
+	lastFocus  tview.Primitive
+
+	idle        time.Duration // lock the screen after this long without a key; 0 never
+	lastInput   time.Time
+	locked      bool
+	lockedFocus tview.Primitive
+	lockHash    []byte // SHA-256 of the unlock passphrase, if any
+
+	watches map[string]context.CancelFunc // running :watch, by source
+
+	explain    *explain.DB
+	inspecting bool
+	basic      bool             // conventional bindings instead of vim
+	types      bool             // show the inferred type column
+	unmask     bool             // show secret-looking values in the clear
+	revealed   string           // key whose secret s shows, or ""
+	revealGen  int              // bumped per reveal; stale timers compare it
+	diff       textdiff.Options // how value diffs are computed
+	budget     *env.Budget      // budget mode, when set
+	tutor      *tutorState
+	wrap       *wrapState              // the command started by "envoy wrap", if any
+	probes     map[string]probe.Result // latest :probe of each key
+
+	whichGen      int    // bumped per key; stale overlay timers compare it
+	whichKeyShown string // prefix the which-key overlay shows, or ""
+
+	locking  bool // take lock files; off for simulated runs
+	locks    map[string]*lock.Lock
+	readOnly map[string]*lock.Info // files left to another session
+
+	writePending   bool // a :w is waiting on a lock prompt
+	quitAfterWrite bool
+
+	journaling    bool   // keep journals; off for simulated runs
+	journalKey    []byte // seals journaled values; nil leaves secrets out
+	recoverOnLoad bool
+	loadingSource bool // a startup source is being merged, which is not an unsaved change
+
+	managedOK map[string]bool // shell-managed keys the user chose to edit anyway
+	addDraft  *env.Item       // the add form's fields when it was last cancelled
+	usage     *usageScan      // keys the project's code reads, for key suggestions
+	awsPath   string          // the path :aws pull last read, where :aws push writes
+
+	buffers []*buffer // the process environment first, then opened files
+	cur     int       // index of the buffer shown
+
+	startupErrs []string // problems NewApp found, shown again after the files open
 }
 
-func Run() error {
-	a := NewApp()
-	return a.App.Run()
+// Options configures a Run.
+type Options struct {
+	Sources []Source // layered over the process environment, in order
+	Recover bool     // replay the journal left by a crashed session
+	Wrap    []string // command to launch with the edited environment
+	Files   []string // env files to open in buffers of their own
+	NoColor bool     // draw without color, as NO_COLOR also asks
+
+	// Accessible turns on accessibility mode; Announce also writes its
+	// announcements to a file or named pipe and implies it.
+	Accessible bool
+	Announce   string
+
+	// Injection points, mainly for tests; nil means the real thing.
+	Screen tcell.Screen
+	Store  Store
+	Config *config.Config
 }
 
-func NewApp() *App {
+func Run(opts Options) (err error) {
+	a := NewApp(opts)
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("panic", "panic", p, "stack", string(debug.Stack()))
+			a.closeJournal(false)
+			a.releaseLocks()
+			fmt.Fprintf(os.Stderr, "envoy crashed: %v\nUnsaved changes are journaled; restart with -recover.\n", p)
+			panic(p)
+		}
+	}()
+	a.locking = true
+	a.openJournal(opts.Recover)
+	a.loadSession(session.Path())
+	// Files are opened once locking and the journal are on, each checked
+	// and locked as :e would, before the sources ask about theirs.
+	a.openFiles(opts.Files, nil, func() {
+		a.withDirEnv(opts.Sources, func(sources []Source) {
+			a.withTrusted(sources, a.loadSources)
+		})
+	})
+	if a.Config.Update.Check {
+		go a.checkUpdate()
+	}
+	go a.watchIdle()
+	err = a.App.Run()
+	a.stopWrapped()
+	a.closeJournal(err == nil)
+	a.releaseLocks()
+	return err
+}
+
+func NewApp(opts Options) *App {
+	noColor := opts.NoColor || os.Getenv("NO_COLOR") != ""
+	if noColor {
+		if opts.Screen == nil {
+			opts.Screen, _ = tcell.NewScreen()
+		}
+		if opts.Screen != nil {
+			opts.Screen = monoScreen{opts.Screen}
+		}
+	}
 	app := tview.NewApplication()
+	if opts.Screen != nil {
+		app.SetScreen(opts.Screen)
+	}
 
-	store := env.NewStore()
+	store := opts.Store
+	if store == nil {
+		store = env.NewStore()
+	}
+	cfg := opts.Config
+	var cfgErr, lockErr error
+	var hlErrs []string
+	if cfg == nil {
+		cfg, cfgErr = config.Load()
+	}
 
 	table := tview.NewTable().
 		SetBorders(false).
@@ -60,12 +228,14 @@ func NewApp() *App {
 		SetFieldWidth(0)
 	cmd.SetBorder(false)
 
+	inspector := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	inspector.SetBorder(true).SetTitle(" Inspector ").SetTitleAlign(tview.AlignLeft)
+
 	pages := tview.NewPages()
 	main := tview.NewFlex().SetDirection(tview.FlexRow)
-	main.AddItem(table, 0, 1, true)
-	main.AddItem(cmd, 1, 0, false)
-	main.AddItem(status, 1, 0, false)
-	pages.AddPage(pageMain, main, true, true)
+	body := tview.NewFlex()
 
 	a := &App{
 		App:    app,
@@ -76,14 +246,94 @@ func NewApp() *App {
 		Layout: main,
 		Store:  store,
 		Vim:    NewVimState(),
+		Creds:  cred.Open(),
+		Config: cfg,
+
+		Body:      body,
+		Inspector: inspector,
+		explain:   explain.New(cfg.Explain),
+
+		locks:     make(map[string]*lock.Lock),
+		readOnly:  make(map[string]*lock.Info),
+		basic:     cfg.Input == inputBasic,
+		types:     cfg.Types,
+		unmask:    cfg.Unmask,
+		diff:      diffOptions(cfg.Diff),
+		folded:    make(map[string]bool),
+		maskKeys:  make(map[string]bool),
+		registers: make(map[string]register),
 	}
+	if cfg.Sort == sortNatural {
+		store.SetNaturalSort(true)
+	}
+	store.SetSectionSort(true)
+	if err := store.SetKeyPolicy(cfg.Keys); err != nil {
+		slog.Warn("ignoring key policy", "err", err)
+	}
+	a.paletteName = cmp.Or(cfg.Palette, paletteDefault)
+	a.statusFormat = cmp.Or(cfg.StatusLine, defaultStatusLine)
+	if noColor {
+		a.paletteName = paletteNone
+	}
+	pal, palOK := palettes[a.paletteName]
+	if !palOK {
+		a.paletteName, pal = paletteDefault, palettes[paletteDefault]
+	}
+	a.pal = pal
+	a.highlights, hlErrs = compileHighlights(cfg.Highlight)
+	a.accessible = opts.Accessible || cfg.Accessible
+	if path := cmp.Or(opts.Announce, cfg.Announce); path != "" {
+		a.accessible = true
+		a.announce = newAnnouncer(path)
+	}
+	app.SetAfterDrawFunc(a.afterDraw)
+	a.idle = time.Duration(cfg.Lock.IdleMinutes) * time.Minute
+	a.lastInput = time.Now()
+	a.lockHash, lockErr = lockHash(cfg.Lock.PassphraseSHA256)
+	app.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		a.lastInput = time.Now()
+		return ev
+	})
+	a.buffers = []*buffer{{name: processBuffer, store: store}}
+
+	// Pastes are delivered whole (bracketed paste) and routed here rather
+	// than being replayed as keystrokes.
+	a.tablePane = &pasteCapture{Primitive: table, fn: a.tablePaste}
+	body.AddItem(a.tablePane, 0, 2, true)
+	inspector.SetInputCapture(a.paneInput)
+	if len(opts.Wrap) > 0 {
+		a.wrap = newWrapState(opts.Wrap)
+		a.wrap.pane.SetChangedFunc(func() { app.Draw() })
+		a.wrap.pane.SetInputCapture(a.paneInput)
+		a.layoutBody()
+	}
+	main.AddItem(body, 0, 1, true)
+	main.AddItem(&pasteCapture{Primitive: cmd, singleLine: true}, 1, 0, false)
+	main.AddItem(status, 1, 0, false)
+	pages.AddPage(pageMain, main, true, true)
 
 	a.initVim()
 	a.hookHandlers()
 	a.renderTable()
 	a.setSelection(1, 0) // first data row, KEY column
-	a.updateStatusHint("NORMAL")
+	a.refreshStatus()
+	var openErrs []string
+	if lockErr != nil {
+		openErrs = append(openErrs, lockErr.Error())
+	}
+	if !palOK {
+		openErrs = append(openErrs, fmt.Sprintf("Unknown palette %q in config", cfg.Palette))
+	}
+	openErrs = append(openErrs, hlErrs...)
+	if cfgErr != nil {
+		openErrs = append(openErrs, fmt.Sprintf("Config %s: %v", config.Path(), cfgErr))
+	}
+	if len(openErrs) > 0 {
+		a.startupErrs = openErrs
+		a.updateStatusInline(strings.Join(openErrs, "; "))
+	}
 
+	app.EnablePaste(true)
 	app.SetRoot(pages, true)
 	return a
 }
@@ -101,16 +351,56 @@ func (a *App) initVim() {
 	a.Vim.CommandFn = func(cmd string) string { return a.execCommand(cmd) }
 	a.Vim.SearchFn = func(q string) { a.applySearch(q) }
 	a.Vim.CancelFn = func() { a.exitMini() }
+	a.Vim.OpenFileFn = func() { a.previewFile() }
+	a.Vim.InspectFn = func() { a.toggleInspector() }
+	a.Vim.NextTodoFn = func(prev bool) { a.nextTodo(prev) }
+	a.Vim.RevealFn = func() { a.updateStatusInline(a.revealSelected()) }
+	a.Vim.UndoFn = func(n int) { a.updateStatusInline(a.undo(false, n)) }
+	a.Vim.FoldFn = func(op string) { a.updateStatusInline(a.fold(op)) }
+	a.Vim.VisualFn = a.visual
+	a.Vim.MarkFn = func(name string) { a.updateStatusInline(a.markKey(name)) }
+	a.Vim.JumpMarkFn = func(name string) { a.updateStatusInline(a.jumpMark(name)) }
+	a.Vim.YankFn = func(valueOnly bool, n int) { a.updateStatusInline(a.yankRows(valueOnly, n)) }
+	a.Vim.PasteFn = func(over bool) {
+		if s := a.paste(a.Vim.Register, over); s != "" {
+			a.updateStatusInline(s)
+		}
+	}
+	a.Vim.RepeatFn = func(n int) { a.updateStatusInline(a.repeatChange(n)) }
 }
 
 func (a *App) hookHandlers() {
 	// Table input capture: Normal-mode keys, plus ":" and "/" to open minibuffer.
 	a.Table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
 		key := normalizeKey(ev)
+		slog.Debug("key", "key", key, "mode", a.Vim.Mode)
+		a.whichKeyInput()
 		switch a.Vim.Mode {
 		case ModeNormal:
+			if a.windowKeys(ev) {
+				return nil
+			}
+			if ev.Key() == tcell.KeyCtrlT {
+				a.Vim.resetPrefix()
+				a.openFinder()
+				return nil
+			}
+			if ev.Key() == tcell.KeyF5 && a.wrap != nil {
+				a.Vim.resetPrefix()
+				a.updateStatusInline(a.launchWrapped())
+				return nil
+			}
+			if a.basic {
+				return a.handleBasic(ev)
+			}
+			if ev.Key() == tcell.KeyCtrlR {
+				a.updateStatusInline(a.undo(true, a.Vim.countOrDefault()))
+				a.Vim.resetPrefix()
+				return nil
+			}
 			if key == ":" {
 				a.enterCommand("")
+				a.scheduleWhichKey(":")
 				return nil
 			}
 			if key == "/" {
@@ -121,6 +411,13 @@ func (a *App) hookHandlers() {
 				a.updateStatusInline("Use :q to quit")
 				return nil
 			}
+			if a.Vim.HandleKey(key) {
+				if a.Vim.PendingOp != "" {
+					a.scheduleWhichKey(a.Vim.PendingOp)
+				}
+				return nil
+			}
+		case ModeVisual:
 			if a.Vim.HandleKey(key) {
 				return nil
 			}
@@ -136,8 +433,26 @@ func (a *App) hookHandlers() {
 
 	// Track selection changes regardless of input source (mouse, arrows, hjkl).
 	a.Table.SetSelectionChangedFunc(func(row, column int) {
-		a.selRow = row
+		a.selRow = a.keyRow(row)
 		a.selCol = column
+		if key, _ := a.selectedKey(); key != a.revealed {
+			a.hideRevealed()
+		}
+		a.updateInspector()
+		a.tutorCheck()
+		if a.accessible {
+			a.refreshStatus()
+			a.say(a.selectionText())
+		} else if a.selectionInStatus() {
+			a.refreshStatus()
+		}
+		// The visual range follows the cursor; redrawing selects the row
+		// again, which comes back here.
+		if a.Vim.Mode == ModeVisual && !a.repainting {
+			a.repainting = true
+			a.renderTable()
+			a.repainting = false
+		}
 	})
 
 	// Command/search minibuffer: Enter applies, ESC cancels, others ignored.
@@ -147,11 +462,14 @@ func (a *App) hookHandlers() {
 		case ModeCommand:
 			switch key {
 			case tcell.KeyEnter:
+				// Leave the minibuffer first so commands that open a modal
+				// keep focus.
+				a.exitMini()
 				out := a.execCommand(strings.TrimSpace(text))
 				if out != "" {
 					a.updateStatusInline(out)
 				}
-				a.exitMini()
+				a.tutorCheck()
 			case tcell.KeyEsc:
 				a.exitMini()
 			default:
@@ -161,9 +479,11 @@ func (a *App) hookHandlers() {
 			switch key {
 			case tcell.KeyEnter:
 				a.applySearch(text)
+				a.rememberSearch(text)
 				a.exitMini()
 			case tcell.KeyEsc:
 				a.exitMini()
+				a.applySearch("")
 			default:
 				// ignore
 			}
@@ -172,10 +492,27 @@ func (a *App) hookHandlers() {
 		}
 	})
 
+	a.Cmd.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if a.Vim.Mode == ModeSearch {
+			switch ev.Key() {
+			case tcell.KeyUp:
+				a.stepSearchHistory(1)
+				return nil
+			case tcell.KeyDown:
+				a.stepSearchHistory(-1)
+				return nil
+			}
+		}
+		return ev
+	})
+
 	// Incremental search.
 	a.Cmd.SetChangedFunc(func(text string) {
-		if a.Vim.Mode == ModeSearch {
+		switch {
+		case a.Vim.Mode == ModeSearch:
 			a.applySearch(text)
+		case a.whichKeyShown == ":":
+			a.showWhichKey(":")
 		}
 	})
 }
@@ -206,28 +543,89 @@ func (a *App) renderTable() {
 	a.Table.Clear()
 
 	// Header
-	a.Table.SetCell(0, 0, headerCell("KEY"))
+	a.Table.SetCell(0, 0, headerCell("   KEY"))
 	a.Table.SetCell(0, 1, headerCell("VALUE"))
+	if a.types {
+		a.Table.SetCell(0, 2, headerCell("T"))
+	}
 
 	keys := a.Store.ListKeys()
+	over := a.overBudget()
+	process := a.Store.Process()
+
+	// With sections, a header row goes above each run of keys from one
+	// section, and the keys of a folded one are left out.
+	grouped := a.Store.SectionSort() && len(a.Store.Sections()) > 0
+	perSection := make(map[string]int)
+	if grouped {
+		for _, k := range keys {
+			perSection[a.Store.Section(k)]++
+		}
+	}
+	a.rowOf = make([]int, len(keys))
+	a.keyOf = []int{0}
+	next, section := 1, ""
 	for i, k := range keys {
-		row := i + 1
+		if grouped {
+			if s := a.Store.Section(k); s != section {
+				section = s
+				a.sectionCells(next, s, perSection[s])
+				a.keyOf = append(a.keyOf, 0)
+				next++
+			}
+			if a.folded[section] {
+				continue
+			}
+		}
+		row := next
+		next++
+		a.rowOf[i] = row
+		a.keyOf = append(a.keyOf, i+1)
 		item, _ := a.Store.GetByIndex(i)
 
-		keyCell := tview.NewTableCell(k).
+		keyCell := tview.NewTableCell(a.gutter(k, item, process) + k).
 			SetExpansion(1).
 			SetSelectable(true)
-		valCell := tview.NewTableCell(item.Value).
+		valCell := tview.NewTableCell(a.shownValue(k, item.Value)).
 			SetExpansion(3).
 			SetSelectable(true)
+		a.highlightCells(k, item.Value, keyCell, valCell)
 
 		if item.Modified {
-			keyCell.SetTextColor(tcell.ColorYellow)
-			valCell.SetTextColor(tcell.ColorYellow)
+			color := a.pal.Modified
+			if _, ok := process[k]; !ok {
+				color = a.pal.Added
+			}
+			keyCell.SetTextColor(color)
+			valCell.SetTextColor(color)
+		}
+		if a.broken(k, item.Value) {
+			valCell.SetTextColor(a.pal.Broken)
+		}
+		if env.IsPlaceholder(item.Value) {
+			valCell.SetTextColor(a.pal.Placeholder)
+		}
+		if a.flagged(k, item.Value) {
+			keyCell.SetTextColor(a.pal.Secret)
+		}
+		if a.Store.Shadowed(k) {
+			keyCell.SetTextColor(a.pal.Shadowed)
+		}
+		if c := a.expiryColor(k); c != 0 {
+			keyCell.SetTextColor(c)
+		}
+		if slices.Contains(over, k) {
+			addAttrs(keyCell, tcell.AttrReverse)
+		}
+		if a.inVisual(i + 1) {
+			a.paintVisual(keyCell, valCell)
 		}
 
 		a.Table.SetCell(row, 0, keyCell)
 		a.Table.SetCell(row, 1, valCell)
+		if a.types {
+			a.Table.SetCell(row, 2, kindCell(k, item.Value))
+		}
 	}
 
 	// Reselect within bounds.
@@ -249,9 +647,11 @@ func (a *App) renderTable() {
 		if a.selCol > 1 {
 			a.selCol = 1
 		}
-		a.Table.Select(a.selRow, a.selCol)
+		a.selRow = a.visibleRow(a.selRow, 1)
+		a.selectTable()
 	}
 
+	a.updateInspector()
 	a.refreshStatus()
 }
 
@@ -265,27 +665,27 @@ func headerCell(s string) *tview.TableCell {
 
 func (a *App) updateStatusInline(s string) {
 	a.Status.SetText(" " + s)
+	a.say(s)
 }
 
 func (a *App) refreshStatus() {
-	mode := "NORMAL"
-	switch a.Vim.Mode {
-	case ModeInsert:
-		mode = "INSERT"
-	case ModeCommand:
-		mode = "COMMAND"
-	case ModeSearch:
-		mode = "SEARCH"
-	}
-	count := a.Store.Count()
-	hints := "[A]dd [i/a] Edit [x] Delete [/ ] Search [:] Cmd (n/N to cycle) | :w :q :import"
-	a.Status.SetText(fmt.Sprintf(" %s | %d vars | %s", mode, count, hints))
+	mode := a.Vim.Mode.String()
+	if a.basic && a.Vim.Mode == ModeNormal {
+		mode = "BASIC"
+	}
+	a.updateStatusHint(mode)
+	a.tutorCheck()
 }
 
 func (a *App) updateStatusHint(mode string) {
-	count := a.Store.Count()
 	hints := "[A]dd [i/a] Edit [x] Delete [/ ] Search [:] Cmd (n/N to cycle) | :w :q :import"
-	a.Status.SetText(fmt.Sprintf(" %s | %d vars | %s", mode, count, hints))
+	if a.basic {
+		hints = basicHints
+	}
+	if a.accessible {
+		hints = tview.Escape(a.selectionText())
+	}
+	a.Status.SetText(a.statusLine(a.statusFormat, mode, hints))
 }
 
 func (a *App) move(dy, dx int) {
@@ -318,25 +718,41 @@ func (a *App) move(dy, dx int) {
 		}
 	}
 
+	if newRow > 0 {
+		dir := 1
+		if dy < 0 {
+			dir = -1
+		}
+		newRow = a.visibleRow(newRow, dir)
+	}
+
 	a.setSelection(newRow, newCol)
 }
 
+// setSelection selects row and col. A key in a folded section is shown by
+// opening the section.
 func (a *App) setSelection(row, col int) {
 	a.selRow = row
 	a.selCol = col
-	a.Table.Select(a.selRow, a.selCol)
+	if row > 0 && a.tableRow(row) == 0 {
+		if it, ok := a.Store.GetByIndex(row - 1); ok {
+			delete(a.folded, a.Store.Section(it.Key))
+			a.renderTable()
+			return
+		}
+	}
+	a.selectTable()
 }
 
 func (a *App) jumpTop() {
-	if a.Store.Count() > 0 {
-		a.setSelection(1, a.selCol)
+	if r := a.visibleRow(1, 1); r > 0 {
+		a.setSelection(r, a.selCol)
 	}
 }
 
 func (a *App) jumpBottom() {
-	n := a.Store.Count()
-	if n > 0 {
-		a.setSelection(n, a.selCol)
+	if r := a.visibleRow(a.Store.Count(), -1); r > 0 {
+		a.setSelection(r, a.selCol)
 	}
 }
 
@@ -346,7 +762,20 @@ func (a *App) openEditForm(append bool) {
 	if !ok {
 		return
 	}
+	if who, managed := env.Managed(item.Key); managed && !a.managedOK[item.Key] {
+		a.confirmManaged(item.Key, who, func() { a.openEditForm(append) })
+		return
+	}
+	if p := env.PickerFor(item.Key); p != env.PickNone {
+		if choices, err := env.Choices(p); err == nil && len(choices) > 0 {
+			a.openPicker(item, choices, append)
+			return
+		}
+	}
+	a.openTextEdit(item, append)
+}
 
+func (a *App) openTextEdit(item env.Item, append bool) {
 	form := tview.NewForm().
 		AddInputField("Key", item.Key, 40, nil, nil).
 		AddInputField("Value", item.Value, 60, nil, nil)
@@ -355,12 +784,13 @@ func (a *App) openEditForm(append bool) {
 		key := form.GetFormItemByLabel("Key").(*tview.InputField).GetText()
 		val := form.GetFormItemByLabel("Value").(*tview.InputField).GetText()
 		key = strings.TrimSpace(key)
-		if key == "" {
-			a.updateStatusInline("Key cannot be empty")
+		if err := a.Store.Upsert(key, val); err != nil {
+			a.updateStatusInline("Not saved: " + err.Error())
 			return
 		}
-		a.Store.Upsert(key, val)
+		a.lastChange = func() string { return a.setSelectedValue(val) }
 		a.closeModal()
+		a.renderTable()
 		// Re-select edited key.
 		a.selectKey(key)
 		a.updateStatusInline(fmt.Sprintf("Saved %s", key))
@@ -374,7 +804,6 @@ func (a *App) openEditForm(append bool) {
 			a.Vim.Mode = ModeNormal
 			a.refreshStatus()
 		})
-	form.SetBorder(true).SetTitle(" Edit variable ").SetTitleAlign(tview.AlignLeft)
 
 	if append {
 		// no explicit caret API; keep value as-is to simulate append behavior
@@ -384,26 +813,56 @@ func (a *App) openEditForm(append bool) {
 		}
 	}
 
+	// A live diff against the original value shows accidental deletions
+	// in long values before they are saved.
+	changes := tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	changes.SetBorder(true).SetTitle(" Changes ").SetTitleAlign(tview.AlignLeft)
+	report := tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	validate := formValidator(form, report, "Save", a.newEntryChecker(item))
+	form.GetFormItemByLabel("Key").(*tview.InputField).SetChangedFunc(func(string) { validate() })
+	form.GetFormItemByLabel("Value").(*tview.InputField).SetChangedFunc(func(val string) {
+		changes.SetText(a.valueDiff(item.Value, val))
+		validate()
+	})
+	changes.SetText(a.valueDiff(item.Value, item.Value))
+	validate()
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(form, 7, 0, true).
+		AddItem(report, 0, 1, false).
+		AddItem(changes, 6, 0, false)
+	layout.SetBorder(true).SetTitle(" Edit variable ").SetTitleAlign(tview.AlignLeft)
+
 	a.Vim.Mode = ModeInsert
-	modal := centerPrimitive(form, 80, 10)
+	modal := centerPrimitive(&pasteCapture{Primitive: layout, singleLine: true}, 80, 19)
 	a.Pages.AddPage(pageModal, modal, true, true)
 	a.App.SetFocus(form)
 	a.refreshStatus()
 }
 
+// openAddForm adds a variable. The key starts as what was typed when the
+// form was last cancelled, or else the active filter; the buttons below
+// take the value from the clipboard or from the row selected when the form
+// opened.
 func (a *App) openAddForm() {
+	key, val := a.lastFilter, ""
+	if d := a.addDraft; d != nil {
+		key, val = d.Key, d.Value
+	}
 	form := tview.NewForm().
-		AddInputField("Key", "", 40, nil, nil).
-		AddInputField("Value", "", 60, nil, nil)
+		AddInputField("Key", key, 40, nil, nil).
+		AddInputField("Value", val, 60, nil, nil)
+	keyField := form.GetFormItemByLabel("Key").(*tview.InputField)
+	valField := form.GetFormItemByLabel("Value").(*tview.InputField)
 
 	addBtn := func() {
-		key := strings.TrimSpace(form.GetFormItemByLabel("Key").(*tview.InputField).GetText())
-		val := form.GetFormItemByLabel("Value").(*tview.InputField).GetText()
-		if key == "" {
-			a.updateStatusInline("Key cannot be empty")
+		key := strings.TrimSpace(keyField.GetText())
+		val := valField.GetText()
+		if err := a.Store.Upsert(key, val); err != nil {
+			a.updateStatusInline("Not added: " + err.Error())
 			return
 		}
-		a.Store.Upsert(key, val)
+		a.addDraft = nil
+		a.rememberKey(key)
 		a.closeModal()
 		a.renderTable()
 		a.selectKey(key)
@@ -411,22 +870,84 @@ func (a *App) openAddForm() {
 		a.Vim.Mode = ModeNormal
 		a.refreshStatus()
 	}
+	cancel := func() {
+		a.addDraft = nil
+		if keyField.GetText() != "" || valField.GetText() != "" {
+			a.addDraft = &env.Item{Key: keyField.GetText(), Value: valField.GetText()}
+		}
+		a.closeModal()
+		a.Vim.Mode = ModeNormal
+		a.refreshStatus()
+	}
+	fill := func(v string) {
+		valField.SetText(v)
+		form.SetFocus(1)
+		a.App.SetFocus(form)
+	}
 
 	form.AddButton("Add", addBtn).
-		AddButton("Cancel", func() {
-			a.closeModal()
-			a.Vim.Mode = ModeNormal
-			a.refreshStatus()
+		AddButton("Cancel", cancel).
+		AddButton("Clipboard", func() {
+			text, err := clipboard.Read()
+			if err != nil {
+				a.updateStatusInline(fmt.Sprintf("Clipboard: %v", err))
+				return
+			}
+			fill(strings.TrimRight(text, "\r\n"))
 		})
-	form.SetBorder(true).SetTitle(" Add variable ").SetTitleAlign(tview.AlignLeft)
+	if sel, ok := a.Store.GetByIndex(a.selRow - 1); ok {
+		form.AddButton("Copy "+sel.Key, func() { fill(sel.Value) })
+	}
+	form.SetCancelFunc(cancel)
+	report := tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	validate := formValidator(form, report, "Add", a.newEntryChecker(env.Item{}))
+	a.suggestKeys(keyField, valField)
+	keyField.SetChangedFunc(func(string) { validate() })
+	valField.SetChangedFunc(func(string) { validate() })
+	validate()
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(form, 7, 0, true).
+		AddItem(report, 0, 1, false)
+	layout.SetBorder(true).SetTitle(" Add variable ").SetTitleAlign(tview.AlignLeft)
+
+	// A multi-line blob pasted into the form is most likely a .env snippet;
+	// offer it to the bulk parser instead of stuffing it into one value.
+	capture := &pasteCapture{Primitive: layout, singleLine: true, fn: func(text string) bool {
+		return isBulkText(text) && a.smartPaste(text)
+	}}
 
 	a.Vim.Mode = ModeInsert
-	modal := centerPrimitive(form, 80, 10)
+	modal := centerPrimitive(capture, 80, 12)
 	a.Pages.AddPage(pageModal, modal, true, true)
 	a.App.SetFocus(form)
 	a.refreshStatus()
 }
 
+// confirmManaged warns that key is maintained by the shell or terminal
+// before an edit. Editing anyway calls edit and stops the warning for key
+// for the rest of the session.
+func (a *App) confirmManaged(key, who string, edit func()) {
+	m := tview.NewModal().
+		SetText(fmt.Sprintf("%s is not yours to manage: %s.\n\nChanging it from Envoy has no lasting effect and may confuse programs that rely on it.", key, who)).
+		AddButtons([]string{"Edit anyway", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			if label != "Edit anyway" {
+				a.Vim.Mode = ModeNormal
+				a.refreshStatus()
+				a.updateStatusInline(key + " left unchanged")
+				return
+			}
+			if a.managedOK == nil {
+				a.managedOK = make(map[string]bool)
+			}
+			a.managedOK[key] = true
+			edit()
+		})
+	a.Pages.AddPage(pageModal, centerPrimitive(m, 60, 10), true, true)
+	a.App.SetFocus(m)
+}
+
 func (a *App) confirmDelete() {
 	idx := a.selRow - 1
 	item, ok := a.Store.GetByIndex(idx)
@@ -434,11 +955,16 @@ func (a *App) confirmDelete() {
 		return
 	}
 
+	text := fmt.Sprintf("Delete %s?", item.Key)
+	if who, managed := env.Managed(item.Key); managed {
+		text += fmt.Sprintf("\n\n%s is not yours to manage: %s. Deleting it here has no lasting effect.", item.Key, who)
+	}
 	m := tview.NewModal().
-		SetText(fmt.Sprintf("Delete %s?", item.Key)).
+		SetText(text).
 		AddButtons([]string{"Yes", "No"}).
 		SetDoneFunc(func(_ int, label string) {
 			if label == "Yes" {
+				a.lastChange = func() string { return a.deleteRows(1) }
 				a.Store.Delete(item.Key)
 				a.renderTable()
 				if a.selRow > a.Store.Count() {
@@ -446,7 +972,7 @@ func (a *App) confirmDelete() {
 					if a.selRow < 1 {
 						a.selRow = 1
 					}
-					a.Table.Select(a.selRow, a.selCol)
+					a.selectTable()
 				}
 				a.updateStatusInline(fmt.Sprintf("Deleted %s", item.Key))
 			}
@@ -493,6 +1019,7 @@ func (a *App) enterCommand(prefill string) {
 
 func (a *App) enterSearch(prefill string) {
 	a.Vim.Mode = ModeSearch
+	a.searchAt = -1
 	a.Cmd.SetLabel("/")
 	a.Cmd.SetText(prefill)
 	a.App.SetFocus(a.Cmd)
@@ -500,10 +1027,13 @@ func (a *App) enterSearch(prefill string) {
 }
 
 func (a *App) exitMini() {
+	a.hideWhichKey()
+	// Leave search mode before clearing the text, or the incremental
+	// search handler would clear the filter just applied.
+	a.Vim.Mode = ModeNormal
 	a.Cmd.SetText("")
 	a.Cmd.SetLabel("")
 	a.App.SetFocus(a.Table)
-	a.Vim.Mode = ModeNormal
 	a.refreshStatus()
 }
 
@@ -523,13 +1053,16 @@ func (a *App) applySearch(q string) {
 
 func (a *App) nextMatch(prev bool) {
 	if a.lastFilter == "" {
+		a.updateStatusInline(a.searchAgain(prev))
 		return
 	}
 	n := a.Store.Count()
 	if n <= 1 {
 		return
 	}
+	dir := 1
 	if prev {
+		dir = -1
 		if a.selRow <= 1 {
 			a.selRow = n
 		} else {
@@ -542,7 +1075,8 @@ func (a *App) nextMatch(prev bool) {
 			a.selRow++
 		}
 	}
-	a.Table.Select(a.selRow, a.selCol)
+	a.selRow = a.visibleRow(a.selRow, dir)
+	a.selectTable()
 }
 
 func (a *App) execCommand(text string) string {
@@ -551,64 +1085,183 @@ func (a *App) execCommand(text string) string {
 	if text == "" {
 		return ""
 	}
+	if rest, ok := strings.CutPrefix(text, visualRange); ok {
+		return a.rangeCommand(rest)
+	}
 	fields := strings.Fields(text)
 	cmd := fields[0]
 	args := fields[1:]
+	slog.Info("command", "cmd", cmd, "args", len(args))
 
 	switch cmd {
 	case "q", "quit":
 		a.App.Stop()
 	case "w":
-		path := ".env"
-		if len(args) >= 1 {
-			path = strings.Join(args, " ")
-		}
-		if !filepath.IsAbs(path) && strings.HasPrefix(path, "~/") {
-			if home, err := os.UserHomeDir(); err == nil {
-				path = filepath.Join(home, path[2:])
+		return a.writeCommand(args)
+	case "w!":
+		if o, err := parseWriteArgs(args); err == nil && len(o.targets) == 0 {
+			if err := a.takeOver(cmp.Or(o.path, a.writeDefault())); err != nil {
+				return fmt.Sprintf("Write failed: %v", err)
 			}
 		}
-		if err := a.Store.Export(path); err != nil {
-			return fmt.Sprintf("Write failed: %v", err)
-		}
-		return fmt.Sprintf("Wrote %s", path)
+		return a.writeCommand(args)
 	case "wq":
 		msg := a.execCommand("w " + strings.Join(args, " "))
+		if a.writePending {
+			a.quitAfterWrite = true
+			return msg
+		}
 		a.App.Stop()
 		return msg
 	case "x":
 		if a.Store.Dirty() {
 			_ = a.execCommand("w " + strings.Join(args, " "))
+			if a.writePending {
+				a.quitAfterWrite = true
+				return ""
+			}
 		}
 		a.App.Stop()
 	case "import":
-		if len(args) < 1 {
-			return "Usage: :import <path>"
-		}
-		path := strings.Join(args, " ")
-		if !filepath.IsAbs(path) && strings.HasPrefix(path, "~/") {
-			if home, err := os.UserHomeDir(); err == nil {
-				path = filepath.Join(home, path[2:])
-			}
-		}
-		n, err := a.Store.Import(path)
-		if err != nil {
-			return fmt.Sprintf("Import failed: %v", err)
-		}
-		a.renderTable()
-		return fmt.Sprintf("Imported %d vars from %s", n, path)
+		return a.importCommand(args)
+	case "apply":
+		return a.applyCommand(args)
+	case "scrub-history":
+		return a.scrubHistoryCommand(args)
+	case "compare-shell":
+		return a.compareShell(args)
+	case "allow":
+		return a.allowCommand(true)
+	case "deny":
+		return a.allowCommand(false)
+	case "bundle":
+		return a.bundleCommand(args)
+	case "paste":
+		a.openPasteForm()
+	case "import-clipboard":
+		return a.importClipboard()
+	case "wrap":
+		return a.wrapCommand(args)
+	case "batch":
+		a.openBatchForm()
+	case "stats":
+		a.showStats()
+	case "overrides":
+		return a.showOverrides()
+	case "tutor", "Tutor":
+		return a.tutorCommand(args)
+	case "diff":
+		return a.diffCommand(args)
+	case "browse":
+		return a.browseCommand(args)
+	case "watch":
+		return a.watchCommand(args)
+	case "unwatch":
+		return a.unwatchCommand(args)
+	case "lock":
+		return a.lockCommand(args)
+	case "legend":
+		a.showLegend()
+		return ""
+	case "set", "se":
+		return a.setCommand(args)
+	case "budget":
+		return a.budgetCommand(args)
+	case "expires":
+		return a.expiresCommand(args)
+	case "expiring":
+		return a.showExpiring(args)
+	case "proxycheck":
+		return a.showProxyCheck()
+	case "compat":
+		return a.compatCommand(args)
+	case "simulate":
+		// The command line keeps its own quoting.
+		return a.showSimulation(strings.TrimSpace(strings.TrimPrefix(text, cmd)))
+	case "lint":
+		return a.showLint()
+	case "meta":
+		return a.metaCommand(args)
+	case "testconn":
+		return a.testConnCommand(args)
+	case "probe":
+		return a.probeCommand(args)
+	case "dupvalues":
+		return a.showDuplicateValues()
+	case "secrets":
+		return a.showSecrets()
+	case "todos":
+		return a.showTodos()
+	case "undo":
+		return a.undoCommand(false, args)
+	case "redo":
+		return a.undoCommand(true, args)
+	case "renameprefix":
+		return a.renamePrefixCommand(args)
+	case "casecheck":
+		return a.showCaseCollisions()
+	case "auth":
+		return a.authCommand(args)
+	case "aws":
+		return a.awsCommand(args)
+	case "pull":
+		return a.pullCommand(args)
+	case "push":
+		return a.pushCommand(args)
+	case "resolve":
+		return a.resolveCommand(args)
+	case "keyring":
+		return a.keyringCommand(args)
+	case "store":
+		return a.storeCommand(args)
+	case "fold":
+		return a.foldCommand(args)
+	case "op":
+		return a.opCommand(args)
+	case "age":
+		return a.ageCommand(args)
+	case "sources":
+		return a.showSources()
+	case "log":
+		return a.showLog()
+	case "recover":
+		return a.recoverJournal()
+	case "inspect":
+		a.toggleInspector()
+		return ""
+	case "explain":
+		return a.showExplain(strings.Join(args, " "))
+	case "version":
+		a.showText("Version", tview.Escape(update.Info()))
+		return ""
 	case "e", "edit":
-		a.Store.LoadFromProcess()
-		a.renderTable()
-		return "Reloaded from process environment"
+		return a.editCommand(args, false)
+	case "e!", "edit!":
+		return a.editCommand(args, true)
+	case "ls", "buffers", "b", "buffer", "bn", "bnext", "bp", "bprev", "bprevious":
+		return a.bufferCommand(cmd, args)
+	case "marks":
+		return a.listMarks()
+	case "registers", "reg", "display", "di":
+		return a.registersCommand()
 	case "help", "h", "?":
-		return "Commands: :w [path] | :q | :wq | :x | :import <path> | :e | /search"
+		return commandHelp()
 	default:
 		return fmt.Sprintf("Unknown command: %s", cmd)
 	}
 	return ""
 }
 
+// expandHome resolves a leading "~/" to the user's home directory.
+func expandHome(path string) string {
+	if !filepath.IsAbs(path) && strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
 func (a *App) closeModal() {
 	a.Pages.RemovePage(pageModal)
 	a.App.SetFocus(a.Table)
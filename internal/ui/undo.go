@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// undo steps back through the store's history n times, or forward when
+// redo is set, and reports what changed.
+func (a *App) undo(redo bool, n int) string {
+	name, verb, step := "undo", "Undid", a.Store.Undo
+	if redo {
+		name, verb, step = "redo", "Redid", a.Store.Redo
+	}
+	var keys []string
+	done := 0
+	for ; done < max(n, 1); done++ {
+		ops, ok := step()
+		if !ok {
+			break
+		}
+		for _, op := range ops {
+			keys = append(keys, op.Key)
+		}
+	}
+	if done == 0 {
+		return "Nothing to " + name
+	}
+	a.renderTable()
+	if len(keys) > 0 {
+		a.selectKey(keys[0])
+	}
+	msg := fmt.Sprintf("%s %d changes", verb, done)
+	if done == 1 {
+		msg = verb + " 1 change"
+	}
+	switch {
+	case len(keys) > 3:
+		msg += fmt.Sprintf(" (%d keys)", len(keys))
+	case len(keys) > 0:
+		msg += " (" + strings.Join(keys, ", ") + ")"
+	}
+	return msg
+}
+
+// undoCommand handles ":undo [n]" and ":redo [n]".
+func (a *App) undoCommand(redo bool, args []string) string {
+	switch len(args) {
+	case 0:
+		return a.undo(redo, 1)
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Sprintf("Bad count %q", args[0])
+		}
+		return a.undo(redo, n)
+	}
+	return "Usage: :undo [count] or :redo [count]"
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/lint"
+	"github.com/rivo/tview"
+)
+
+// entryIssue is a problem with the key or value typed into the add or edit
+// form. Errors keep the form from saving; the rest do not.
+type entryIssue struct {
+	field string // "Key", "Value" or the lint rule
+	msg   string
+	level lint.Severity
+}
+
+// entryChecker validates the add and edit forms as they are typed.
+type entryChecker struct {
+	store Store
+	orig  env.Item      // the item being edited; zero when adding
+	rules *lint.Ruleset // nil when the rule files fail to load
+}
+
+func (a *App) newEntryChecker(orig env.Item) *entryChecker {
+	rules, _ := lint.Default()
+	return &entryChecker{store: a.Store, orig: orig, rules: rules}
+}
+
+// check validates key and val: the key's characters and whether it is
+// taken, the value against the key's picker and the kind of value it held,
+// and both against the lint rules for the key.
+func (c *entryChecker) check(key, val string) []entryIssue {
+	var out []entryIssue
+	add := func(field, msg string, level lint.Severity) {
+		out = append(out, entryIssue{field, msg, level})
+	}
+	key = strings.TrimSpace(key)
+	renamed := key != c.orig.Key
+	_, taken := c.store.Get(key)
+	keyErr, _ := c.store.CheckKey(key).(*env.KeyError)
+	switch {
+	case key == "":
+		// Nothing to say before anything is typed; saving stays off.
+		add("Key", "", lint.Error)
+	case strings.ContainsAny(key, " \t="):
+		add("Key", "may not contain spaces or ="+keyFix(keyErr), lint.Error)
+	case keyErr != nil:
+		level := lint.Warning
+		if keyErr.Rejected && !taken {
+			level = lint.Error
+		}
+		add("Key", keyErr.Problem+keyFix(keyErr), level)
+	}
+	if taken && renamed {
+		add("Key", "already set; saving replaces its value", lint.Warning)
+	}
+
+	if msg := invalidChoice(key, val); msg != "" {
+		add("Value", msg, lint.Error)
+	}
+	if t := strings.TrimSpace(val); t != "" && (t[0] == '{' || t[0] == '[') && !json.Valid([]byte(t)) {
+		add("Value", "looks like JSON but does not parse", lint.Warning)
+	}
+	switch was := env.Infer(c.orig.Key, c.orig.Value); was {
+	case env.KindInt, env.KindBool, env.KindURL, env.KindJSON:
+		if now := env.Infer(key, val); now != was && now != env.KindNone {
+			add("Value", fmt.Sprintf("was %s, now %s", was, now), lint.Warning)
+		}
+	}
+
+	// The lint rules, key-chars among them, act as the schema.
+	if c.rules != nil && key != "" {
+		vars := c.store.Vars()
+		vars[key] = val
+		for _, f := range c.rules.Check(vars) {
+			// The key policy has already spoken about the key's characters.
+			if f.Key == key && !(f.Rule == "key-chars" && keyErr != nil) {
+				add(f.Rule, f.Message, f.Severity)
+			}
+		}
+	}
+	return out
+}
+
+// keyFix suggests the fixed key, if any, for a message about e.
+func keyFix(e *env.KeyError) string {
+	if e == nil || e.Fix == "" {
+		return ""
+	}
+	return "; try " + e.Fix
+}
+
+// entryReport renders issues for the form's message area and reports
+// whether any is an error.
+func entryReport(issues []entryIssue) (string, bool) {
+	var b strings.Builder
+	blocked := false
+	for _, is := range issues {
+		if is.level == lint.Error {
+			blocked = true
+		}
+		if is.msg == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]%s: %s[-]\n", severityColor[is.level], is.field, tview.Escape(is.msg))
+	}
+	return strings.TrimSuffix(b.String(), "\n"), blocked
+}
+
+// formValidator returns a func that checks form's Key and Value fields,
+// showing the issues in report and disabling the button labelled save
+// while any is an error. Call it from the fields' changed funcs.
+func formValidator(form *tview.Form, report *tview.TextView, save string, c *entryChecker) func() {
+	keyField := form.GetFormItemByLabel("Key").(*tview.InputField)
+	valField := form.GetFormItemByLabel("Value").(*tview.InputField)
+	button := form.GetButton(form.GetButtonIndex(save))
+	return func() {
+		text, blocked := entryReport(c.check(keyField.GetText(), valField.GetText()))
+		report.SetText(text)
+		button.SetDisabled(blocked)
+	}
+}
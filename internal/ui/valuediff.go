@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/config"
+	"github.com/rivethorn/envoy/internal/textdiff"
+
+	"github.com/rivo/tview"
+)
+
+// diffContext is how many unchanged runes are kept on each side of a change;
+// longer unchanged runs are elided.
+const diffContext = 12
+
+// Diff units selectable with :set diffunit= and the "diff" config key.
+const (
+	diffChar = "char"
+	diffWord = "word"
+)
+
+// diffOptions reads the diff settings, ignoring unknown names.
+func diffOptions(c config.Diff) textdiff.Options {
+	alg, _ := textdiff.ParseAlgorithm(c.Algorithm)
+	return textdiff.Options{Algorithm: alg, Words: c.Unit == diffWord}
+}
+
+func (a *App) diffUnit() string {
+	if a.diff.Words {
+		return diffWord
+	}
+	return diffChar
+}
+
+// valueDiff renders the changes from old to cur, by character or by word
+// as configured: deletions struck through, insertions underlined, each in
+// its palette color.
+func (a *App) valueDiff(old, cur string) string {
+	if old == cur {
+		return "[gray]unchanged[-]"
+	}
+	edits := textdiff.Diff(old, cur, a.diff)
+	var b strings.Builder
+	for i, e := range edits {
+		text := e.Text
+		switch e.Kind {
+		case textdiff.Delete:
+			b.WriteString("[" + tag(a.pal.Removed) + "::s]" + tview.Escape(text) + "[-::-]")
+		case textdiff.Insert:
+			b.WriteString("[" + tag(a.pal.Added) + "::u]" + tview.Escape(text) + "[-::-]")
+		default:
+			r := []rune(text)
+			first, last := i == 0, i == len(edits)-1
+			switch {
+			case first && len(r) > diffContext:
+				text = "…" + string(r[len(r)-diffContext:])
+			case last && len(r) > diffContext:
+				text = string(r[:diffContext]) + "…"
+			case !first && !last && len(r) > 2*diffContext:
+				text = string(r[:diffContext]) + "…" + string(r[len(r)-diffContext:])
+			}
+			b.WriteString(tview.Escape(text))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/logging"
+	"github.com/rivethorn/envoy/internal/update"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showText opens a scrollable read-only page. ESC, Enter or q closes it.
+func (a *App) showText(title, text string) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(text)
+	view.SetBorder(true).SetTitle(" " + title + " ").SetTitleAlign(tview.AlignLeft)
+	view.SetDoneFunc(func(tcell.Key) { a.closeModal() })
+	view.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Rune() == 'q' {
+			a.closeModal()
+			return nil
+		}
+		return ev
+	})
+	a.Pages.AddPage(pageModal, centerPrimitive(view, 100, 30), true, true)
+	a.App.SetFocus(view)
+	return view
+}
+
+func (a *App) showStats() {
+	st := a.Store.Stats()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[::b]Variables[::-]   %d\n", st.Count)
+	fmt.Fprintf(&b, "[::b]Total size[::-]  %d bytes\n\n", st.Bytes)
+
+	b.WriteString("[::b]Longest values[::-]\n")
+	for _, it := range st.Longest {
+		fmt.Fprintf(&b, "  %-30s %d bytes\n", tview.Escape(it.Key), len(it.Value))
+	}
+
+	writeKeyList(&b, "Empty values", st.Empty)
+	writeKeyList(&b, "Probable secrets", st.Secrets)
+	writeKeyList(&b, "Shadowing system defaults", st.Shadowing)
+
+	fmt.Fprintf(&b, "\n[::b]Values shared across keys[::-] (%d)\n", len(st.Shared))
+	for _, keys := range st.Shared {
+		fmt.Fprintf(&b, "  %s\n", tview.Escape(strings.Join(keys, ", ")))
+	}
+
+	a.showText("Environment stats", b.String())
+}
+
+func writeKeyList(b *strings.Builder, title string, keys []string) {
+	fmt.Fprintf(b, "\n[::b]%s[::-] (%d)\n", title, len(keys))
+	for _, k := range keys {
+		fmt.Fprintf(b, "  %s\n", tview.Escape(k))
+	}
+}
+
+// previewLimit caps how much of a referenced file gf shows.
+const previewLimit = 64 << 10
+
+// previewFile shows the file or directory the selected value points to.
+func (a *App) previewFile() {
+	item, ok := a.Store.GetByIndex(a.selRow - 1)
+	if !ok {
+		return
+	}
+	if !env.LooksLikePath(item.Key, item.Value) {
+		a.updateStatusInline(fmt.Sprintf("%s does not look like a path", item.Key))
+		return
+	}
+	path := env.ResolvePath(item.Value)
+	info, err := os.Stat(path)
+	if err != nil {
+		a.updateStatusInline(fmt.Sprintf("%s: %v", item.Key, err))
+		return
+	}
+
+	var b strings.Builder
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			a.updateStatusInline(fmt.Sprintf("%s: %v", item.Key, err))
+			return
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			b.WriteString(tview.Escape(name) + "\n")
+		}
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			a.updateStatusInline(fmt.Sprintf("%s: %v", item.Key, err))
+			return
+		}
+		data, _ := io.ReadAll(io.LimitReader(f, previewLimit))
+		f.Close()
+		switch {
+		case bytes.IndexByte(data, 0) >= 0:
+			fmt.Fprintf(&b, "(binary file, %d bytes)", info.Size())
+		default:
+			b.WriteString(tview.Escape(string(data)))
+			if info.Size() > previewLimit {
+				fmt.Fprintf(&b, "\n[gray]… %d more bytes[-]", info.Size()-previewLimit)
+			}
+		}
+	}
+	a.showText(path, b.String())
+}
+
+// logLines is how much of the log file :log shows.
+const logLines = 500
+
+func (a *App) showLog() string {
+	lines, err := logging.Tail(logLines)
+	if err != nil {
+		return fmt.Sprintf("Log: %v", err)
+	}
+	view := tview.Escape(strings.Join(lines, "\n"))
+	a.showText("Log "+logging.Path(), view).ScrollToEnd()
+	return ""
+}
+
+// checkUpdate reports a newer release in the status line. It runs in the
+// background when update.check is set in the config.
+func (a *App) checkUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rel, err := update.Check(ctx)
+	if err != nil {
+		slog.Debug("update check failed", "err", err)
+		return
+	}
+	if rel == nil {
+		return
+	}
+	slog.Info("update available", "current", update.Current(), "latest", rel.Tag)
+	a.App.QueueUpdateDraw(func() {
+		a.updateStatusInline(fmt.Sprintf("envoy %s is available (running %s): envoy self-update", rel.Tag, update.Current()))
+	})
+}
@@ -13,12 +13,29 @@ const (
 	ModeInsert
 	ModeCommand
 	ModeSearch
+	ModeVisual // V: a range of rows from an anchor to the cursor
 )
 
+func (m Mode) String() string {
+	switch m {
+	case ModeInsert:
+		return "INSERT"
+	case ModeCommand:
+		return "COMMAND"
+	case ModeSearch:
+		return "SEARCH"
+	case ModeVisual:
+		return "VISUAL LINE"
+	default:
+		return "NORMAL"
+	}
+}
+
 type VimState struct {
 	Mode         Mode
 	PendingNum   string
 	PendingOp    string
+	Register     string // register named by a " prefix for the next y or p, or ""
 	LastSearch   string
 	StatusFn     func(s string)
 	RedrawFn     func()
@@ -32,6 +49,18 @@ type VimState struct {
 	CommandFn    func(cmd string) string
 	SearchFn     func(query string)
 	CancelFn     func()
+	OpenFileFn   func()
+	InspectFn    func()
+	NextTodoFn   func(prev bool)
+	RevealFn     func()
+	UndoFn       func(n int)
+	RepeatFn     func(n int)
+	FoldFn       func(op string)
+	VisualFn     func(op string) // "V" starts, "ESC" ends, others act on the range
+	MarkFn       func(name string)
+	JumpMarkFn   func(name string)
+	YankFn       func(valueOnly bool, n int)
+	PasteFn      func(over bool)
 }
 
 // NewVimState return a vim state as normal mode
@@ -49,6 +78,25 @@ func (v *VimState) SetStatus(format string, a ...any) {
 func (v *VimState) resetPrefix() {
 	v.PendingNum = ""
 	v.PendingOp = ""
+	v.Register = ""
+}
+
+// isRegister reports whether key names a register: " for the unnamed one,
+// a to z, or A to Z to append to a to z.
+func isRegister(key string) bool {
+	return len(key) == 1 && (key == `"` || key[0] >= 'a' && key[0] <= 'z' || key[0] >= 'A' && key[0] <= 'Z')
+}
+
+// pickRegister ends a " prefix, keeping the register named by key for the
+// command that follows. It reports whether key named one.
+func (v *VimState) pickRegister(key string) bool {
+	if !isRegister(key) {
+		return false
+	}
+	v.Register = key
+	v.PendingOp = ""
+	v.SetStatus("-- %s", v.prefixText())
+	return true
 }
 
 func (v *VimState) countOrDefault() int {
@@ -68,6 +116,8 @@ func (v *VimState) HandleKey(key string) bool {
 	switch v.Mode {
 	case ModeNormal:
 		return v.handleNormal(key)
+	case ModeVisual:
+		return v.handleVisual(key)
 	default:
 		return false
 	}
@@ -91,9 +141,9 @@ func (v *VimState) handleNormal(key string) bool {
 			v.MoveFn(v.countOrDefault(), 0)
 		case "k":
 			v.MoveFn(-v.countOrDefault(), 0)
-		case "g":
-			v.PendingOp = "g"
-			v.SetStatus("-- g")
+		case "g", "]", "[", "z", "y", `"`, "m", "'":
+			v.PendingOp = key
+			v.SetStatus("-- %s", key)
 			return true
 		case "G":
 			v.JumpBottomFn()
@@ -123,6 +173,21 @@ func (v *VimState) handleNormal(key string) bool {
 			v.AddFn()
 		case "x":
 			v.DeleteFn()
+		case "p":
+			v.PasteFn(false)
+		case "P":
+			v.PasteFn(true)
+		case "u":
+			v.UndoFn(v.countOrDefault())
+		case ".":
+			v.RepeatFn(v.countOrDefault())
+		case "K":
+			v.InspectFn()
+		case "s":
+			v.RevealFn()
+		case "V":
+			v.Mode = ModeVisual
+			v.VisualFn("V")
 		case "ESC":
 			v.CancelFn()
 		default:
@@ -133,9 +198,40 @@ func (v *VimState) handleNormal(key string) bool {
 	} else {
 		// we have a pending op
 		switch v.PendingOp {
+		case `"`:
+			if v.pickRegister(key) {
+				return true
+			}
+		case "m":
+			if key >= "a" && key <= "z" {
+				v.MarkFn(key)
+			}
+		case "'":
+			if key >= "a" && key <= "z" || key == "'" {
+				v.JumpMarkFn(key)
+			}
+		case "y":
+			switch key {
+			case "y":
+				v.YankFn(false, v.countOrDefault())
+			case "v":
+				v.YankFn(true, 1)
+			}
 		case "g":
-			if key == "g" {
+			switch key {
+			case "g":
 				v.JumpTopFn()
+			case "f":
+				v.OpenFileFn()
+			}
+		case "]", "[":
+			if key == "t" {
+				v.NextTodoFn(v.PendingOp == "[")
+			}
+		case "z":
+			switch key {
+			case "a", "o", "c", "R", "M":
+				v.FoldFn(key)
 			}
 		}
 	}
@@ -143,8 +239,52 @@ func (v *VimState) handleNormal(key string) bool {
 	return true
 }
 
+// handleVisual moves the cursor end of the range with j, k, G and gg, and
+// hands d, x, y, m and : to VisualFn to act on it; "x picks the register y
+// yanks into. ESC or V ends the mode. Other keys are swallowed.
+func (v *VimState) handleVisual(key string) bool {
+	if key >= "1" && key <= "9" || key == "0" && v.PendingNum != "" {
+		v.PendingNum += key
+		return true
+	}
+	switch v.PendingOp {
+	case "g":
+		if key == "g" {
+			v.JumpTopFn()
+		}
+		v.resetPrefix()
+		return true
+	case `"`:
+		if !v.pickRegister(key) {
+			v.resetPrefix()
+		}
+		return true
+	}
+	switch key {
+	case "j":
+		v.MoveFn(v.countOrDefault(), 0)
+	case "k":
+		v.MoveFn(-v.countOrDefault(), 0)
+	case "G":
+		v.JumpBottomFn()
+	case "g", `"`:
+		v.PendingOp = key
+		return true
+	case "ESC", "V":
+		v.Mode = ModeNormal
+		v.VisualFn("ESC")
+	case "d", "x", "y", "m", ":":
+		v.VisualFn(key)
+	}
+	v.resetPrefix()
+	return true
+}
+
 func (v *VimState) prefixText() string {
 	var b strings.Builder
+	if v.Register != "" {
+		b.WriteString(`"` + v.Register)
+	}
 	if v.PendingNum != "" {
 		b.WriteString(v.PendingNum)
 	}
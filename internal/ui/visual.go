@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// visualRange is what ":" prefills in visual mode; a command line starting
+// with it acts on the rows that were selected.
+const visualRange = "'<,'>"
+
+// visual handles the visual mode operations VimState passes on.
+func (a *App) visual(op string) {
+	switch op {
+	case "V":
+		if a.Store.Count() == 0 {
+			a.Vim.Mode = ModeNormal
+			return
+		}
+		a.visualFrom = a.selRow
+		a.renderTable()
+		a.updateStatusInline("-- VISUAL LINE --  d delete  y yank  m mask  : command")
+		return
+	case "ESC":
+		a.endVisual()
+		return
+	}
+	keys := a.visualKeys()
+	a.endVisual()
+	switch op {
+	case "d", "x":
+		a.deleteKeys(keys)
+	case "y":
+		a.updateStatusInline(a.yank(keys))
+	case "m":
+		a.updateStatusInline(a.toggleMask(keys))
+	case ":":
+		a.rangeKeys = keys
+		a.enterCommand(visualRange)
+	}
+}
+
+// endVisual leaves visual mode, unpainting the range.
+func (a *App) endVisual() {
+	a.Vim.Mode = ModeNormal
+	a.visualFrom = 0
+	a.renderTable()
+}
+
+// inVisual reports whether row r, counted as a.selRow is, is in the visual
+// range.
+func (a *App) inVisual(r int) bool {
+	if a.Vim.Mode != ModeVisual || a.visualFrom == 0 {
+		return false
+	}
+	lo, hi := min(a.visualFrom, a.selRow), max(a.visualFrom, a.selRow)
+	return r >= lo && r <= hi
+}
+
+// visualKeys lists the keys in the visual range, folded ones included.
+func (a *App) visualKeys() []string {
+	var out []string
+	for i, k := range a.Store.ListKeys() {
+		if a.inVisual(i + 1) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// toggleMask masks the values of keys whatever they look like, or, when
+// they all are masked that way already, stops.
+func (a *App) toggleMask(keys []string) string {
+	all := true
+	for _, k := range keys {
+		all = all && a.maskKeys[k]
+	}
+	for _, k := range keys {
+		if all {
+			delete(a.maskKeys, k)
+		} else {
+			a.maskKeys[k] = true
+		}
+	}
+	a.renderTable()
+	if all {
+		return fmt.Sprintf("Unmasked %d variables", len(keys))
+	}
+	return fmt.Sprintf("Masked %d variables", len(keys))
+}
+
+// deleteKeys asks, then deletes keys as one change.
+func (a *App) deleteKeys(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	shown := keys
+	if len(shown) > 8 {
+		shown = append(shown[:8:8], fmt.Sprintf("and %d more", len(keys)-8))
+	}
+	m := tview.NewModal().
+		SetText(fmt.Sprintf("Delete %d variables?\n\n%s", len(keys), strings.Join(shown, "\n"))).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			a.Vim.Mode = ModeNormal
+			if label == "Yes" {
+				a.lastChange = func() string { return a.deleteRows(len(keys)) }
+				a.Store.Begin()
+				for _, k := range keys {
+					a.Store.Delete(k)
+				}
+				_ = a.Store.Commit()
+				a.renderTable()
+				a.updateStatusInline(fmt.Sprintf("Deleted %d variables", len(keys)))
+			}
+			a.refreshStatus()
+		})
+	a.Pages.AddPage(pageModal, centerPrimitive(m, 50, min(len(shown), 8)+7), true, true)
+	a.App.SetFocus(m)
+}
+
+// rangeCommand runs the ":'<,'>" commands on the keys selected in visual
+// mode: w writes just them, d deletes and y yanks them.
+func (a *App) rangeCommand(text string) string {
+	keys := a.rangeKeys
+	a.rangeKeys = nil
+	if keys == nil {
+		return "No visual range"
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	switch fields[0] {
+	case "w", "w!":
+		a.onlyKeys = keys
+		defer func() { a.onlyKeys = nil }()
+		return a.execCommand(text)
+	case "d", "delete":
+		a.deleteKeys(keys)
+		return ""
+	case "y", "yank":
+		return a.yank(keys)
+	}
+	return fmt.Sprintf(":%s does not take a range", fields[0])
+}
+
+// paintVisual marks the cells of a row in the visual range: with the
+// palette's color, or underlined without one.
+func (a *App) paintVisual(keyCell, valCell *tview.TableCell) {
+	for _, c := range []*tview.TableCell{keyCell, valCell} {
+		if a.pal.Visual == 0 {
+			addAttrs(c, tcell.AttrUnderline)
+		} else {
+			c.SetBackgroundColor(a.pal.Visual)
+		}
+	}
+}
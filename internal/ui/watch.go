@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+// watchCommand handles ":watch <provider> <path>", which pulls a remote
+// layer and keeps it up to date as it changes. A key edited here since the
+// last update keeps its local value.
+func (a *App) watchCommand(args []string) string {
+	if len(args) < 2 {
+		return "Usage: :watch <provider> <path>"
+	}
+	name, path := args[0], args[1]
+	source := name + ":" + path
+	if _, ok := a.watches[source]; ok {
+		return "Already watching " + source
+	}
+	p, err := a.openProvider(name)
+	if err != nil {
+		return fmt.Sprintf("Watch failed: %v", err)
+	}
+	w, ok := p.(remote.Watcher)
+	if !ok {
+		return fmt.Sprintf("Watch failed: %s cannot watch for changes", name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if a.watches == nil {
+		a.watches = make(map[string]context.CancelFunc)
+	}
+	a.watches[source] = cancel
+	slog.Info("watch", "source", source)
+
+	var last map[string]string // values of the previous update
+	go func() {
+		err := w.Watch(ctx, path, func(entries []remote.Entry) {
+			a.App.QueueUpdateDraw(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				a.updateStatusInline(a.applyWatched(source, last, entries))
+				last = make(map[string]string, len(entries))
+				for _, e := range entries {
+					last[e.Key] = e.Value
+				}
+			})
+		})
+		a.App.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			delete(a.watches, source)
+			slog.Error("watch failed", "source", source, "err", err)
+			a.updateStatusInline(remoteError("Watch of "+source, err))
+		})
+	}()
+	return "Watching " + source
+}
+
+// applyWatched brings the store up to date with a watched source. prev
+// holds the source's values at the last update, nil on the first. A key
+// whose value here no longer matches prev was edited locally and is left
+// alone; a key gone from the source is deleted unless edited.
+func (a *App) applyWatched(source string, prev map[string]string, entries []remote.Entry) string {
+	var items []env.Item
+	var kept []string
+	edited := func(key string) bool {
+		it, ok := a.Store.Get(key)
+		old, had := prev[key]
+		return had && (!ok || it.Value != old)
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		seen[e.Key] = true
+		if old, had := prev[e.Key]; had && old == e.Value {
+			continue
+		}
+		if edited(e.Key) {
+			kept = append(kept, e.Key)
+			continue
+		}
+		items = append(items, env.Item{Key: e.Key, Value: e.Value})
+	}
+	var gone []string
+	for _, k := range slices.Sorted(maps.Keys(prev)) {
+		if seen[k] {
+			continue
+		}
+		if edited(k) {
+			kept = append(kept, k)
+			continue
+		}
+		gone = append(gone, k)
+	}
+	if prev != nil && len(items) == 0 && len(gone) == 0 && len(kept) == 0 {
+		return ""
+	}
+
+	a.Store.Begin()
+	a.Store.Merge(source, items)
+	for _, k := range gone {
+		a.Store.Delete(k)
+	}
+	_ = a.Store.Commit()
+	a.renderTable()
+
+	msg := fmt.Sprintf("Pulled %d vars from %s", len(items), source)
+	if prev != nil {
+		msg = fmt.Sprintf("%s changed: %d updated, %d removed", source, len(items), len(gone))
+	}
+	if len(kept) > 0 {
+		msg += fmt.Sprintf("; kept local edits to %s", strings.Join(kept, ", "))
+	}
+	return msg
+}
+
+// unwatchCommand handles ":unwatch [<provider> <path>]", stopping one
+// watch or, without arguments, all of them.
+func (a *App) unwatchCommand(args []string) string {
+	if len(args) == 0 {
+		if len(a.watches) == 0 {
+			return "Not watching anything"
+		}
+		n := len(a.watches)
+		for source, cancel := range a.watches {
+			cancel()
+			delete(a.watches, source)
+		}
+		return fmt.Sprintf("Stopped %d watches", n)
+	}
+	if len(args) < 2 {
+		return "Usage: :unwatch [<provider> <path>]"
+	}
+	source := args[0] + ":" + args[1]
+	cancel, ok := a.watches[source]
+	if !ok {
+		return "Not watching " + source
+	}
+	cancel()
+	delete(a.watches, source)
+	return "Stopped watching " + source
+}
+
+// pushBase is what a conditional push expects the remote to hold: the
+// values last pulled from source.
+func (a *App) pushBase(source string, entries []remote.Entry) map[string]string {
+	base := make(map[string]string)
+	for _, e := range entries {
+		for _, l := range a.Store.Layers(e.Key) {
+			if l.Source == source {
+				base[e.Key] = l.Value
+			}
+		}
+	}
+	return base
+}
+
+// conflictNote explains a refused conditional push.
+func conflictNote(err error) (string, bool) {
+	var ce *remote.ConflictError
+	if !errors.As(err, &ce) {
+		return "", false
+	}
+	verb := "refused"
+	if len(ce.Written) > 0 {
+		verb = "stopped partway"
+	}
+	return fmt.Sprintf("Push %s: %v since the last pull; :pull to refresh or :push --force to overwrite", verb, ce), true
+}
@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// whichKeyDelay is how long a prefix must sit before its continuations
+// are shown.
+const whichKeyDelay = 500 * time.Millisecond
+
+const pageWhichKey = "whichkey"
+
+// keyHint is one continuation of a prefix.
+type keyHint struct {
+	key, desc string
+}
+
+// commandHints describes the : commands; :help is built from it too.
+var commandHints = []keyHint{
+	{"w [path] [--order alpha|natural|file|prefix|schema] [--prefix AWS_,GCP_] [--section name] [--format json|bash|fish|pwsh|envrc|toml|properties|chamber|k8s-secret|k8s-configmap|markdown|csv] [--name object] [--targets fly,heroku,vercel,json,bash,fish,pwsh,envrc,toml,properties,chamber,k8s-secret,k8s-configmap,markdown,csv] [--provenance] [--redact profile] [--encoding utf-8|utf-16le|...] [--crlf] [--sign] [--resolve]", "write"},
+	{"w! [path]", "write, taking over another session's lock"},
+	{"q", "quit"},
+	{"wq [path]", "write and quit"},
+	{"x [path]", "write if changed and quit"},
+	{"import <path>", "merge a dotenv, JSON, YAML, TOML or Java properties file, ConfigMaps and Secrets from a manifest, or a .env.vault#environment"},
+	{"import compose <file> [service]", "merge the environment a docker-compose service would get"},
+	{"import chamber <file>", "merge a chamber export, keys named as chamber exec sets them"},
+	{"apply [KEY...|%]", "set edits in the running process"},
+	{"paste", "paste KEY=VALUE lines or JSON"},
+	{"import-clipboard", "add KEY=VALUE lines or JSON from the system clipboard"},
+	{"batch", "type several KEY=VALUE lines, checked per line"},
+	{"bundle save|load <path>", "share this session's variables and metadata"},
+	{"stats", "environment statistics"},
+	{"overrides", "keys shadowed across layers"},
+	{"lint", "check against the lint rules"},
+	{"casecheck", "keys differing only by case"},
+	{"dupvalues", "keys sharing an identical value"},
+	{"renameprefix OLD_ NEW_", "rename every key with a prefix, and references to them"},
+	{"todos", "values still holding a placeholder such as <CHANGE_ME> or TODO"},
+	{"secrets", "values that look like live credentials, by token format, key name and entropy"},
+	{"proxycheck", "proxy variable consistency"},
+	{"probe [KEY...|%]", "request URL values and show status and latency"},
+	{"testconn [KEY...|%]", "connect to the databases named by DSN values"},
+	{"simulate [cmd]", "the environment a child process would get"},
+	{"compat [key]", "how docker, compose, bash and Ruby dotenv read the value back from .env"},
+	{"wrap [stop|diff]", "relaunch the wrapped command (F5), or list edits since launch"},
+	{"diff [-u] [path]", "compare with an env file and take its values"},
+	{"compare-shell [shell]", "diff against a fresh login shell"},
+	{"scrub-history [file...]", "find secrets from the table in shell history and redact those lines"},
+	{"allow", "always load this directory's env files"},
+	{"deny", "never load this directory's env files"},
+	{"expires <date|+days|none>", "set the selected key's expiry"},
+	{"expiring [days]", "keys due for rotation"},
+	{"meta [@name=value|delete name]", "meta-variables that values refer to as ${@name}"},
+	{"set inputmode=vim|basic", "choose vim or conventional key bindings"},
+	{"set sort=alpha|natural", "order keys plainly or with numbers by value"},
+	{"set types|notypes", "show each value's inferred type"},
+	{"set mask|unmask", "hide secret-looking values as •••• (s reveals the selected one)"},
+	{"set diff=myers|histogram", "choose the value diff algorithm"},
+	{"set diffunit=char|word", "diff values by character or by word"},
+	{"set palette=NAME", "default, high-contrast, colorblind or none"},
+	{"set accessible|noaccessible", "describe the selection and announce changes"},
+	{"set statusline=FORMAT", "status line template: %m mode, %f file, %r/%R row/count, %q filter, %d modified"},
+	{"set idle=MINUTES", "lock the screen after minutes without a key; 0 never"},
+	{"lock", "blank the screen until a key or the passphrase"},
+	{"legend", "explain the table's gutter symbols"},
+	{"budget <name|off>", "size budget mode"},
+	{"tutor", "interactive tutorial"},
+	{"auth <provider>", "store provider credentials"},
+	{"pull <provider> <path>", "pull a remote layer"},
+	{"browse <provider> <path>", "list a remote path page by page and import entries"},
+	{"push <provider> <path> [--force]", "push visible keys, refused if changed remotely since the pull"},
+	{"aws pull|push [/app/prod/|sm:secret] [--force]", "Parameter Store or Secrets Manager; push sends pulled and edited keys"},
+	{"watch <provider> <path>", "pull a remote layer and follow its changes"},
+	{"unwatch [<provider> <path>]", "stop following remote changes"},
+	{"resolve [entry|op://ref]", "resolve pass:// and op:// references"},
+	{"store [entry]", "move the selected value into pass"},
+	{"fold [section|all|none]", "fold or open a section of the table"},
+	{"op [vault[/item[/field]]]", "move the selected value into 1Password, leaving an op:// reference"},
+	{"age", "set the identity and recipients .age files are decrypted and encrypted with"},
+	{"keyring [service/account]", "move the selected value into the OS keychain, leaving a keyring:// reference"},
+	{"sources", "startup source status"},
+	{"log", "show the log"},
+	{"recover", "replay journaled changes"},
+	{"inspect", "toggle the inspector"},
+	{"explain [key]", "describe a variable"},
+	{"version", "build information"},
+	{"undo [count]", "revert the latest edit, delete or import (u)"},
+	{"redo [count]", "reapply what undo reverted (C-r)"},
+	{"e [path]", "open an env file in a buffer, or reload the buffer shown"},
+	{"e!", "reload the buffer shown, discarding its changes"},
+	{"ls", "list buffers"},
+	{"b <n|name>", "show a buffer"},
+	{"bnext, bprev", "show the next or previous buffer"},
+	{"marks", "list the marks set with m{a-z} ('{a-z} jumps back)"},
+	{"registers", "list what \"a to \"z and the unnamed register hold (yy, yv, p, P)"},
+	{"help", "list commands"},
+}
+
+// prefixHints lists the continuations of normal-mode prefixes.
+var prefixHints = map[string][]keyHint{
+	"g": {
+		{"g", "first row"},
+		{"f", "preview the file the value names"},
+	},
+	"]": {
+		{"t", "next placeholder value"},
+	},
+	"[": {
+		{"t", "previous placeholder value"},
+	},
+	"z": {
+		{"a", "fold or open the selected key's section"},
+		{"o c", "open or fold the selected key's section"},
+		{"R M", "open or fold every section"},
+	},
+	"m": {
+		{"a-z", "mark the selected key"},
+	},
+	"'": {
+		{"a-z", "jump to the marked key"},
+		{"'", "jump back to where the last jump left"},
+	},
+	"y": {
+		{"y", "yank the KEY=VALUE of the selected row, or count rows"},
+		{"v", "yank the selected value alone"},
+	},
+	`"`: {
+		{"a-z", "use a register for the next y or p"},
+		{"A-Z", "append to a register with the next y"},
+		{`"`, "the unnamed register"},
+	},
+	windowPrefix: {
+		{"w", "switch between the table and side panes"},
+		{"< >", "narrow or widen the focused pane"},
+		{"=", "reset pane widths"},
+		{"z", "zoom the focused pane, or restore"},
+	},
+}
+
+func commandHelp() string {
+	var parts []string
+	for _, h := range commandHints {
+		parts = append(parts, ":"+h.key)
+	}
+	return "Commands: " + strings.Join(parts, " | ") + " | /search"
+}
+
+// scheduleWhichKey shows prefix's continuations if it is still pending
+// after whichKeyDelay. Any later key cancels it.
+func (a *App) scheduleWhichKey(prefix string) {
+	a.whichGen++
+	gen := a.whichGen
+	time.AfterFunc(whichKeyDelay, func() {
+		a.App.QueueUpdateDraw(func() {
+			if gen != a.whichGen || !a.prefixPending(prefix) {
+				return
+			}
+			a.showWhichKey(prefix)
+		})
+	})
+}
+
+func (a *App) prefixPending(prefix string) bool {
+	if prefix == ":" {
+		return a.Vim.Mode == ModeCommand
+	}
+	return a.Vim.Mode == ModeNormal && a.Vim.PendingOp == prefix
+}
+
+// whichKeyInput is called for every key the table or command line sees.
+func (a *App) whichKeyInput() {
+	a.whichGen++
+	if a.whichKeyShown != "" && a.whichKeyShown != ":" {
+		a.hideWhichKey()
+	}
+}
+
+func (a *App) showWhichKey(prefix string) {
+	var hints []keyHint
+	if prefix == ":" {
+		typed := strings.TrimSpace(a.Cmd.GetText())
+		for _, h := range commandHints {
+			if strings.HasPrefix(h.key, typed) {
+				hints = append(hints, h)
+			}
+		}
+	} else {
+		hints = prefixHints[prefix]
+	}
+	if len(hints) == 0 {
+		a.hideWhichKey()
+		return
+	}
+
+	width := 0
+	for _, h := range hints {
+		width = max(width, len(h.key))
+	}
+	var b strings.Builder
+	for _, h := range hints {
+		fmt.Fprintf(&b, "[yellow]%s%-*s[-]  %s\n", tview.Escape(prefix), width, tview.Escape(h.key), tview.Escape(h.desc))
+	}
+	view := tview.NewTextView().SetDynamicColors(true).SetText(strings.TrimSuffix(b.String(), "\n"))
+	view.SetBorder(true).SetTitle(" " + prefix + " ").SetTitleAlign(tview.AlignLeft)
+
+	// Bottom-anchored above the status and command lines.
+	height := min(len(hints)+2, 16)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(view, height, 0, false).
+		AddItem(nil, 2, 0, false)
+	a.Pages.RemovePage(pageWhichKey)
+	a.Pages.AddPage(pageWhichKey, layout, true, true)
+	a.whichKeyShown = prefix
+	// AddPage focuses the new page; give focus back.
+	if prefix == ":" {
+		a.App.SetFocus(a.Cmd)
+	} else {
+		a.App.SetFocus(a.Table)
+	}
+}
+
+func (a *App) hideWhichKey() {
+	if a.whichKeyShown == "" {
+		return
+	}
+	a.whichKeyShown = ""
+	a.Pages.RemovePage(pageWhichKey)
+}
@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivethorn/envoy/internal/snapshot"
+	"github.com/rivo/tview"
+)
+
+// wrapMaxLines bounds the output kept in the wrap pane.
+const wrapMaxLines = 2000
+
+// wrapState is the command started by "envoy wrap": its output pane, the
+// environment of its latest launch and how that run ended.
+type wrapState struct {
+	argv     []string
+	pane     *tview.TextView
+	cmd      *exec.Cmd
+	launched map[string]string // environment of the latest launch
+	runs     int
+	started  time.Time
+	exit     string // how the latest run ended; "" while it runs
+	relaunch bool   // launch again once the running one has exited
+}
+
+func newWrapState(argv []string) *wrapState {
+	pane := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetMaxLines(wrapMaxLines)
+	pane.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+	return &wrapState{argv: argv, pane: pane}
+}
+
+func (w *wrapState) running() bool { return w.cmd != nil && w.exit == "" }
+
+// launchWrapped starts the wrapped command with the store's variables as
+// its whole environment, recording them for :wrap diff.
+func (a *App) launchWrapped() string {
+	w := a.wrap
+	if w.running() {
+		w.relaunch = true
+		if err := w.cmd.Process.Kill(); err != nil {
+			return fmt.Sprintf("Stop %s: %v", w.argv[0], err)
+		}
+		return "Stopping " + w.argv[0] + " to relaunch…"
+	}
+
+	vars := a.Store.Vars()
+	for k, v := range vars {
+		vars[k], _ = a.Store.Resolve(v)
+	}
+	if err := resolveRefs(vars); err != nil {
+		return fmt.Sprintf("Launch failed: %v", err)
+	}
+	changes := snapshot.Diff(w.launched, vars)
+	environ := make([]string, 0, len(vars))
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		environ = append(environ, k+"="+vars[k])
+	}
+
+	cmd := exec.Command(w.argv[0], w.argv[1:]...)
+	cmd.Env = environ
+	out := tview.ANSIWriter(w.pane)
+	cmd.Stdout, cmd.Stderr = out, out
+
+	w.runs++
+	if w.runs > 1 {
+		fmt.Fprintf(w.pane, "[yellow]── run %d: %s ──[-]\n", w.runs, tview.Escape(changeSummary(changes)))
+	}
+	if err := cmd.Start(); err != nil {
+		w.cmd, w.exit = nil, "failed to start"
+		fmt.Fprintf(w.pane, "[red]%s[-]\n", tview.Escape(err.Error()))
+		a.wrapTitle()
+		slog.Error("wrap start", "argv", w.argv, "err", err)
+		return fmt.Sprintf("Launch failed: %v", err)
+	}
+	w.cmd, w.exit, w.launched, w.started = cmd, "", vars, time.Now()
+	slog.Info("wrap start", "argv", w.argv, "pid", cmd.Process.Pid, "run", w.runs, "changes", len(changes))
+	a.wrapTitle()
+	w.pane.ScrollToEnd()
+
+	go func() {
+		err := cmd.Wait()
+		a.App.QueueUpdateDraw(func() { a.wrapExited(cmd, err) })
+	}()
+	return fmt.Sprintf("Launched %s (run %d)", w.argv[0], w.runs)
+}
+
+// wrapExited records how cmd ended and relaunches it when asked to.
+func (a *App) wrapExited(cmd *exec.Cmd, err error) {
+	w := a.wrap
+	if w == nil || w.cmd != cmd {
+		return
+	}
+	took := time.Since(w.started).Round(100 * time.Millisecond)
+	switch {
+	case err == nil:
+		w.exit = fmt.Sprintf("exited 0 after %s", took)
+	case cmd.ProcessState != nil && cmd.ProcessState.ExitCode() >= 0:
+		w.exit = fmt.Sprintf("exited %d after %s", cmd.ProcessState.ExitCode(), took)
+	default:
+		w.exit = fmt.Sprintf("%v after %s", err, took)
+	}
+	slog.Info("wrap exit", "argv", w.argv, "run", w.runs, "exit", w.exit)
+	fmt.Fprintf(w.pane, "[gray]── %s ──[-]\n", tview.Escape(w.exit))
+	a.wrapTitle()
+	if w.relaunch {
+		w.relaunch = false
+		a.updateStatusInline(a.launchWrapped())
+		return
+	}
+	a.updateStatusInline(w.argv[0] + " " + w.exit + ": edit variables, then F5 to relaunch")
+}
+
+func (a *App) wrapTitle() {
+	w := a.wrap
+	state := "running"
+	if !w.running() {
+		state = w.exit
+	}
+	w.pane.SetTitle(fmt.Sprintf(" %s — run %d, %s — F5 relaunch ", strings.Join(w.argv, " "), w.runs, state))
+}
+
+// stopWrapped kills the wrapped command when Envoy quits.
+func (a *App) stopWrapped() {
+	if a.wrap == nil || !a.wrap.running() {
+		return
+	}
+	a.wrap.relaunch = false
+	if err := a.wrap.cmd.Process.Kill(); err != nil {
+		slog.Warn("wrap stop", "err", err)
+	}
+}
+
+// wrapCommand handles ":wrap", which relaunches, ":wrap stop" and
+// ":wrap diff", which lists edits made since the latest launch.
+func (a *App) wrapCommand(args []string) string {
+	if a.wrap == nil {
+		return "No wrapped command (start with: envoy wrap -- <cmd>)"
+	}
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "":
+		return a.launchWrapped()
+	case "stop":
+		if !a.wrap.running() {
+			return a.wrap.argv[0] + " is not running"
+		}
+		a.stopWrapped()
+		return "Stopping " + a.wrap.argv[0]
+	case "diff":
+		a.showWrapDiff(snapshot.Diff(a.wrap.launched, a.Store.Vars()))
+		return ""
+	default:
+		return "Usage: :wrap [stop|diff]"
+	}
+}
+
+// showWrapDiff lists the variables changed since the latest launch. Enter
+// jumps to the key.
+func (a *App) showWrapDiff(changes []snapshot.Change) {
+	if len(changes) == 0 {
+		a.updateStatusInline("No changes since run " + fmt.Sprint(a.wrap.runs))
+		return
+	}
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+	table.SetCell(0, 0, headerCell("KEY"))
+	table.SetCell(0, 1, headerCell("AT LAUNCH"))
+	table.SetCell(0, 2, headerCell("NOW"))
+	for i, c := range changes {
+		mark, color := a.changeMark(c.Kind == snapshot.Added, c.Kind == snapshot.Removed)
+		table.SetCell(i+1, 0, tview.NewTableCell(mark+c.Key).SetTextColor(color).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(c.Old).SetExpansion(2).SetMaxWidth(50))
+		now := tview.Escape(c.New)
+		if c.Kind == snapshot.Changed {
+			now = a.valueDiff(c.Old, c.New)
+		}
+		table.SetCell(i+1, 2, tview.NewTableCell(now).SetExpansion(2).SetMaxWidth(50))
+	}
+	table.Select(1, 0)
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %d changes since run %d — Enter go to key, ESC close ", len(changes), a.wrap.runs)).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEsc {
+			a.closeModal()
+		}
+	})
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(changes) {
+			return
+		}
+		a.closeModal()
+		a.selectKey(changes[row-1].Key)
+	})
+	a.Pages.AddPage(pageModal, centerPrimitive(table, 110, 20), true, true)
+	a.App.SetFocus(table)
+}
+
+// changeSummary describes the edits between two launches in one line.
+func changeSummary(changes []snapshot.Change) string {
+	if len(changes) == 0 {
+		return "same environment"
+	}
+	var parts []string
+	for _, c := range changes {
+		switch c.Kind {
+		case snapshot.Added:
+			parts = append(parts, "+"+c.Key)
+		case snapshot.Removed:
+			parts = append(parts, "-"+c.Key)
+		default:
+			parts = append(parts, "~"+c.Key)
+		}
+	}
+	const show = 6
+	if len(parts) > show {
+		parts = append(parts[:show], fmt.Sprintf("and %d more", len(parts)-show))
+	}
+	return strings.Join(parts, " ")
+}
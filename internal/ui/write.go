@@ -0,0 +1,322 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+)
+
+// defaultWritePath is written by :w without a path.
+const defaultWritePath = ".env"
+
+// writeOpts holds the parsed arguments of :w.
+type writeOpts struct {
+	path       string
+	targets    []string
+	format     string
+	order      string
+	provenance *bool
+	redact     string
+	encoding   string
+	name       string
+	prefixes   []string // --prefix: write only keys starting with one
+	section    string   // --section: write only the keys of a section
+	crlf       *bool
+	sign       *bool
+	resolve    *bool
+}
+
+// boolWriteFlags take no value; "--flag=false" turns them off.
+var boolWriteFlags = map[string]bool{"provenance": true, "crlf": true, "sign": true, "resolve": true}
+
+// parseWriteArgs accepts "--flag value" and "--flag=value" anywhere in args;
+// the remaining words form the path.
+func parseWriteArgs(args []string) (writeOpts, error) {
+	var o writeOpts
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			rest = append(rest, arg)
+			continue
+		}
+		name, val, hasVal := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if boolWriteFlags[name] {
+			b, err := strconv.ParseBool(cmp.Or(val, "true"))
+			if err != nil {
+				return o, fmt.Errorf("--%s: %v", name, err)
+			}
+			switch name {
+			case "crlf":
+				o.crlf = &b
+			case "sign":
+				o.sign = &b
+			case "resolve":
+				o.resolve = &b
+			default:
+				o.provenance = &b
+			}
+			continue
+		}
+		if !hasVal {
+			if i+1 >= len(args) {
+				return o, fmt.Errorf("--%s needs a value", name)
+			}
+			i++
+			val = args[i]
+		}
+		switch name {
+		case "targets":
+			o.targets = strings.Split(val, ",")
+		case "format":
+			o.format = val
+		case "order":
+			o.order = val
+		case "redact":
+			o.redact = val
+		case "encoding":
+			o.encoding = val
+		case "name":
+			o.name = val
+		case "prefix":
+			o.prefixes = strings.Split(val, ",")
+		case "section":
+			o.section = val
+		default:
+			return o, fmt.Errorf("unknown option --%s", name)
+		}
+	}
+	o.path = expandHome(strings.Join(rest, " "))
+	return o, nil
+}
+
+// exportOptions resolves the format and order for path. --format wins over
+// the format path's extension selects (.md, .csv, .json, .sh, .fish, .ps1,
+// .envrc, .toml, .properties); --order wins over the config entry for that
+// file, which wins over the global config default. --provenance likewise
+// overrides export.provenance, --redact names the redaction profile and
+// --name the object a k8s format writes. --resolve and --resolve=false
+// decide whether keyring:// and op:// references are resolved. A file
+// imported from UTF-16, with a byte order mark or with CRLF line endings is
+// written back the same way unless --encoding or --crlf say otherwise.
+// After :'<,'> only the keys selected in visual mode are written, and
+// --prefix and --section narrow them further.
+func (a *App) exportOptions(path string, o writeOpts) (env.ExportOptions, error) {
+	fe := a.Config.ExportFor(path)
+	name := fe.Order
+	if o.order != "" {
+		name = o.order
+	}
+	order, err := env.ParseOrder(name)
+	if err != nil {
+		return env.ExportOptions{}, err
+	}
+	prov := a.Config.Export.Provenance
+	if o.provenance != nil {
+		prov = *o.provenance
+	}
+	eo := env.ExportOptions{Format: cmp.Or(o.format, env.FormatForPath(path)), Order: order, Schema: fe.Keys, Provenance: prov, Name: o.name, ResolveRefs: o.resolve, Only: a.onlyKeys}
+	if o.redact != "" {
+		r, ok := a.Config.Redaction(o.redact)
+		if !ok {
+			return eo, fmt.Errorf("unknown redaction profile %q (%s)", o.redact, strings.Join(a.Config.RedactionNames(), ", "))
+		}
+		if err := r.Validate(); err != nil {
+			return eo, fmt.Errorf("redaction profile %s: %w", o.redact, err)
+		}
+		eo.Redact = &r
+	}
+	eo.Encoding, _ = a.Store.Encoding(path)
+	if o.encoding != "" {
+		c, err := env.ParseCharset(o.encoding)
+		if err != nil {
+			return eo, err
+		}
+		eo.Encoding.Charset = c
+	}
+	if o.crlf != nil {
+		eo.Encoding.CRLF = *o.crlf
+	}
+	if len(o.prefixes) > 0 || o.section != "" {
+		if eo.Only, err = a.subsetKeys(o, eo.Only); err != nil {
+			return eo, err
+		}
+	}
+	return eo, nil
+}
+
+// subsetKeys returns the keys --prefix and --section pick, of only when
+// it is not nil. A section is named as :fold names it, by the start of its
+// name.
+func (a *App) subsetKeys(o writeOpts, only []string) ([]string, error) {
+	section := ""
+	if o.section != "" {
+		for _, name := range a.Store.Sections() {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(o.section)) {
+				section = name
+				break
+			}
+		}
+		if section == "" {
+			return nil, fmt.Errorf("no section %q", o.section)
+		}
+	}
+	keys := []string{}
+	for _, k := range a.Store.AllKeys() {
+		if only != nil && !slices.Contains(only, k) {
+			continue
+		}
+		if len(o.prefixes) > 0 && !slices.ContainsFunc(o.prefixes, func(p string) bool { return strings.HasPrefix(k, p) }) {
+			continue
+		}
+		if section != "" && a.Store.Section(k) != section {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys match %s", subsetText(o))
+	}
+	return keys, nil
+}
+
+// subsetText describes the --prefix and --section options of o.
+func subsetText(o writeOpts) string {
+	var parts []string
+	if len(o.prefixes) > 0 {
+		parts = append(parts, "--prefix "+strings.Join(o.prefixes, ","))
+	}
+	if o.section != "" {
+		parts = append(parts, "--section "+o.section)
+	}
+	return strings.Join(parts, " ")
+}
+
+// encodingNote names eo's encoding for the status line when it is not
+// plain UTF-8.
+func encodingNote(eo env.ExportOptions) string {
+	if eo.Encoding.Plain() {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", eo.Encoding)
+}
+
+// encodingWarning returns a status suffix when path was converted on
+// import, or "".
+func (a *App) encodingWarning(path string) string {
+	e, ok := a.Store.Encoding(path)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("; converted from %s, :w writes it back the same way (--encoding utf-8 --crlf=false to convert)", e)
+}
+
+// redactNote reports how many values eo's profile rewrites, for the
+// status line.
+func (a *App) redactNote(eo env.ExportOptions) string {
+	if eo.Redact == nil {
+		return ""
+	}
+	n := 0
+	for _, k := range a.Store.AllKeys() {
+		it, _ := a.Store.Get(k)
+		if _, ok := eo.Redact.Apply(k, it.Value); ok {
+			n++
+		}
+	}
+	return fmt.Sprintf(" (%d values redacted)", n)
+}
+
+func (a *App) writeCommand(args []string) string {
+	o, err := parseWriteArgs(args)
+	if err != nil {
+		return fmt.Sprintf("Write failed: %v", err)
+	}
+
+	if len(o.targets) > 0 {
+		// With --targets the path names the output directory.
+		dir := o.path
+		if dir == "" {
+			dir = "."
+		}
+		eo, err := a.exportOptions(dir, o)
+		if err != nil {
+			return fmt.Sprintf("Write failed: %v", err)
+		}
+		written, err := a.Store.ExportTargets(dir, o.targets, eo)
+		if err != nil {
+			return fmt.Sprintf("Write failed: %v", err)
+		}
+		if eo.Redact == nil && eo.Only == nil && !slices.ContainsFunc(o.targets, env.IsDocFormat) {
+			a.journalSaved()
+		}
+		return fmt.Sprintf("Wrote %s", strings.Join(written, ", ")) + encodingNote(eo) + a.redactNote(eo) + a.signFiles(written, a.signWanted(o)) + a.todoWarning() + a.secretWarning(eo)
+	}
+
+	path := o.path
+	if path == "" && subsetText(o) != "" {
+		// A subset written over the buffer's own file would lose the rest.
+		return fmt.Sprintf("Write failed: %s needs a file to write", subsetText(o))
+	}
+	if path == "" {
+		path = a.writeDefault()
+		if f, ok := env.TargetFile(o.format); ok {
+			path = f
+		}
+	}
+	eo, err := a.exportOptions(path, o)
+	if err != nil {
+		return fmt.Sprintf("Write failed: %v", err)
+	}
+
+	// When another session holds the file, the write waits for the user's
+	// answer and reports in the status line.
+	var msg string
+	waiting := true
+	a.writePending = true
+	a.bindFile(path, func() {
+		a.writePending = false
+		msg = a.writePath(path, eo, a.signWanted(o))
+		if waiting {
+			return
+		}
+		a.updateStatusInline(msg)
+		if a.quitAfterWrite {
+			a.App.Stop()
+		}
+	})
+	waiting = false
+	return msg
+}
+
+func (a *App) writePath(path string, eo env.ExportOptions, sign bool) string {
+	if msg := a.lockedOut(path); msg != "" {
+		return "Write failed: " + msg
+	}
+	err := a.Store.ExportWith(path, eo)
+	if a.ageRetry(err, func() string { return a.writePath(path, eo, sign) }) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("Write failed: %v", err)
+	}
+	// A redacted copy or a table for a wiki does not hold the session's
+	// values in a form they can be loaded from.
+	if eo.Redact == nil && eo.Only == nil && !env.IsDocFormat(eo.Format) {
+		a.journalSaved()
+	}
+	msg := fmt.Sprintf("Wrote %s", path) + encodingNote(eo) + a.redactNote(eo) + a.signFiles([]string{path}, sign) + a.todoWarning()
+	if eo.Format == "envrc" {
+		// direnv loads nothing it has not been allowed to since the change.
+		msg += "; run direnv allow to load it"
+	}
+	// Credentials are what an encrypted file is for.
+	if env.IsAgePath(path) || env.IsSopsFile(path) || env.IsVaultPath(path) {
+		return msg
+	}
+	return msg + a.secretWarning(eo)
+}
@@ -0,0 +1,39 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkInterval limits background checks to one per day.
+const checkInterval = 24 * time.Hour
+
+func stampPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "envoy", "update-check")
+}
+
+// Check returns a newer release, or nil when the running build is current.
+// It queries at most once per checkInterval; later calls return nil.
+func Check(ctx context.Context) (*Release, error) {
+	stamp := stampPath()
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < checkInterval {
+		return nil, nil
+	}
+	rel, err := Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(stamp), 0o755); err == nil {
+		_ = os.WriteFile(stamp, []byte(rel.Tag+"\n"), 0o644)
+	}
+	if !Newer(rel.Tag, Current()) {
+		return nil, nil
+	}
+	return rel, nil
+}
@@ -0,0 +1,250 @@
+// Package update reports the running version and installs newer releases.
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivethorn/envoy/internal/remote"
+)
+
+// Version and PublicKey are set at release build time with
+//
+//	-ldflags "-X github.com/rivethorn/envoy/internal/update.Version=v1.2.3
+//	          -X github.com/rivethorn/envoy/internal/update.PublicKey=<base64>"
+//
+// PublicKey is the ed25519 key that signs each release's SHA256SUMS.
+var (
+	Version   = ""
+	PublicKey = ""
+)
+
+const releasesURL = "https://api.github.com/repos/rivethorn/envoy/releases/latest"
+
+// maxDownload bounds release asset downloads.
+const maxDownload = 128 << 20
+
+// Current returns the running version: the linked-in Version, else the
+// module version recorded by `go install`, else "dev".
+func Current() string {
+	if Version != "" {
+		return Version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+	return "dev"
+}
+
+// Info describes the build for `envoy version` and :version.
+func Info() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "envoy %s\n", Current())
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				fmt.Fprintf(&b, "%-13s %s\n", strings.TrimPrefix(s.Key, "vcs.")+":", s.Value)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "%-13s %s\n", "go:", runtime.Version())
+	fmt.Fprintf(&b, "%-13s %s/%s\n", "platform:", runtime.GOOS, runtime.GOARCH)
+	return b.String()
+}
+
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Latest fetches the newest published release.
+func Latest(ctx context.Context) (*Release, error) {
+	var rel Release
+	h := http.Header{"Accept": {"application/vnd.github+json"}}
+	if err := remote.NewClient().JSON(ctx, http.MethodGet, releasesURL, h, nil, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// Newer reports whether tag is a later version than current. Development
+// builds never report an update.
+func Newer(tag, current string) bool {
+	a, ok1 := parseVersion(tag)
+	b, ok2 := parseVersion(current)
+	if !ok1 || !ok2 {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
+
+// parseVersion reads vMAJOR.MINOR.PATCH, ignoring any pre-release suffix.
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// AssetName is the release binary for this platform.
+func AssetName() string {
+	name := fmt.Sprintf("envoy_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Install downloads this platform's binary from rel, checks it against the
+// release's signed SHA256SUMS and replaces the running executable. It
+// returns the path replaced.
+func Install(ctx context.Context, rel *Release) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return "", errors.New("this build has no release signing key; reinstall from a release")
+	}
+	assets := make(map[string]string)
+	for _, a := range rel.Assets {
+		assets[a.Name] = a.URL
+	}
+	name := AssetName()
+	for _, n := range []string{name, "SHA256SUMS", "SHA256SUMS.sig"} {
+		if assets[n] == "" {
+			return "", fmt.Errorf("release %s has no %s", rel.Tag, n)
+		}
+	}
+
+	sums, err := download(ctx, assets["SHA256SUMS"])
+	if err != nil {
+		return "", err
+	}
+	sig, err := download(ctx, assets["SHA256SUMS.sig"])
+	if err != nil {
+		return "", err
+	}
+	if raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig))); err == nil {
+		sig = raw
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), sums, sig) {
+		return "", errors.New("SHA256SUMS signature does not verify")
+	}
+	want, ok := checksum(sums, name)
+	if !ok {
+		return "", fmt.Errorf("SHA256SUMS has no entry for %s", name)
+	}
+
+	bin, err := download(ctx, assets[name])
+	if err != nil {
+		return "", err
+	}
+	if got := sha256.Sum256(bin); hex.EncodeToString(got[:]) != want {
+		return "", fmt.Errorf("%s checksum mismatch", name)
+	}
+	return replaceExecutable(bin)
+}
+
+// checksum finds name in sha256sum(1) output.
+func checksum(sums []byte, name string) (string, bool) {
+	for _, line := range strings.Split(string(sums), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 2 && strings.TrimPrefix(f[1], "*") == name {
+			return strings.ToLower(f[0]), true
+		}
+	}
+	return "", false
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, &remote.HTTPError{Status: resp.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownload {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxDownload)
+	}
+	return data, nil
+}
+
+// replaceExecutable writes bin next to the running executable and renames
+// it into place, so a failed write leaves the old binary intact.
+func replaceExecutable(bin []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, bin, 0o755); err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be replaced, only renamed.
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return exe, nil
+}
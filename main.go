@@ -1,13 +1,224 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"strings"
 
+	"github.com/rivethorn/envoy/internal/completion"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/lint"
+	"github.com/rivethorn/envoy/internal/logging"
+	"github.com/rivethorn/envoy/internal/remote"
+	"github.com/rivethorn/envoy/internal/secmem"
 	"github.com/rivethorn/envoy/internal/ui"
+	"github.com/rivethorn/envoy/internal/update"
 )
 
+// sourceFlag appends to a shared, ordered source list so that -layer and
+// -remote keep their relative order on the command line.
+type sourceFlag struct {
+	list   *[]ui.Source
+	remote bool
+}
+
+func (f sourceFlag) String() string {
+	if f.list == nil {
+		return ""
+	}
+	var specs []string
+	for _, s := range *f.list {
+		specs = append(specs, s.Spec)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (f sourceFlag) Set(v string) error {
+	*f.list = append(*f.list, ui.Source{Spec: v, Remote: f.remote})
+	return nil
+}
+
 func main() {
-	if err := ui.Run(); err != nil {
+	var sources []ui.Source
+	layer := sourceFlag{list: &sources}
+	remote := sourceFlag{list: &sources, remote: true}
+	flag.Var(layer, "layer", "import `file` as a layer over the process environment (repeatable)")
+	flag.Var(layer, "l", "shorthand for -layer")
+	flag.Var(remote, "remote", "pull `provider:path` as a layer (repeatable)")
+	flag.Var(remote, "r", "shorthand for -remote")
+	noColor := flag.Bool("no-color", false, "draw without color (also set by NO_COLOR)")
+	accessible := flag.Bool("accessible", false, "describe the selection and announce changes in the status line")
+	announce := flag.String("announce", "", "also write announcements to `file` or named pipe (implies -accessible)")
+	recoverFlag := flag.Bool("recover", false, "replay unsaved changes journaled by a crashed session")
+	logLevel := flag.String("log-level", "info", "log `level`: debug, info, warn or error")
+	logFile := flag.String("log-file", "", "log to `file` (default "+logging.DefaultPath()+")")
+	flag.Parse()
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
 		log.Fatal(err)
 	}
+	if closer, err := logging.Setup(*logFile, level); err != nil {
+		log.Printf("logging disabled: %v", err)
+		logging.Discard()
+	} else {
+		defer closer.Close()
+	}
+	slog.Info("start", "version", update.Current(), "args", os.Args[1:])
+	if err := secmem.DisableCoreDumps(); err != nil {
+		slog.Warn("core dumps stay enabled", "err", err)
+	}
+	defer secmem.Purge()
+
+	switch flag.Arg(0) {
+	case "version":
+		fmt.Print(update.Info())
+		return
+	case "completion":
+		if err := completion.Write(os.Stdout, flag.Arg(1), completionSpec()); err != nil {
+			fatal("completion", err)
+		}
+		return
+	case "self-update":
+		if err := selfUpdate(); err != nil {
+			fatal("self-update", err)
+		}
+		return
+	case "check":
+		n, err := check(sources, flag.Args()[1:])
+		if err != nil {
+			fatal("check", err)
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+		return
+	case "snapshot":
+		if err := snapshotCommand(flag.Args()[1:]); err != nil {
+			fatal("snapshot", err)
+		}
+		return
+	case "run":
+		if err := runCommand(sources, flag.Args()[1:]); err != nil {
+			fatal("run", err)
+		}
+		return
+	case "exec":
+		if err := execCommand(sources, flag.Args()[1:]); err != nil {
+			fatal("exec", err)
+		}
+		return
+	}
+
+	opts := ui.Options{Sources: sources, Recover: *recoverFlag, NoColor: *noColor,
+		Accessible: *accessible, Announce: *announce}
+	if flag.Arg(0) == "wrap" {
+		argv := flag.Args()[1:]
+		if len(argv) > 0 && argv[0] == "--" {
+			argv = argv[1:]
+		}
+		if len(argv) == 0 {
+			fatal("wrap", fmt.Errorf("usage: envoy wrap -- <cmd> [args...]"))
+		}
+		opts.Wrap = argv
+	} else {
+		// Any other arguments are env files, each opened in a buffer.
+		opts.Files = flag.Args()
+	}
+	if err := ui.Run(opts); err != nil {
+		fatal("exit", err)
+	}
+}
+
+func selfUpdate() error {
+	if update.Current() == "dev" {
+		return fmt.Errorf("development builds cannot self-update; install a release")
+	}
+	ctx := context.Background()
+	rel, err := update.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !update.Newer(rel.Tag, update.Current()) {
+		fmt.Printf("envoy %s is up to date (latest release %s)\n", update.Current(), rel.Tag)
+		return nil
+	}
+	fmt.Printf("Updating envoy %s to %s...\n", update.Current(), rel.Tag)
+	path, err := update.Install(ctx, rel)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s\n", path)
+	return nil
+}
+
+// check lints the given env files merged in order, or the process
+// environment with any -layer files over it, printing one line per finding.
+// It returns the number of error findings.
+func check(sources []ui.Source, files []string) (int, error) {
+	rs, err := lint.Default()
+	if err != nil {
+		return 0, err
+	}
+	store := env.NewStore()
+	if len(files) > 0 {
+		store = env.NewStoreFrom(nil)
+	} else {
+		for _, s := range sources {
+			if s.Remote {
+				return 0, fmt.Errorf("check does not fetch remote layers (%s)", s.Spec)
+			}
+			files = append(files, s.Spec)
+		}
+	}
+	for _, f := range files {
+		if _, err := store.Import(f); err != nil {
+			return 0, err
+		}
+	}
+
+	findings := rs.Check(store.Vars())
+	for _, f := range findings {
+		fmt.Printf("%s: %s [%s] %s\n", f.Severity, f.Key, f.Rule, f.Message)
+	}
+	errs := lint.Errors(findings)
+	fmt.Fprintf(os.Stderr, "%d findings, %d errors (%d rules)\n", len(findings), errs, rs.Len())
+	return errs, nil
+}
+
+func completionSpec() completion.Spec {
+	return completion.Spec{
+		Commands: []completion.Command{
+			{Name: "version", Usage: "print build information"},
+			{Name: "self-update", Usage: "install the latest release"},
+			{Name: "completion", Usage: "print a bash, fish or zsh completion script"},
+			{Name: "check", Usage: "lint env files or the environment against the rules"},
+			{Name: "snapshot", Usage: "save, list or diff snapshots of the environment"},
+			{Name: "run", Usage: "ask for missing required variables, then run a command"},
+			{Name: "exec", Usage: "run a command with the variables of chamber services"},
+			{Name: "wrap", Usage: "launch a command and relaunch it with edited variables"},
+		},
+		Flags: completion.FromFlagSet(flag.CommandLine, map[string]completion.Kind{
+			"layer": completion.EnvFile, "l": completion.EnvFile,
+			"remote": completion.Remote, "r": completion.Remote,
+			"log-level": completion.Level,
+			"log-file":  completion.File,
+			"announce":  completion.File,
+		}),
+		Providers: remote.Names(),
+		Levels:    logging.Levels,
+	}
+}
+
+// fatal logs err and exits. Once logging is set up the log package writes
+// to the log file, so the message is echoed to stderr directly.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	fmt.Fprintln(os.Stderr, "envoy:", err)
+	secmem.Purge()
+	os.Exit(1)
 }
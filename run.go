@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/cred"
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/lint"
+	"github.com/rivethorn/envoy/internal/ui"
+	"golang.org/x/term"
+)
+
+const runUsage = "usage: envoy [-layer file]... run [-save file] [-no-prompt] [-no-scan] -- <cmd> [args...]"
+
+// runVar is an unset variable "envoy run" asks for, and why.
+type runVar struct {
+	Key, Reason string
+	Required    bool // an error-level lint rule requires it
+}
+
+// runCommand runs "envoy run": it builds the environment from the process
+// and the -layer files, asks on the terminal for the variables the lint
+// rules require or the code reads that are unset, optionally appends the
+// answers to a layer file, and executes the command in that environment.
+func runCommand(sources []ui.Source, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	save := fs.String("save", "", "append the answers to `file` instead of asking where")
+	noPrompt := fs.Bool("no-prompt", false, "fail on missing required variables instead of asking")
+	noScan := fs.Bool("no-scan", false, "ask only for variables the lint rules name, not those the code reads")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	argv := fs.Args()
+	if len(argv) == 0 {
+		return errors.New(runUsage)
+	}
+
+	store := env.NewStore()
+	var layers []string
+	for _, s := range sources {
+		if s.Remote {
+			return fmt.Errorf("run does not fetch remote layers (%s)", s.Spec)
+		}
+		if _, err := store.Import(s.Spec); err != nil {
+			return err
+		}
+		layers = append(layers, s.Spec)
+	}
+	vars := store.Vars()
+	missing, err := missingVars(vars, !*noScan)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		if *noPrompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+			var keys []string
+			for _, m := range missing {
+				if m.Required {
+					keys = append(keys, m.Key)
+				}
+			}
+			if len(keys) > 0 {
+				return fmt.Errorf("missing required variables: %s", strings.Join(keys, ", "))
+			}
+		} else {
+			p := &prompter{in: bufio.NewReader(os.Stdin)}
+			answers, err := p.askMissing(missing)
+			if err != nil {
+				return err
+			}
+			if len(answers) > 0 {
+				if err := p.saveAnswers(answers, *save, layers); err != nil {
+					return err
+				}
+			}
+			for _, it := range answers {
+				vars[it.Key] = it.Value
+			}
+		}
+	}
+
+	for k, v := range vars {
+		vars[k], _ = store.Resolve(v)
+		if cred.IsRef(vars[k]) {
+			if vars[k], err = cred.ResolveRef(vars[k]); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	environ := make([]string, 0, len(vars))
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		environ = append(environ, k+"="+vars[k])
+	}
+	// The command is looked up on the PATH it will run with.
+	if path, ok := vars["PATH"]; ok {
+		os.Setenv("PATH", path)
+	}
+	return execve(argv, environ)
+}
+
+// missingVars lists the variables unset in vars that the lint rules flag,
+// then, with scan, those the code in the working directory reads. A
+// variable the code reads counts as set when it is present, even empty.
+func missingVars(vars map[string]string, scan bool) ([]runVar, error) {
+	rs, err := lint.Default()
+	if err != nil {
+		return nil, err
+	}
+	var out []runVar
+	seen := make(map[string]bool)
+	for _, f := range rs.Check(vars) {
+		if vars[f.Key] != "" {
+			continue
+		}
+		if seen[f.Key] {
+			i := slices.IndexFunc(out, func(m runVar) bool { return m.Key == f.Key })
+			out[i].Required = out[i].Required || f.Severity == lint.Error
+			continue
+		}
+		seen[f.Key] = true
+		out = append(out, runVar{Key: f.Key, Reason: f.Message, Required: f.Severity == lint.Error})
+	}
+	if scan {
+		for _, k := range env.UsedKeys(".") {
+			if _, ok := vars[k]; ok || seen[k] {
+				continue
+			}
+			out = append(out, runVar{Key: k, Reason: "read by the code"})
+		}
+	}
+	return out, nil
+}
+
+// prompter asks questions on the terminal. Prompts go to stderr so the
+// command's own output is left alone.
+type prompter struct {
+	in *bufio.Reader
+}
+
+func (p *prompter) ask(question string) (string, error) {
+	fmt.Fprint(os.Stderr, question)
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("cancelled")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// askHidden reads an answer without echoing it.
+func (p *prompter) askHidden(question string) (string, error) {
+	fmt.Fprint(os.Stderr, question)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.New("cancelled")
+	}
+	return string(b), nil
+}
+
+// askMissing asks for each required variable, then offers to fill in the
+// rest. Secret-sounding variables are read without echo; an empty answer
+// leaves the variable unset.
+func (p *prompter) askMissing(missing []runVar) ([]env.Item, error) {
+	var required, optional []runVar
+	for _, m := range missing {
+		if m.Required {
+			required = append(required, m)
+		} else {
+			optional = append(optional, m)
+		}
+	}
+	todo := required
+	if len(required) > 0 {
+		fmt.Fprintf(os.Stderr, "%d required variables are unset.\n", len(required))
+	}
+	if len(optional) > 0 {
+		var keys []string
+		for _, m := range optional {
+			keys = append(keys, m.Key)
+		}
+		a, err := p.ask(fmt.Sprintf("%d more are unset that the rules suggest or the code reads: %s\nFill them in? [y/N] ",
+			len(optional), strings.Join(keys, ", ")))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.ToLower(a), "y") {
+			todo = append(todo, optional...)
+		}
+	}
+
+	var out []env.Item
+	for _, m := range todo {
+		question := fmt.Sprintf("%s (%s): ", m.Key, m.Reason)
+		ask := p.ask
+		if env.SecretKey(m.Key) {
+			ask = p.askHidden
+		}
+		v, err := ask(question)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			out = append(out, env.Item{Key: m.Key, Value: v})
+		}
+	}
+	return out, nil
+}
+
+// saveAnswers appends answers to path, or, when path is empty, to the
+// layer file or path the user picks; they may also decline.
+func (p *prompter) saveAnswers(answers []env.Item, path string, layers []string) error {
+	if path == "" {
+		if len(layers) == 0 {
+			layers = []string{".env"}
+		}
+		var opts []string
+		for i, l := range layers {
+			opts = append(opts, fmt.Sprintf("[%d] %s", i+1, l))
+		}
+		a, err := p.ask(fmt.Sprintf("Save the answers to %s, another file, or Enter to not save: ", strings.Join(opts, " ")))
+		if err != nil {
+			return err
+		}
+		if path = strings.TrimSpace(a); path == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(path); err == nil && n >= 1 && n <= len(layers) {
+			path = layers[n-1]
+		}
+	}
+	if err := env.AppendDotenv(path, answers); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved %d variables to %s\n", len(answers), path)
+	return nil
+}
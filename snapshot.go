@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rivethorn/envoy/internal/env"
+	"github.com/rivethorn/envoy/internal/snapshot"
+)
+
+// snapshotNow names the current environment in "snapshot diff".
+const snapshotNow = "now"
+
+const snapshotUsage = "usage: envoy snapshot save [name] | list | diff <a> [b|now]"
+
+// snapshotCommand runs "envoy snapshot save|list|diff".
+func snapshotCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(snapshotUsage)
+	}
+	dir := snapshot.Dir()
+	switch args[0] {
+	case "save":
+		if len(args) > 2 {
+			return errors.New(snapshotUsage)
+		}
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		path, err := snapshot.Save(dir, snapshot.New(name, os.Environ()))
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+	case "list", "ls":
+		all, err := snapshot.List(dir)
+		if err != nil {
+			return err
+		}
+		for _, s := range all {
+			fmt.Printf("%-40s %4d vars  %s\n", s.Label(), len(s.Vars), s.Path)
+		}
+	case "diff":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New(snapshotUsage)
+		}
+		b := snapshotNow
+		if len(args) == 3 {
+			b = args[2]
+		}
+		from, err := findSnapshot(dir, args[1])
+		if err != nil {
+			return err
+		}
+		to, err := findSnapshot(dir, b)
+		if err != nil {
+			return err
+		}
+		printSnapshotDiff(from, to)
+	default:
+		return errors.New(snapshotUsage)
+	}
+	return nil
+}
+
+func findSnapshot(dir, ref string) (snapshot.Snapshot, error) {
+	if ref == snapshotNow {
+		s := snapshot.New("", os.Environ())
+		s.Name = snapshotNow
+		return s, nil
+	}
+	return snapshot.Find(dir, ref)
+}
+
+// printSnapshotDiff prints one line per changed variable, listing the
+// entries of PATH-like values rather than both whole values. Secret-looking
+// values are masked.
+func printSnapshotDiff(from, to snapshot.Snapshot) {
+	mask := env.Redaction{Secrets: true, URLs: true}
+	show := func(k, v string) string {
+		v, _ = mask.Apply(k, v)
+		return v
+	}
+	sep := string(os.PathListSeparator)
+
+	fmt.Printf("--- %s\n+++ %s\n", from.Label(), to.Label())
+	changes := snapshot.Diff(from.Vars, to.Vars)
+	for _, c := range changes {
+		switch c.Kind {
+		case snapshot.Added:
+			fmt.Printf("+ %s=%s\n", c.Key, show(c.Key, c.New))
+		case snapshot.Removed:
+			fmt.Printf("- %s=%s\n", c.Key, show(c.Key, c.Old))
+		case snapshot.Changed:
+			removed, added, ok := snapshot.ListDiff(c.Old, c.New, sep)
+			if !ok {
+				fmt.Printf("~ %s: %s -> %s\n", c.Key, show(c.Key, c.Old), show(c.Key, c.New))
+				continue
+			}
+			if len(removed) == 0 && len(added) == 0 {
+				fmt.Printf("~ %s: entries reordered\n", c.Key)
+				continue
+			}
+			fmt.Printf("~ %s:\n", c.Key)
+			for _, e := range removed {
+				fmt.Printf("    - %s\n", e)
+			}
+			for _, e := range added {
+				fmt.Printf("    + %s\n", e)
+			}
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Println("no differences")
+		return
+	}
+	var counts []string
+	for kind, label := range []string{"added", "removed", "changed"} {
+		n := 0
+		for _, c := range changes {
+			if int(c.Kind) == kind {
+				n++
+			}
+		}
+		counts = append(counts, fmt.Sprintf("%d %s", n, label))
+	}
+	fmt.Fprintln(os.Stderr, strings.Join(counts, ", "))
+}